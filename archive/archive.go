@@ -0,0 +1,154 @@
+// package archive reads files stored in zip, gzip and 7z archives.
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound is returned when reading a file which isn't in the archive.
+var ErrNotFound = errors.New("file not found in archive")
+
+// Entry is a file stored in an archive.
+type Entry struct {
+	Name string // path of the file inside the archive
+	Size int64  // uncompressed size
+}
+
+type reader interface {
+	entries() []Entry
+	read(name string) ([]byte, error)
+}
+
+// IsArchive reports whether path is an archive, based on its extension.
+func IsArchive(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".zip", ".gz", ".7z":
+		return true
+	}
+	return false
+}
+
+// List returns the files stored in the archive at path.
+func List(path string) ([]Entry, error) {
+	r, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	return r.entries(), nil
+}
+
+// ReadFile returns the content of the file with the given name, stored in the
+// archive at path.
+func ReadFile(path, name string) ([]byte, error) {
+	r, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	return r.read(name)
+}
+
+func open(path string) (reader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var r reader
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".zip":
+		r, err = openZip(buf)
+	case ".gz":
+		r, err = openGzip(buf, path)
+	case ".7z":
+		r, err = openSevenZip(buf)
+	default:
+		return nil, fmt.Errorf("unsupported archive format: %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return r, nil
+}
+
+type zipArchive struct {
+	zr *zip.Reader
+}
+
+func openZip(buf []byte) (*zipArchive, error) {
+	zr, err := zip.NewReader(bytes.NewReader(buf), int64(len(buf)))
+	if err != nil {
+		return nil, err
+	}
+	return &zipArchive{zr: zr}, nil
+}
+
+func (a *zipArchive) entries() []Entry {
+	var entries []Entry
+	for _, zf := range a.zr.File {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+		entries = append(entries, Entry{Name: zf.Name, Size: int64(zf.UncompressedSize64)})
+	}
+	return entries
+}
+
+func (a *zipArchive) read(name string) ([]byte, error) {
+	for _, zf := range a.zr.File {
+		if zf.Name != name {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// gzipArchive holds a single file. Its name is the one stored in the gzip
+// header, if any, or the archive name without the .gz extension.
+type gzipArchive struct {
+	name string
+	data []byte
+}
+
+func openGzip(buf []byte, path string) (*gzipArchive, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	name := zr.Name
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return &gzipArchive{name: name, data: data}, nil
+}
+
+func (a *gzipArchive) entries() []Entry {
+	return []Entry{{Name: a.name, Size: int64(len(a.data))}}
+}
+
+func (a *gzipArchive) read(name string) ([]byte, error) {
+	if name != a.name {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return a.data, nil
+}
@@ -0,0 +1,175 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// romData returns n bytes of test data, the same as the one stored in the
+// testdata 7z archives.
+func romData(n int) []byte {
+	buf := make([]byte, n)
+	for i := range buf {
+		buf[i] = uint8((i / 3) ^ (i * i >> 7) ^ (i >> 9))
+	}
+	return buf
+}
+
+var testFiles = []struct {
+	name string
+	data []byte
+}{
+	{"game.nes", romData(6000)},
+	{"docs/readme.txt", bytes.Repeat([]byte("nestor archive test\n"), 8)},
+}
+
+func writeZip(t *testing.T, path string) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range testFiles {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(f.data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchives(t *testing.T) {
+	dir := t.TempDir()
+	writeZip(t, filepath.Join(dir, "roms.zip"))
+
+	want := []Entry{
+		{Name: "game.nes", Size: 6000},
+		{Name: "docs/readme.txt", Size: 160},
+	}
+
+	for _, path := range []string{
+		filepath.Join(dir, "roms.zip"),
+		filepath.Join("testdata", "lzma.7z"),
+		filepath.Join("testdata", "lzma2.7z"),
+	} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			entries, err := List(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, entries); diff != "" {
+				t.Fatalf("List mismatch (-want +got):\n%s", diff)
+			}
+
+			for _, f := range testFiles {
+				data, err := ReadFile(path, f.name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(data, f.data) {
+					t.Errorf("%s: content mismatch", f.name)
+				}
+			}
+
+			if _, err := ReadFile(path, "missing.nes"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("ReadFile(missing) error = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestGzip(t *testing.T) {
+	dir := t.TempDir()
+	data := testFiles[0].data
+
+	// Without a name in the gzip header, the file is named after the archive.
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	path := filepath.Join(dir, "Game (U).nes.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := List(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]Entry{{Name: "Game (U).nes", Size: int64(len(data))}}, entries); diff != "" {
+		t.Fatalf("List mismatch (-want +got):\n%s", diff)
+	}
+	got, err := ReadFile(path, "Game (U).nes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("content mismatch")
+	}
+}
+
+func TestCorrupted7z(t *testing.T) {
+	buf, err := os.ReadFile(filepath.Join("testdata", "lzma.7z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Flip bytes in the compressed data, decoding must fail or produce
+	// different data, but never panic.
+	for off := 32; off < 32+200; off += 7 {
+		corrupted := bytes.Clone(buf)
+		corrupted[off] ^= 0x5A
+		path := filepath.Join(t.TempDir(), "corrupted.7z")
+		if err := os.WriteFile(path, corrupted, 0644); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ReadFile(path, "game.nes")
+		if err == nil && bytes.Equal(data, testFiles[0].data) {
+			t.Errorf("offset %d: corruption not detected", off)
+		}
+	}
+
+	// Truncated archives.
+	for _, n := range []int{0, 16, 32, len(buf) / 2, len(buf) - 1} {
+		path := filepath.Join(t.TempDir(), "truncated.7z")
+		if err := os.WriteFile(path, buf[:n], 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := List(path); err == nil {
+			t.Errorf("List succeeded on archive truncated to %d bytes", n)
+		}
+	}
+}
+
+func FuzzOpenSevenZip(f *testing.F) {
+	for _, name := range []string{"lzma.7z", "lzma2.7z"} {
+		buf, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(buf)
+	}
+
+	f.Fuzz(func(t *testing.T, buf []byte) {
+		sz, err := openSevenZip(buf)
+		if err != nil {
+			return
+		}
+		for _, e := range sz.entries() {
+			if e.Size < 0 {
+				t.Errorf("%s: negative size %d", e.Name, e.Size)
+			}
+			sz.read(e.Name)
+		}
+	})
+}
@@ -0,0 +1,476 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+)
+
+var errCorrupted = errors.New("corrupted lzma data")
+
+// rangeDecoder is the LZMA range decoder.
+type rangeDecoder struct {
+	in    []byte
+	pos   int
+	rng   uint32
+	code  uint32
+	inErr bool // tried to read past the end of input
+}
+
+const (
+	numBitModelTotalBits = 11
+	bitModelTotal        = 1 << numBitModelTotalBits
+	numMoveBits          = 5
+	topValue             = 1 << 24
+	probInit             = bitModelTotal / 2
+)
+
+func (rc *rangeDecoder) init(in []byte) error {
+	*rc = rangeDecoder{in: in, rng: 0xFFFFFFFF}
+	if rc.readByte() != 0 {
+		return errCorrupted
+	}
+	for range 4 {
+		rc.code = rc.code<<8 | uint32(rc.readByte())
+	}
+	if rc.code == rc.rng {
+		return errCorrupted
+	}
+	return nil
+}
+
+func (rc *rangeDecoder) readByte() uint8 {
+	if rc.pos >= len(rc.in) {
+		rc.inErr = true
+		return 0
+	}
+	b := rc.in[rc.pos]
+	rc.pos++
+	return b
+}
+
+func (rc *rangeDecoder) normalize() {
+	if rc.rng < topValue {
+		rc.rng <<= 8
+		rc.code = rc.code<<8 | uint32(rc.readByte())
+	}
+}
+
+func (rc *rangeDecoder) decodeDirectBits(n int) uint32 {
+	var res uint32
+	for ; n > 0; n-- {
+		rc.rng >>= 1
+		rc.code -= rc.rng
+		t := 0 - (rc.code >> 31)
+		rc.code += rc.rng & t
+		rc.normalize()
+		res = res<<1 + t + 1
+	}
+	return res
+}
+
+func (rc *rangeDecoder) decodeBit(prob *uint16) uint32 {
+	v := uint32(*prob)
+	bound := (rc.rng >> numBitModelTotalBits) * v
+	var bit uint32
+	if rc.code < bound {
+		v += (bitModelTotal - v) >> numMoveBits
+		rc.rng = bound
+	} else {
+		v -= v >> numMoveBits
+		rc.code -= bound
+		rc.rng -= bound
+		bit = 1
+	}
+	*prob = uint16(v)
+	rc.normalize()
+	return bit
+}
+
+func initProbs(probs []uint16) {
+	for i := range probs {
+		probs[i] = probInit
+	}
+}
+
+// bitTreeDecode decodes a numBits symbol from the probabilities tree, which
+// has 1<<numBits entries.
+func (rc *rangeDecoder) bitTreeDecode(probs []uint16, numBits int) uint32 {
+	m := uint32(1)
+	for range numBits {
+		m = m<<1 + rc.decodeBit(&probs[m])
+	}
+	return m - 1<<numBits
+}
+
+func (rc *rangeDecoder) bitTreeReverseDecode(probs []uint16, numBits int) uint32 {
+	m, sym := uint32(1), uint32(0)
+	for i := range numBits {
+		bit := rc.decodeBit(&probs[m])
+		m = m<<1 + bit
+		sym |= bit << i
+	}
+	return sym
+}
+
+const (
+	numPosBitsMax = 4
+	numStates     = 12
+
+	numLenToPosStates = 4
+	numAlignBits      = 4
+	endPosModelIdx    = 14
+	numFullDistances  = 1 << (endPosModelIdx >> 1)
+	matchMinLen       = 2
+)
+
+type lenDecoder struct {
+	choice  uint16
+	choice2 uint16
+	low     [1 << numPosBitsMax][1 << 3]uint16
+	mid     [1 << numPosBitsMax][1 << 3]uint16
+	high    [1 << 8]uint16
+}
+
+func (ld *lenDecoder) init() {
+	ld.choice, ld.choice2 = probInit, probInit
+	for i := range ld.low {
+		initProbs(ld.low[i][:])
+		initProbs(ld.mid[i][:])
+	}
+	initProbs(ld.high[:])
+}
+
+func (ld *lenDecoder) decode(rc *rangeDecoder, posState uint32) uint32 {
+	if rc.decodeBit(&ld.choice) == 0 {
+		return rc.bitTreeDecode(ld.low[posState][:], 3)
+	}
+	if rc.decodeBit(&ld.choice2) == 0 {
+		return 8 + rc.bitTreeDecode(ld.mid[posState][:], 3)
+	}
+	return 16 + rc.bitTreeDecode(ld.high[:], 8)
+}
+
+// lzmaDecoder decodes LZMA streams. The whole output is kept in memory and is
+// used as the dictionary.
+type lzmaDecoder struct {
+	lc, lp, pb uint
+
+	out []byte
+	// dictStart is the position, in out, of the start of the dictionary. It's
+	// moved forward by LZMA2 dictionary resets.
+	dictStart int
+
+	rc rangeDecoder
+
+	literals   []uint16
+	posSlot    [numLenToPosStates][1 << 6]uint16
+	posDecoder [1 + numFullDistances - endPosModelIdx]uint16
+	align      [1 << numAlignBits]uint16
+	lenDec     lenDecoder
+	repLenDec  lenDecoder
+
+	isMatch    [numStates << numPosBitsMax]uint16
+	isRep      [numStates]uint16
+	isRepG0    [numStates]uint16
+	isRepG1    [numStates]uint16
+	isRepG2    [numStates]uint16
+	isRep0Long [numStates << numPosBitsMax]uint16
+
+	state                  uint32
+	rep0, rep1, rep2, rep3 uint32
+}
+
+// setProps decodes the lc/lp/pb properties byte.
+func (d *lzmaDecoder) setProps(props uint8) error {
+	if props >= 9*5*5 {
+		return fmt.Errorf("invalid lzma properties: %#02x", props)
+	}
+	d.lc = uint(props % 9)
+	props /= 9
+	d.lp = uint(props % 5)
+	d.pb = uint(props / 5)
+	return nil
+}
+
+// reset resets the decoder state and probabilities.
+func (d *lzmaDecoder) reset() {
+	d.literals = make([]uint16, 0x300<<(d.lc+d.lp))
+	initProbs(d.literals)
+	for i := range d.posSlot {
+		initProbs(d.posSlot[i][:])
+	}
+	initProbs(d.posDecoder[:])
+	initProbs(d.align[:])
+	d.lenDec.init()
+	d.repLenDec.init()
+	initProbs(d.isMatch[:])
+	initProbs(d.isRep[:])
+	initProbs(d.isRepG0[:])
+	initProbs(d.isRepG1[:])
+	initProbs(d.isRepG2[:])
+	initProbs(d.isRep0Long[:])
+	d.state = 0
+	d.rep0, d.rep1, d.rep2, d.rep3 = 0, 0, 0, 0
+}
+
+// byteAt returns the byte at the given distance (1 is the last byte) back
+// from the end of output.
+func (d *lzmaDecoder) byteAt(dist uint32) uint8 {
+	return d.out[len(d.out)-int(dist)]
+}
+
+func (d *lzmaDecoder) dictLen() int {
+	return len(d.out) - d.dictStart
+}
+
+func (d *lzmaDecoder) decodeLiteral() {
+	var prevByte uint32
+	if d.dictLen() > 0 {
+		prevByte = uint32(d.byteAt(1))
+	}
+
+	pos := uint32(len(d.out) - d.dictStart)
+	litState := ((pos & (1<<d.lp - 1)) << d.lc) + prevByte>>(8-d.lc)
+	probs := d.literals[0x300*litState:]
+
+	symbol := uint32(1)
+	if d.state >= 7 {
+		matchByte := uint32(d.byteAt(d.rep0 + 1))
+		for symbol < 0x100 {
+			matchBit := (matchByte >> 7) & 1
+			matchByte <<= 1
+			bit := d.rc.decodeBit(&probs[((1+matchBit)<<8)+symbol])
+			symbol = symbol<<1 | bit
+			if matchBit != bit {
+				break
+			}
+		}
+	}
+	for symbol < 0x100 {
+		symbol = symbol<<1 | d.rc.decodeBit(&probs[symbol])
+	}
+	d.out = append(d.out, uint8(symbol))
+}
+
+func (d *lzmaDecoder) decodeDistance(length uint32) uint32 {
+	lenState := min(length, numLenToPosStates-1)
+	posSlot := d.rc.bitTreeDecode(d.posSlot[lenState][:], 6)
+	if posSlot < 4 {
+		return posSlot
+	}
+	numDirectBits := int(posSlot>>1) - 1
+	dist := (2 | posSlot&1) << numDirectBits
+	if posSlot < endPosModelIdx {
+		return dist + d.rc.bitTreeReverseDecode(d.posDecoder[dist-posSlot:], numDirectBits)
+	}
+	dist += d.rc.decodeDirectBits(numDirectBits-numAlignBits) << numAlignBits
+	return dist + d.rc.bitTreeReverseDecode(d.align[:], numAlignBits)
+}
+
+// decode decodes the LZMA data in, until size bytes have been appended to the
+// output or the end marker has been found.
+func (d *lzmaDecoder) decode(in []byte, size int) error {
+	if err := d.rc.init(in); err != nil {
+		return err
+	}
+
+	end := len(d.out) + size
+	pbMask := uint32(1)<<d.pb - 1
+	for len(d.out) < end {
+		if d.rc.inErr {
+			return errCorrupted
+		}
+		posState := uint32(d.dictLen()) & pbMask
+
+		if d.rc.decodeBit(&d.isMatch[d.state<<numPosBitsMax+posState]) == 0 {
+			d.decodeLiteral()
+			switch {
+			case d.state < 4:
+				d.state = 0
+			case d.state < 10:
+				d.state -= 3
+			default:
+				d.state -= 6
+			}
+			continue
+		}
+
+		var length uint32
+		if d.rc.decodeBit(&d.isRep[d.state]) != 0 {
+			if int(d.rep0) >= d.dictLen() {
+				return errCorrupted
+			}
+			if d.rc.decodeBit(&d.isRepG0[d.state]) == 0 {
+				if d.rc.decodeBit(&d.isRep0Long[d.state<<numPosBitsMax+posState]) == 0 {
+					// Short rep: a single byte at distance rep0.
+					if d.state < 7 {
+						d.state = 9
+					} else {
+						d.state = 11
+					}
+					d.out = append(d.out, d.byteAt(d.rep0+1))
+					continue
+				}
+			} else {
+				var dist uint32
+				if d.rc.decodeBit(&d.isRepG1[d.state]) == 0 {
+					dist = d.rep1
+				} else {
+					if d.rc.decodeBit(&d.isRepG2[d.state]) == 0 {
+						dist = d.rep2
+					} else {
+						dist = d.rep3
+						d.rep3 = d.rep2
+					}
+					d.rep2 = d.rep1
+				}
+				d.rep1 = d.rep0
+				d.rep0 = dist
+				if int(d.rep0) >= d.dictLen() {
+					return errCorrupted
+				}
+			}
+			length = d.repLenDec.decode(&d.rc, posState)
+			if d.state < 7 {
+				d.state = 8
+			} else {
+				d.state = 11
+			}
+		} else {
+			d.rep3, d.rep2, d.rep1 = d.rep2, d.rep1, d.rep0
+			length = d.lenDec.decode(&d.rc, posState)
+			if d.state < 7 {
+				d.state = 7
+			} else {
+				d.state = 10
+			}
+			d.rep0 = d.decodeDistance(length)
+			if d.rep0 == 0xFFFFFFFF {
+				// End marker.
+				break
+			}
+			if int(d.rep0) >= d.dictLen() {
+				return errCorrupted
+			}
+		}
+
+		length += matchMinLen
+		if len(d.out)+int(length) > end {
+			return errCorrupted
+		}
+		for range length {
+			d.out = append(d.out, d.byteAt(d.rep0+1))
+		}
+	}
+	if d.rc.inErr {
+		return errCorrupted
+	}
+	return nil
+}
+
+// outCap returns the initial capacity of the output buffer when decoding in,
+// of the given uncompressed size. The size comes from the archive header, it
+// can't be trusted, so the buffer rather grows with the decoded data.
+func outCap(in []byte, size int) int {
+	return min(size, 16*len(in))
+}
+
+// decodeLZMA decodes a raw LZMA stream (as found in 7z archives) of the given
+// uncompressed size. props holds the lc/lp/pb byte followed by the dictionary
+// size.
+func decodeLZMA(props, in []byte, size int) ([]byte, error) {
+	if len(props) < 1 {
+		return nil, fmt.Errorf("invalid lzma properties")
+	}
+	d := &lzmaDecoder{out: make([]byte, 0, outCap(in, size))}
+	if err := d.setProps(props[0]); err != nil {
+		return nil, err
+	}
+	d.reset()
+	if err := d.decode(in, size); err != nil {
+		return nil, err
+	}
+	if len(d.out) != size {
+		return nil, fmt.Errorf("lzma: got %d bytes, want %d", len(d.out), size)
+	}
+	return d.out, nil
+}
+
+// decodeLZMA2 decodes a LZMA2 stream of the given uncompressed size.
+func decodeLZMA2(in []byte, size int) ([]byte, error) {
+	d := &lzmaDecoder{out: make([]byte, 0, outCap(in, size))}
+	needProps := true
+
+	for {
+		if len(in) < 1 {
+			return nil, errCorrupted
+		}
+		ctrl := in[0]
+		in = in[1:]
+
+		switch {
+		case ctrl == 0x00:
+			if len(d.out) != size {
+				return nil, fmt.Errorf("lzma2: got %d bytes, want %d", len(d.out), size)
+			}
+			return d.out, nil
+
+		case ctrl == 0x01 || ctrl == 0x02:
+			// Uncompressed chunk, 0x01 resets the dictionary.
+			if len(in) < 2 {
+				return nil, errCorrupted
+			}
+			n := int(in[0])<<8 | int(in[1]) + 1
+			in = in[2:]
+			if len(in) < n {
+				return nil, errCorrupted
+			}
+			if ctrl == 0x01 {
+				d.dictStart = len(d.out)
+			}
+			d.out = append(d.out, in[:n]...)
+			in = in[n:]
+
+		case ctrl >= 0x80:
+			if len(in) < 4 {
+				return nil, errCorrupted
+			}
+			unpacked := int(ctrl&0x1F)<<16 | int(in[0])<<8 | int(in[1]) + 1
+			packed := int(in[2])<<8 | int(in[3]) + 1
+			in = in[4:]
+
+			// Bits 5-6: 0 no reset, 1 state reset, 2 state reset and new
+			// properties, 3 dictionary reset too.
+			mode := (ctrl >> 5) & 0x03
+			if mode == 3 {
+				d.dictStart = len(d.out)
+			}
+			if mode >= 2 {
+				if len(in) < 1 {
+					return nil, errCorrupted
+				}
+				if err := d.setProps(in[0]); err != nil {
+					return nil, err
+				}
+				in = in[1:]
+				needProps = false
+			}
+			if needProps {
+				return nil, errCorrupted
+			}
+			if mode >= 1 {
+				d.reset()
+			}
+			if len(in) < packed {
+				return nil, errCorrupted
+			}
+			if err := d.decode(in[:packed], unpacked); err != nil {
+				return nil, err
+			}
+			in = in[packed:]
+
+		default:
+			return nil, fmt.Errorf("lzma2: invalid control byte %#02x", ctrl)
+		}
+	}
+}
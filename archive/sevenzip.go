@@ -0,0 +1,519 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+)
+
+// This is a minimal 7z archive reader, supporting the copy, LZMA and LZMA2
+// methods, which are the ones used to compress roms. Filters (BCJ, etc.),
+// encryption and multi-coder folders are not supported.
+
+const sevenZipMagic = "7z\xBC\xAF\x27\x1C"
+
+// 7z header property IDs.
+const (
+	idEnd                   = 0x00
+	idHeader                = 0x01
+	idArchiveProperties     = 0x02
+	idAdditionalStreamsInfo = 0x03
+	idMainStreamsInfo       = 0x04
+	idFilesInfo             = 0x05
+	idPackInfo              = 0x06
+	idUnpackInfo            = 0x07
+	idSubStreamsInfo        = 0x08
+	idSize                  = 0x09
+	idCRC                   = 0x0A
+	idFolder                = 0x0B
+	idCodersUnpackSize      = 0x0C
+	idNumUnpackStream       = 0x0D
+	idEmptyStream           = 0x0E
+	idEmptyFile             = 0x0F
+	idName                  = 0x11
+	idEncodedHeader         = 0x17
+)
+
+// 7z compression methods.
+var (
+	methodCopy  = []byte{0x00}
+	methodLZMA  = []byte{0x03, 0x01, 0x01}
+	methodLZMA2 = []byte{0x21}
+)
+
+var errBadHeader = errors.New("invalid 7z header")
+
+// maxUnpackSize is the maximum uncompressed size of a folder, way more than
+// the biggest roms.
+const maxUnpackSize = 64 << 20
+
+// szFolder is a 7z folder: a compressed stream, holding one or more files.
+type szFolder struct {
+	method     []byte
+	props      []byte
+	packOff    int64 // offset of the packed stream in the archive
+	packSize   int64
+	unpackSize int64
+
+	// sizes of the files (substreams) stored in this folder.
+	files []int64
+}
+
+type szFile struct {
+	name   string
+	size   int64
+	folder int   // -1 for empty files
+	offset int64 // in the folder unpacked data
+}
+
+type sevenZip struct {
+	buf     []byte
+	folders []szFolder
+	files   []szFile
+}
+
+// szReader reads 7z header structures.
+type szReader struct {
+	buf []byte
+	err error
+}
+
+// fail records err, unless an error has already been recorded.
+func (r *szReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *szReader) byte() uint8 {
+	if len(r.buf) == 0 {
+		r.fail(errBadHeader)
+		return 0
+	}
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b
+}
+
+func (r *szReader) bytes(n int64) []byte {
+	if n < 0 || int64(len(r.buf)) < n {
+		r.fail(errBadHeader)
+		r.buf = nil
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+// number reads a 7z variable length number.
+func (r *szReader) number() int64 {
+	first := r.byte()
+	mask := uint8(0x80)
+	var v uint64
+	for i := range 8 {
+		if first&mask == 0 {
+			high := uint64(first & (mask - 1))
+			v |= high << (8 * i)
+			break
+		}
+		v |= uint64(r.byte()) << (8 * i)
+		mask >>= 1
+	}
+	if v > 1<<40 {
+		// Way too big for a rom archive.
+		r.fail(errBadHeader)
+		return 0
+	}
+	return int64(v)
+}
+
+// count reads a number of items. Since each item takes at least one byte in
+// the header, the count can't exceed the number of bytes left.
+func (r *szReader) count() int {
+	n := r.number()
+	if n > int64(len(r.buf)) {
+		r.fail(errBadHeader)
+		return 0
+	}
+	return int(n)
+}
+
+// bits reads a vector of n booleans.
+func (r *szReader) bits(n int) []bool {
+	v := make([]bool, n)
+	var b, mask uint8
+	for i := range v {
+		if mask == 0 {
+			b, mask = r.byte(), 0x80
+		}
+		v[i] = b&mask != 0
+		mask >>= 1
+	}
+	return v
+}
+
+// skipDigests skips a digests structure of n items.
+func (r *szReader) skipDigests(n int) {
+	defined := make([]bool, n)
+	if r.byte() == 0 {
+		defined = r.bits(n)
+	} else {
+		for i := range defined {
+			defined[i] = true
+		}
+	}
+	for _, d := range defined {
+		if d {
+			r.bytes(4)
+		}
+	}
+}
+
+func openSevenZip(buf []byte) (*sevenZip, error) {
+	if len(buf) < 32 || string(buf[:6]) != sevenZipMagic {
+		return nil, fmt.Errorf("not a 7z archive")
+	}
+	off := 32 + int64(binary.LittleEndian.Uint64(buf[12:]))
+	size := int64(binary.LittleEndian.Uint64(buf[20:]))
+	if off < 32 || size < 0 || off+size > int64(len(buf)) || off+size < off {
+		return nil, errBadHeader
+	}
+
+	sz := &sevenZip{buf: buf}
+	hdr := buf[off : off+size]
+	if len(hdr) == 0 {
+		// Empty archive.
+		return sz, nil
+	}
+
+	// The header may be compressed (encoded), the encoded header describes
+	// the streams holding the real header.
+	for hdr[0] == idEncodedHeader {
+		r := &szReader{buf: hdr[1:]}
+		folders := sz.readStreamsInfo(r)
+		if r.err != nil {
+			return nil, r.err
+		}
+		if len(folders) == 0 {
+			return nil, errBadHeader
+		}
+		var err error
+		if hdr, err = sz.unpack(&folders[0]); err != nil {
+			return nil, fmt.Errorf("failed to decode 7z header: %w", err)
+		}
+		if len(hdr) == 0 {
+			return nil, errBadHeader
+		}
+	}
+	if hdr[0] != idHeader {
+		return nil, errBadHeader
+	}
+
+	r := &szReader{buf: hdr[1:]}
+	if err := sz.readHeader(r); err != nil {
+		return nil, err
+	}
+	return sz, nil
+}
+
+func (sz *sevenZip) readHeader(r *szReader) error {
+	id := r.byte()
+	if id == idArchiveProperties {
+		for r.err == nil && r.byte() != idEnd {
+			r.bytes(r.number())
+		}
+		id = r.byte()
+	}
+	if id == idAdditionalStreamsInfo {
+		sz.readStreamsInfo(r)
+		id = r.byte()
+	}
+	if id == idMainStreamsInfo {
+		sz.folders = sz.readStreamsInfo(r)
+		id = r.byte()
+	}
+	if id == idFilesInfo {
+		sz.readFilesInfo(r)
+		id = r.byte()
+	}
+	if r.err != nil {
+		return r.err
+	}
+	if id != idEnd {
+		return errBadHeader
+	}
+	return nil
+}
+
+func (sz *sevenZip) readStreamsInfo(r *szReader) []szFolder {
+	var (
+		packPos   int64
+		packSizes []int64
+		folders   []szFolder
+	)
+
+	id := r.byte()
+	if id == idPackInfo {
+		packPos = 32 + r.number()
+		npack := r.count()
+		for id = r.byte(); r.err == nil && id != idEnd; id = r.byte() {
+			switch id {
+			case idSize:
+				packSizes = make([]int64, npack)
+				for i := range packSizes {
+					packSizes[i] = r.number()
+				}
+			case idCRC:
+				r.skipDigests(npack)
+			default:
+				r.fail(errBadHeader)
+			}
+		}
+		id = r.byte()
+	}
+
+	if id == idUnpackInfo {
+		if r.byte() != idFolder {
+			r.fail(errBadHeader)
+			return nil
+		}
+		nfolders := r.count()
+		if r.byte() != 0 {
+			r.fail(errors.New("7z: external folders are not supported"))
+			return nil
+		}
+		if nfolders > len(packSizes) {
+			r.fail(errBadHeader)
+			return nil
+		}
+		folders = make([]szFolder, nfolders)
+		for i := range folders {
+			sz.readFolder(r, &folders[i])
+			folders[i].packOff = packPos
+			folders[i].packSize = packSizes[i]
+			folders[i].files = []int64{-1}
+			packPos += packSizes[i]
+		}
+		if r.byte() != idCodersUnpackSize {
+			r.fail(errBadHeader)
+			return nil
+		}
+		for i := range folders {
+			folders[i].unpackSize = r.number()
+			folders[i].files[0] = folders[i].unpackSize
+		}
+		for id = r.byte(); r.err == nil && id != idEnd; id = r.byte() {
+			if id != idCRC {
+				r.fail(errBadHeader)
+				break
+			}
+			r.skipDigests(nfolders)
+		}
+		id = r.byte()
+	}
+
+	if id == idSubStreamsInfo {
+		sz.readSubStreamsInfo(r, folders)
+		id = r.byte()
+	}
+
+	if id != idEnd {
+		r.fail(errBadHeader)
+	}
+	return folders
+}
+
+func (sz *sevenZip) readFolder(r *szReader, f *szFolder) {
+	if r.number() != 1 {
+		r.fail(errors.New("7z: multi-coder compression is not supported"))
+		return
+	}
+	flags := r.byte()
+	f.method = r.bytes(int64(flags & 0x0F))
+	if flags&0x10 != 0 {
+		// Complex coder.
+		if r.number() != 1 || r.number() != 1 {
+			r.fail(errors.New("7z: multi-stream coders are not supported"))
+			return
+		}
+	}
+	if flags&0x20 != 0 {
+		f.props = r.bytes(r.number())
+	}
+}
+
+func (sz *sevenZip) readSubStreamsInfo(r *szReader, folders []szFolder) {
+	id := r.byte()
+	if id == idNumUnpackStream {
+		for i := range folders {
+			folders[i].files = make([]int64, r.count())
+		}
+		id = r.byte()
+	}
+
+	if id == idSize {
+		for i := range folders {
+			f := &folders[i]
+			if len(f.files) == 0 {
+				continue
+			}
+			var sum int64
+			for j := range len(f.files) - 1 {
+				f.files[j] = r.number()
+				sum += f.files[j]
+			}
+			if sum > f.unpackSize {
+				r.fail(errBadHeader)
+				return
+			}
+			f.files[len(f.files)-1] = f.unpackSize - sum
+		}
+		id = r.byte()
+	} else {
+		for i := range folders {
+			if len(folders[i].files) == 1 {
+				folders[i].files[0] = folders[i].unpackSize
+			}
+		}
+	}
+
+	for ; r.err == nil && id != idEnd; id = r.byte() {
+		if id != idCRC {
+			r.fail(errBadHeader)
+			return
+		}
+		n := 0
+		for _, f := range folders {
+			n += len(f.files)
+		}
+		r.skipDigests(n)
+	}
+}
+
+func (sz *sevenZip) readFilesInfo(r *szReader) {
+	nfiles := r.count()
+	var (
+		emptyStream []bool
+		names       []string
+	)
+	for id := r.byte(); r.err == nil && id != idEnd; id = r.byte() {
+		prop := &szReader{buf: r.bytes(r.number())}
+		switch id {
+		case idEmptyStream:
+			emptyStream = prop.bits(nfiles)
+		case idName:
+			if prop.byte() != 0 {
+				r.fail(errors.New("7z: external file names are not supported"))
+				return
+			}
+			names = decodeNames(prop.buf)
+		}
+		// Other properties (empty files, times, attributes, etc.) are ignored.
+		if prop.err != nil {
+			r.fail(prop.err)
+		}
+	}
+	if r.err != nil {
+		return
+	}
+	if len(names) != nfiles {
+		r.fail(errBadHeader)
+		return
+	}
+
+	// Non-empty files are stored in order in the folders substreams.
+	folder, sub := 0, 0
+	var offset int64
+	for i := range nfiles {
+		file := szFile{name: names[i], folder: -1}
+		if emptyStream == nil || !emptyStream[i] {
+			for folder < len(sz.folders) && sub >= len(sz.folders[folder].files) {
+				folder, sub, offset = folder+1, 0, 0
+			}
+			if folder >= len(sz.folders) {
+				r.fail(errBadHeader)
+				return
+			}
+			file.folder = folder
+			file.offset = offset
+			file.size = sz.folders[folder].files[sub]
+			offset += file.size
+			sub++
+		}
+		sz.files = append(sz.files, file)
+	}
+}
+
+// decodeNames decodes null-terminated UTF-16LE file names.
+func decodeNames(buf []byte) []string {
+	var (
+		names []string
+		cur   []uint16
+	)
+	for i := 0; i+1 < len(buf); i += 2 {
+		c := binary.LittleEndian.Uint16(buf[i:])
+		if c == 0 {
+			names = append(names, string(utf16.Decode(cur)))
+			cur = cur[:0]
+			continue
+		}
+		cur = append(cur, c)
+	}
+	return names
+}
+
+// unpack decompresses a folder.
+func (sz *sevenZip) unpack(f *szFolder) ([]byte, error) {
+	if f.packOff+f.packSize > int64(len(sz.buf)) || f.packSize < 0 {
+		return nil, errBadHeader
+	}
+	if f.unpackSize > maxUnpackSize {
+		return nil, fmt.Errorf("7z: unpacked size too big (%d bytes)", f.unpackSize)
+	}
+	packed := sz.buf[f.packOff : f.packOff+f.packSize]
+	size := int(f.unpackSize)
+
+	switch {
+	case bytes.Equal(f.method, methodCopy):
+		if len(packed) < size {
+			return nil, errBadHeader
+		}
+		return packed[:size], nil
+	case bytes.Equal(f.method, methodLZMA):
+		return decodeLZMA(f.props, packed, size)
+	case bytes.Equal(f.method, methodLZMA2):
+		return decodeLZMA2(packed, size)
+	}
+	return nil, fmt.Errorf("7z: unsupported compression method %x", f.method)
+}
+
+func (sz *sevenZip) entries() []Entry {
+	var entries []Entry
+	for _, f := range sz.files {
+		entries = append(entries, Entry{Name: f.name, Size: f.size})
+	}
+	return entries
+}
+
+func (sz *sevenZip) read(name string) ([]byte, error) {
+	for _, f := range sz.files {
+		if f.name != name {
+			continue
+		}
+		if f.folder == -1 {
+			return []byte{}, nil
+		}
+		data, err := sz.unpack(&sz.folders[f.folder])
+		if err != nil {
+			return nil, err
+		}
+		if f.offset+f.size > int64(len(data)) {
+			return nil, errBadHeader
+		}
+		return data[f.offset : f.offset+f.size], nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+}
@@ -5,27 +5,34 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/alecthomas/kong"
 
+	"nestor/emu"
 	"nestor/emu/log"
 )
 
 type mode byte
 
 const (
-	guiMode      mode = iota // Start Nestor GUI
-	runMode                  // Just run a ROM
-	romInfosMode             // Show ROM infos
-	versionMode              // Show Nestor version
-	captureMode              // Show input capture window (hidden option)
+	guiMode         mode = iota // Start Nestor GUI
+	runMode                     // Just run a ROM
+	nsfMode                     // Play a NSF music file
+	romInfosMode                // Show ROM infos
+	versionMode                 // Show Nestor version
+	patchCreateMode             // Create a ROM patch
+	patchApplyMode              // Apply a patch to a ROM
+	captureMode                 // Show input capture window (hidden option)
 )
 
 type (
 	CLI struct {
 		GUI      GUI      `cmd:"" help:"Run Nestor graphical user interface. (default command)" default:"true"`
 		Run      Run      `cmd:"" help:"Run ROM in emulator."`
+		NSF      NSF      `cmd:"" help:"Play NSF and NSFe music files." name:"nsf"`
 		RomInfos RomInfos `cmd:"" help:"Show ROM infos." name:"rom-infos"`
+		Patch    Patch    `cmd:"" help:"Create or apply IPS, BPS and UPS ROM patches."`
 		Version  Version  `cmd:"" help:"Show Nestor version."`
 		Capture  Capture  `cmd:"" hidden:"true"`
 
@@ -41,13 +48,27 @@ type (
 
 	Run struct {
 		RomPath string `arg:"" name:"/path/to/rom" help:"${rompath_help}" required:"true" type:"existingfile"`
+		Entry   string `name:"entry" help:"${entry_help}"`
+		Patch   string `name:"patch" help:"${patch_help}" type:"existingfile"`
 
 		Monitor    int32    `name:"monitor" help:"Monitor index to use." default:"0"`
+		FDSBIOS    string   `name:"fds-bios" help:"${fdsbios_help}" type:"existingfile"`
+		Tape       string   `name:"tape" help:"${tape_help}" type:"path" placeholder:"FILE"`
 		CPUProfile string   `name:"cpuprofile" help:"${cpuprofile_help}" type:"path"`
 		Trace      *outfile `name:"trace" help:"Write CPU trace log." placeholder:"FILE|stdout|stderr"`
 		Port       int      `name:"port" hidden:"true"`
 	}
 
+	NSF struct {
+		Path     string        `arg:"" name:"/path/to/nsf" help:"NSF or NSFe file." type:"existingfile"`
+		Entry    string        `name:"entry" help:"${entry_help}"`
+		Track    int           `name:"track" help:"Track to play, starting at 1. (default: the file starting track)"`
+		WAV      string        `name:"wav" help:"Render the track to a WAV file, without video or audio output." type:"path" placeholder:"FILE"`
+		Duration time.Duration `name:"duration" help:"Duration of the rendered track, before the fade out. (default: from the file, or ${default_duration})"`
+		Fade     time.Duration `name:"fade" help:"Duration of the fade out of the rendered track. (default: from the file, or ${default_fade})"`
+		Monitor  int32         `name:"monitor" help:"Monitor index to use." default:"0"`
+	}
+
 	Capture struct {
 		Button  string `name:"button" hidden:"true" required:""`
 		Monitor int32  `name:"monitor" help:"Monitor index to use." default:"0"`
@@ -55,15 +76,41 @@ type (
 
 	RomInfos struct {
 		RomPath string `arg:"" name:"/path/to/rom" type:"existingfile"`
+		Entry   string `name:"entry" help:"${entry_help}"`
+		Patch   string `name:"patch" help:"${patch_help}" type:"existingfile"`
+	}
+
+	Patch struct {
+		Create PatchCreate `cmd:"" help:"Create a patch from the original and the modified ROM."`
+		Apply  PatchApply  `cmd:"" help:"Apply a patch to a ROM and write the patched ROM."`
+	}
+
+	PatchCreate struct {
+		Orig   string `arg:"" name:"orig" help:"Original ROM." type:"existingfile"`
+		Mod    string `arg:"" name:"modified" help:"Modified ROM." type:"existingfile"`
+		Output string `arg:"" name:"patch" help:"Patch file to create, its format is deduced from its extension, unless --format is set." type:"path"`
+		Format string `name:"format" help:"Patch format: ips, bps or ups."`
+	}
+
+	PatchApply struct {
+		RomPath string `arg:"" name:"rom" help:"ROM to patch." type:"existingfile"`
+		Patch   string `arg:"" name:"patch" help:"IPS, BPS or UPS patch." type:"existingfile"`
+		Output  string `arg:"" name:"output" help:"Patched ROM file to create." type:"path"`
 	}
 
 	Version struct{}
 )
 
 var vars = kong.Vars{
-	"rompath_help":    "Run the ROM directly, skip the graphical user interface.",
-	"cpuprofile_help": "Write CPU profile to file. (only when running a ROM)",
-	"log_help":        "Enable logging for specified modules.",
+	"rompath_help":     "Run the ROM directly, skip the graphical user interface.",
+	"cpuprofile_help":  "Write CPU profile to file. (only when running a ROM)",
+	"fdsbios_help":     "Famicom Disk System BIOS file, overrides the one set in the configuration.",
+	"tape_help":        "WAV file the data recorder plays and records tapes to. (default: named after the ROM)",
+	"log_help":         "Enable logging for specified modules.",
+	"entry_help":       "ROM file to use, when the ROM path is a .zip, .gz or .7z archive.",
+	"patch_help":       "IPS, BPS or UPS patch to apply to the ROM. By default, a patch having the same name as the ROM is applied, if found.",
+	"default_duration": emu.DefaultTrackDuration.String(),
+	"default_fade":     emu.DefaultTrackFade.String(),
 }
 
 func parseArgs(args []string) CLI {
@@ -87,10 +134,16 @@ func parseArgs(args []string) CLI {
 		cfg.mode = guiMode
 	case "capture":
 		cfg.mode = captureMode
+	case "nsf </path/to/nsf>":
+		cfg.mode = nsfMode
 	case "rom-infos </path/to/rom>":
 		cfg.mode = romInfosMode
 	case "version":
 		cfg.mode = versionMode
+	case "patch create <orig> <modified> <patch>":
+		cfg.mode = patchCreateMode
+	case "patch apply <rom> <patch> <output>":
+		cfg.mode = patchApplyMode
 	default:
 		cfg.mode = runMode
 	}
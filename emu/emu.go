@@ -4,32 +4,51 @@ import (
 	"fmt"
 	"image"
 	"io"
+	"os"
 	"path/filepath"
 	"slices"
 	"sync/atomic"
 	"time"
 
 	"nestor/emu/log"
+	"nestor/fds"
 	"nestor/hw"
 	"nestor/hw/input"
 	"nestor/hw/shaders"
 	"nestor/ines"
+	"nestor/nsf"
 )
 
 type Output interface {
 	BeginFrame() hw.Frame
 	EndFrame(hw.Frame)
+	TryEndFrame(hw.Frame) bool
 	Poll() bool
 	Close()
 	Screenshot() *image.RGBA
 }
 
 type Config struct {
-	Input input.Config `toml:"input"`
-	Video VideoConfig  `toml:"video"`
-	Audio AudioConfig  `toml:"audio"`
+	Input   input.Config      `toml:"input"`
+	Video   VideoConfig       `toml:"video"`
+	Audio   AudioConfig       `toml:"audio"`
+	FDS     FDSConfig         `toml:"fds"`
+	Vs      VsConfig          `toml:"vs"`
+	Speed   SpeedConfig       `toml:"speed"`
+	Hotkeys hw.HotkeyBindings `toml:"hotkeys"`
 
 	TraceOut io.WriteCloser `toml:"-"`
+
+	// Path of the file in which the cartridge non-volatile memory is saved.
+	// Nothing is saved if empty.
+	SavePath string `toml:"-"`
+
+	// Path of the WAV file the data recorder plays and records tapes to.
+	TapePath string `toml:"-"`
+
+	// Path, without extension, of the screenshots taken with the screenshot
+	// hotkey. The date and time are appended to it.
+	ScreenshotPath string `toml:"-"`
 }
 
 type VideoConfig struct {
@@ -51,6 +70,16 @@ func (vcfg *VideoConfig) Check() {
 
 type AudioConfig struct {
 	DisableAudio bool `toml:"disable_audio"`
+
+	// Mix Namco 163 channels rather than multiplexing them, this avoids the
+	// high-pitched whine heard in games using many channels.
+	N163MixChannels bool `toml:"n163_mix_channels"`
+}
+
+type FDSConfig struct {
+	// Path to the Famicom Disk System BIOS ROM file (8 KB), required to run
+	// disk images.
+	BIOSPath string `toml:"bios_path"`
 }
 
 type Emulator struct {
@@ -63,6 +92,40 @@ type Emulator struct {
 	reset   atomic.Bool
 	restart atomic.Bool
 
+	// Pending disk operation (Famicom Disk System only).
+	diskreq atomic.Int32
+
+	// Pending track change (NSF only).
+	trackreq atomic.Int32
+
+	// Pending coin insertions and service button presses (Vs. System only).
+	coinreq atomic.Int32
+
+	// Pending data recorder operation (Family BASIC keyboard only).
+	tapereq  atomic.Int32
+	tapePath string
+
+	// Frame advance and emulation speed.
+	advance     atomic.Bool
+	speed       atomic.Int32 // in percents, see SetSpeed
+	fastForward atomic.Bool
+	ffHold      atomic.Bool
+	slowMotion  atomic.Bool
+	ffSpeed     int
+	slowSpeed   int
+	pacer       pacer
+
+	// Pending volume change, in percents, and current volume.
+	volreq atomic.Int32
+	volume int32
+
+	// Pending screenshot.
+	screenshot     atomic.Bool
+	screenshotPath string
+
+	inprov *input.Provider
+	mic    *input.Microphone // captured host microphone, if any.
+
 	tmpdir string
 }
 
@@ -70,10 +133,57 @@ type Emulator struct {
 // video and audio streams and plugs controllers. It doesn't start the emulation
 // loop, call Run() for that.
 func Launch(rom *ines.Rom, cfg Config) (*Emulator, error) {
-	nes, err := powerUp(rom)
+	nes, err := powerUp(rom, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("power up failed: %s", err)
 	}
+	return launch(nes, cfg)
+}
+
+// LaunchFDS is like Launch, but for a Famicom Disk System disk image. The FDS
+// BIOS is read from the path set in the configuration.
+func LaunchFDS(disk *fds.Disk, cfg Config) (*Emulator, error) {
+	if cfg.FDS.BIOSPath == "" {
+		return nil, fmt.Errorf("the FDS BIOS is required to run disk images, and its path is not configured")
+	}
+	bios, err := os.ReadFile(cfg.FDS.BIOSPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read FDS BIOS: %s", err)
+	}
+
+	nes, err := powerUpFDS(disk, bios, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("power up failed: %s", err)
+	}
+	return launch(nes, cfg)
+}
+
+// LaunchNSF is like Launch, but plays a NSF music file.
+func LaunchNSF(f *nsf.File, cfg Config) (*Emulator, error) {
+	nes, err := powerUpNSF(f, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("power up failed: %s", err)
+	}
+	return launch(nes, cfg)
+}
+
+func launch(nes *NES, cfg Config) (*Emulator, error) {
+	e := &Emulator{
+		NES:            nes,
+		tapePath:       cfg.TapePath,
+		screenshotPath: cfg.ScreenshotPath,
+		volume:         100,
+		ffSpeed:        cfg.Speed.FastForward,
+		slowSpeed:      cfg.Speed.SlowMotion,
+	}
+	e.speed.Store(NormalSpeed)
+
+	// The mouse is only tracked for the devices driven with it.
+	devices := portDevices(nes.Rom, cfg.Input)
+	var mouse *input.Mouse
+	if devices[0].UsesMouse() || devices[1].UsesMouse() {
+		mouse = input.NewMouse()
+	}
 
 	// Output setup.
 	out := hw.NewOutput(hw.OutputConfig{
@@ -85,6 +195,9 @@ func Launch(rom *ines.Rom, cfg Config) (*Emulator, error) {
 		DisableVSync:    cfg.Video.DisableVSync,
 		Monitor:         cfg.Video.Monitor,
 		Shader:          cfg.Video.Shader,
+		Hotkeys:         cfg.Hotkeys,
+		OnHotkey:        e.handleHotkey,
+		Mouse:           mouse,
 	})
 	if err := out.EnableVideo(true); err != nil {
 		return nil, err
@@ -99,39 +212,72 @@ func Launch(rom *ines.Rom, cfg Config) (*Emulator, error) {
 		log.ModEmu.InfoZ("Audio enabled").End()
 	}
 
-	inprov := input.NewProvider(cfg.Input)
-	nes.CPU.PlugInputDevice(inprov)
+	// The microphone only exists on the Famicom second controller.
+	famicom := nes.isFamicom(cfg.Input)
+	if famicom && cfg.Input.Microphone.Capture {
+		mic, err := input.OpenMicrophone(cfg.Input.Microphone.Device)
+		if err != nil {
+			log.ModEmu.WarnZ("Failed to capture microphone").Error("err", err).End()
+		} else {
+			e.mic = mic
+		}
+	}
+
+	e.inprov = input.NewProvider(cfg.Input, mouse, e.mic)
+	nes.plugPortDevices(devices, e.inprov)
+	nes.plugExpansionDevice(expansionDevice(nes.Rom, cfg.Input), e.inprov)
+	if famicom {
+		nes.plugMicrophone(e.inprov)
+	}
 
 	// CPU execution trace setup.
 	if cfg.TraceOut != nil {
 		nes.CPU.SetTraceOutput(cfg.TraceOut)
 	}
 
-	return &Emulator{
-		NES: nes,
-		out: out,
-	}, nil
+	e.out = out
+	return e, nil
 }
+
 func (e *Emulator) RunOneFrame() {
+	e.out.EndFrame(e.emulateFrame())
+}
+
+// emulateFrame emulates the next frame, and returns it for output.
+func (e *Emulator) emulateFrame() hw.Frame {
 	frame := e.out.BeginFrame()
 	e.NES.RunOneFrame(frame)
-	e.out.EndFrame(frame)
+	if e.inprov != nil {
+		e.inprov.EndFrame()
+	}
+	if e.NES.Cart != nil {
+		frame.Status = e.NES.Cart.Status()
+	}
+	return frame
 }
 
 func (e *Emulator) loop() {
 	for {
-		// Handle pause.
-		if e.isPaused() {
-			// Don't burn cpu while paused.
-			time.Sleep(100 * time.Millisecond)
-		} else {
+		switch {
+		case !e.isPaused():
+			e.runFrames()
+		case e.advance.CompareAndSwap(true, false):
 			e.RunOneFrame()
+		default:
+			// Don't burn cpu while paused.
+			time.Sleep(20 * time.Millisecond)
 		}
 		if e.shouldStop() {
 			e.out.Close()
 			break
 		}
 		e.handleReset()
+		e.handleDiskRequest()
+		e.handleTrackRequest()
+		e.handleCoinRequest()
+		e.handleTapeRequest()
+		e.handleVolumeRequest()
+		e.handleScreenshot()
 	}
 }
 
@@ -146,9 +292,26 @@ func (e *Emulator) Run() {
 	e.loop()
 	log.ModEmu.InfoZ("Emulation loop exited").End()
 
+	e.stopTape()
+	if e.mic != nil {
+		e.mic.Close()
+	}
+
 	if e.tmpdir != "" {
 		e.save()
 	}
+
+	if e.NES.Cart != nil {
+		if err := e.NES.Cart.Save(); err != nil {
+			log.ModEmu.WarnZ("Failed to save cartridge memory").Error("err", err).End()
+		}
+	}
+
+	if e.NES.FDS != nil {
+		if err := e.NES.FDS.SaveDisk(); err != nil {
+			log.ModEmu.WarnZ("Failed to save disk writes").Error("err", err).End()
+		}
+	}
 }
 
 func (e *Emulator) save() {
@@ -170,6 +333,130 @@ func (e *Emulator) Stop() {
 	e.quit.Store(true)
 }
 
+// Disk operation requests, for the Famicom Disk System. Values greater or
+// equal to insertSideReq request to insert disk side (value - insertSideReq).
+const (
+	noDiskReq int32 = iota
+	ejectDiskReq
+	switchSideReq
+	nextDiskReq
+	insertSideReq
+)
+
+// SwitchDiskSide, InsertNextDisk, InsertDisk and EjectDisk allow to control
+// the Famicom Disk System drive in a concurrent-safe way. They have no effect
+// when not running a disk image.
+
+func (e *Emulator) SwitchDiskSide()     { e.diskreq.Store(switchSideReq) }
+func (e *Emulator) InsertNextDisk()     { e.diskreq.Store(nextDiskReq) }
+func (e *Emulator) InsertDisk(side int) { e.diskreq.Store(insertSideReq + int32(side)) }
+func (e *Emulator) EjectDisk()          { e.diskreq.Store(ejectDiskReq) }
+
+func (e *Emulator) handleDiskRequest() {
+	req := e.diskreq.Swap(noDiskReq)
+	drive := e.NES.FDS
+	if req == noDiskReq || drive == nil {
+		return
+	}
+
+	cur := max(drive.Side(), 0)
+
+	var side int
+	switch req {
+	case ejectDiskReq:
+		drive.EjectDisk()
+		return
+	case switchSideReq:
+		// Flip the disk: side A <-> side B.
+		side = cur ^ 1
+		if side >= drive.NumSides() {
+			log.ModEmu.WarnZ("Disk has no side B").Int("side", cur).End()
+			return
+		}
+	case nextDiskReq:
+		// Side A of the next disk, or of the first one after the last.
+		side = (cur/2 + 1) * 2
+		if side >= drive.NumSides() {
+			side = 0
+		}
+	default:
+		side = int(req - insertSideReq)
+	}
+
+	if err := drive.InsertDisk(side); err != nil {
+		log.ModEmu.WarnZ("Failed to insert disk").Error("err", err).End()
+	}
+}
+
+// Track change requests, for NSF files.
+const (
+	noTrackReq int32 = iota
+	prevTrackReq
+	nextTrackReq
+)
+
+// PrevTrack and NextTrack allow to change the song being played in a
+// concurrent-safe way. They have no effect when not playing a NSF file.
+
+func (e *Emulator) PrevTrack() { e.trackreq.Store(prevTrackReq) }
+func (e *Emulator) NextTrack() { e.trackreq.Store(nextTrackReq) }
+
+func (e *Emulator) handleTrackRequest() {
+	player := e.NES.NSF
+	if player == nil {
+		return
+	}
+
+	dir := 0
+	switch e.trackreq.Swap(noTrackReq) {
+	case prevTrackReq:
+		dir = -1
+	case nextTrackReq:
+		dir = 1
+	default:
+		// Move on to the next track once the current one is over, if its
+		// duration is known.
+		track := player.File().Track(player.Track())
+		if track.Duration == 0 || player.Elapsed() < track.Duration+track.Fade {
+			return
+		}
+		dir = 1
+	}
+
+	e.NES.PlayTrack(nextSong(player.File(), player.Track(), dir))
+}
+
+// Coin requests, for the Vs. System, as a bitmask.
+const (
+	coin1Req int32 = 1 << iota
+	coin2Req
+	serviceReq
+)
+
+// InsertCoin and PressService allow to operate the Vs. System cabinet in a
+// concurrent-safe way. They have no effect when not running a Vs. System rom.
+
+func (e *Emulator) InsertCoin(slot int) { e.coinreq.Or(coin1Req << (slot & 1)) }
+func (e *Emulator) PressService()       { e.coinreq.Or(serviceReq) }
+
+func (e *Emulator) handleCoinRequest() {
+	req := e.coinreq.Swap(0)
+	vs := e.NES.Vs
+	if req == 0 || vs == nil {
+		return
+	}
+
+	if req&coin1Req != 0 {
+		vs.InsertCoin(0)
+	}
+	if req&coin2Req != 0 {
+		vs.InsertCoin(1)
+	}
+	if req&serviceReq != 0 {
+		vs.PressService()
+	}
+}
+
 func (e *Emulator) isPaused() bool {
 	return e.paused.Load()
 }
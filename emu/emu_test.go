@@ -23,7 +23,7 @@ func BenchmarkCPUSpeed(b *testing.B) {
 		b.Fatal(err)
 	}
 
-	nes, err := powerUp(rom)
+	nes, err := powerUp(rom, Config{})
 	if err != nil {
 		b.Fatal(err)
 	}
@@ -0,0 +1,108 @@
+package emu
+
+import (
+	"time"
+
+	"nestor/emu/log"
+	"nestor/hw"
+)
+
+const (
+	// volumeStep is the volume change, in percents, of the volume hotkeys.
+	volumeStep = 10
+)
+
+func (e *Emulator) handleHotkey(hk hw.Hotkey, pressed bool) {
+	if hk == hw.HotkeyFastForwardHold {
+		e.ffHold.Store(pressed)
+		return
+	}
+	if !pressed {
+		return
+	}
+
+	switch hk {
+	case hw.HotkeyPause:
+		e.SetPause(!e.isPaused())
+	case hw.HotkeyFrameAdvance:
+		e.FrameAdvance()
+	case hw.HotkeySoftReset:
+		e.Reset()
+	case hw.HotkeyHardReset:
+		e.Restart()
+	case hw.HotkeyFastForward:
+		e.fastForward.Store(!e.fastForward.Load())
+	case hw.HotkeySlowMotion:
+		e.slowMotion.Store(!e.slowMotion.Load())
+	case hw.HotkeySpeedUp:
+		e.SetSpeed(stepSpeed(e.Speed(), true))
+	case hw.HotkeySpeedDown:
+		e.SetSpeed(stepSpeed(e.Speed(), false))
+	case hw.HotkeyNormalSpeed:
+		e.SetSpeed(NormalSpeed)
+	case hw.HotkeyScreenshot:
+		e.screenshot.Store(true)
+	case hw.HotkeyVolumeUp:
+		e.volreq.Add(volumeStep)
+	case hw.HotkeyVolumeDown:
+		e.volreq.Add(-volumeStep)
+	case hw.HotkeyFDSSwitchSide:
+		e.SwitchDiskSide()
+	case hw.HotkeyFDSInsertNextDisk:
+		e.InsertNextDisk()
+	case hw.HotkeyFDSEjectDisk:
+		e.EjectDisk()
+	case hw.HotkeyNSFPrevTrack:
+		e.PrevTrack()
+	case hw.HotkeyNSFNextTrack:
+		e.NextTrack()
+	case hw.HotkeyVsInsertCoin1:
+		e.InsertCoin(0)
+	case hw.HotkeyVsInsertCoin2:
+		e.InsertCoin(1)
+	case hw.HotkeyVsService:
+		e.PressService()
+	case hw.HotkeyTapePlay:
+		e.PlayTape()
+	case hw.HotkeyTapeRecord:
+		e.RecordTape()
+	case hw.HotkeyTapeStop:
+		e.StopTape()
+	}
+}
+
+// FrameAdvance pauses the emulator or, if it's already paused, emulates a
+// single frame. It's safe for concurrent use.
+func (e *Emulator) FrameAdvance() {
+	if e.paused.CompareAndSwap(false, true) {
+		return
+	}
+	e.advance.Store(true)
+}
+
+func (e *Emulator) handleVolumeRequest() {
+	delta := e.volreq.Swap(0)
+	if delta == 0 {
+		return
+	}
+	e.volume = min(max(e.volume+delta, 0), 100)
+	e.NES.Mixer.SetVolume(float64(e.volume) / 100)
+	log.ModEmu.InfoZ("Volume changed").Int32("volume", e.volume).End()
+}
+
+func (e *Emulator) handleScreenshot() {
+	if !e.screenshot.CompareAndSwap(true, false) {
+		return
+	}
+	if e.screenshotPath == "" {
+		log.ModEmu.WarnZ("No screenshot path").End()
+		return
+	}
+
+	path := e.screenshotPath + time.Now().Format("-20060102-150405") + ".png"
+	if err := hw.SaveAsPNG(e.out.Screenshot(), path); err != nil {
+		log.ModEmu.WarnZ("Failed to save screenshot").String("path", path).Error("err", err).End()
+		return
+	}
+	log.ModEmu.InfoZ("Screenshot saved").String("path", path).End()
+}
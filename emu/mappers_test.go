@@ -0,0 +1,250 @@
+package emu
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+
+	"nestor/hw"
+	"nestor/hw/hwdefs"
+	"nestor/hw/mappers"
+	"nestor/ines"
+	"nestor/tests"
+)
+
+func powerUpMapperRom(t *testing.T, mapper uint16, submapper uint8, prgKB, chrKB int) *NES {
+	t.Helper()
+
+	rom, err := ines.Decode(tests.MapperRom(mapper, submapper, prgKB, chrKB))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nes, err := powerUp(rom, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nes
+}
+
+// checkMirroring checks the nametable arrangement by writing a different value
+// into each nametable and reading them back.
+func checkMirroring(t *testing.T, nes *NES, want ines.NTMirroring) {
+	t.Helper()
+
+	for i := range uint16(4) {
+		nes.PPU.Bus.Write8(0x2000+i*0x400, uint8(i+1))
+	}
+
+	var vals [4]uint8
+	switch want {
+	case ines.HorzMirroring:
+		vals = [4]uint8{2, 2, 4, 4}
+	case ines.VertMirroring:
+		vals = [4]uint8{3, 4, 3, 4}
+	case ines.OnlyAScreen, ines.OnlyBScreen:
+		vals = [4]uint8{4, 4, 4, 4}
+	}
+	for i := range uint16(4) {
+		checkedRead8(t, nes.PPU.Bus, 0x2000+i*0x400, vals[i])
+	}
+}
+
+// runUntilIRQ runs the CPU until the external IRQ source is set, or the max
+// number of cycles is reached. It returns the number of CPU cycles ran.
+func runUntilIRQ(nes *NES, max int64) int64 {
+	start := nes.CPU.Cycles
+	for nes.CPU.Cycles-start < max && !nes.CPU.HasIRQSource(hwdefs.External) {
+		nes.CPU.Run(1)
+	}
+	return nes.CPU.Cycles - start
+}
+
+// testBoard is a board implemented outside of the mappers package, with
+// 8 KB PRG banking, one-screen mirroring and a CPU cycle IRQ counter.
+type testBoard struct {
+	*mappers.Base
+
+	counter uint8
+}
+
+func (m *testBoard) writeReg(addr uint16, val uint8) {
+	switch addr & 0xE000 {
+	case 0x8000:
+		m.SelectPRGPage8KB(0, int(val))
+	case 0xA000:
+		m.SetNametables(m.CIRAM(int(val)), m.CIRAM(int(val)), m.CIRAM(int(val)), m.CIRAM(int(val)))
+	case 0xC000:
+		m.counter = val
+		m.CPU().ClearIRQSource(hwdefs.External)
+	}
+}
+
+func (m *testBoard) tick() {
+	if m.counter == 0 {
+		return
+	}
+	m.counter--
+	if m.counter == 0 {
+		m.CPU().SetIRQSource(hwdefs.External)
+	}
+}
+
+var testBoardDesc = mappers.MapperDesc{
+	Name: "test board",
+	Load: func(b *mappers.Base) error {
+		m := &testBoard{Base: b}
+		b.Init(m.writeReg)
+		b.CPU().SetCycleHook(m.tick)
+		b.SetNTMirroring(ines.VertMirroring)
+		b.SelectPRGPage8KB(0, 0)
+		b.SelectPRGPage8KB(1, 1)
+		b.SelectPRGPage8KB(2, -2)
+		b.SelectPRGPage8KB(3, -1)
+		b.SelectCHRROMPage8KB(0)
+		return nil
+	},
+}
+
+func init() {
+	mappers.Register(4000, testBoardDesc)
+	mappers.RegisterUNIF("UNL-NESTOR-TEST", testBoardDesc)
+}
+
+func TestRegisteredMapper(t *testing.T) {
+	nes := powerUpMapperRom(t, 4000, 0, 64, 8)
+	bus := nes.CPU.Bus
+
+	checkMirroring(t, nes, ines.VertMirroring)
+	checkedRead8(t, bus, 0x8000, 0)
+	checkedRead8(t, bus, 0xE000, 7*8)
+
+	bus.Write8(0x8000, 5)
+	checkedRead8(t, bus, 0x8000, 5*8)
+	bus.Write8(0xA000, 1)
+	checkMirroring(t, nes, ines.OnlyBScreen)
+
+	bus.Write8(0xC000, 100)
+	if n := runUntilIRQ(nes, 1000); n < 95 || n > 105 {
+		t.Errorf("IRQ after %d cycles, want ~100", n)
+	}
+}
+
+func TestRegisteredUNIFBoard(t *testing.T) {
+	rom, err := ines.Decode(tests.MapperRom(0, 0, 64, 8))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Registered boards are looked up by their normalized name first.
+	rom.Board = "NESTOR-TEST"
+	nes, err := powerUp(rom, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nes.CPU.Bus.Write8(0x8000, 5)
+	checkedRead8(t, nes.CPU.Bus, 0x8000, 5*8)
+
+	// Boards with neither a registration nor an iNES equivalent are rejected.
+	rom.Board = "UNKNOWN-BOARD"
+	if _, err := powerUp(rom, Config{}); err == nil {
+		t.Errorf("powerUp succeeded with an unknown UNIF board")
+	}
+}
+
+func TestVsSystem(t *testing.T) {
+	img := tests.MapperRom(99, 0, 32, 16)
+	img[7] |= 0x01 // Vs. System
+	img[13] = 0x08 // RC2C05-01
+	rom, err := ines.Decode(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rom.Name = "vs.nes"
+
+	cfg := Config{Vs: VsConfig{Games: map[string]VsGameConfig{
+		"vs": {DIPSwitches: 0b1010_0110},
+	}}}
+	nes, err := powerUp(rom, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := nes.PPU.Model(); got != hw.PPU2C05_01 {
+		t.Errorf("PPU model = %d, want %d", got, hw.PPU2C05_01)
+	}
+	bus := nes.CPU.Bus
+	if id := bus.Peek8(0x2002) & 0x1F; id != 0x1B {
+		t.Errorf("PPUSTATUS ID = $%02X, want $1B", id)
+	}
+	// PPUCTRL and PPUMASK are swapped.
+	bus.Write8(0x2001, 0x04)
+	if nes.PPU.PPUCTRL != 0x04 {
+		t.Errorf("PPUCTRL = $%02X after writing $2001, want $04", nes.PPU.PPUCTRL)
+	}
+
+	// DIP switches 1-2 are read from $4016, 3-8 from $4017.
+	checkedRead8(t, bus, 0x4016, 0x10)
+	checkedRead8(t, bus, 0x4017, 0xA4)
+
+	nes.Vs.InsertCoin(1)
+	nes.Vs.PressService()
+	checkedRead8(t, bus, 0x4016, 0x54)
+	for range 4 {
+		nes.Vs.EndFrame()
+	}
+	checkedRead8(t, bus, 0x4016, 0x10)
+
+	// CHR-ROM bank selected by $4016 bit 2.
+	checkedRead8(t, nes.PPU.Bus, 0x0000, 0)
+	bus.Write8(0x4016, 0x04)
+	checkedRead8(t, nes.PPU.Bus, 0x0000, 8)
+
+	// Four-screen nametables.
+	for i := range uint16(4) {
+		nes.PPU.Bus.Write8(0x2000+i*0x400, uint8(i+1))
+	}
+	for i := range uint16(4) {
+		checkedRead8(t, nes.PPU.Bus, 0x2000+i*0x400, uint8(i+1))
+	}
+}
+
+func TestVsSystemPPUOverride(t *testing.T) {
+	// iNES 1.0 header: the PPU isn't specified.
+	img := tests.MapperRom(99, 0, 32, 16)
+	img[7] = 0x60 | 0x01
+	rom, err := ines.Decode(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rom.Name = "vs.nes"
+
+	nes, err := powerUp(rom, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nes.PPU.Model(); got != hw.PPU2C03 {
+		t.Errorf("PPU model = %d, want %d", got, hw.PPU2C03)
+	}
+
+	var cfg Config
+	if _, err := toml.Decode(`
+[games.vs]
+ppu = "RC2C05-04"
+`, &cfg.Vs); err != nil {
+		t.Fatal(err)
+	}
+	nes, err = powerUp(rom, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nes.PPU.Model(); got != hw.PPU2C05_04 {
+		t.Errorf("PPU model = %d, want %d", got, hw.PPU2C05_04)
+	}
+
+	if _, err := toml.Decode(`
+[games.vs]
+ppu = "RP2C05"
+`, &cfg.Vs); err == nil {
+		t.Errorf("decoding an unknown PPU model succeeded")
+	}
+}
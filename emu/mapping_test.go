@@ -25,7 +25,7 @@ func TestMapper000(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	nes, err := powerUp(rom)
+	nes, err := powerUp(rom, Config{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -72,7 +72,7 @@ func TestPPURegisterMapping(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	nes, err := powerUp(rom)
+	nes, err := powerUp(rom, Config{})
 	if err != nil {
 		t.Fatal(err)
 	}
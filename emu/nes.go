@@ -1,10 +1,12 @@
 package emu
 
 import (
+	"nestor/fds"
 	"nestor/hw"
 	"nestor/hw/hwdefs"
 	"nestor/hw/mappers"
 	"nestor/ines"
+	"nestor/nsf"
 )
 
 type NES struct {
@@ -13,9 +15,27 @@ type NES struct {
 	APU   *hw.APU
 	Rom   *ines.Rom
 	Mixer *hw.AudioMixer
+	Cart  mappers.Cartridge
+
+	// FDS is the Famicom Disk System, only set when running a disk image
+	// (then Rom is nil).
+	FDS *mappers.FDS
+
+	// NSF is the synthetic cartridge playing a NSF file, only set when
+	// playing music (then Rom is nil).
+	NSF *mappers.NSF
+
+	// Vs is the Vs. System arcade hardware, only set when running Vs.
+	// System roms.
+	Vs *hw.VsSystem
+
+	// Recorder is the data recorder, only set when the Family BASIC
+	// keyboard is plugged in.
+	Recorder *hw.DataRecorder
 }
 
-func powerUp(rom *ines.Rom) (*NES, error) {
+// newNES creates the console hardware, without any cartridge.
+func newNES() *NES {
 	audioMixer := hw.NewAudioMixer()
 	ppu := hw.NewPPU()
 	cpu := hw.NewCPU(ppu)
@@ -24,21 +44,69 @@ func powerUp(rom *ines.Rom) (*NES, error) {
 	cpu.APU = apu
 	cpu.InitBus()
 
-	if err := mappers.Load(rom, cpu, ppu); err != nil {
-		return nil, err
-	}
-
-	nes := &NES{
+	return &NES{
 		CPU:   cpu,
 		PPU:   ppu,
 		APU:   apu,
-		Rom:   rom,
 		Mixer: audioMixer,
 	}
+}
+
+func mapperOptions(cfg Config) mappers.Options {
+	return mappers.Options{
+		N163MixChannels: cfg.Audio.N163MixChannels,
+		SavePath:        cfg.SavePath,
+	}
+}
+
+func powerUp(rom *ines.Rom, cfg Config) (*NES, error) {
+	nes := newNES()
+	cart, err := mappers.Load(rom, nes.CPU, nes.PPU, mapperOptions(cfg))
+	if err != nil {
+		return nil, err
+	}
+	nes.Rom = rom
+	nes.Cart = cart
+	if rom.ConsoleType() == ines.VsSystem {
+		nes.plugVsSystem(rom, cfg.Vs)
+	}
 	nes.Reset(hwdefs.HardReset)
 	return nes, nil
 }
 
+// powerUpFDS powers up a Famicom with the Disk System plugged in, and the
+// first side of the given disk inserted in the drive.
+func powerUpFDS(disk *fds.Disk, bios []byte, cfg Config) (*NES, error) {
+	nes := newNES()
+	adapter, err := mappers.LoadFDS(disk, bios, nes.CPU, nes.PPU, mapperOptions(cfg))
+	if err != nil {
+		return nil, err
+	}
+	nes.FDS = adapter
+	nes.Reset(hwdefs.HardReset)
+	return nes, nil
+}
+
+// powerUpNSF powers up the console with a synthetic cartridge playing the
+// given NSF file, starting with its first song.
+func powerUpNSF(f *nsf.File, cfg Config) (*NES, error) {
+	nes := newNES()
+	cart, err := mappers.LoadNSF(f, nes.CPU, nes.PPU, mapperOptions(cfg))
+	if err != nil {
+		return nil, err
+	}
+	nes.NSF = cart
+	nes.Cart = cart
+	nes.Reset(hwdefs.HardReset)
+	return nes, nil
+}
+
+// PlayTrack starts playing the given NSF song (0-based), from the start.
+func (nes *NES) PlayTrack(song int) {
+	nes.NSF.SetTrack(song)
+	nes.Reset(hwdefs.HardReset)
+}
+
 func (nes *NES) Reset(soft bool) {
 	nes.PPU.Reset()
 	nes.APU.Reset(soft)
@@ -50,4 +118,7 @@ func (nes *NES) RunOneFrame(frame hw.Frame) {
 	nes.PPU.SetFrameBuffer(frame.Video)
 	nes.CPU.Run(29781)
 	nes.APU.EndFrame()
+	if nes.Vs != nil {
+		nes.Vs.EndFrame()
+	}
 }
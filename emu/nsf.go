@@ -0,0 +1,88 @@
+package emu
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"nestor/hw"
+	"nestor/nsf"
+)
+
+// nextSong returns the song after (dir > 0) or before (dir < 0) the given one,
+// in the playing order of f, wrapping around at both ends.
+func nextSong(f *nsf.File, song, dir int) int {
+	order := f.Order()
+	i := slices.Index(order, song)
+	if i == -1 {
+		return order[0]
+	}
+	i = (i + dir + len(order)) % len(order)
+	return order[i]
+}
+
+// Defaults for RenderNSF, when the track duration isn't known.
+const (
+	DefaultTrackDuration = 2*time.Minute + 30*time.Second
+	DefaultTrackFade     = 5 * time.Second
+)
+
+// WAVSampleRate is the sample rate of the audio rendered by RenderNSF.
+const WAVSampleRate = 44100
+
+// RenderNSF plays a NSF song (0-based), without any video or audio output,
+// and writes the audio as a 16-bit stereo WAV file to w. The song plays for
+// the given duration, then fades out. Zero durations are taken from the file
+// metadata, if present, or from the defaults otherwise.
+func RenderNSF(f *nsf.File, song int, duration, fade time.Duration, w io.WriteSeeker, cfg Config) error {
+	if song < 0 || song >= f.Songs {
+		return fmt.Errorf("invalid track %d (file has %d tracks)", song+1, f.Songs)
+	}
+
+	track := f.Track(song)
+	if duration == 0 {
+		duration = cmp.Or(track.Duration, DefaultTrackDuration)
+	}
+	if fade == 0 {
+		fade = cmp.Or(track.Fade, DefaultTrackFade)
+	}
+
+	nes, err := powerUpNSF(f, cfg)
+	if err != nil {
+		return fmt.Errorf("power up failed: %s", err)
+	}
+
+	wav, err := newWAVWriter(w, WAVSampleRate, 2)
+	if err != nil {
+		return err
+	}
+
+	var (
+		pos    int // current sample
+		fadeAt = int(duration.Seconds() * WAVSampleRate)
+		end    = fadeAt + int(fade.Seconds()*WAVSampleRate)
+	)
+	nes.Mixer.SetSink(WAVSampleRate, func(samples []int16) {
+		for i := 0; i < len(samples) && pos < end; i += 2 {
+			if pos >= fadeAt {
+				gain := float64(end-pos) / float64(end-fadeAt)
+				samples[i] = int16(float64(samples[i]) * gain)
+				samples[i+1] = int16(float64(samples[i+1]) * gain)
+			}
+			pos++
+			wav.write(samples[i : i+2])
+		}
+	})
+	nes.PlayTrack(song)
+
+	frame := hw.Frame{Video: make([]byte, hw.NTSCWidth*hw.NTSCHeight*4)}
+	for pos < end && wav.err == nil {
+		nes.RunOneFrame(frame)
+		if nes.CPU.IsHalted() {
+			return fmt.Errorf("CPU halted")
+		}
+	}
+	return wav.close()
+}
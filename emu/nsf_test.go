@@ -0,0 +1,168 @@
+package emu
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nestor/nsf"
+	"nestor/tests"
+)
+
+// writesProgram assembles a routine writing each value to its address, and
+// returning.
+func writesProgram(writes ...[2]uint16) []byte {
+	var prg []byte
+	for _, w := range writes {
+		prg = append(prg,
+			0xA9, uint8(w[1]), // LDA #val
+			0x8D, uint8(w[0]), uint8(w[0]>>8), // STA addr
+		)
+	}
+	return append(prg, 0x60) // RTS
+}
+
+func decodeNSF(t *testing.T, img []byte) *nsf.File {
+	t.Helper()
+	f, err := nsf.Decode(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestNSFPlayer(t *testing.T) {
+	// $8000: STA $0200 (song)
+	//        STX $0201 (region)
+	//        RTS
+	// $8007: INC $0202 (play calls)
+	//        RTS
+	prg := []byte{0x8D, 0x00, 0x02, 0x8E, 0x01, 0x02, 0x60, 0xEE, 0x02, 0x02, 0x60}
+	f := decodeNSF(t, tests.NSF(3, 0, [8]uint8{}, 0x8007, prg))
+
+	nes, err := powerUpNSF(f, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runFrames := func(n int) {
+		frame := newTestingOutput(TestingOutputConfig{Width: 256, Height: 240}).BeginFrame()
+		for range n {
+			nes.RunOneFrame(frame)
+		}
+	}
+
+	runFrames(60)
+	bus := nes.CPU.Bus
+	checkedRead8(t, bus, 0x0200, 0)
+	checkedRead8(t, bus, 0x0201, 0)
+	if calls := bus.Peek8(0x0202); calls < 59 || calls > 61 {
+		t.Errorf("PLAY called %d times in 60 frames, want ~60", calls)
+	}
+	if got := nes.NSF.Elapsed().Round(100 * time.Millisecond); got != time.Second {
+		t.Errorf("Elapsed() = %s, want 1s", got)
+	}
+
+	nes.PlayTrack(nextSong(f, nes.NSF.Track(), -1))
+	runFrames(1)
+	checkedRead8(t, bus, 0x0200, 2)
+	if calls := bus.Peek8(0x0202); calls > 1 {
+		t.Errorf("PLAY called %d times after track change, want <= 1", calls)
+	}
+	if status, want := nes.NSF.Status(), "Track 3/3 - Test Song - 0:00"; status != want {
+		t.Errorf("Status() = %q, want %q", status, want)
+	}
+}
+
+func TestNSFBanking(t *testing.T) {
+	// 4 banks of 4 KB, filled with their index.
+	data := make([]byte, 4*0x1000)
+	for i := range data {
+		data[i] = uint8(i / 0x1000)
+	}
+	copy(data, writesProgram([2]uint16{0x5FFF, 3}))
+
+	f := decodeNSF(t, tests.NSF(1, 0, [8]uint8{0, 1, 2, 1, 0, 0, 0, 2}, 0x8000+0x0FFF, data))
+	nes, err := powerUpNSF(f, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Initial banks.
+	bus := nes.CPU.Bus
+	checkedRead8(t, bus, 0x9000, 1)
+	checkedRead8(t, bus, 0xA000, 2)
+	checkedRead8(t, bus, 0xB000, 1)
+	checkedRead8(t, bus, 0xF000, 2)
+
+	nes.CPU.Run(30000)
+	checkedRead8(t, bus, 0xF000, 3)
+
+	bus.Write8(0x5FF9, 7) // outside of the data
+	checkedRead8(t, bus, 0x9000, 0)
+}
+
+func TestRenderNSF(t *testing.T) {
+	tones := map[string]struct {
+		exp    nsf.Expansion
+		writes [][2]uint16
+	}{
+		"APU": {0, [][2]uint16{{0x4000, 0xBF}, {0x4002, 0xFD}, {0x4003, 0x00}}},
+		"VRC6": {nsf.VRC6, [][2]uint16{
+			{0x9000, 0x3F}, {0x9001, 0xFF}, {0x9002, 0x81}, // pulse 1
+			{0xB000, 0x10}, {0xB001, 0x80}, {0xB002, 0x81}, // saw
+		}},
+		"VRC7": {nsf.VRC7, [][2]uint16{
+			{0x9010, 0x30}, {0x9030, 0x10}, // instrument 1, max volume
+			{0x9010, 0x10}, {0x9030, 0xAC}, // fnum
+			{0x9010, 0x20}, {0x9030, 0x18}, // key on, block 4
+		}},
+		"MMC5": {nsf.MMC5, [][2]uint16{{0x5015, 0x01}, {0x5000, 0xBF}, {0x5002, 0xFD}, {0x5003, 0x08}}},
+		"5B": {nsf.Sunsoft5B, [][2]uint16{
+			{0xC000, 0x07}, {0xE000, 0x3E}, // tone A only
+			{0xC000, 0x08}, {0xE000, 0x0F}, // volume A
+			{0xC000, 0x00}, {0xE000, 0x80}, // period A
+		}},
+	}
+
+	for name, tone := range tones {
+		t.Run(name, func(t *testing.T) {
+			prg := writesProgram(tone.writes...)
+			f := decodeNSF(t, tests.NSF(1, uint8(tone.exp), [8]uint8{}, 0x8000+uint16(len(prg)-1), prg))
+
+			path := filepath.Join(t.TempDir(), "out.wav")
+			out, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+
+			err = RenderNSF(f, 0, 500*time.Millisecond, 100*time.Millisecond, out, Config{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buf, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			const want = WAVSampleRate * 6 / 10 * 4
+			if size := binary.LittleEndian.Uint32(buf[40:]); size != want || len(buf) != wavHeaderSize+want {
+				t.Fatalf("data size = %d (file size %d), want %d", size, len(buf), want)
+			}
+
+			// Check there's some sound.
+			var sum float64
+			samples := buf[wavHeaderSize:]
+			for i := 0; i < len(samples); i += 2 {
+				s := float64(int16(binary.LittleEndian.Uint16(samples[i:])))
+				sum += s * s
+			}
+			if rms := math.Sqrt(sum / float64(len(samples)/2)); rms < 100 {
+				t.Errorf("output is silent, rms = %.1f", rms)
+			}
+		})
+	}
+}
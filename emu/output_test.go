@@ -74,6 +74,11 @@ func (to *TestingOutput) EndFrame(_ hw.Frame) {
 	to.framecounter++
 }
 
+func (to *TestingOutput) TryEndFrame(frame hw.Frame) bool {
+	to.EndFrame(frame)
+	return true
+}
+
 func (to *TestingOutput) Poll() bool {
 	return to.framecounter <= int(to.cfg.SaveFrameNum)
 }
@@ -0,0 +1,126 @@
+package emu
+
+import (
+	"nestor/emu/log"
+	"nestor/hw"
+	"nestor/hw/input"
+	"nestor/ines"
+)
+
+// romDevices maps the NES 2.0 default expansion devices to the devices to plug
+// in the controller ports. Roms using standard controllers, or an unsupported
+// device, use the devices set in the input configuration.
+var romDevices = map[ines.ExpansionDevice][2]input.Device{
+	ines.ExpFourScore:  {input.DeviceFourScore, input.DeviceFourScore},
+	ines.ExpZapper:     {input.DeviceController, input.DeviceZapper},
+	ines.ExpTwoZappers: {input.DeviceZapper, input.DeviceZapper},
+	ines.ExpPowerPadA:  {input.DeviceController, input.DevicePowerPad},
+	ines.ExpPowerPadB:  {input.DeviceController, input.DevicePowerPad},
+	ines.ExpVausNES:    {input.DeviceController, input.DeviceVaus},
+	ines.ExpSNESMouse:  {input.DeviceController, input.DeviceSNESMouse},
+}
+
+// romExpansionDevices maps the NES 2.0 default expansion devices to the device
+// to plug in the Famicom expansion port. The data recorder connects to the
+// Family BASIC keyboard, so it comes with it.
+var romExpansionDevices = map[ines.ExpansionDevice]input.ExpansionDevice{
+	ines.ExpFamilyBASICKeyboard: input.ExpansionFamilyKeyboard,
+	ines.ExpDataRecorder:        input.ExpansionFamilyKeyboard,
+}
+
+// portDevices returns the devices to plug in the controller ports, either the
+// ones the rom expects or the configured ones.
+func portDevices(rom *ines.Rom, cfg input.Config) [2]input.Device {
+	devices := cfg.Ports
+	if rom != nil {
+		exp := rom.ExpansionDevice()
+		_, isExp := romExpansionDevices[exp]
+		if romdevs, ok := romDevices[exp]; ok {
+			devices = romdevs
+		} else if exp != ines.ExpUnspecified && exp != ines.ExpStdControllers && !isExp {
+			log.ModEmu.WarnZ("Unsupported input device, using configured ones").String("device", exp.String()).End()
+		}
+	}
+
+	// The Four Score takes both ports.
+	if devices[0] == input.DeviceFourScore || devices[1] == input.DeviceFourScore {
+		devices = [2]input.Device{input.DeviceFourScore, input.DeviceFourScore}
+	}
+	return devices
+}
+
+// plugPortDevices plugs the given devices in the controller ports.
+func (nes *NES) plugPortDevices(devices [2]input.Device, prov *input.Provider) {
+	if devices[0] == input.DeviceFourScore {
+		port1, port2 := hw.NewFourScore(prov)
+		nes.CPU.PlugPortDevice(0, port1)
+		nes.CPU.PlugPortDevice(1, port2)
+		log.ModEmu.InfoZ("Four Score plugged").End()
+		return
+	}
+
+	for port, dev := range devices {
+		var pd hw.PortDevice
+		switch dev {
+		case input.DeviceController:
+			pd = hw.NewStdController(port, prov)
+		case input.DeviceZapper:
+			pd = hw.NewZapper(nes.PPU, prov)
+		case input.DeviceVaus:
+			pd = hw.NewVaus(prov)
+		case input.DevicePowerPad:
+			pd = hw.NewPowerPad(prov)
+		case input.DeviceSNESMouse:
+			pd = hw.NewSNESMouse(prov)
+		default:
+			log.ModEmu.WarnZ("Invalid input device").Int("port", port+1).Uint8("device", uint8(dev)).End()
+			continue
+		}
+		nes.CPU.PlugPortDevice(port, pd)
+		log.ModEmu.InfoZ("Input device plugged").Int("port", port+1).String("device", dev.String()).End()
+	}
+}
+
+// expansionDevice returns the device to plug in the Famicom expansion port,
+// either the one the rom expects or the configured one.
+func expansionDevice(rom *ines.Rom, cfg input.Config) input.ExpansionDevice {
+	if rom != nil {
+		if dev, ok := romExpansionDevices[rom.ExpansionDevice()]; ok {
+			return dev
+		}
+	}
+	return cfg.Expansion
+}
+
+// plugExpansionDevice plugs the given device in the Famicom expansion port.
+func (nes *NES) plugExpansionDevice(dev input.ExpansionDevice, prov *input.Provider) {
+	switch dev {
+	case input.ExpansionNone:
+		return
+	case input.ExpansionFamilyKeyboard:
+		nes.Recorder = hw.NewDataRecorder(nes.CPU)
+		nes.CPU.PlugExpansionDevice(hw.NewFamilyKeyboard(prov, nes.Recorder))
+	default:
+		log.ModEmu.WarnZ("Invalid expansion device").Uint8("device", uint8(dev)).End()
+		return
+	}
+	log.ModEmu.InfoZ("Expansion device plugged").String("device", dev.String()).End()
+}
+
+// isFamicom reports whether the emulated console is a Famicom, either as set in
+// the input configuration or because it runs a Famicom Disk System image.
+func (nes *NES) isFamicom(cfg input.Config) bool {
+	switch {
+	case nes.FDS != nil:
+		return true
+	case nes.Vs != nil || nes.NSF != nil:
+		return false
+	}
+	return cfg.Famicom
+}
+
+// plugMicrophone connects the microphone of the Famicom second controller.
+func (nes *NES) plugMicrophone(prov *input.Provider) {
+	nes.CPU.PlugMicrophone(prov)
+	log.ModEmu.InfoZ("Famicom microphone plugged").End()
+}
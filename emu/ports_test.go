@@ -0,0 +1,77 @@
+package emu
+
+import (
+	"testing"
+
+	"nestor/hw/input"
+	"nestor/ines"
+	"nestor/tests"
+)
+
+func TestPortDevices(t *testing.T) {
+	cfg := input.Config{Ports: [2]input.Device{input.DeviceController, input.DeviceSNESMouse}}
+
+	tab := []struct {
+		name string
+		exp  byte // NES 2.0 default expansion device
+		cfg  input.Config
+		want [2]input.Device
+	}{
+		{"unspecified", 0x00, cfg, cfg.Ports},
+		{"standard controllers", 0x01, cfg, cfg.Ports},
+		{"unsupported", 0x17, cfg, cfg.Ports},
+		{"zapper", 0x08, cfg, [2]input.Device{input.DeviceController, input.DeviceZapper}},
+		{"vaus", 0x0F, cfg, [2]input.Device{input.DeviceController, input.DeviceVaus}},
+		{"four score", 0x02, cfg, [2]input.Device{input.DeviceFourScore, input.DeviceFourScore}},
+		{
+			"configured four score", 0x00,
+			input.Config{Ports: [2]input.Device{input.DeviceZapper, input.DeviceFourScore}},
+			[2]input.Device{input.DeviceFourScore, input.DeviceFourScore},
+		},
+	}
+	for _, tt := range tab {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := tests.MapperRom(0, 0, 2, 1)
+			buf[15] = tt.exp
+			rom, err := ines.Decode(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := portDevices(rom, tt.cfg); got != tt.want {
+				t.Errorf("portDevices() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpansionDevice(t *testing.T) {
+	cfg := input.Config{Expansion: input.ExpansionNone}
+
+	tab := []struct {
+		name string
+		exp  byte // NES 2.0 default expansion device
+		cfg  input.Config
+		want input.ExpansionDevice
+	}{
+		{"unspecified", 0x00, cfg, input.ExpansionNone},
+		{"configured", 0x00, input.Config{Expansion: input.ExpansionFamilyKeyboard}, input.ExpansionFamilyKeyboard},
+		{"family basic", 0x23, cfg, input.ExpansionFamilyKeyboard},
+		{"data recorder", 0x20, cfg, input.ExpansionFamilyKeyboard},
+	}
+	for _, tt := range tab {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := tests.MapperRom(0, 0, 2, 1)
+			buf[15] = tt.exp
+			rom, err := ines.Decode(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := expansionDevice(rom, tt.cfg); got != tt.want {
+				t.Errorf("expansionDevice() = %v, want %v", got, tt.want)
+			}
+			if got := portDevices(rom, tt.cfg); got != tt.cfg.Ports {
+				t.Errorf("portDevices() = %v, want %v", got, tt.cfg.Ports)
+			}
+		})
+	}
+}
@@ -35,7 +35,7 @@ func TestNestest(t *testing.T) {
 	}
 	println("nestest log:", flog.Name())
 
-	nes, err := powerUp(rom)
+	nes, err := powerUp(rom, Config{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -188,7 +188,7 @@ func runBlarggTestRom(path string) func(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		nes, err := powerUp(rom)
+		nes, err := powerUp(rom, Config{})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -358,7 +358,7 @@ func TestNametableMirroring(t *testing.T) {
 	if rom.Mirroring() != ines.HorzMirroring {
 		t.Errorf("incorrect nt mirroring")
 	}
-	nes, err := powerUp(rom)
+	nes, err := powerUp(rom, Config{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -533,7 +533,7 @@ func runAndCompareFrame(t *testing.T, romPath, frameDir, framePath string, frame
 		t.Fatal(err)
 	}
 
-	nes, err := powerUp(rom)
+	nes, err := powerUp(rom, Config{})
 	if err != nil {
 		t.Fatal(err)
 	}
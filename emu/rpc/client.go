@@ -55,6 +55,13 @@ func (c *Client) IsReady() bool          { return request[bool](c.client, "emu.I
 func (c *Client) Reset()                 { call(c.client, "emu.Reset", nil) }
 func (c *Client) Restart()               { call(c.client, "emu.Restart", nil) }
 func (c *Client) SetPause(pause bool)    { call(c.client, "emu.SetPause", pause) }
+func (c *Client) SetSpeed(percent int)   { call(c.client, "emu.SetSpeed", percent) }
+func (c *Client) Speed() int             { return request[int](c.client, "emu.Speed", nil) }
+func (c *Client) FrameAdvance()          { call(c.client, "emu.FrameAdvance", nil) }
+func (c *Client) SwitchDiskSide()        { call(c.client, "emu.SwitchDiskSide", nil) }
+func (c *Client) InsertNextDisk()        { call(c.client, "emu.InsertNextDisk", nil) }
+func (c *Client) InsertDisk(side int)    { call(c.client, "emu.InsertDisk", side) }
+func (c *Client) EjectDisk()             { call(c.client, "emu.EjectDisk", nil) }
 func (c *Client) Stop() {
 	call(c.client, "emu.Stop", nil)
 }
@@ -14,6 +14,17 @@ type Emu interface {
 	SetPause(pause bool)
 	Stop()
 
+	// Emulation speed, in percents (0 is unthrottled), and frame advance.
+	SetSpeed(percent int)
+	Speed() int
+	FrameAdvance()
+
+	// Famicom Disk System drive control.
+	SwitchDiskSide()
+	InsertNextDisk()
+	InsertDisk(side int)
+	EjectDisk()
+
 	SetTempDir(path string)
 }
 
@@ -28,6 +39,18 @@ func (ep *emuProxy) Restart(_, _ *struct{}) error              { ep.emu.Restart(
 func (ep *emuProxy) SetPause(pause bool, _ *struct{}) error    { ep.emu.SetPause(pause); return nil }
 func (ep *emuProxy) Stop(_ *struct{}, _ *struct{}) error       { ep.emu.Stop(); return nil }
 
+func (ep *emuProxy) SetSpeed(percent int, _ *struct{}) error { ep.emu.SetSpeed(percent); return nil }
+func (ep *emuProxy) FrameAdvance(_, _ *struct{}) error       { ep.emu.FrameAdvance(); return nil }
+func (ep *emuProxy) Speed(_ *struct{}, reply *int) error {
+	*reply = ep.emu.Speed()
+	return nil
+}
+
+func (ep *emuProxy) SwitchDiskSide(_, _ *struct{}) error    { ep.emu.SwitchDiskSide(); return nil }
+func (ep *emuProxy) InsertNextDisk(_, _ *struct{}) error    { ep.emu.InsertNextDisk(); return nil }
+func (ep *emuProxy) InsertDisk(side int, _ *struct{}) error { ep.emu.InsertDisk(side); return nil }
+func (ep *emuProxy) EjectDisk(_, _ *struct{}) error         { ep.emu.EjectDisk(); return nil }
+
 func (ep *emuProxy) IsReady(_ *struct{}, reply *bool) error {
 	*reply = true
 	return nil
@@ -0,0 +1,153 @@
+package emu
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"nestor/emu/log"
+)
+
+// Emulation speeds, in percents of the normal speed.
+const (
+	NormalSpeed = 100
+	MinSpeed    = 25
+	MaxSpeed    = 1000
+	Unthrottled = 0 // as fast as possible
+)
+
+// speedSteps are the speeds selected by the speed hotkeys.
+var speedSteps = []int{25, 50, 75, 100, 150, 200, 300, 400, 500, 750, 1000, Unthrottled}
+
+type SpeedConfig struct {
+	// Speed, in percents, while fast-forwarding. 0 runs unthrottled.
+	FastForward int `toml:"fast_forward"`
+
+	// Speed, in percents, in slow motion.
+	SlowMotion int `toml:"slow_motion"`
+}
+
+func (scfg *SpeedConfig) Check() {
+	scfg.FastForward = clampSpeed(scfg.FastForward)
+	if scfg.SlowMotion == Unthrottled {
+		scfg.SlowMotion = NormalSpeed / 2
+	}
+	scfg.SlowMotion = clampSpeed(scfg.SlowMotion)
+}
+
+// clampSpeed clamps speed between MinSpeed and MaxSpeed, unless unthrottled.
+func clampSpeed(speed int) int {
+	if speed == Unthrottled {
+		return Unthrottled
+	}
+	return min(max(speed, MinSpeed), MaxSpeed)
+}
+
+// SetSpeed sets the emulation speed, in percents of the normal speed, from
+// MinSpeed to MaxSpeed, or Unthrottled. It's safe for concurrent use.
+func (e *Emulator) SetSpeed(percent int) {
+	speed := clampSpeed(percent)
+	e.speed.Store(int32(speed))
+	log.ModEmu.InfoZ("Emulation speed changed").String("speed", speedString(speed)).End()
+}
+
+// Speed returns the emulation speed set with SetSpeed.
+func (e *Emulator) Speed() int {
+	return int(e.speed.Load())
+}
+
+// currentSpeed returns the speed at which to emulate, taking fast-forward and
+// slow motion into account.
+func (e *Emulator) currentSpeed() int {
+	switch {
+	case e.fastForward.Load() || e.ffHold.Load():
+		return e.ffSpeed
+	case e.slowMotion.Load():
+		return e.slowSpeed
+	}
+	return e.Speed()
+}
+
+// stepSpeed returns the speed step following speed, or preceding it if up is
+// false.
+func stepSpeed(speed int, up bool) int {
+	rank := func(speed int) int {
+		if speed == Unthrottled {
+			return math.MaxInt
+		}
+		return speed
+	}
+
+	if up {
+		for _, s := range speedSteps {
+			if rank(s) > rank(speed) {
+				return s
+			}
+		}
+		return speedSteps[len(speedSteps)-1]
+	}
+	for _, s := range slices.Backward(speedSteps) {
+		if rank(s) < rank(speed) {
+			return s
+		}
+	}
+	return speedSteps[0]
+}
+
+func speedString(speed int) string {
+	if speed == Unthrottled {
+		return "Unthrottled"
+	}
+	return fmt.Sprintf("%d%%", speed)
+}
+
+// framePeriod is the duration of a frame at normal speed.
+const framePeriod = time.Second / 60
+
+// runFrames emulates the next frame at the current speed. At normal speed,
+// the output paces the emulation (i.e vertical sync). At other speeds, the
+// emulation is paced here and frames are dropped, rather than waited for,
+// when the output can't keep up. Sound is then muted.
+func (e *Emulator) runFrames() {
+	speed := e.currentSpeed()
+	e.NES.Mixer.SetMuted(speed != NormalSpeed)
+
+	if speed == NormalSpeed {
+		e.RunOneFrame()
+		return
+	}
+
+	frame := e.emulateFrame()
+	if frame.Status == "" {
+		frame.Status = speedString(speed)
+	} else {
+		frame.Status += " - " + speedString(speed)
+	}
+	e.out.TryEndFrame(frame)
+
+	if speed != Unthrottled {
+		e.pacer.wait(framePeriod * NormalSpeed / time.Duration(speed))
+	}
+}
+
+// maxLag is how late the emulation can run before the pacer gives up
+// catching up.
+const maxLag = 100 * time.Millisecond
+
+// A pacer paces the emulation, by sleeping until the next frame is due.
+type pacer struct {
+	next time.Time // time at which the next frame is due
+}
+
+// wait sleeps until the next frame is due, period after the previous one.
+// When running late by more than maxLag (e.g after a pause), the schedule
+// starts over rather than running frames in bursts to catch up.
+func (p *pacer) wait(period time.Duration) {
+	now := time.Now()
+	if now.Sub(p.next) > maxLag {
+		p.next = now
+	}
+	p.next = p.next.Add(period)
+	time.Sleep(time.Until(p.next))
+}
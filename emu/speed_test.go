@@ -0,0 +1,67 @@
+package emu
+
+import "testing"
+
+func TestStepSpeed(t *testing.T) {
+	tests := []struct {
+		speed    int
+		up, down int
+	}{
+		{speed: 100, up: 150, down: 75},
+		{speed: 25, up: 50, down: 25},
+		{speed: 120, up: 150, down: 100},
+		{speed: 1000, up: Unthrottled, down: 750},
+		{speed: Unthrottled, up: Unthrottled, down: 1000},
+	}
+	for _, tt := range tests {
+		if got := stepSpeed(tt.speed, true); got != tt.up {
+			t.Errorf("stepSpeed(%d, up) = %d, want %d", tt.speed, got, tt.up)
+		}
+		if got := stepSpeed(tt.speed, false); got != tt.down {
+			t.Errorf("stepSpeed(%d, down) = %d, want %d", tt.speed, got, tt.down)
+		}
+	}
+}
+
+func TestSetSpeed(t *testing.T) {
+	var e Emulator
+	e.ffSpeed = Unthrottled
+	e.slowSpeed = 50
+
+	for _, tt := range []struct{ set, want int }{
+		{set: 200, want: 200},
+		{set: 10, want: MinSpeed},
+		{set: 5000, want: MaxSpeed},
+		{set: Unthrottled, want: Unthrottled},
+		{set: NormalSpeed, want: NormalSpeed},
+	} {
+		e.SetSpeed(tt.set)
+		if got := e.Speed(); got != tt.want {
+			t.Errorf("SetSpeed(%d): Speed() = %d, want %d", tt.set, got, tt.want)
+		}
+	}
+
+	e.slowMotion.Store(true)
+	if got := e.currentSpeed(); got != 50 {
+		t.Errorf("slow motion speed = %d, want 50", got)
+	}
+	// Fast-forward takes precedence.
+	e.ffHold.Store(true)
+	if got := e.currentSpeed(); got != Unthrottled {
+		t.Errorf("fast-forward speed = %d, want unthrottled", got)
+	}
+}
+
+func TestSpeedConfigCheck(t *testing.T) {
+	cfg := SpeedConfig{FastForward: 2000, SlowMotion: 0}
+	cfg.Check()
+	if want := (SpeedConfig{FastForward: MaxSpeed, SlowMotion: 50}); cfg != want {
+		t.Errorf("Check() = %+v, want %+v", cfg, want)
+	}
+
+	cfg = SpeedConfig{FastForward: Unthrottled, SlowMotion: 10}
+	cfg.Check()
+	if want := (SpeedConfig{FastForward: Unthrottled, SlowMotion: MinSpeed}); cfg != want {
+		t.Errorf("Check() = %+v, want %+v", cfg, want)
+	}
+}
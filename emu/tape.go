@@ -0,0 +1,131 @@
+package emu
+
+import (
+	"fmt"
+	"os"
+
+	"nestor/emu/log"
+	"nestor/hw"
+)
+
+// Data recorder requests.
+const (
+	noTapeReq int32 = iota
+	playTapeReq
+	recordTapeReq
+	stopTapeReq
+)
+
+// tapeAmplitude is the amplitude of the recorded tape signal.
+const tapeAmplitude = 0x4000
+
+// PlayTape, RecordTape and StopTape allow to operate the data recorder in a
+// concurrent-safe way. Tapes are played from, and recorded to, the WAV file
+// at the tape path set in the configuration. They have no effect when the
+// Family BASIC keyboard isn't plugged in.
+
+func (e *Emulator) PlayTape()   { e.tapereq.Store(playTapeReq) }
+func (e *Emulator) RecordTape() { e.tapereq.Store(recordTapeReq) }
+func (e *Emulator) StopTape()   { e.tapereq.Store(stopTapeReq) }
+
+func (e *Emulator) handleTapeRequest() {
+	req := e.tapereq.Swap(noTapeReq)
+	dr := e.NES.Recorder
+	if req == noTapeReq || dr == nil {
+		return
+	}
+
+	// Any request first stops the current tape, saving it if it was being
+	// recorded.
+	e.stopTape()
+
+	switch req {
+	case playTapeReq:
+		tape, err := loadTape(e.tapePath)
+		if err != nil {
+			log.ModEmu.WarnZ("Failed to load tape").String("path", e.tapePath).Error("err", err).End()
+			return
+		}
+		dr.Play(tape)
+		log.ModEmu.InfoZ("Playing tape").String("path", e.tapePath).End()
+	case recordTapeReq:
+		dr.Record()
+		log.ModEmu.InfoZ("Recording tape").String("path", e.tapePath).End()
+	}
+}
+
+// stopTape stops the data recorder, and saves the tape if it was being
+// recorded.
+func (e *Emulator) stopTape() {
+	dr := e.NES.Recorder
+	if dr == nil {
+		return
+	}
+	if !dr.Recording() {
+		dr.Stop()
+		return
+	}
+
+	tape := dr.Stop()
+	if err := saveTape(e.tapePath, tape); err != nil {
+		log.ModEmu.WarnZ("Failed to save tape").String("path", e.tapePath).Error("err", err).End()
+		return
+	}
+	log.ModEmu.InfoZ("Tape saved").String("path", e.tapePath).Int("samples", len(tape)).End()
+}
+
+// loadTape reads a WAV file and converts it to a data recorder tape.
+func loadTape(path string) ([]uint8, error) {
+	if path == "" {
+		return nil, fmt.Errorf("no tape path")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	samples, rate, err := readWAV(f)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resample to the tape sample rate, and keep the sign of the signal.
+	tape := make([]uint8, int64(len(samples))*hw.TapeSampleRate/int64(rate))
+	for i := range tape {
+		if samples[int64(i)*int64(rate)/hw.TapeSampleRate] > 0 {
+			tape[i] = 1
+		}
+	}
+	return tape, nil
+}
+
+// saveTape writes a data recorder tape as a 16-bit mono WAV file.
+func saveTape(path string, tape []uint8) error {
+	if path == "" {
+		return fmt.Errorf("no tape path")
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	wav, err := newWAVWriter(f, hw.TapeSampleRate, 1)
+	if err != nil {
+		return err
+	}
+	samples := make([]int16, len(tape))
+	for i, bit := range tape {
+		samples[i] = -tapeAmplitude
+		if bit != 0 {
+			samples[i] = tapeAmplitude
+		}
+	}
+	wav.write(samples)
+	if err := wav.close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,57 @@
+package emu
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"nestor/hw"
+)
+
+func TestTapeRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tape.wav")
+	tape := []uint8{0, 1, 1, 0, 1, 0, 0, 0, 1, 1}
+
+	if err := saveTape(path, tape); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadTape(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, tape) {
+		t.Errorf("loadTape() = %v, want %v", got, tape)
+	}
+}
+
+func TestLoadTape8Bit(t *testing.T) {
+	// 8-bit stereo at twice the tape sample rate.
+	const rate = hw.TapeSampleRate * 2
+	data := []byte{
+		0xC0, 0xC0, 0xC0, 0xC0, // 1
+		0x40, 0x40, 0x40, 0x40, // 0
+		0xFF, 0x20, 0xFF, 0x20, // 1 (mixed)
+	}
+
+	hdr := []byte("RIFF\x00\x00\x00\x00WAVEfmt \x10\x00\x00\x00\x01\x00\x02\x00")
+	hdr = binary.LittleEndian.AppendUint32(hdr, rate)
+	hdr = binary.LittleEndian.AppendUint32(hdr, rate*2)
+	hdr = append(hdr, 0x02, 0x00, 0x08, 0x00)
+	hdr = append(hdr, "data"...)
+	hdr = binary.LittleEndian.AppendUint32(hdr, uint32(len(data)))
+
+	path := filepath.Join(t.TempDir(), "tape.wav")
+	if err := os.WriteFile(path, append(hdr, data...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := loadTape(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint8{1, 0, 1}; !slices.Equal(got, want) {
+		t.Errorf("loadTape() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,85 @@
+package emu
+
+import (
+	"path/filepath"
+	"strings"
+
+	"nestor/emu/log"
+	"nestor/hw"
+	"nestor/ines"
+)
+
+type VsConfig struct {
+	// Games holds the settings of Vs. System games, by rom name (file name
+	// without extension).
+	Games map[string]VsGameConfig `toml:"games"`
+}
+
+// VsGameConfig holds the settings of a Vs. System arcade cabinet.
+type VsGameConfig struct {
+	// DIP switches 1 to 8 (bits 0 to 7), they set the number of coins per
+	// credit, the difficulty, the number of lives, etc. Their meaning is
+	// specific to each game.
+	DIPSwitches uint8 `toml:"dip_switches"`
+
+	// Some games expect the first player joystick on the second port.
+	SwapControllers bool `toml:"swap_controllers"`
+
+	// PPU overrides the PPU model set in the rom header, by its name (e.g.
+	// "RC2C05-04"). iNES 1.0 headers don't specify it, and RP2C03B is
+	// assumed, which shows wrong colors for games made for other models.
+	PPU *ines.VsPPU `toml:"ppu,omitempty"`
+}
+
+// Game returns the settings of the Vs. System game in the given rom file.
+func (cfg VsConfig) Game(romName string) VsGameConfig {
+	return cfg.Games[strings.TrimSuffix(romName, filepath.Ext(romName))]
+}
+
+// vsPPUModels maps the PPUs found in Vs. System arcade boards to the
+// emulated models, the RP2C03 and RC2C03 revisions behave the same.
+var vsPPUModels = map[ines.VsPPU]hw.PPUModel{
+	ines.RP2C03B:     hw.PPU2C03,
+	ines.RP2C03G:     hw.PPU2C03,
+	ines.RP2C04_0001: hw.PPU2C04_0001,
+	ines.RP2C04_0002: hw.PPU2C04_0002,
+	ines.RP2C04_0003: hw.PPU2C04_0003,
+	ines.RP2C04_0004: hw.PPU2C04_0004,
+	ines.RC2C03B:     hw.PPU2C03,
+	ines.RC2C03C:     hw.PPU2C03,
+	ines.RC2C05_01:   hw.PPU2C05_01,
+	ines.RC2C05_02:   hw.PPU2C05_02,
+	ines.RC2C05_03:   hw.PPU2C05_03,
+	ines.RC2C05_04:   hw.PPU2C05_04,
+	ines.RC2C05_05:   hw.PPU2C05_05,
+}
+
+// plugVsSystem sets up the Vs. System arcade hardware surrounding the
+// cartridge: its RGB PPU, coin slots, service button and DIP switches.
+func (nes *NES) plugVsSystem(rom *ines.Rom, cfg VsConfig) {
+	game := cfg.Game(rom.Name)
+	nes.Vs = &hw.VsSystem{
+		DIPSwitches:     game.DIPSwitches,
+		SwapControllers: game.SwapControllers,
+	}
+	nes.CPU.PlugVsSystem(nes.Vs)
+
+	ppu := rom.VsPPU()
+	if game.PPU != nil {
+		ppu = *game.PPU
+	}
+	model, ok := vsPPUModels[ppu]
+	if !ok {
+		log.ModEmu.WarnZ("Unknown Vs. System PPU, using RP2C03").Uint8("ppu", uint8(ppu)).End()
+		model = hw.PPU2C03
+	}
+	nes.PPU.SetModel(model)
+
+	switch rom.VsHardware() {
+	case ines.VsUnisystem:
+	case ines.VsDualSystem, ines.VsRaidOnBungelingBay:
+		log.ModEmu.WarnZ("Vs. Dual System is not supported, only the primary CPU is emulated").End()
+	default:
+		log.ModEmu.WarnZ("Vs. System protection hardware is not emulated").String("hardware", rom.VsHardware().String()).End()
+	}
+}
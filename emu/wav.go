@@ -0,0 +1,153 @@
+package emu
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// wavWriter writes 16-bit PCM samples as a WAV file. The header,
+// which holds the data size, is completed when closing.
+type wavWriter struct {
+	w    io.WriteSeeker
+	size uint32 // data size, in bytes
+	buf  []byte
+	err  error
+}
+
+const wavHeaderSize = 44
+
+// newWAVWriter writes the WAV header to w. Samples of multiple channels are
+// interleaved.
+func newWAVWriter(w io.WriteSeeker, sampleRate, channels int) (*wavWriter, error) {
+	const bitsPerSample = 16
+	blockAlign := channels * bitsPerSample / 8
+
+	hdr := make([]byte, 0, wavHeaderSize)
+	hdr = append(hdr, "RIFF"...)
+	hdr = binary.LittleEndian.AppendUint32(hdr, 0) // RIFF size, set by close
+	hdr = append(hdr, "WAVE"...)
+	hdr = append(hdr, "fmt "...)
+	hdr = binary.LittleEndian.AppendUint32(hdr, 16)
+	hdr = binary.LittleEndian.AppendUint16(hdr, 1) // PCM
+	hdr = binary.LittleEndian.AppendUint16(hdr, uint16(channels))
+	hdr = binary.LittleEndian.AppendUint32(hdr, uint32(sampleRate))
+	hdr = binary.LittleEndian.AppendUint32(hdr, uint32(sampleRate*blockAlign))
+	hdr = binary.LittleEndian.AppendUint16(hdr, uint16(blockAlign))
+	hdr = binary.LittleEndian.AppendUint16(hdr, bitsPerSample)
+	hdr = append(hdr, "data"...)
+	hdr = binary.LittleEndian.AppendUint32(hdr, 0) // data size, set by close
+
+	if _, err := w.Write(hdr); err != nil {
+		return nil, err
+	}
+	return &wavWriter{w: w}, nil
+}
+
+func (ww *wavWriter) write(samples []int16) {
+	if ww.err != nil {
+		return
+	}
+	ww.buf = ww.buf[:0]
+	for _, s := range samples {
+		ww.buf = binary.LittleEndian.AppendUint16(ww.buf, uint16(s))
+	}
+	_, ww.err = ww.w.Write(ww.buf)
+	ww.size += uint32(len(ww.buf))
+}
+
+// close completes the WAV header, w isn't closed.
+func (ww *wavWriter) close() error {
+	if ww.err != nil {
+		return ww.err
+	}
+
+	var sizes [4]byte
+	binary.LittleEndian.PutUint32(sizes[:], wavHeaderSize-8+ww.size)
+	if _, err := ww.w.Seek(4, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := ww.w.Write(sizes[:]); err != nil {
+		return err
+	}
+
+	binary.LittleEndian.PutUint32(sizes[:], ww.size)
+	if _, err := ww.w.Seek(wavHeaderSize-4, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := ww.w.Write(sizes[:])
+	return err
+}
+
+// readWAV reads a 8 or 16-bit PCM WAV file and returns its samples, with all
+// channels mixed, and its sample rate.
+func readWAV(r io.Reader) (samples []int16, sampleRate int, err error) {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(buf) < 12 || string(buf[0:4]) != "RIFF" || string(buf[8:12]) != "WAVE" {
+		return nil, 0, fmt.Errorf("not a WAV file")
+	}
+
+	var (
+		channels, bitsPerSample int
+		data                    []byte
+		hasFmt                  bool
+	)
+	for buf = buf[12:]; len(buf) >= 8 && data == nil; {
+		id, size := string(buf[0:4]), binary.LittleEndian.Uint32(buf[4:8])
+		buf = buf[8:]
+		if uint64(size) > uint64(len(buf)) {
+			size = uint32(len(buf))
+		}
+		chunk := buf[:size]
+
+		switch id {
+		case "fmt ":
+			if len(chunk) < 16 {
+				return nil, 0, fmt.Errorf("WAV format chunk too short")
+			}
+			if format := binary.LittleEndian.Uint16(chunk[0:2]); format != 1 {
+				return nil, 0, fmt.Errorf("unsupported WAV format %d, only PCM is supported", format)
+			}
+			channels = int(binary.LittleEndian.Uint16(chunk[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(chunk[4:8]))
+			bitsPerSample = int(binary.LittleEndian.Uint16(chunk[14:16]))
+			hasFmt = true
+		case "data":
+			data = chunk
+		}
+
+		// Chunks are word-aligned.
+		buf = buf[min(int(size)+int(size&1), len(buf)):]
+	}
+
+	switch {
+	case !hasFmt:
+		return nil, 0, fmt.Errorf("WAV format chunk not found")
+	case data == nil:
+		return nil, 0, fmt.Errorf("WAV data chunk not found")
+	case channels == 0 || sampleRate == 0:
+		return nil, 0, fmt.Errorf("invalid WAV format")
+	case bitsPerSample != 8 && bitsPerSample != 16:
+		return nil, 0, fmt.Errorf("unsupported %d-bit WAV samples", bitsPerSample)
+	}
+
+	blockAlign := channels * bitsPerSample / 8
+	samples = make([]int16, len(data)/blockAlign)
+	for i := range samples {
+		block := data[i*blockAlign:]
+		var sum int
+		for c := range channels {
+			if bitsPerSample == 8 {
+				// 8-bit samples are unsigned.
+				sum += (int(block[c]) - 0x80) << 8
+			} else {
+				sum += int(int16(binary.LittleEndian.Uint16(block[c*2:])))
+			}
+		}
+		samples[i] = int16(sum / channels)
+	}
+	return samples, sampleRate, nil
+}
@@ -0,0 +1,294 @@
+// package fds implements a Reader for Famicom Disk System images, in the fwNES
+// (.fds) format, with or without header, or in the QD format.
+package fds
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"nestor/patch"
+)
+
+const Magic = "FDS\x1a"
+
+const (
+	SideSize   = 65500 // size of a disk side in .fds images
+	qdSideSize = 65536 // size of a disk side in QD images (includes CRCs)
+)
+
+// Block types.
+const (
+	blockDiskInfo   = 1
+	blockFileAmount = 2
+	blockFileHeader = 3
+	blockFileData   = 4
+)
+
+const diskInfoMagic = "*NINTENDO-HVC*"
+
+type Disk struct {
+	Sides [][]byte // Disk sides, in .fds format (without gaps and CRCs)
+	Name  string
+
+	// orig is the disk, as it was before applying saved disk writes. Disk
+	// writes are saved as a patch of it.
+	orig []byte
+
+	// path to the file containing saved disk writes.
+	savePath string
+}
+
+func (d *Disk) PrintInfos(w io.Writer) {
+	fmt.Fprintf(w, "%s\n", d.Name)
+	fmt.Fprintf(w, "|Disk sides             | % 14d |\n", len(d.Sides))
+	for i, side := range d.Sides {
+		fmt.Fprintf(w, "|Side %-2d game name      | % 14q |\n", i, string(side[16:19]))
+	}
+}
+
+// ReadDisk loads a disk image, then applies the disk writes previously saved
+// alongside it, if any.
+func ReadDisk(path string) (*Disk, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return LoadDisk(buf, filepath.Base(path), SavePath(path))
+}
+
+// LoadDisk decodes a disk image, then applies the disk writes previously saved
+// in savePath, if any. Disk writes are later saved in savePath.
+func LoadDisk(buf []byte, name, savePath string) (*Disk, error) {
+	disk, err := Decode(buf)
+	if err != nil {
+		return nil, err
+	}
+	disk.Name = name
+	disk.savePath = savePath
+
+	ips, err := os.ReadFile(disk.savePath)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return disk, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to read saved disk writes: %w", err)
+	}
+
+	cur, err := patch.ApplyIPS(bytes.Join(disk.Sides, nil), ips)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply saved disk writes: %w", err)
+	}
+	if len(cur) != len(disk.orig) {
+		return nil, fmt.Errorf("failed to apply saved disk writes: disk size changed")
+	}
+	for i := range disk.Sides {
+		disk.Sides[i] = cur[i*SideSize : (i+1)*SideSize]
+	}
+	return disk, nil
+}
+
+// SavePath returns the path of the file where disk writes are saved, for the
+// disk image at the given path. Writes are saved as an IPS patch so that the
+// original image is never modified.
+func SavePath(path string) string {
+	return path + ".sav"
+}
+
+// SaveWrites saves the changes made to the disk since it's been loaded, as a
+// patch of the original disk image.
+func (d *Disk) SaveWrites() error {
+	if d.savePath == "" {
+		return nil
+	}
+	cur := bytes.Join(d.Sides, nil)
+	if bytes.Equal(cur, d.orig) {
+		return nil
+	}
+	ips, err := patch.CreateIPS(d.orig, cur)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(d.savePath, ips, 0644)
+}
+
+// Decode decodes a disk image, in .fds (with or without header) or in QD
+// format.
+func Decode(buf []byte) (*Disk, error) {
+	var sides [][]byte
+
+	switch {
+	case len(buf) >= 16 && string(buf[:4]) == Magic:
+		nsides := int(buf[4])
+		buf = buf[16:]
+		if len(buf) < nsides*SideSize {
+			return nil, fmt.Errorf("incomplete disk, header says %d sides", nsides)
+		}
+		for i := range nsides {
+			sides = append(sides, buf[i*SideSize:(i+1)*SideSize])
+		}
+	case len(buf) > 0 && len(buf)%SideSize == 0:
+		for i := range len(buf) / SideSize {
+			sides = append(sides, buf[i*SideSize:(i+1)*SideSize])
+		}
+	case len(buf) > 0 && len(buf)%qdSideSize == 0:
+		for i := range len(buf) / qdSideSize {
+			sides = append(sides, fromQD(buf[i*qdSideSize:(i+1)*qdSideSize]))
+		}
+	default:
+		return nil, fmt.Errorf("unknown disk image format (size: %d bytes)", len(buf))
+	}
+	if len(sides) == 0 {
+		return nil, fmt.Errorf("disk has no sides")
+	}
+
+	for i := range sides {
+		// Copy sides so that modifying them doesn't modify the input buffer.
+		sides[i] = bytes.Clone(sides[i])
+		if sides[i][0] != blockDiskInfo || string(sides[i][1:15]) != diskInfoMagic {
+			return nil, fmt.Errorf("side %d: invalid disk info block", i)
+		}
+	}
+
+	return &Disk{
+		Sides: sides,
+		orig:  bytes.Join(sides, nil),
+	}, nil
+}
+
+// blockLength returns the length of the block starting at data[0], or 0 if
+// it's not a valid block.
+func blockLength(data []byte, filesize int) int {
+	switch data[0] {
+	case blockDiskInfo:
+		return 56
+	case blockFileAmount:
+		return 2
+	case blockFileHeader:
+		return 16
+	case blockFileData:
+		return 1 + filesize
+	}
+	return 0
+}
+
+// forEachBlock calls fn for each block of the given disk side, skipping crclen
+// bytes after each block, until fn returns false.
+func forEachBlock(side []byte, crclen int, fn func(off, length int) bool) {
+	filesize := 0
+	for off := 0; off < len(side); {
+		length := blockLength(side[off:], filesize)
+		if length == 0 || off+length > len(side) {
+			return
+		}
+		if side[off] == blockFileHeader {
+			filesize = int(side[off+13]) | int(side[off+14])<<8
+		}
+		if !fn(off, length) {
+			return
+		}
+		off += length + crclen
+	}
+}
+
+// fromQD converts a QD disk side into a .fds disk side, by removing CRCs.
+func fromQD(qd []byte) []byte {
+	side := make([]byte, 0, SideSize)
+	forEachBlock(qd, 2, func(off, length int) bool {
+		side = append(side, qd[off:off+length]...)
+		return true
+	})
+	return side[:SideSize]
+}
+
+// Gap lengths, in bytes, as they're found on real disks.
+const (
+	leadInGap = 28300 / 8 // before the first block
+	blockGap  = 976 / 8   // between blocks
+)
+
+// RawSize is the size of a disk side as seen by the disk drive, that is with
+// gaps, block start marks and CRCs.
+const RawSize = leadInGap + SideSize + 4096
+
+// AddGaps converts a .fds disk side into the byte stream seen by the disk
+// drive, that is:
+//   - a lead-in gap,
+//   - for each block, a block start mark ($80), the block data, its CRC and a
+//     gap.
+func AddGaps(side []byte) []byte {
+	raw := make([]byte, leadInGap, RawSize)
+	forEachBlock(side, 0, func(off, length int) bool {
+		if len(raw)+length+3+blockGap > RawSize {
+			return false
+		}
+		start := len(raw)
+		raw = append(raw, 0x80)
+		raw = append(raw, side[off:off+length]...)
+		crc := CRC(raw[start:])
+		raw = append(raw, uint8(crc), uint8(crc>>8))
+		raw = append(raw, make([]byte, blockGap)...)
+		return true
+	})
+	return raw[:RawSize]
+}
+
+// RemoveGaps converts the byte stream seen by the disk drive back into a .fds
+// disk side.
+func RemoveGaps(raw []byte) []byte {
+	side := make([]byte, 0, SideSize)
+	filesize := 0
+	for off := 0; off < len(raw); {
+		// Skip gap up to the block start mark.
+		for off < len(raw) && raw[off] == 0 {
+			off++
+		}
+		if off >= len(raw) || raw[off] != 0x80 {
+			break
+		}
+		off++
+
+		length := blockLength(raw[off:], filesize)
+		if length == 0 || off+length > len(raw) || len(side)+length > SideSize {
+			break
+		}
+		if raw[off] == blockFileHeader {
+			filesize = int(raw[off+13]) | int(raw[off+14])<<8
+		}
+		side = append(side, raw[off:off+length]...)
+		off += length + 2 // skip CRC
+	}
+	return side[:SideSize]
+}
+
+// CRC computes the CRC of a block, as computed by the disk drive, data must
+// include the block start mark.
+func CRC(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc = UpdateCRC(crc, b)
+	}
+	// The CRC is then finalized by shifting 16 more zero bits.
+	crc = UpdateCRC(crc, 0)
+	crc = UpdateCRC(crc, 0)
+	return crc
+}
+
+// UpdateCRC shifts 8 bits, LSB first, into the CRC accumulator.
+func UpdateCRC(crc uint16, val uint8) uint16 {
+	for n := uint8(0x01); n != 0; n <<= 1 {
+		carry := crc & 0x01
+		crc >>= 1
+		if carry != 0 {
+			crc ^= 0x8408
+		}
+		if val&n != 0 {
+			crc ^= 0x8000
+		}
+	}
+	return crc
+}
@@ -0,0 +1,110 @@
+package fds
+
+import (
+	"bytes"
+	"testing"
+)
+
+// testSide builds a disk side with a single 4 bytes file.
+func testSide() []byte {
+	side := make([]byte, 0, SideSize)
+
+	info := make([]byte, 56)
+	info[0] = blockDiskInfo
+	copy(info[1:], diskInfoMagic)
+	copy(info[16:], "TST")
+	side = append(side, info...)
+
+	side = append(side, blockFileAmount, 1)
+
+	hdr := make([]byte, 16)
+	hdr[0] = blockFileHeader
+	hdr[13] = 4 // file size
+	side = append(side, hdr...)
+
+	side = append(side, blockFileData, 0xDE, 0xAD, 0xBE, 0xEF)
+	return side[:SideSize]
+}
+
+func TestDecode(t *testing.T) {
+	side := testSide()
+
+	hdr := make([]byte, 16)
+	copy(hdr, Magic)
+	hdr[4] = 2
+
+	tests := []struct {
+		name string
+		img  []byte
+	}{
+		{"fds", bytes.Join([][]byte{hdr, side, side}, nil)},
+		{"headerless", bytes.Join([][]byte{side, side}, nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			disk, err := Decode(tt.img)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(disk.Sides) != 2 {
+				t.Fatalf("got %d sides, want 2", len(disk.Sides))
+			}
+			for i := range disk.Sides {
+				if !bytes.Equal(disk.Sides[i], side) {
+					t.Errorf("side %d differs", i)
+				}
+			}
+		})
+	}
+}
+
+func TestGapsRoundTrip(t *testing.T) {
+	side := testSide()
+	raw := AddGaps(side)
+	if len(raw) != RawSize {
+		t.Fatalf("raw side size = %d, want %d", len(raw), RawSize)
+	}
+
+	// Each block, along with its start mark and CRC, should have a zero CRC.
+	nblocks := 0
+	for off := leadInGap; raw[off] == 0x80; nblocks++ {
+		length := blockLength(raw[off+1:], 4)
+		var crc uint16
+		for _, b := range raw[off : off+1+length+2] {
+			crc = UpdateCRC(crc, b)
+		}
+		if crc != 0 {
+			t.Errorf("block %d: crc = %#04x, want 0", nblocks, crc)
+		}
+		off += 1 + length + 2 + blockGap
+	}
+	if nblocks != 4 {
+		t.Errorf("found %d blocks, want 4", nblocks)
+	}
+
+	if got := RemoveGaps(raw); !bytes.Equal(got, side) {
+		t.Errorf("RemoveGaps(AddGaps(side)) != side")
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	hdr := make([]byte, 16)
+	copy(hdr, Magic)
+
+	tests := []struct {
+		name string
+		img  []byte
+	}{
+		{"no sides", hdr},
+		{"missing side", bytes.Join([][]byte{{'F', 'D', 'S', 0x1A, 2}, hdr[5:], testSide()}, nil)},
+		{"empty", nil},
+		{"short", testSide()[:100]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if disk, err := Decode(tt.img); err == nil {
+				t.Errorf("Decode succeeded with %d sides", len(disk.Sides))
+			}
+		})
+	}
+}
@@ -215,6 +215,12 @@ func (a *APU) Run() {
 	}
 }
 
+// AddExpansionAudioDelta adds a change in the output level of an expansion
+// audio channel, at the current APU cycle.
+func (a *APU) AddExpansionAudioDelta(ch apu.Channel, delta int16) {
+	a.mixer.AddDelta(ch, a.curCycle, delta)
+}
+
 func (a *APU) SetNeedToRun() {
 	a.needToRun_ = true
 }
@@ -0,0 +1,303 @@
+package apu
+
+// FDSAudio is the expansion audio of the Famicom Disk System. It has a single
+// wavetable channel (64 6-bit samples) whose pitch can be modulated by a
+// modulation unit, itself driven by a 64 entries table of 3-bit values. Both
+// the wave and the modulation units have a volume/gain envelope.
+type FDSAudio struct {
+	mixer expansionMixer
+
+	volume fdsEnvelope
+	mod    fdsModulator
+
+	waveTable     [64]uint8
+	waveWrite     bool // wave table write enable (also halts the channel)
+	haltWave      bool
+	haltEnvelopes bool
+	masterVolume  uint8
+
+	waveAccum uint16
+	wavePos   uint8
+
+	lastOutput int16
+}
+
+func NewFDSAudio(mixer expansionMixer) FDSAudio {
+	return FDSAudio{
+		mixer:  mixer,
+		volume: fdsEnvelope{masterSpeed: 0xE8},
+		mod:    fdsModulator{fdsEnvelope: fdsEnvelope{masterSpeed: 0xE8}},
+	}
+}
+
+// fdsEnvelope is the envelope and frequency unit shared by the wave and
+// modulation units.
+type fdsEnvelope struct {
+	speed       uint8
+	gain        uint8
+	envDisabled bool
+	increase    bool
+	freq        uint16
+	timer       uint32
+	masterSpeed uint8
+}
+
+// writeEnvelope handles writes to the envelope registers ($4080/$4084).
+//
+//	7  bit  0
+//	---- ----
+//	MDSS SSSS
+//	|||| ||||
+//	||++-++++- Envelope speed (or gain if envelope is disabled)
+//	|+-------- Envelope direction (0 = decrease, 1 = increase)
+//	+--------- Envelope disabled
+func (e *fdsEnvelope) writeEnvelope(val uint8) {
+	e.speed = val & 0x3F
+	e.increase = val&0x40 != 0
+	e.envDisabled = val&0x80 != 0
+	e.resetTimer()
+	if e.envDisabled {
+		e.gain = e.speed
+	}
+}
+
+func (e *fdsEnvelope) writeFreqLo(val uint8) { e.freq = e.freq&0x0F00 | uint16(val) }
+func (e *fdsEnvelope) writeFreqHi(val uint8) { e.freq = e.freq&0x00FF | uint16(val&0x0F)<<8 }
+
+func (e *fdsEnvelope) resetTimer() {
+	e.timer = 8 * (uint32(e.speed) + 1) * uint32(e.masterSpeed)
+}
+
+// tick clocks the envelope and reports whether the gain has been updated.
+func (e *fdsEnvelope) tick() bool {
+	if e.envDisabled || e.masterSpeed == 0 {
+		return false
+	}
+	e.timer--
+	if e.timer != 0 {
+		return false
+	}
+	e.resetTimer()
+	if e.increase && e.gain < 32 {
+		e.gain++
+	} else if !e.increase && e.gain > 0 {
+		e.gain--
+	}
+	return true
+}
+
+type fdsModulator struct {
+	fdsEnvelope
+
+	counter  int8 // 7-bit signed
+	disabled bool
+	table    [64]uint8
+	tablePos uint8
+	accum    uint16
+	output   int32
+}
+
+// Modulation table values adjust the modulation counter, 4 means reset to 0.
+var fdsModAdjust = [8]int8{0, 1, 2, 4, 0, -4, -2, -1}
+
+func (m *fdsModulator) setCounter(val int8) {
+	// Wrap to 7-bit signed.
+	m.counter = int8(uint8(val)<<1) >> 1
+}
+
+// writeTable appends a value to the modulation table, only when the
+// modulation unit is disabled. Each write fills 2 entries.
+func (m *fdsModulator) writeTable(val uint8) {
+	if !m.disabled {
+		return
+	}
+	m.table[m.tablePos&0x3F] = val & 0x07
+	m.table[(m.tablePos+1)&0x3F] = val & 0x07
+	m.tablePos = (m.tablePos + 2) & 0x3F
+}
+
+func (m *fdsModulator) enabled() bool {
+	return !m.disabled && m.freq > 0
+}
+
+// tick clocks the modulation unit and reports whether the modulation counter
+// has been updated.
+func (m *fdsModulator) tick() bool {
+	if !m.enabled() {
+		return false
+	}
+	prev := m.accum
+	m.accum += m.freq
+	if m.accum >= prev {
+		return false
+	}
+
+	// Accumulator overflowed, apply the next modulation table entry.
+	entry := m.table[m.tablePos]
+	if entry == 4 {
+		m.setCounter(0)
+	} else {
+		m.setCounter(m.counter + fdsModAdjust[entry])
+	}
+	m.tablePos = (m.tablePos + 1) & 0x3F
+	return true
+}
+
+// updateOutput computes the pitch adjustment from the modulation counter and
+// gain, for the given wave pitch. The rounding is what the hardware does, see
+// https://www.nesdev.org/wiki/FDS_audio#Frequency_modulation
+func (m *fdsModulator) updateOutput(pitch uint16) {
+	temp := int32(m.counter) * int32(m.gain)
+	rem := temp & 0x0F
+	temp >>= 4
+	if rem > 0 && temp&0x80 == 0 {
+		if m.counter < 0 {
+			temp--
+		} else {
+			temp += 2
+		}
+	}
+
+	if temp >= 192 {
+		temp -= 256
+	} else if temp < -64 {
+		temp += 256
+	}
+
+	temp *= int32(pitch)
+	rem = temp & 0x3F
+	temp >>= 6
+	if rem >= 32 {
+		temp++
+	}
+	m.output = temp
+}
+
+func (m *fdsModulator) pitchOutput() int32 {
+	if !m.enabled() {
+		return 0
+	}
+	return m.output
+}
+
+// ReadRegister handles reads of $4040-$4097. Only the low 6 bits are driven,
+// the others are taken from open bus.
+func (f *FDSAudio) ReadRegister(addr uint16, openbus uint8) uint8 {
+	switch {
+	case addr <= 0x407F:
+		return openbus&0xC0 | f.waveTable[addr&0x3F]
+	case addr == 0x4090:
+		return openbus&0xC0 | f.volume.gain
+	case addr == 0x4092:
+		return openbus&0xC0 | f.mod.gain
+	}
+	return openbus
+}
+
+// WriteRegister handles writes to $4040-$408A.
+//
+//	$4040-$407F: wave table (6-bit samples), only when writes are enabled.
+//	$4080      : volume envelope.
+//	$4082-$4083: wave frequency (12-bit), and for $4083:
+//	             bit 7: halt waveform, bit 6: halt envelopes.
+//	$4084      : modulation envelope.
+//	$4085      : modulation counter (7-bit signed).
+//	$4086-$4087: modulation frequency (12-bit), and for $4087:
+//	             bit 7: halt modulation (allows writes to the table).
+//	$4088      : modulation table.
+//	$4089      : bit 7: wave table write enable, bits 0-1: master volume.
+//	$408A      : envelope speed multiplier.
+func (f *FDSAudio) WriteRegister(addr uint16, val uint8) {
+	if addr <= 0x407F {
+		if f.waveWrite {
+			f.waveTable[addr&0x3F] = val & 0x3F
+		}
+		return
+	}
+
+	switch addr {
+	case 0x4080:
+		f.volume.writeEnvelope(val)
+	case 0x4082:
+		f.volume.writeFreqLo(val)
+	case 0x4083:
+		f.haltEnvelopes = val&0x40 != 0
+		f.haltWave = val&0x80 != 0
+		if f.haltEnvelopes {
+			f.volume.resetTimer()
+			f.mod.resetTimer()
+		}
+		f.volume.writeFreqHi(val)
+	case 0x4084:
+		f.mod.writeEnvelope(val)
+	case 0x4085:
+		f.mod.setCounter(int8(val & 0x7F))
+	case 0x4086:
+		f.mod.writeFreqLo(val)
+	case 0x4087:
+		f.mod.writeFreqHi(val)
+		f.mod.disabled = val&0x80 != 0
+		if f.mod.disabled {
+			f.mod.accum = 0
+		}
+	case 0x4088:
+		f.mod.writeTable(val)
+	case 0x4089:
+		f.masterVolume = val & 0x03
+		f.waveWrite = val&0x80 != 0
+	case 0x408A:
+		f.volume.masterSpeed = val
+		f.mod.masterSpeed = val
+	}
+
+	if addr >= 0x4084 && addr <= 0x4087 {
+		// Modulation gain or counter may have been modified.
+		f.mod.updateOutput(f.volume.freq)
+	}
+}
+
+// Master volume, as a fraction of 36.
+var fdsMasterVolume = [4]uint32{36, 24, 17, 14}
+
+// Clock must be called on every CPU cycle.
+func (f *FDSAudio) Clock() {
+	freq := f.volume.freq
+
+	if !f.haltWave && !f.haltEnvelopes {
+		f.volume.tick()
+		if f.mod.fdsEnvelope.tick() {
+			f.mod.updateOutput(freq)
+		}
+	}
+
+	if f.mod.tick() {
+		f.mod.updateOutput(freq)
+	}
+
+	if f.haltWave {
+		f.wavePos = 0
+		f.updateOutput()
+		return
+	}
+
+	f.updateOutput()
+
+	pitch := int32(freq) + f.mod.pitchOutput()
+	if pitch > 0 && !f.waveWrite {
+		prev := f.waveAccum
+		f.waveAccum += uint16(pitch)
+		if f.waveAccum < prev {
+			f.wavePos = (f.wavePos + 1) & 0x3F
+		}
+	}
+}
+
+func (f *FDSAudio) updateOutput() {
+	level := uint32(min(f.volume.gain, 32)) * fdsMasterVolume[f.masterVolume]
+	output := int16(uint32(f.waveTable[f.wavePos]) * level / 1152)
+
+	if output != f.lastOutput {
+		f.mixer.AddExpansionAudioDelta(FDS, output-f.lastOutput)
+		f.lastOutput = output
+	}
+}
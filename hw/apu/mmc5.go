@@ -0,0 +1,148 @@
+package apu
+
+// MMC5Audio is the expansion audio of the Nintendo MMC5 mapper. It has 2 pulse
+// channels, identical to the APU ones except for the missing sweep unit, and a
+// raw 8-bit PCM channel.
+//
+// The envelopes and length counters aren't clocked by the APU frame counter
+// but by the MMC5 itself, at a fixed rate of 240Hz.
+type MMC5Audio struct {
+	mixer expansionMixer
+
+	pulse [2]mmc5Pulse
+
+	pcm         uint8
+	pcmReadMode bool
+
+	frameTimer uint16 // CPU cycles before next envelope/length counter tick
+
+	lastOutput int16
+}
+
+// CPU cycles between envelope and length counter ticks (~240Hz).
+const mmc5FramePeriod = 7457
+
+func NewMMC5Audio(mixer expansionMixer) MMC5Audio {
+	a := MMC5Audio{mixer: mixer, frameTimer: mmc5FramePeriod}
+	for i := range a.pulse {
+		a.pulse[i].envelope.lenCounter = lengthCounter{apu: nopAPU{}, channel: MMC5}
+	}
+	return a
+}
+
+// nopAPU satisfies the apu interface for the envelope and length counters of
+// the MMC5 pulses, which are not run by the APU.
+type nopAPU struct{}
+
+func (nopAPU) SetNeedToRun()              {}
+func (nopAPU) Run()                       {}
+func (nopAPU) FrameCounterTick(FrameType) {}
+
+type mmc5Pulse struct {
+	envelope envelope
+
+	duty    uint8
+	dutyPos uint8
+	period  uint16
+	timer   uint16
+}
+
+func (p *mmc5Pulse) write(reg uint16, val uint8) {
+	switch reg {
+	case 0:
+		p.envelope.init(val)
+		p.duty = (val & 0xC0) >> 6
+	case 2:
+		p.period = p.period&0x0700 | uint16(val)
+	case 3:
+		p.envelope.lenCounter.load(val >> 3)
+		p.period = p.period&0x00FF | uint16(val&0x07)<<8
+		p.dutyPos = 0
+		p.envelope.restart()
+	}
+}
+
+// clock is called every CPU cycle, the sequencer is stepped every other
+// cycle, like APU pulses.
+func (p *mmc5Pulse) clock() {
+	p.envelope.lenCounter.reload()
+	if p.timer > 0 {
+		p.timer--
+		return
+	}
+	p.timer = p.period*2 + 1
+	p.dutyPos = (p.dutyPos - 1) & 0x07
+}
+
+func (p *mmc5Pulse) output() int16 {
+	return int16(squareDuty[p.duty][p.dutyPos] * uint8(p.envelope.volume()))
+}
+
+// ReadRegister handles reads of $5010 and $5015.
+func (a *MMC5Audio) ReadRegister(addr uint16) uint8 {
+	switch addr {
+	case 0x5010:
+		var val uint8
+		if a.pcmReadMode {
+			val |= 0x01
+		}
+		return val
+	case 0x5015:
+		var val uint8
+		for i := range a.pulse {
+			if a.pulse[i].envelope.lenCounter.status() {
+				val |= 1 << i
+			}
+		}
+		return val
+	}
+	return 0
+}
+
+// WriteRegister handles writes to $5000-$5015.
+//
+//	$5000/$5004: DDLC VVVV pulse duty, envelope loop/length counter halt,
+//	             constant volume, volume/envelope period.
+//	$5002/$5006: pulse period low byte.
+//	$5003/$5007: LLLL LPPP pulse length counter load, period high bits.
+//	$5010      : .... ...M PCM read mode (M), PCM IRQs aren't emulated.
+//	$5011      : PCM raw data, writes of 0 are ignored.
+//	$5015      : .... ..BA enable pulse 2 (B) and 1 (A).
+func (a *MMC5Audio) WriteRegister(addr uint16, val uint8) {
+	switch {
+	case addr <= 0x5007:
+		a.pulse[(addr>>2)&0x01].write(addr&0x03, val)
+	case addr == 0x5010:
+		a.pcmReadMode = val&0x01 != 0
+	case addr == 0x5011:
+		if !a.pcmReadMode && val != 0 {
+			a.pcm = val
+		}
+	case addr == 0x5015:
+		a.pulse[0].envelope.lenCounter.setEnabled(val&0x01 != 0)
+		a.pulse[1].envelope.lenCounter.setEnabled(val&0x02 != 0)
+	}
+}
+
+// Clock must be called on every CPU cycle.
+func (a *MMC5Audio) Clock() {
+	a.frameTimer--
+	if a.frameTimer == 0 {
+		a.frameTimer = mmc5FramePeriod
+		for i := range a.pulse {
+			a.pulse[i].envelope.tick()
+			a.pulse[i].envelope.lenCounter.tick()
+		}
+	}
+
+	a.pulse[0].clock()
+	a.pulse[1].clock()
+
+	// The PCM channel is roughly as loud as the DMC, which has 7-bit samples,
+	// while the pulses have the same volume as the APU ones.
+	output := a.pulse[0].output() + a.pulse[1].output() + int16(a.pcm>>2)
+	if output != a.lastOutput {
+		a.mixer.AddExpansionAudioDelta(MMC5, output-a.lastOutput)
+		a.lastOutput = output
+	}
+}
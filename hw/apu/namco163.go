@@ -0,0 +1,162 @@
+package apu
+
+// Namco163Audio is the expansion audio of the Namco 163 mapper. It has up to 8
+// wavetable channels, whose registers and 4-bit samples share the same 128
+// bytes of internal RAM (which can also be battery-backed).
+//
+// The chip updates a single channel every 15 CPU cycles and outputs that
+// channel only, cycling through all enabled channels. With many channels
+// enabled, the switching frequency falls in the audible range and produces an
+// audible whine, which can be avoided by mixing all channels together instead.
+type Namco163Audio struct {
+	mixer expansionMixer
+
+	// Internal RAM. Channel registers occupy the end of it, from $40 (if all
+	// 8 channels are enabled) to $7F.
+	RAM [128]uint8
+
+	addr     uint8 // address port: bits 0-6 address, bit 7 auto-increment
+	disabled bool
+
+	// mixChannels outputs the average of all enabled channels rather than
+	// time-multiplexing them.
+	mixChannels bool
+
+	divider    uint8 // CPU cycles before next channel update
+	curChannel int
+
+	chanOutput [8]int16
+	lastOutput int16
+}
+
+func NewNamco163Audio(mixer expansionMixer, mixChannels bool) Namco163Audio {
+	return Namco163Audio{
+		mixer:       mixer,
+		mixChannels: mixChannels,
+		divider:     15,
+		curChannel:  7,
+	}
+}
+
+// WriteAddress handles writes to the address port ($F800-$FFFF).
+//
+//	7  bit  0
+//	---- ----
+//	IAAA AAAA
+//	|||| ||||
+//	|+++-++++- Internal RAM address
+//	+--------- Auto-increment
+func (n *Namco163Audio) WriteAddress(val uint8) {
+	n.addr = val
+}
+
+// ReadData handles reads from the data port ($4800-$4FFF).
+func (n *Namco163Audio) ReadData() uint8 {
+	val := n.RAM[n.addr&0x7F]
+	n.incAddr()
+	return val
+}
+
+// PeekData is like ReadData, without side effects.
+func (n *Namco163Audio) PeekData() uint8 {
+	return n.RAM[n.addr&0x7F]
+}
+
+// WriteData handles writes to the data port ($4800-$4FFF).
+func (n *Namco163Audio) WriteData(val uint8) {
+	n.RAM[n.addr&0x7F] = val
+	n.incAddr()
+}
+
+func (n *Namco163Audio) incAddr() {
+	if n.addr&0x80 != 0 {
+		n.addr = 0x80 | (n.addr+1)&0x7F
+	}
+}
+
+// SetDisabled enables or disables sound output.
+func (n *Namco163Audio) SetDisabled(disabled bool) {
+	n.disabled = disabled
+}
+
+// numChannels returns the number of enabled channels, from 1 to 8.
+func (n *Namco163Audio) numChannels() int {
+	return int(n.RAM[0x7F]>>4&0x07) + 1
+}
+
+// Clock must be called on every CPU cycle.
+func (n *Namco163Audio) Clock() {
+	if n.disabled {
+		n.setOutput(0)
+		return
+	}
+
+	n.divider--
+	if n.divider != 0 {
+		return
+	}
+	n.divider = 15
+
+	// Channels are updated from the last one (7) down to the first enabled
+	// one (8 - number of enabled channels).
+	nchans := n.numChannels()
+	if n.curChannel < 8-nchans {
+		n.curChannel = 7
+	}
+	n.updateChannel(n.curChannel)
+
+	if n.mixChannels {
+		var sum int16
+		for ch := 8 - nchans; ch < 8; ch++ {
+			sum += n.chanOutput[ch]
+		}
+		n.setOutput(sum / int16(nchans))
+	} else {
+		n.setOutput(n.chanOutput[n.curChannel])
+	}
+
+	n.curChannel--
+	if n.curChannel < 8-nchans {
+		n.curChannel = 7
+	}
+}
+
+// updateChannel advances the phase of the given channel and computes its
+// output from the current waveform sample.
+//
+// Channel registers, at $40 + channel * 8:
+//
+//	+0: frequency low 8 bits
+//	+1: phase low 8 bits
+//	+2: frequency middle 8 bits
+//	+3: phase middle 8 bits
+//	+4: LLLL LLFF wave length (256 - L*4 4-bit samples), frequency high 2 bits
+//	+5: phase high 8 bits
+//	+6: wave address (in 4-bit samples)
+//	+7: .... VVVV volume (also number of enabled channels for channel 7)
+func (n *Namco163Audio) updateChannel(ch int) {
+	regs := n.RAM[0x40+ch*8 : 0x48+ch*8]
+
+	freq := uint32(regs[0]) | uint32(regs[2])<<8 | uint32(regs[4]&0x03)<<16
+	phase := uint32(regs[1]) | uint32(regs[3])<<8 | uint32(regs[5])<<16
+	length := 256 - uint32(regs[4]&0xFC)
+
+	phase = (phase + freq) % (length << 16)
+	regs[1] = uint8(phase)
+	regs[3] = uint8(phase >> 8)
+	regs[5] = uint8(phase >> 16)
+
+	// Samples are stored 2 per byte, low nibble first.
+	saddr := uint8(phase>>16) + regs[6]
+	sample := n.RAM[saddr>>1] >> ((saddr & 0x01) * 4) & 0x0F
+
+	vol := int16(regs[7] & 0x0F)
+	n.chanOutput[ch] = (int16(sample) - 8) * vol
+}
+
+func (n *Namco163Audio) setOutput(output int16) {
+	if output != n.lastOutput {
+		n.mixer.AddExpansionAudioDelta(Namco163, output-n.lastOutput)
+		n.lastOutput = output
+	}
+}
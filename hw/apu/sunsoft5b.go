@@ -0,0 +1,207 @@
+package apu
+
+import (
+	"math"
+
+	"nestor/emu/log"
+)
+
+// Sunsoft5BAudio is the expansion audio of the Sunsoft 5B mapper, a variant of
+// the Yamaha YM2149F (itself a clone of the General Instrument AY-3-8910). It
+// has 3 square wave (tone) channels, a noise generator and an envelope
+// generator, each channel can output any combination of tone and noise.
+//
+// The chip is internally clocked at half the CPU clock, and all its dividers
+// count in units of 8 internal clocks, so we tick it every 16 CPU cycles.
+type Sunsoft5BAudio struct {
+	mixer expansionMixer
+
+	regs   [16]uint8
+	regsel uint8
+
+	divider uint8 // CPU cycles before next tick
+
+	tone [3]struct {
+		timer uint16
+		out   bool
+	}
+
+	noiseTimer uint16
+	noiseDiv   bool   // noise is clocked at half the rate of tones
+	lfsr       uint32 // 17-bit noise shift register
+
+	envTimer     uint16
+	envStep      int8
+	envAttack    uint8
+	envHold      bool
+	envAlternate bool
+	envHolding   bool
+
+	lastOutput int16
+}
+
+// 5B volume is logarithmic, 1.5dB per envelope step (32 steps). Fixed channel
+// volumes have 16 steps, of 3dB each.
+var s5bVolumeLUT = func() [32]int16 {
+	var lut [32]int16
+	for i := 1; i < len(lut); i++ {
+		lut[i] = int16(math.Pow(10, 1.5*float64(i)/20))
+	}
+	return lut
+}()
+
+func NewSunsoft5BAudio(mixer expansionMixer) Sunsoft5BAudio {
+	return Sunsoft5BAudio{mixer: mixer, lfsr: 1, divider: 16}
+}
+
+// SelectRegister handles writes to the register select port ($C000-$DFFF).
+func (s *Sunsoft5BAudio) SelectRegister(val uint8) {
+	s.regsel = val
+}
+
+// WriteRegister handles writes to the register write port ($E000-$FFFF).
+//
+//	$00-$05: tone period (12-bit, low then high byte) for channels A, B, C.
+//	$06    : noise period (5-bit).
+//	$07    : ..CB Acba tone (abc) and noise (ABC) disable bits.
+//	$08-$0A: ...E VVVV envelope enable (E), fixed volume (V) for channels A, B, C.
+//	$0B-$0C: envelope period (16-bit, low then high byte).
+//	$0D    : .... CAaH envelope shape: continue, attack, alternate, hold.
+func (s *Sunsoft5BAudio) WriteRegister(val uint8) {
+	// Writes are ignored when the upper 4 bits of the selected register
+	// aren't all zeroes.
+	if s.regsel > 0x0F {
+		return
+	}
+
+	log.ModSound.DebugZ("write 5B register").
+		Hex8("reg", s.regsel).
+		Hex8("val", val).
+		End()
+
+	s.regs[s.regsel] = val
+	if s.regsel == 0x0D {
+		s.resetEnvelope()
+	}
+}
+
+func (s *Sunsoft5BAudio) tonePeriod(ch int) uint16 {
+	return uint16(s.regs[ch*2]) | uint16(s.regs[ch*2+1]&0x0F)<<8
+}
+
+func (s *Sunsoft5BAudio) noisePeriod() uint16 {
+	return uint16(s.regs[0x06] & 0x1F)
+}
+
+func (s *Sunsoft5BAudio) envPeriod() uint16 {
+	return uint16(s.regs[0x0B]) | uint16(s.regs[0x0C])<<8
+}
+
+func (s *Sunsoft5BAudio) resetEnvelope() {
+	shape := s.regs[0x0D]
+
+	s.envAttack = 0
+	if shape&0x04 != 0 {
+		s.envAttack = 0x1F
+	}
+	if shape&0x08 == 0 {
+		// When continue is not set, the shape is equivalent to one with
+		// continue and hold set, and alternate set if attack is set.
+		s.envHold = true
+		s.envAlternate = s.envAttack != 0
+	} else {
+		s.envHold = shape&0x01 != 0
+		s.envAlternate = shape&0x02 != 0
+	}
+	s.envStep = 0x1F
+	s.envHolding = false
+	s.envTimer = 0
+}
+
+// Clock must be called on every CPU cycle.
+func (s *Sunsoft5BAudio) Clock() {
+	s.divider--
+	if s.divider != 0 {
+		return
+	}
+	s.divider = 16
+
+	for ch := range s.tone {
+		tone := &s.tone[ch]
+		tone.timer++
+		if tone.timer >= s.tonePeriod(ch) {
+			tone.timer = 0
+			tone.out = !tone.out
+		}
+	}
+
+	s.noiseTimer++
+	if s.noiseTimer >= s.noisePeriod() {
+		s.noiseTimer = 0
+		s.noiseDiv = !s.noiseDiv
+		if s.noiseDiv {
+			// 17-bit LFSR with taps at bits 0 and 3.
+			s.lfsr = (s.lfsr >> 1) | ((s.lfsr^s.lfsr>>3)&0x01)<<16
+		}
+	}
+
+	s.envTimer++
+	if s.envTimer >= s.envPeriod() {
+		s.envTimer = 0
+		s.stepEnvelope()
+	}
+
+	s.updateOutput()
+}
+
+func (s *Sunsoft5BAudio) stepEnvelope() {
+	if s.envHolding {
+		return
+	}
+
+	s.envStep--
+	if s.envStep < 0 {
+		if s.envHold {
+			if s.envAlternate {
+				s.envAttack ^= 0x1F
+			}
+			s.envHolding = true
+			s.envStep = 0
+		} else {
+			if s.envAlternate {
+				s.envAttack ^= 0x1F
+			}
+			s.envStep &= 0x1F
+		}
+	}
+}
+
+func (s *Sunsoft5BAudio) volume(ch int) int16 {
+	vol := s.regs[0x08+ch]
+	if vol&0x10 != 0 {
+		return s5bVolumeLUT[uint8(s.envStep)^s.envAttack]
+	}
+	if vol &= 0x0F; vol == 0 {
+		return 0
+	}
+	return s5bVolumeLUT[vol*2+1]
+}
+
+func (s *Sunsoft5BAudio) updateOutput() {
+	mixer := s.regs[0x07]
+	noise := s.lfsr&0x01 != 0
+
+	var output int16
+	for ch := range s.tone {
+		toneOff := mixer&(0x01<<ch) != 0
+		noiseOff := mixer&(0x08<<ch) != 0
+		if (s.tone[ch].out || toneOff) && (noise || noiseOff) {
+			output += s.volume(ch)
+		}
+	}
+
+	if output != s.lastOutput {
+		s.mixer.AddExpansionAudioDelta(Sunsoft5B, output-s.lastOutput)
+		s.lastOutput = output
+	}
+}
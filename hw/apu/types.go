@@ -10,12 +10,26 @@ const (
 	Triangle
 	Noise
 	DPCM
+
+	// Expansion audio channels.
+	Sunsoft5B
+	Namco163
+	FDS
+	VRC6
+	VRC7
+	MMC5
 )
 
 type mixer interface {
 	AddDelta(ch Channel, time uint32, delta int16)
 }
 
+// expansionMixer receives the output of expansion audio chips, which are
+// clocked by the cartridge rather than by the APU.
+type expansionMixer interface {
+	AddExpansionAudioDelta(ch Channel, delta int16)
+}
+
 type FrameType uint8
 
 const (
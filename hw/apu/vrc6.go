@@ -0,0 +1,172 @@
+package apu
+
+// VRC6Audio is the expansion audio of the Konami VRC6 mapper. It has 2 pulse
+// channels, with 8 duty cycles and a 'digitized' mode outputting a constant
+// volume, and a sawtooth channel.
+type VRC6Audio struct {
+	mixer expansionMixer
+
+	pulse [2]vrc6Pulse
+	saw   vrc6Saw
+
+	halt      bool
+	freqShift uint8 // frequency control: 0, 4 or 8
+
+	lastOutput int16
+}
+
+func NewVRC6Audio(mixer expansionMixer) VRC6Audio {
+	return VRC6Audio{mixer: mixer}
+}
+
+type vrc6Pulse struct {
+	volume  uint8
+	duty    uint8
+	ignDuty bool // digitized mode, outputs volume whatever the duty step
+	period  uint16
+	enabled bool
+
+	timer uint16
+	step  uint8
+}
+
+func (p *vrc6Pulse) write(reg uint16, val uint8) {
+	switch reg {
+	case 0:
+		p.volume = val & 0x0F
+		p.duty = (val >> 4) & 0x07
+		p.ignDuty = val&0x80 != 0
+	case 1:
+		p.period = p.period&0x0F00 | uint16(val)
+	case 2:
+		p.period = p.period&0x00FF | uint16(val&0x0F)<<8
+		p.enabled = val&0x80 != 0
+		if !p.enabled {
+			p.step = 0
+		}
+	}
+}
+
+func (p *vrc6Pulse) clock(shift uint8) {
+	if !p.enabled {
+		return
+	}
+	if p.timer > 0 {
+		p.timer--
+		return
+	}
+	p.timer = p.period >> shift
+	p.step = (p.step + 1) & 0x0F
+}
+
+func (p *vrc6Pulse) output() int16 {
+	if !p.enabled {
+		return 0
+	}
+	if p.ignDuty || p.step <= p.duty {
+		return int16(p.volume)
+	}
+	return 0
+}
+
+type vrc6Saw struct {
+	rate    uint8 // accumulator rate
+	period  uint16
+	enabled bool
+
+	timer uint16
+	step  uint8 // 0-13
+	accum uint8
+}
+
+func (s *vrc6Saw) write(reg uint16, val uint8) {
+	switch reg {
+	case 0:
+		s.rate = val & 0x3F
+	case 1:
+		s.period = s.period&0x0F00 | uint16(val)
+	case 2:
+		s.period = s.period&0x00FF | uint16(val&0x0F)<<8
+		s.enabled = val&0x80 != 0
+		if !s.enabled {
+			s.accum = 0
+			s.step = 0
+		}
+	}
+}
+
+// clock steps the accumulator, which is incremented on every other step and
+// reset after 7 increments.
+func (s *vrc6Saw) clock(shift uint8) {
+	if !s.enabled {
+		return
+	}
+	if s.timer > 0 {
+		s.timer--
+		return
+	}
+	s.timer = s.period >> shift
+
+	s.step++
+	switch {
+	case s.step == 14:
+		s.step = 0
+		s.accum = 0
+	case s.step&0x01 == 0:
+		s.accum += s.rate
+	}
+}
+
+// The top 5 bits of the accumulator are output.
+func (s *vrc6Saw) output() int16 {
+	return int16(s.accum >> 3)
+}
+
+// WriteRegister handles writes to the audio registers, addr being one of
+// $9000-$9003, $A000-$A002 or $B000-$B002.
+//
+//	$9000/$A000: MDDD VVVV pulse mode (M), duty (D), volume (V).
+//	$9001/$A001: pulse period low byte.
+//	$9002/$A002: E... PPPP pulse enable (E), period high nibble.
+//	$9003      : .... .ABH frequency x256 (A), x16 (B), halt all (H).
+//	$B000      : ..AA AAAA saw accumulator rate.
+//	$B001      : saw period low byte.
+//	$B002      : E... PPPP saw enable (E), period high nibble.
+func (v *VRC6Audio) WriteRegister(addr uint16, val uint8) {
+	reg := addr & 0x03
+	switch addr & 0xF000 {
+	case 0x9000:
+		if reg == 3 {
+			v.halt = val&0x01 != 0
+			switch {
+			case val&0x04 != 0:
+				v.freqShift = 8
+			case val&0x02 != 0:
+				v.freqShift = 4
+			default:
+				v.freqShift = 0
+			}
+			return
+		}
+		v.pulse[0].write(reg, val)
+	case 0xA000:
+		v.pulse[1].write(reg, val)
+	case 0xB000:
+		v.saw.write(reg, val)
+	}
+}
+
+// Clock must be called on every CPU cycle.
+func (v *VRC6Audio) Clock() {
+	if !v.halt {
+		v.pulse[0].clock(v.freqShift)
+		v.pulse[1].clock(v.freqShift)
+		v.saw.clock(v.freqShift)
+	}
+
+	output := v.pulse[0].output() + v.pulse[1].output() + v.saw.output()
+	if output != v.lastOutput {
+		v.mixer.AddExpansionAudioDelta(VRC6, output-v.lastOutput)
+		v.lastOutput = output
+	}
+}
@@ -0,0 +1,388 @@
+package apu
+
+import "math"
+
+// VRC7Audio is the expansion audio of the Konami VRC7 mapper, a derivative of
+// the Yamaha YM2413 (OPLL) FM synthesizer. It has 6 channels, each made of a
+// modulator and a carrier operator, playing one of 15 built-in instruments or
+// a custom one.
+//
+// The chip computes a sample every 72 clocks of its 3.58MHz clock, that's
+// every 36 CPU cycles (~49.7kHz).
+type VRC7Audio struct {
+	mixer expansionMixer
+
+	regs   [0x40]uint8
+	regsel uint8
+
+	divider uint8 // CPU cycles before next sample
+
+	ch [6]opllChannel
+
+	egCounter uint32 // envelope generator global counter
+	amCounter uint32 // tremolo LFO
+	pmCounter uint32 // vibrato LFO
+
+	lastOutput int16
+}
+
+func NewVRC7Audio(mixer expansionMixer) VRC7Audio {
+	v := VRC7Audio{mixer: mixer, divider: 36}
+	for i := range v.ch {
+		v.ch[i].mod.state = egOff
+		v.ch[i].car.state = egOff
+		v.ch[i].mod.env = egMaxLevel
+		v.ch[i].car.env = egMaxLevel
+	}
+	return v
+}
+
+// vrc7Patches are the built-in instruments, in the same format as the custom
+// instrument registers ($00-$07).
+var vrc7Patches = [15][8]uint8{
+	{0x03, 0x21, 0x05, 0x06, 0xE8, 0x81, 0x42, 0x27},
+	{0x13, 0x41, 0x14, 0x0D, 0xD8, 0xF6, 0x23, 0x12},
+	{0x11, 0x11, 0x08, 0x08, 0xFA, 0xB2, 0x20, 0x12},
+	{0x31, 0x61, 0x0C, 0x07, 0xA8, 0x64, 0x61, 0x27},
+	{0x32, 0x21, 0x1E, 0x06, 0xE1, 0x76, 0x01, 0x28},
+	{0x02, 0x01, 0x06, 0x00, 0xA3, 0xE2, 0xF4, 0xF4},
+	{0x21, 0x61, 0x1D, 0x07, 0x82, 0x81, 0x11, 0x07},
+	{0x23, 0x21, 0x22, 0x17, 0xA2, 0x72, 0x01, 0x17},
+	{0x35, 0x11, 0x25, 0x00, 0x40, 0x73, 0x72, 0x01},
+	{0xB5, 0x01, 0x0F, 0x0F, 0xA8, 0xA5, 0x51, 0x02},
+	{0x17, 0xC1, 0x24, 0x07, 0xF8, 0xF8, 0x22, 0x12},
+	{0x71, 0x23, 0x11, 0x06, 0x65, 0x74, 0x18, 0x16},
+	{0x01, 0x02, 0xD3, 0x05, 0xC9, 0x95, 0x03, 0x02},
+	{0x61, 0x63, 0x0C, 0x00, 0x94, 0xC0, 0x33, 0xF6},
+	{0x21, 0x72, 0x0D, 0x00, 0xC1, 0xD5, 0x56, 0x06},
+}
+
+// Operators work in the log domain: attenuations are expressed in 1/256th of
+// a power of 2 (~0.0235dB), and converted back to linear with exp2LUT.
+var (
+	// -log2(sin(x)) for a quarter of a sine period.
+	logSinLUT = func() [256]uint16 {
+		var lut [256]uint16
+		for i := range lut {
+			x := math.Sin((float64(i) + 0.5) * math.Pi / 512)
+			lut[i] = uint16(math.Round(-math.Log2(x) * 256))
+		}
+		return lut
+	}()
+
+	// 2^x for the fractional part of an attenuation.
+	exp2LUT = func() [256]uint16 {
+		var lut [256]uint16
+		for i := range lut {
+			lut[i] = uint16(math.Round((math.Pow(2, float64(i)/256) - 1) * 1024))
+		}
+		return lut
+	}()
+)
+
+// Operator frequency multipliers, doubled.
+var opllMul2 = [16]uint32{1, 2, 4, 6, 8, 10, 12, 14, 16, 18, 20, 20, 24, 24, 30, 30}
+
+// Key scale levels, in units of 0.75dB, indexed by the 4 high bits of the
+// frequency number, for the highest octave.
+var opllKSL = [16]int32{0, 24, 32, 37, 40, 43, 45, 47, 48, 50, 51, 52, 53, 54, 55, 56}
+
+// Vibrato frequency adjustment, indexed by the 3 high bits of the frequency
+// number and the vibrato LFO phase.
+var opllPM = [8][8]int32{
+	{0, 0, 0, 0, 0, 0, 0, 0},
+	{0, 0, 1, 0, 0, 0, -1, 0},
+	{0, 1, 2, 1, 0, -1, -2, -1},
+	{0, 1, 3, 1, 0, -1, -3, -1},
+	{0, 2, 4, 2, 0, -2, -4, -2},
+	{0, 2, 5, 2, 0, -2, -5, -2},
+	{0, 3, 6, 3, 0, -3, -6, -3},
+	{0, 3, 7, 3, 0, -3, -7, -3},
+}
+
+// Envelope level increments, over 8 consecutive steps, for the 4 fractional
+// rates.
+var opllEGInc = [4][8]uint8{
+	{0, 1, 0, 1, 0, 1, 0, 1},
+	{0, 1, 0, 1, 1, 1, 0, 1},
+	{0, 1, 1, 1, 0, 1, 1, 1},
+	{0, 1, 1, 1, 1, 1, 1, 1},
+}
+
+type egState uint8
+
+const (
+	egOff egState = iota
+	egAttack
+	egDecay
+	egSustain
+	egRelease
+)
+
+const egMaxLevel = 0x7F // in units of 0.375dB
+
+type opllOperator struct {
+	phase uint32 // 19-bit phase accumulator
+	state egState
+	env   uint8 // envelope attenuation (7-bit)
+	out   [2]int32
+}
+
+type opllChannel struct {
+	mod, car opllOperator
+	keyOn    bool
+}
+
+func (v *VRC7Audio) patch(ch int) []uint8 {
+	inst := v.regs[0x30+ch] >> 4
+	if inst == 0 {
+		return v.regs[:8]
+	}
+	return vrc7Patches[inst-1][:]
+}
+
+func (v *VRC7Audio) fnum(ch int) uint32 {
+	return uint32(v.regs[0x10+ch]) | uint32(v.regs[0x20+ch]&0x01)<<8
+}
+
+func (v *VRC7Audio) block(ch int) uint32 {
+	return uint32(v.regs[0x20+ch]>>1) & 0x07
+}
+
+// SelectRegister handles writes to the register select port ($9010).
+func (v *VRC7Audio) SelectRegister(val uint8) {
+	v.regsel = val
+}
+
+// WriteRegister handles writes to the register write port ($9030).
+//
+//	$00-$07: custom instrument.
+//	$10-$15: frequency number low 8 bits, for channels 0-5.
+//	$20-$25: ..SK BBBF sustain (S), key on (K), block (B), frequency bit 8 (F).
+//	$30-$35: IIII VVVV instrument (I), volume (V).
+func (v *VRC7Audio) WriteRegister(val uint8) {
+	reg := v.regsel & 0x3F
+	if reg >= 0x08 && reg&0x0F > 0x05 {
+		return
+	}
+	v.regs[reg] = val
+
+	if reg&0xF0 == 0x20 {
+		ch := &v.ch[reg&0x0F]
+		keyOn := val&0x10 != 0
+		switch {
+		case keyOn && !ch.keyOn:
+			ch.mod.keyOn()
+			ch.car.keyOn()
+		case !keyOn && ch.keyOn:
+			ch.mod.state = egRelease
+			ch.car.state = egRelease
+		}
+		ch.keyOn = keyOn
+	}
+}
+
+func (op *opllOperator) keyOn() {
+	op.state = egAttack
+	op.phase = 0
+}
+
+// Clock must be called on every CPU cycle.
+func (v *VRC7Audio) Clock() {
+	v.divider--
+	if v.divider != 0 {
+		return
+	}
+	v.divider = 36
+
+	v.egCounter++
+	v.amCounter++
+	v.pmCounter++
+
+	var output int32
+	for i := range v.ch {
+		output += v.sample(i)
+	}
+	// Each channel outputs up to ±4095.
+	out := int16(output >> 3)
+
+	if out != v.lastOutput {
+		v.mixer.AddExpansionAudioDelta(VRC7, out-v.lastOutput)
+		v.lastOutput = out
+	}
+}
+
+// am returns the tremolo attenuation (~4.8dB depth at ~3.7Hz) in units of
+// 0.375dB.
+func (v *VRC7Audio) am() uint32 {
+	pos := (v.amCounter >> 6) % 210
+	if pos >= 105 {
+		pos = 209 - pos
+	}
+	return pos >> 3
+}
+
+// sample computes the next output sample of a channel.
+func (v *VRC7Audio) sample(ch int) int32 {
+	c := &v.ch[ch]
+	p := v.patch(ch)
+	fnum, block := v.fnum(ch), v.block(ch)
+
+	// Key scale level, in units of 0.75dB (6dB per octave).
+	ksl := opllKSL[fnum>>5] - 8*(7-int32(block))
+	ksl = max(ksl, 0)
+
+	// Key scale rate offset.
+	rks := block >> 1
+	modRks, carRks := rks, rks
+	if p[0]&0x10 != 0 {
+		modRks = block<<1 | fnum>>8
+	}
+	if p[1]&0x10 != 0 {
+		carRks = block<<1 | fnum>>8
+	}
+
+	sus := v.regs[0x20+ch]&0x20 != 0
+	v.stepEnvelope(&c.mod, p[0], p[4], p[6], modRks, sus)
+	v.stepEnvelope(&c.car, p[1], p[5], p[7], carRks, sus)
+
+	v.stepPhase(&c.mod, p[0], fnum, block)
+	v.stepPhase(&c.car, p[1], fnum, block)
+
+	// Modulator, with feedback.
+	var fb int32
+	if n := p[3] & 0x07; n != 0 {
+		fb = (c.mod.out[0] + c.mod.out[1]) >> (9 - n)
+	}
+	att := v.attenuation(&c.mod, p[0], uint32(p[2]&0x3F)<<5, p[2]>>6, ksl)
+	modOut := operatorOutput(int32(c.mod.phase>>9)+fb, att, p[3]&0x08 != 0)
+	c.mod.out[1] = c.mod.out[0]
+	c.mod.out[0] = modOut
+
+	// Carrier, modulated by the modulator output.
+	vol := uint32(v.regs[0x30+ch]&0x0F) << 7
+	att = v.attenuation(&c.car, p[1], vol, p[3]>>6, ksl)
+	carOut := operatorOutput(int32(c.car.phase>>9)+modOut>>1, att, p[3]&0x10 != 0)
+	if c.car.state == egOff {
+		carOut = 0
+	}
+	c.car.out[1] = c.car.out[0]
+	c.car.out[0] = carOut
+	return carOut
+}
+
+// attenuation returns the total attenuation of an operator (in log units),
+// from its envelope, volume (or total level), key scale level and tremolo.
+func (v *VRC7Audio) attenuation(op *opllOperator, flags uint8, vol uint32, kslShift uint8, ksl int32) uint32 {
+	att := uint32(op.env)<<4 + vol
+	if kslShift != 0 {
+		att += uint32(ksl>>(3-kslShift)) << 5
+	}
+	if flags&0x80 != 0 {
+		att += v.am() << 4
+	}
+	return att
+}
+
+func (v *VRC7Audio) stepPhase(op *opllOperator, flags uint8, fnum, block uint32) {
+	f := int32(fnum) * 2
+	if flags&0x40 != 0 {
+		pmPhase := (v.pmCounter >> 10) & 0x07
+		f += opllPM[fnum>>6][pmPhase]
+	}
+	inc := uint32(f) * opllMul2[flags&0x0F] << block >> 2
+	op.phase = (op.phase + inc) & 0x7FFFF
+}
+
+// stepEnvelope steps the envelope generator of an operator. The rates are 4
+// bits values, as found in the instrument.
+func (v *VRC7Audio) stepEnvelope(op *opllOperator, flags, adr, slrr uint8, rks uint32, sus bool) {
+	var rate uint32
+	switch op.state {
+	case egOff:
+		return
+	case egAttack:
+		rate = uint32(adr >> 4)
+	case egDecay:
+		rate = uint32(adr & 0x0F)
+	case egSustain:
+		if flags&0x20 != 0 {
+			// Sustained tone, the level is held while the key is on.
+			return
+		}
+		rate = uint32(slrr & 0x0F)
+	case egRelease:
+		switch {
+		case sus:
+			rate = 5
+		case flags&0x20 != 0:
+			rate = uint32(slrr & 0x0F)
+		default:
+			rate = 7
+		}
+	}
+	if rate == 0 {
+		return
+	}
+
+	rate = min(rate*4+rks, 63)
+	hi, lo := rate>>2, rate&0x03
+
+	shift := uint32(0)
+	if hi < 13 {
+		shift = 13 - hi
+		if v.egCounter&(1<<shift-1) != 0 {
+			return
+		}
+	}
+	inc := uint32(opllEGInc[lo][(v.egCounter>>shift)&0x07])
+	if hi > 13 {
+		inc <<= hi - 13
+	}
+
+	if op.state == egAttack {
+		if hi == 15 {
+			op.env = 0
+		} else if inc != 0 {
+			op.env -= uint8(min(uint32(op.env)*inc>>3+1, uint32(op.env)))
+		}
+		if op.env == 0 {
+			op.state = egDecay
+		}
+		return
+	}
+
+	op.env = uint8(min(uint32(op.env)+inc, egMaxLevel))
+	if op.state == egDecay && op.env >= (slrr>>4)<<3 {
+		op.state = egSustain
+	}
+	if op.state == egRelease && op.env == egMaxLevel {
+		op.state = egOff
+	}
+}
+
+// operatorOutput returns the output of an operator for the given phase (a full
+// sine period being 1024 steps) and attenuation. A rectified operator only
+// outputs the positive half of the sine wave.
+func operatorOutput(phase int32, att uint32, rectified bool) int32 {
+	phase &= 0x3FF
+	neg := phase&0x200 != 0
+	if neg && rectified {
+		return 0
+	}
+
+	idx := phase & 0xFF
+	if phase&0x100 != 0 {
+		idx = 0xFF - idx
+	}
+	att += uint32(logSinLUT[idx])
+	if att >= 0x1000 {
+		return 0
+	}
+
+	out := int32((exp2LUT[att&0xFF^0xFF]|0x400)<<1) >> (att >> 8)
+	if neg {
+		return -out
+	}
+	return out
+}
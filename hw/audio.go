@@ -11,7 +11,7 @@ import (
 	"nestor/hw/apu"
 )
 
-const numChannels = 5 // Square1, Square2, Triangle, Noise, DMC
+const numChannels = 11 // Square1, Square2, Triangle, Noise, DMC, Sunsoft5B, Namco163, FDS, VRC6, VRC7, MMC5
 
 const maxSampleRate = 96000
 const maxSamplesPerFrame = maxSampleRate / 60 * 4 * 2 //x4 to allow CPU overclocking up to 10x, x2 for panning stereo
@@ -30,8 +30,8 @@ type AudioMixer struct {
 	bufleft  *blip.Buffer
 	bufright *blip.Buffer
 
-	prevOutleft  int16
-	prevOutright int16
+	prevOutleft  int32
+	prevOutright int32
 
 	nsamples   int
 	hasPanning bool
@@ -45,6 +45,12 @@ type AudioMixer struct {
 
 	clockRate  uint32
 	sampleRate uint32
+
+	volume float64 // master volume, from 0 to 1
+	muted  bool    // don't play samples on the audio device
+
+	// sink, if set, receives the audio samples rather than the audio device.
+	sink func(samples []int16)
 }
 
 func NewAudioMixer() *AudioMixer {
@@ -52,6 +58,7 @@ func NewAudioMixer() *AudioMixer {
 		bufleft:    blip.NewBuffer(maxSamplesPerFrame),
 		bufright:   blip.NewBuffer(maxSamplesPerFrame),
 		sampleRate: maxSampleRate,
+		volume:     1,
 	}
 
 	return am
@@ -95,22 +102,46 @@ func (am *AudioMixer) PlayAudioBuffer(time uint32) {
 
 	// TODO: apply stereo filters
 
-	// Actuall play this with SDL2
-	// copy the buffer
-	buf := unsafe.Slice((*byte)(unsafe.Pointer(&out[0])), sampleCount*2*2)
-	cpy := make([]byte, len(buf))
-	copy(cpy, buf)
-
-	// play the buffer
-	if err := sdl.QueueAudio(audioDeviceID, cpy); err != nil {
-		log.ModSound.DebugZ("failed to queue audio buffer").Error("err", err).End()
+	if am.sink != nil {
+		am.sink(out[:sampleCount*2])
+	} else if !am.muted {
+		// Actuall play this with SDL2
+		// copy the buffer
+		buf := unsafe.Slice((*byte)(unsafe.Pointer(&out[0])), sampleCount*2*2)
+		cpy := make([]byte, len(buf))
+		copy(cpy, buf)
+
+		// play the buffer
+		if err := sdl.QueueAudio(audioDeviceID, cpy); err != nil {
+			log.ModSound.DebugZ("failed to queue audio buffer").Error("err", err).End()
+		}
 	}
 
 	am.nsamples = 0
 	am.updateRates(false)
 }
 
-const ntscClockRate uint32 = 1789773
+// SetSink makes the mixer send the audio samples, interleaved stereo
+// samples at the given sample rate, to sink rather than to the audio device.
+// This allows to render audio without any audio device.
+func (am *AudioMixer) SetSink(sampleRate int, sink func(samples []int16)) {
+	am.sampleRate = uint32(min(sampleRate, maxSampleRate))
+	am.sink = sink
+	am.updateRates(true)
+}
+
+// SetVolume sets the master volume, from 0 (silent) to 1 (full volume).
+func (am *AudioMixer) SetVolume(volume float64) {
+	am.volume = min(max(volume, 0), 1)
+}
+
+// SetMuted stops, or resumes, playing samples on the audio device. Samples
+// are still sent to the sink, if any.
+func (am *AudioMixer) SetMuted(muted bool) {
+	am.muted = muted
+}
+
+const ntscClockRate uint32 = NTSCCPUClock
 
 func (am *AudioMixer) updateRates(forceUpdate bool) {
 	clockRate := ntscClockRate
@@ -121,12 +152,11 @@ func (am *AudioMixer) updateRates(forceUpdate bool) {
 		am.bufright.SetRates(float64(am.clockRate), float64(am.sampleRate))
 	}
 
-	// TODO: apply general volume
 	// TODO: handle panning
 
 	hasPanning := false
 	for i := range numChannels {
-		am.volumes[i] = 0.8
+		am.volumes[i] = 0.8 * am.volume
 		am.panning[i] = 1.0
 		if am.panning[i] != 1.0 {
 			if !am.hasPanning {
@@ -146,7 +176,7 @@ func (am *AudioMixer) channelOutput(ch apu.Channel, right bool) float64 {
 	return float64(am.curOutput[ch]) * am.volumes[ch] * (2.0 - am.panning[ch])
 }
 
-func (am *AudioMixer) outputVolume(isRight bool) int16 {
+func (am *AudioMixer) outputVolume(isRight bool) int32 {
 	squareOutput := am.channelOutput(apu.Square1, isRight) + am.channelOutput(apu.Square2, isRight)
 	tndOutput := am.channelOutput(apu.DPCM, isRight) +
 		2.7516713261*am.channelOutput(apu.Triangle, isRight) +
@@ -155,7 +185,16 @@ func (am *AudioMixer) outputVolume(isRight bool) int16 {
 	squareVolume := uint16(((95.88 * 5000.0) / (8128.0/squareOutput + 100.0)))
 	tndVolume := uint16(((159.79 * 5000.0) / (22638.0/tndOutput + 100.0)))
 
-	return int16(squareVolume + tndVolume)
+	// Expansion audio channels are linearly mixed, each one with its own
+	// weight, relative to the APU output.
+	expansionOutput := am.channelOutput(apu.Sunsoft5B, isRight)*15 +
+		am.channelOutput(apu.Namco163, isRight)*20 +
+		am.channelOutput(apu.FDS, isRight)*20 +
+		am.channelOutput(apu.VRC6, isRight)*75 +
+		am.channelOutput(apu.VRC7, isRight) +
+		am.channelOutput(apu.MMC5, isRight)*43
+
+	return int32(float64(squareVolume) + float64(tndVolume) + expansionOutput)
 }
 
 func (am *AudioMixer) AddDelta(ch apu.Channel, time uint32, delta int16) {
@@ -176,12 +215,12 @@ func (am *AudioMixer) EndFrame(time uint32) {
 		}
 
 		currentOut := am.outputVolume(false) * 4
-		am.bufleft.AddDelta(uint64(stamp), int32(currentOut-am.prevOutleft))
+		am.bufleft.AddDelta(uint64(stamp), currentOut-am.prevOutleft)
 		am.prevOutleft = currentOut
 
 		if am.hasPanning {
 			currentOut = am.outputVolume(true) * 4
-			am.bufright.AddDelta(uint64(stamp), int32(currentOut-am.prevOutright))
+			am.bufright.AddDelta(uint64(stamp), currentOut-am.prevOutright)
 			am.prevOutright = currentOut
 		}
 	}
@@ -6,7 +6,6 @@ import (
 	"nestor/emu/log"
 	"nestor/hw/hwdefs"
 	"nestor/hw/hwio"
-	"nestor/hw/input"
 )
 
 // Locations reserved for vector pointers.
@@ -32,6 +31,10 @@ type CPU struct {
 
 	input InputPorts
 
+	// Optional hook called once per CPU cycle, used by cartridge hardware
+	// clocked by the CPU (i.e mapper IRQ counters).
+	cycleHook func()
+
 	halted      bool
 	Cycles      int64 // CPU cycles
 	masterClock int64
@@ -78,8 +81,35 @@ func (nopDebugger) WatchWrite(addr uint16, val uint16)         {}
 func (nopDebugger) Break(msg string)                           {}
 func (nopDebugger) FrameEnd()                                  {}
 
-func (c *CPU) PlugInputDevice(ip *input.Provider) {
-	c.input.provider = ip
+// PlugPortDevice plugs a device into the given controller port (0 or 1), or
+// unplugs the port if dev is nil.
+func (c *CPU) PlugPortDevice(port int, dev PortDevice) {
+	c.input.ports[port&1] = dev
+}
+
+// PlugExpansionDevice plugs a device into the Famicom expansion port, or
+// unplugs it if dev is nil.
+func (c *CPU) PlugExpansionDevice(dev ExpansionDevice) {
+	c.input.exp = dev
+}
+
+// PlugMicrophone connects the microphone of the Famicom second controller, read
+// on bit 2 of $4016, or disconnects it if mic is nil.
+func (c *CPU) PlugMicrophone(mic microphoneLoader) {
+	c.input.mic = mic
+}
+
+// PlugVsSystem connects the Vs. System coin slots, service button and DIP
+// switches to the controller ports.
+func (c *CPU) PlugVsSystem(vs *VsSystem) {
+	c.input.vs = vs
+}
+
+// SetOutputHook sets a function to call on writes to $4016, which drive the
+// OUT0-OUT2 pins of the expansion port (and of the Vs. System cartridge
+// connector).
+func (c *CPU) SetOutputHook(hook func(val uint8)) {
+	c.input.outHook = hook
 }
 
 // Forward to PPU memory map handlers.
@@ -267,10 +297,18 @@ const (
 	ppuOffset = 1
 )
 
+// NTSCCPUClock is the clock rate of the NTSC CPU, in Hz.
+const NTSCCPUClock = 1789773
+
 func (c *CPU) CurrentCycle() int64 {
 	return c.Cycles
 }
 
+// SetCycleHook sets a function to call on every CPU cycle.
+func (c *CPU) SetCycleHook(hook func()) {
+	c.cycleHook = hook
+}
+
 func (c *CPU) cycleBegin(forRead bool) {
 	if forRead {
 		c.masterClock += ntscStartClockCount - 1
@@ -285,6 +323,9 @@ func (c *CPU) cycleBegin(forRead bool) {
 	if c.APU != nil && c.APU.enabled {
 		c.APU.Tick()
 	}
+	if c.cycleHook != nil {
+		c.cycleHook()
+	}
 }
 
 func (c *CPU) cycleEnd(forRead bool) {
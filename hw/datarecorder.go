@@ -0,0 +1,141 @@
+package hw
+
+// TapeSampleRate is the sample rate, in Hz, of the tapes played and recorded by
+// the data recorder.
+const TapeSampleRate = 44100
+
+type cycleCounter interface {
+	CurrentCycle() int64
+}
+
+type recorderState uint8
+
+const (
+	recorderStopped recorderState = iota
+	recorderPlaying
+	recorderRecording
+)
+
+// DataRecorder is the Famicom data recorder (HVC-008), a cassette tape deck
+// connected to the audio in and out jacks of the Family BASIC keyboard.
+//
+// Tapes are 1-bit signals (0 or 1 per sample) at TapeSampleRate, sampled and
+// played back following the CPU clock.
+type DataRecorder struct {
+	clock cycleCounter
+
+	state recorderState
+	tape  []uint8
+	pos   int   // current sample
+	frac  int64 // CPU cycles elapsed in the current sample, times TapeSampleRate
+	last  int64 // CPU cycle of the last update
+	level uint8 // last sample played or written by the CPU
+}
+
+// NewDataRecorder returns a stopped data recorder following the cycles counted
+// by clock (usually the CPU).
+func NewDataRecorder(clock cycleCounter) *DataRecorder {
+	return &DataRecorder{clock: clock}
+}
+
+// Play starts playing back tape from its beginning.
+func (dr *DataRecorder) Play(tape []uint8) {
+	dr.start(recorderPlaying)
+	dr.tape = tape
+	if len(tape) > 0 {
+		dr.level = tape[0]
+	}
+}
+
+// Record starts recording on a blank tape.
+func (dr *DataRecorder) Record() {
+	dr.start(recorderRecording)
+	dr.tape = nil
+}
+
+// Stop stops playing or recording and returns the recorded tape, if any.
+func (dr *DataRecorder) Stop() []uint8 {
+	dr.advance()
+	tape := dr.tape
+	recording := dr.state == recorderRecording
+	dr.state = recorderStopped
+	dr.tape = nil
+	dr.level = 0
+	if !recording {
+		return nil
+	}
+	return tape
+}
+
+// Playing reports whether a tape is being played back.
+func (dr *DataRecorder) Playing() bool { return dr.state == recorderPlaying }
+
+// Recording reports whether a tape is being recorded.
+func (dr *DataRecorder) Recording() bool { return dr.state == recorderRecording }
+
+func (dr *DataRecorder) start(state recorderState) {
+	dr.state = state
+	dr.pos, dr.frac = 0, 0
+	dr.last = dr.clock.CurrentCycle()
+	dr.level = 0
+}
+
+// advance moves the tape forward, up to the current CPU cycle.
+func (dr *DataRecorder) advance() {
+	now := dr.clock.CurrentCycle()
+	if now < dr.last {
+		// CPU has been reset.
+		dr.last = now
+	}
+	dr.frac += (now - dr.last) * TapeSampleRate
+	dr.last = now
+
+	n := int(dr.frac / NTSCCPUClock)
+	dr.frac %= NTSCCPUClock
+	if n == 0 {
+		return
+	}
+
+	switch dr.state {
+	case recorderPlaying:
+		dr.pos += n
+		if dr.pos >= len(dr.tape) {
+			dr.state = recorderStopped
+			dr.level = 0
+			return
+		}
+		dr.level = dr.tape[dr.pos]
+	case recorderRecording:
+		// The level written by the CPU has been held since the last update.
+		for range n {
+			dr.tape = append(dr.tape, dr.level)
+		}
+		dr.pos += n
+	}
+}
+
+// write is called when the CPU outputs a bit to the recorder.
+func (dr *DataRecorder) write(bit uint8) {
+	if dr.state != recorderRecording {
+		return
+	}
+	dr.advance()
+	dr.level = bit
+}
+
+// read returns the bit currently played back by the recorder.
+func (dr *DataRecorder) read() uint8 {
+	if dr.state != recorderPlaying {
+		return 0
+	}
+	dr.advance()
+	return dr.level
+}
+
+// peek is like read but without moving the tape forward.
+func (dr *DataRecorder) peek() uint8 {
+	if dr.state != recorderPlaying {
+		return 0
+	}
+	return dr.level
+}
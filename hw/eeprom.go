@@ -0,0 +1,174 @@
+package hw
+
+// EEPROM emulates the serial (I²C) EEPROMs found on some cartridge boards, in
+// place of battery-backed RAM. Both the 24C02 (256 bytes, standard I²C
+// protocol) and the Xicor X24C01 (128 bytes, simplified protocol without
+// device select byte) are supported.
+//
+// The board drives the SCL (clock) and SDA (data) lines via Write, and reads
+// the SDA line driven by the EEPROM via Read. Data bits are sampled on SCL
+// rising edges and the EEPROM outputs data on SCL falling edges. Every byte is
+// followed by an acknowledge bit.
+type EEPROM struct {
+	// Data is the EEPROM content, which the board is expected to persist.
+	Data []byte
+
+	x24c01   bool  // no device select byte
+	pageMask uint8 // page writes wrap within pages.
+
+	state eepromState
+	next  eepromState // state after the acknowledge bit
+
+	scl, sda bool  // lines driven by the board
+	out      bool  // SDA line driven by the EEPROM (true when released)
+	bit      uint8 // number of clock pulses in the current byte (9 with ack)
+	shift    uint8
+	ack      bool // acknowledge the byte just received
+	addr     uint8
+}
+
+type eepromState uint8
+
+const (
+	eepromIdle    eepromState = iota
+	eepromDevice              // receiving the device select byte
+	eepromAddress             // receiving the word address
+	eepromWrite               // receiving data bytes
+	eepromRead                // sending data bytes
+)
+
+// NewEEPROM24C01 returns a 128 bytes Xicor X24C01 EEPROM.
+func NewEEPROM24C01() *EEPROM {
+	return &EEPROM{
+		Data:     make([]byte, 128),
+		x24c01:   true,
+		pageMask: 0x03,
+		out:      true,
+	}
+}
+
+// NewEEPROM24C02 returns a 256 bytes 24C02 EEPROM.
+func NewEEPROM24C02() *EEPROM {
+	return &EEPROM{
+		Data:     make([]byte, 256),
+		pageMask: 0x07,
+		out:      true,
+	}
+}
+
+// Read returns the state of the SDA line, as driven by the EEPROM.
+func (e *EEPROM) Read() bool {
+	return e.out
+}
+
+// Write sets the state of the SCL and SDA lines, as driven by the board.
+func (e *EEPROM) Write(scl, sda bool) {
+	prevSCL, prevSDA := e.scl, e.sda
+	e.scl, e.sda = scl, sda
+
+	switch {
+	case prevSCL && scl && prevSDA && !sda:
+		// SDA falling while SCL is high: start condition.
+		e.start()
+	case prevSCL && scl && !prevSDA && sda:
+		// SDA rising while SCL is high: stop condition.
+		e.state = eepromIdle
+		e.out = true
+	case !prevSCL && scl:
+		e.rise()
+	case prevSCL && !scl:
+		e.fall()
+	}
+}
+
+func (e *EEPROM) start() {
+	e.state = eepromDevice
+	if e.x24c01 {
+		e.state = eepromAddress
+	}
+	e.bit = 0
+	e.shift = 0
+	e.out = true
+}
+
+func (e *EEPROM) rise() {
+	if e.state == eepromIdle {
+		return
+	}
+
+	e.bit++
+	switch {
+	case e.bit <= 8 && e.state != eepromRead:
+		e.shift = e.shift<<1 | b2u8(e.sda)
+		if e.bit == 8 {
+			e.receive(e.shift)
+		}
+	case e.bit == 9 && e.state == eepromRead && e.sda:
+		// No acknowledge from the board, end of transfer.
+		e.next = eepromIdle
+	}
+}
+
+func (e *EEPROM) fall() {
+	if e.state == eepromIdle {
+		return
+	}
+
+	switch e.bit {
+	case 8:
+		// Acknowledge the received byte, or release SDA so that the board
+		// can acknowledge the byte we sent.
+		e.out = e.state == eepromRead || !e.ack
+	case 9:
+		e.bit = 0
+		e.state = e.next
+		e.out = true
+		if e.state == eepromRead {
+			e.shift = e.Data[e.addr]
+			e.addr = (e.addr + 1) & uint8(len(e.Data)-1)
+			e.out = e.shift&0x80 != 0
+		}
+	default:
+		if e.state == eepromRead {
+			e.out = e.shift&(0x80>>e.bit) != 0
+		}
+	}
+}
+
+// receive processes a byte received from the board.
+func (e *EEPROM) receive(val uint8) {
+	e.ack = true
+
+	switch e.state {
+	case eepromDevice:
+		// 1010 AAAR: device type identifier, device address (ignored) and
+		// read/write bit.
+		if val&0xF0 != 0xA0 {
+			e.ack = false
+			e.next = eepromIdle
+			return
+		}
+		e.next = eepromAddress
+		if val&0x01 != 0 {
+			e.next = eepromRead
+		}
+
+	case eepromAddress:
+		if e.x24c01 {
+			// AAAA AAAR: word address and read/write bit.
+			e.addr = val >> 1
+			e.next = eepromWrite
+			if val&0x01 != 0 {
+				e.next = eepromRead
+			}
+			return
+		}
+		e.addr = val
+		e.next = eepromWrite
+
+	case eepromWrite:
+		e.Data[e.addr] = val
+		e.addr = e.addr&^e.pageMask | (e.addr+1)&e.pageMask
+		e.next = eepromWrite
+	}
+}
@@ -0,0 +1,112 @@
+package hw
+
+import (
+	"bytes"
+	"testing"
+)
+
+// i2cMaster bit-bangs the I²C protocol, as a cartridge board would.
+type i2cMaster struct {
+	t  *testing.T
+	ee *EEPROM
+}
+
+func (m i2cMaster) start() {
+	m.ee.Write(false, true)
+	m.ee.Write(true, true)
+	m.ee.Write(true, false)
+	m.ee.Write(false, false)
+}
+
+func (m i2cMaster) stop() {
+	m.ee.Write(false, false)
+	m.ee.Write(true, false)
+	m.ee.Write(true, true)
+}
+
+// clock pulses SCL with the given SDA value and returns the SDA line as driven
+// by the EEPROM while SCL is high.
+func (m i2cMaster) clock(sda bool) bool {
+	m.ee.Write(false, sda)
+	m.ee.Write(true, sda)
+	out := m.ee.Read()
+	m.ee.Write(false, sda)
+	return out
+}
+
+func (m i2cMaster) send(val uint8) {
+	m.t.Helper()
+	for i := 7; i >= 0; i-- {
+		m.clock(val&(1<<i) != 0)
+	}
+	if m.clock(true) {
+		m.t.Fatalf("byte %#02x not acknowledged", val)
+	}
+}
+
+func (m i2cMaster) recv(ack bool) uint8 {
+	var val uint8
+	for range 8 {
+		val = val<<1 | b2u8(m.clock(true))
+	}
+	m.clock(!ack)
+	return val
+}
+
+func TestEEPROM24C02(t *testing.T) {
+	ee := NewEEPROM24C02()
+	m := i2cMaster{t: t, ee: ee}
+
+	// Page write, wrapping within the 8 bytes page.
+	m.start()
+	m.send(0xA0)
+	m.send(0x15)
+	for _, b := range []byte{1, 2, 3, 4} {
+		m.send(b)
+	}
+	m.stop()
+
+	want := []byte{4, 0, 0, 0, 0, 1, 2, 3}
+	if !bytes.Equal(ee.Data[0x10:0x18], want) {
+		t.Fatalf("data = % x, want % x", ee.Data[0x10:0x18], want)
+	}
+
+	// Random read (dummy write then repeated start), sequential reads
+	// cross page boundaries.
+	ee.Data[0x18] = 5
+	m.start()
+	m.send(0xA0)
+	m.send(0x15)
+	m.start()
+	m.send(0xA1)
+	var got []byte
+	for i := range 4 {
+		got = append(got, m.recv(i != 3))
+	}
+	m.stop()
+
+	if want := []byte{1, 2, 3, 5}; !bytes.Equal(got, want) {
+		t.Fatalf("read % x, want % x", got, want)
+	}
+}
+
+func TestEEPROM24C01(t *testing.T) {
+	ee := NewEEPROM24C01()
+	m := i2cMaster{t: t, ee: ee}
+
+	m.start()
+	m.send(0x7F<<1 | 0)
+	m.send(0xAB)
+	m.stop()
+
+	if ee.Data[0x7F] != 0xAB {
+		t.Fatalf("data[0x7f] = %#02x, want 0xab", ee.Data[0x7F])
+	}
+
+	m.start()
+	m.send(0x7F<<1 | 1)
+	if got := m.recv(false); got != 0xAB {
+		t.Fatalf("read %#02x, want 0xab", got)
+	}
+	m.stop()
+}
@@ -0,0 +1,80 @@
+package hw
+
+type familyKeyboardLoader interface {
+	FamilyKeyboardState(row, col int) uint8
+}
+
+// FamilyKeyboard is the Family BASIC keyboard (HVC-007), plugged in the
+// Famicom expansion port. Its 72 keys are laid out in a matrix of 9 rows of 2
+// columns, the program selects a row/column and reads 4 keys at once.
+//
+//	$4016 write: ---- -KCR
+//	                   ||+- reset to row 0
+//	                   |+-- select column (a 1 to 0 transition selects the next row)
+//	                   +--- enable keyboard (also data recorder audio out)
+//
+//	$4017 read:  ---D CBA-
+//	                +-+++-- keys of the selected row/column (0: pressed)
+//
+//	$4016 read:  ---- --T-
+//	                    +-- data recorder audio in
+type FamilyKeyboard struct {
+	provider familyKeyboardLoader
+	recorder *DataRecorder // optional
+
+	row, col int
+	enabled  bool
+}
+
+// familyKeyboardRows is the number of rows the keyboard scans through, the last
+// one doesn't have any key.
+const familyKeyboardRows = 10
+
+// NewFamilyKeyboard returns a keyboard reading the state of its keys from
+// provider, with an optional data recorder plugged in.
+func NewFamilyKeyboard(provider familyKeyboardLoader, recorder *DataRecorder) *FamilyKeyboard {
+	return &FamilyKeyboard{provider: provider, recorder: recorder}
+}
+
+func (kb *FamilyKeyboard) Write(val uint8) {
+	col := int(val>>1) & 1
+	kb.enabled = val&0x04 != 0
+	if kb.enabled {
+		if kb.col == 1 && col == 0 {
+			kb.row = (kb.row + 1) % familyKeyboardRows
+		}
+		if val&0x01 != 0 {
+			kb.row = 0
+		}
+	}
+	kb.col = col
+
+	if kb.recorder != nil {
+		kb.recorder.write(val >> 2 & 1)
+	}
+}
+
+func (kb *FamilyKeyboard) Read(port uint8) uint8 {
+	if port == 0 {
+		if kb.recorder != nil {
+			return kb.recorder.read() << 1
+		}
+		return 0
+	}
+	return kb.Peek(port)
+}
+
+func (kb *FamilyKeyboard) Peek(port uint8) uint8 {
+	switch {
+	case port == 0:
+		if kb.recorder != nil {
+			return kb.recorder.peek() << 1
+		}
+		return 0
+	case !kb.enabled:
+		return 0
+	case kb.row >= familyKeyboardRows-1:
+		return 0x1E
+	}
+	return ^(kb.provider.FamilyKeyboardState(kb.row, kb.col) << 1) & 0x1E
+}
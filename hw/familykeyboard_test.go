@@ -0,0 +1,93 @@
+package hw
+
+import (
+	"slices"
+	"testing"
+)
+
+type fakeKeyboard map[[2]int]uint8
+
+func (kb fakeKeyboard) FamilyKeyboardState(row, col int) uint8 {
+	return kb[[2]int{row, col}]
+}
+
+type fakeClock struct{ cycles int64 }
+
+func (c *fakeClock) CurrentCycle() int64 { return c.cycles }
+
+func TestFamilyKeyboard(t *testing.T) {
+	keys := fakeKeyboard{
+		{0, 0}: 0b0010, // RETURN
+		{3, 1}: 0b1001, // M and 8
+		{8, 1}: 0b0100, // DEL
+	}
+
+	var ip InputPorts
+	ip.exp = NewFamilyKeyboard(keys, nil)
+
+	// Scan the whole matrix as Family BASIC does: reset to row 0, then
+	// toggle the column, reading both of each row.
+	var got []uint8
+	ip.WriteIN(0, 0x05)
+	for range familyKeyboardRows {
+		ip.WriteIN(0, 0x04)
+		got = append(got, ip.regval(1)&0x1E)
+		ip.WriteIN(0, 0x06)
+		got = append(got, ip.regval(1)&0x1E)
+	}
+
+	want := []uint8{
+		0x1A, 0x1E, // row 0
+		0x1E, 0x1E,
+		0x1E, 0x1E,
+		0x1E, 0x0C, // row 3
+		0x1E, 0x1E,
+		0x1E, 0x1E,
+		0x1E, 0x1E,
+		0x1E, 0x1E,
+		0x1E, 0x16, // row 8
+		0x1E, 0x1E, // row 9, no keys
+	}
+	checkBits(t, "$4017", got, want)
+
+	// Disabled keyboard doesn't drive the data lines.
+	ip.WriteIN(0, 0x00)
+	if got := ip.regval(1) & 0x1E; got != 0 {
+		t.Errorf("disabled keyboard: $4017 = %02X, want 00", got)
+	}
+}
+
+func TestDataRecorder(t *testing.T) {
+	// One tape sample every cyclesPerSample CPU cycles (rounded up).
+	const cyclesPerSample = (NTSCCPUClock + TapeSampleRate - 1) / TapeSampleRate
+
+	clock := &fakeClock{}
+	dr := NewDataRecorder(clock)
+	var ip InputPorts
+	ip.exp = NewFamilyKeyboard(fakeKeyboard{}, dr)
+
+	signal := []uint8{1, 1, 0, 1, 0, 0, 1, 0}
+
+	dr.Record()
+	for _, bit := range signal {
+		ip.WriteIN(0, bit<<2)
+		clock.cycles += cyclesPerSample
+	}
+	tape := dr.Stop()
+	if !slices.Equal(tape, signal) {
+		t.Fatalf("recorded tape = %v, want %v", tape, signal)
+	}
+
+	dr.Play(tape)
+	var got []uint8
+	for range signal {
+		got = append(got, ip.regval(0)>>1&1)
+		clock.cycles += cyclesPerSample
+	}
+	if !slices.Equal(got, signal) {
+		t.Errorf("played back %v, want %v", got, signal)
+	}
+	if dr.read(); dr.Playing() {
+		t.Errorf("recorder still playing after the end of the tape")
+	}
+}
@@ -0,0 +1,169 @@
+package hw
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+)
+
+// Flash emulates the SST39SF040 flash memory, used in place of PRG-ROM by some
+// homebrew boards, which can reprogram it to save games. Only the byte program
+// and erase commands are emulated (software ID mode isn't).
+//
+// Commands are issued with a sequence of writes at specific addresses, decoded
+// on the flash address lines A0-A14 only:
+//
+//	byte program: $5555=$AA, $2AAA=$55, $5555=$A0, addr=data
+//	sector erase: $5555=$AA, $2AAA=$55, $5555=$80, $5555=$AA, $2AAA=$55, sector=$30
+//	chip erase  : $5555=$AA, $2AAA=$55, $5555=$80, $5555=$AA, $2AAA=$55, $5555=$10
+type Flash struct {
+	// Data is the flash content.
+	Data []byte
+
+	state flashState
+
+	// Sectors modified since power up, or restored from a save, which are
+	// the only ones saved.
+	dirty []bool
+
+	// SHA-1 of the original flash content (i.e the rom), identifying saves.
+	romsum [sha1.Size]byte
+}
+
+type flashState uint8
+
+const (
+	flashIdle    flashState = iota
+	flashUnlock1            // received $AA
+	flashUnlock2            // received $55
+	flashProgram            // byte program command, waiting for data
+	flashErase              // erase command
+	flashErase1             // erase command, received $AA
+	flashErase2             // erase command, received $55
+)
+
+const flashSectorSize = 4096
+
+// NewFlash returns a flash memory chip containing data, whose size must be a
+// multiple of the 4 KB sector size.
+func NewFlash(data []byte) *Flash {
+	return &Flash{
+		Data:   data,
+		dirty:  make([]bool, len(data)/flashSectorSize),
+		romsum: sha1.Sum(data),
+	}
+}
+
+// Write handles a write at the given flash address, and reports whether the
+// flash content has been modified.
+func (f *Flash) Write(addr uint32, val uint8) bool {
+	addr %= uint32(len(f.Data))
+	cmdaddr := addr & 0x7FFF
+
+	if val == 0xF0 && f.state != flashProgram {
+		// Reset.
+		f.state = flashIdle
+		return false
+	}
+
+	state := f.state
+	f.state = flashIdle
+
+	switch {
+	case state == flashIdle && cmdaddr == 0x5555 && val == 0xAA:
+		f.state = flashUnlock1
+	case state == flashUnlock1 && cmdaddr == 0x2AAA && val == 0x55:
+		f.state = flashUnlock2
+	case state == flashUnlock2 && cmdaddr == 0x5555 && val == 0xA0:
+		f.state = flashProgram
+	case state == flashUnlock2 && cmdaddr == 0x5555 && val == 0x80:
+		f.state = flashErase
+
+	case state == flashProgram:
+		// Programming can only clear bits.
+		f.Data[addr] &= val
+		f.dirty[addr/flashSectorSize] = true
+		return true
+
+	case state == flashErase && cmdaddr == 0x5555 && val == 0xAA:
+		f.state = flashErase1
+	case state == flashErase1 && cmdaddr == 0x2AAA && val == 0x55:
+		f.state = flashErase2
+	case state == flashErase2 && val == 0x30:
+		start := addr &^ (flashSectorSize - 1)
+		fill(f.Data[start:start+flashSectorSize], 0xFF)
+		f.dirty[addr/flashSectorSize] = true
+		return true
+	case state == flashErase2 && cmdaddr == 0x5555 && val == 0x10:
+		fill(f.Data, 0xFF)
+		fill(f.dirty, true)
+		return true
+	}
+	return false
+}
+
+// Flash save format: magic, SHA-1 of the original content, then each modified
+// sector as its index (uint16 LE) followed by its content.
+const flashSaveMagic = "FLSH"
+
+var (
+	errFlashSave    = errors.New("not a flash save")
+	errFlashSaveRom = errors.New("flash save made with another rom")
+)
+
+// SaveData returns the modified sectors, in the format read by RestoreSave,
+// or nil if none has been modified.
+func (f *Flash) SaveData() []byte {
+	var buf []byte
+	for i, dirty := range f.dirty {
+		if !dirty {
+			continue
+		}
+		if buf == nil {
+			buf = append([]byte(flashSaveMagic), f.romsum[:]...)
+		}
+		start := i * flashSectorSize
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(i))
+		buf = append(buf, f.Data[start:start+flashSectorSize]...)
+	}
+	return buf
+}
+
+// RestoreSave restores the sectors saved with SaveData. The save is rejected,
+// leaving the flash untouched, if it has been made with another rom.
+func (f *Flash) RestoreSave(buf []byte) error {
+	hdrlen := len(flashSaveMagic) + sha1.Size
+	if len(buf) < hdrlen || string(buf[:len(flashSaveMagic)]) != flashSaveMagic {
+		return errFlashSave
+	}
+	if !bytes.Equal(buf[len(flashSaveMagic):hdrlen], f.romsum[:]) {
+		return errFlashSaveRom
+	}
+
+	// Check the whole save before modifying the flash.
+	const seclen = 2 + flashSectorSize
+	sectors := buf[hdrlen:]
+	if len(sectors)%seclen != 0 {
+		return errFlashSave
+	}
+	for off := 0; off < len(sectors); off += seclen {
+		if int(binary.LittleEndian.Uint16(sectors[off:])) >= len(f.dirty) {
+			return errFlashSave
+		}
+	}
+
+	for off := 0; off < len(sectors); off += seclen {
+		i := int(binary.LittleEndian.Uint16(sectors[off:]))
+		start := i * flashSectorSize
+		copy(f.Data[start:start+flashSectorSize], sectors[off+2:off+seclen])
+		f.dirty[i] = true
+	}
+	return nil
+}
+
+func fill[T any](buf []T, val T) {
+	for i := range buf {
+		buf[i] = val
+	}
+}
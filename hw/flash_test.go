@@ -0,0 +1,99 @@
+package hw
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFlash(t *testing.T) {
+	data := make([]byte, 512*1024)
+	for i := range data {
+		data[i] = 0xFF
+	}
+	f := NewFlash(data)
+
+	cmd := func(seq ...uint32) {
+		for i := 0; i < len(seq); i += 2 {
+			f.Write(seq[i], uint8(seq[i+1]))
+		}
+	}
+
+	// Byte program, in another 32 KB bank than the command addresses.
+	cmd(0x5555, 0xAA, 0x2AAA, 0x55, 0x5555, 0xA0)
+	if !f.Write(0x12345, 0x5A) {
+		t.Fatalf("byte program not performed")
+	}
+	if data[0x12345] != 0x5A {
+		t.Fatalf("data[0x12345] = %#02x, want 0x5a", data[0x12345])
+	}
+
+	// Programming can only clear bits.
+	cmd(0x5555, 0xAA, 0x2AAA, 0x55, 0x5555, 0xA0, 0x12345, 0xA5)
+	if data[0x12345] != 0x00 {
+		t.Fatalf("data[0x12345] = %#02x, want 0x00", data[0x12345])
+	}
+
+	// Writes outside of a command sequence are ignored.
+	if f.Write(0x12346, 0x00) {
+		t.Fatalf("write without command modified flash")
+	}
+
+	// Sector erase.
+	cmd(0x5555, 0xAA, 0x2AAA, 0x55, 0x5555, 0x80, 0x5555, 0xAA, 0x2AAA, 0x55, 0x12000, 0x30)
+	if data[0x12345] != 0xFF {
+		t.Fatalf("data[0x12345] = %#02x after sector erase, want 0xff", data[0x12345])
+	}
+}
+
+func TestFlashSave(t *testing.T) {
+	rom := make([]byte, 64*1024)
+	for i := range rom {
+		rom[i] = uint8(i)
+	}
+	f := NewFlash(bytes.Clone(rom))
+	if buf := f.SaveData(); buf != nil {
+		t.Fatalf("SaveData() = %d bytes for an unmodified flash, want nil", len(buf))
+	}
+
+	// Erase a sector and program a byte in another one.
+	for _, w := range [][2]uint32{
+		{0x5555, 0xAA}, {0x2AAA, 0x55}, {0x5555, 0x80}, {0x5555, 0xAA}, {0x2AAA, 0x55}, {0x3000, 0x30},
+		{0x5555, 0xAA}, {0x2AAA, 0x55}, {0x5555, 0xA0}, {0xE123, 0x00},
+	} {
+		f.Write(w[0], uint8(w[1]))
+	}
+	save := f.SaveData()
+	if want := 4 + 20 + 2*(2+4096); len(save) != want {
+		t.Fatalf("SaveData() = %d bytes, want %d", len(save), want)
+	}
+
+	// Restored into the same rom.
+	g := NewFlash(bytes.Clone(rom))
+	if err := g.RestoreSave(save); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(g.Data, f.Data) {
+		t.Fatalf("restored flash content differs")
+	}
+	if !bytes.Equal(g.SaveData(), save) {
+		t.Fatalf("restored sectors aren't saved again")
+	}
+
+	// Rejected by another rom, or when corrupted.
+	other := bytes.Clone(rom)
+	other[0] ^= 0xFF
+	g = NewFlash(other)
+	if err := g.RestoreSave(save); err == nil {
+		t.Errorf("save restored into another rom")
+	}
+	g = NewFlash(bytes.Clone(rom))
+	if err := g.RestoreSave(save[:len(save)-1]); err == nil {
+		t.Errorf("truncated save restored")
+	}
+	if err := g.RestoreSave(rom); err == nil {
+		t.Errorf("raw PRG-ROM save restored")
+	}
+	if !bytes.Equal(g.Data, rom) {
+		t.Errorf("flash modified by a rejected save")
+	}
+}
@@ -0,0 +1,47 @@
+package hw
+
+// The NES Four Score adapter, for 4 players games, takes both controller
+// ports. Each port reads 24 bits: the first controller, the additional one,
+// then a signature telling the adapter is present.
+//
+//	$4016 read: pad 1 (8 bits), pad 3 (8 bits), 0x08 (8 bits), then 1s.
+//	$4017 read: pad 2 (8 bits), pad 4 (8 bits), 0x04 (8 bits), then 1s.
+type fourScorePort struct {
+	provider padStateLoader
+	pads     [2]int
+	sig      uint8
+	strobe   bool
+	state    uint32 // 24-bit shift register
+}
+
+// NewFourScore returns the 2 halves of a Four Score, to plug in ports 1 and 2.
+func NewFourScore(provider padStateLoader) (PortDevice, PortDevice) {
+	return &fourScorePort{provider: provider, pads: [2]int{0, 2}, sig: 0x08},
+		&fourScorePort{provider: provider, pads: [2]int{1, 3}, sig: 0x04}
+}
+
+func (fs *fourScorePort) load() {
+	fs.state = uint32(fs.provider.PadState(fs.pads[0])) |
+		uint32(fs.provider.PadState(fs.pads[1]))<<8 |
+		uint32(fs.sig)<<16
+}
+
+func (fs *fourScorePort) Strobe(on bool) {
+	if fs.strobe && !on {
+		fs.load()
+	}
+	fs.strobe = on
+}
+
+func (fs *fourScorePort) Read() uint8 {
+	if fs.strobe {
+		fs.load()
+	}
+	ret := uint8(fs.state & 1)
+	fs.state = fs.state>>1 | 1<<23
+	return ret
+}
+
+func (fs *fourScorePort) Peek() uint8 {
+	return uint8(fs.state & 1)
+}
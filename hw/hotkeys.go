@@ -0,0 +1,242 @@
+package hw
+
+import (
+	"fmt"
+
+	"github.com/veandco/go-sdl2/sdl"
+
+	"nestor/hw/input"
+)
+
+// Hotkey is an emulator action triggered by an input (keyboard key, game
+// controller button, etc.) while the emulator window has focus.
+//
+// There are no save state hotkeys, since save states aren't supported.
+type Hotkey uint8
+
+const (
+	HotkeyPause             Hotkey = iota // Pause or resume emulation
+	HotkeyFrameAdvance                    // Pause and emulate a single frame
+	HotkeySoftReset                       // Press the reset button
+	HotkeyHardReset                       // Power cycle the console
+	HotkeyFastForward                     // Toggle fast-forward
+	HotkeyFastForwardHold                 // Fast-forward while held
+	HotkeySlowMotion                      // Toggle slow motion
+	HotkeySpeedUp                         // Raise the emulation speed
+	HotkeySpeedDown                       // Lower the emulation speed
+	HotkeyNormalSpeed                     // Reset the emulation speed
+	HotkeyScreenshot                      // Save a screenshot
+	HotkeyFullscreen                      // Toggle fullscreen
+	HotkeyVolumeUp                        // Raise the volume
+	HotkeyVolumeDown                      // Lower the volume
+	HotkeyNextShader                      // Switch to the next shader
+	HotkeyFDSSwitchSide                   // Flip the disk in the FDS drive
+	HotkeyFDSInsertNextDisk               // Insert the next disk in the FDS drive
+	HotkeyFDSEjectDisk                    // Eject the disk from the FDS drive
+	HotkeyNSFPrevTrack                    // Play the previous NSF track
+	HotkeyNSFNextTrack                    // Play the next NSF track
+	HotkeyVsInsertCoin1                   // Insert a coin in the Vs. System slot 1
+	HotkeyVsInsertCoin2                   // Insert a coin in the Vs. System slot 2
+	HotkeyVsService                       // Press the Vs. System service button
+	HotkeyTapePlay                        // Play the data recorder tape
+	HotkeyTapeRecord                      // Record on the data recorder tape
+	HotkeyTapeStop                        // Stop the data recorder
+
+	HotkeyCount
+)
+
+var hotkeyNames = [HotkeyCount]string{
+	"pause",
+	"frame-advance",
+	"soft-reset",
+	"hard-reset",
+	"fast-forward",
+	"fast-forward-hold",
+	"slow-motion",
+	"speed-up",
+	"speed-down",
+	"normal-speed",
+	"screenshot",
+	"fullscreen",
+	"volume-up",
+	"volume-down",
+	"next-shader",
+	"fds-switch-side",
+	"fds-insert-next-disk",
+	"fds-eject-disk",
+	"nsf-prev-track",
+	"nsf-next-track",
+	"vs-insert-coin1",
+	"vs-insert-coin2",
+	"vs-service",
+	"tape-play",
+	"tape-record",
+	"tape-stop",
+}
+
+var hotkeyTitles = [HotkeyCount]string{
+	"Pause",
+	"Frame Advance",
+	"Soft Reset",
+	"Hard Reset",
+	"Fast-Forward",
+	"Fast-Forward (Hold)",
+	"Slow Motion",
+	"Speed Up",
+	"Speed Down",
+	"Normal Speed",
+	"Screenshot",
+	"Fullscreen",
+	"Volume Up",
+	"Volume Down",
+	"Next Shader",
+	"FDS: Switch Disk Side",
+	"FDS: Insert Next Disk",
+	"FDS: Eject Disk",
+	"NSF: Previous Track",
+	"NSF: Next Track",
+	"Vs.: Insert Coin 1",
+	"Vs.: Insert Coin 2",
+	"Vs.: Service Button",
+	"Tape: Play",
+	"Tape: Record",
+	"Tape: Stop",
+}
+
+// defaultHotkeys are the keys bound to each hotkey by default. The keys used
+// by the default paddle presets and the Power Pad are avoided.
+var defaultHotkeys = [HotkeyCount]sdl.Scancode{
+	HotkeyPause:             sdl.SCANCODE_P,
+	HotkeyFrameAdvance:      sdl.SCANCODE_O,
+	HotkeySoftReset:         sdl.SCANCODE_F5,
+	HotkeyHardReset:         sdl.SCANCODE_F1,
+	HotkeyFastForward:       sdl.SCANCODE_L,
+	HotkeyFastForwardHold:   sdl.SCANCODE_TAB,
+	HotkeySlowMotion:        sdl.SCANCODE_K,
+	HotkeySpeedUp:           sdl.SCANCODE_PERIOD,
+	HotkeySpeedDown:         sdl.SCANCODE_COMMA,
+	HotkeyNormalSpeed:       sdl.SCANCODE_SLASH,
+	HotkeyScreenshot:        sdl.SCANCODE_F12,
+	HotkeyFullscreen:        sdl.SCANCODE_RETURN,
+	HotkeyVolumeUp:          sdl.SCANCODE_RIGHTBRACKET,
+	HotkeyVolumeDown:        sdl.SCANCODE_LEFTBRACKET,
+	HotkeyNextShader:        sdl.SCANCODE_BACKSLASH,
+	HotkeyFDSSwitchSide:     sdl.SCANCODE_F6,
+	HotkeyFDSInsertNextDisk: sdl.SCANCODE_F7,
+	HotkeyFDSEjectDisk:      sdl.SCANCODE_F8,
+	HotkeyNSFPrevTrack:      sdl.SCANCODE_PAGEUP,
+	HotkeyNSFNextTrack:      sdl.SCANCODE_PAGEDOWN,
+	HotkeyVsInsertCoin1:     sdl.SCANCODE_F2,
+	HotkeyVsInsertCoin2:     sdl.SCANCODE_F3,
+	HotkeyVsService:         sdl.SCANCODE_F4,
+	HotkeyTapePlay:          sdl.SCANCODE_F9,
+	HotkeyTapeRecord:        sdl.SCANCODE_F10,
+	HotkeyTapeStop:          sdl.SCANCODE_F11,
+}
+
+// String returns the user-friendly name of the hotkey.
+func (hk Hotkey) String() string {
+	if hk >= HotkeyCount {
+		return fmt.Sprintf("Hotkey(%d)", hk)
+	}
+	return hotkeyTitles[hk]
+}
+
+func (hk Hotkey) MarshalText() ([]byte, error) {
+	if hk >= HotkeyCount {
+		return nil, fmt.Errorf("invalid hotkey %d", hk)
+	}
+	return []byte(hotkeyNames[hk]), nil
+}
+
+func (hk *Hotkey) UnmarshalText(text []byte) error {
+	for i, name := range hotkeyNames {
+		if name == string(text) {
+			*hk = Hotkey(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unrecognized hotkey %q", text)
+}
+
+// A HotkeyBinding binds a hotkey to an input and an alternate one, so that a
+// hotkey can be triggered from both the keyboard and a game controller.
+type HotkeyBinding struct {
+	Hotkey Hotkey     `toml:"action"`
+	Input  input.Code `toml:"input"`
+	Alt    input.Code `toml:"alt"`
+}
+
+// HotkeyBindings holds the inputs bound to the hotkeys, with at most one
+// binding per hotkey.
+type HotkeyBindings []HotkeyBinding
+
+// DefaultHotkeys returns the default hotkey bindings, all on the keyboard.
+func DefaultHotkeys() HotkeyBindings {
+	hb := make(HotkeyBindings, HotkeyCount)
+	for hk := range HotkeyCount {
+		hb[hk] = defaultBinding(hk)
+	}
+	return hb
+}
+
+func defaultBinding(hk Hotkey) HotkeyBinding {
+	return HotkeyBinding{
+		Hotkey: hk,
+		Input:  input.Code{Type: input.KeyboardCtrl, Scancode: defaultHotkeys[hk]},
+	}
+}
+
+// Binding returns the binding of the given hotkey, or nil if there's none.
+func (hb HotkeyBindings) Binding(hk Hotkey) *HotkeyBinding {
+	for i := range hb {
+		if hb[i].Hotkey == hk {
+			return &hb[i]
+		}
+	}
+	return nil
+}
+
+// PostLoad adds the default bindings of the hotkeys missing from hb, such as
+// the hotkeys added after the configuration has been saved.
+func (hb *HotkeyBindings) PostLoad() {
+	for hk := range HotkeyCount {
+		if hb.Binding(hk) == nil {
+			*hb = append(*hb, defaultBinding(hk))
+		}
+	}
+}
+
+// hotkeyEvent reports that a hotkey has been pressed or released.
+type hotkeyEvent struct {
+	hotkey  Hotkey
+	pressed bool
+}
+
+// hotkeyTracker detects when hotkeys are pressed and released, from the
+// state of the inputs they're bound to.
+type hotkeyTracker struct {
+	bindings HotkeyBindings
+	held     []bool // held[i] is true while bindings[i] is pressed
+}
+
+func newHotkeyTracker(bindings HotkeyBindings) *hotkeyTracker {
+	return &hotkeyTracker{
+		bindings: bindings,
+		held:     make([]bool, len(bindings)),
+	}
+}
+
+// update reads the state of the bound inputs with pressed, and appends to
+// events the hotkeys which have been pressed or released since the last
+// update.
+func (t *hotkeyTracker) update(pressed func(input.Code) bool, events []hotkeyEvent) []hotkeyEvent {
+	for i, b := range t.bindings {
+		down := pressed(b.Input) || pressed(b.Alt)
+		if down != t.held[i] {
+			t.held[i] = down
+			events = append(events, hotkeyEvent{hotkey: b.Hotkey, pressed: down})
+		}
+	}
+	return events
+}
@@ -0,0 +1,134 @@
+package hw
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/veandco/go-sdl2/sdl"
+
+	"nestor/hw/input"
+)
+
+func TestHotkeyText(t *testing.T) {
+	for hk := range HotkeyCount {
+		text, err := hk.MarshalText()
+		if err != nil {
+			t.Fatalf("%v: MarshalText: %v", hk, err)
+		}
+		var got Hotkey
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatalf("%v: UnmarshalText(%q): %v", hk, text, err)
+		}
+		if got != hk {
+			t.Errorf("UnmarshalText(%q) = %v, want %v", text, got, hk)
+		}
+	}
+
+	var hk Hotkey
+	if err := hk.UnmarshalText([]byte("self-destruct")); err == nil {
+		t.Errorf("UnmarshalText should fail with unknown hotkey")
+	}
+}
+
+func TestHotkeyBindingsTOML(t *testing.T) {
+	type config struct {
+		Hotkeys HotkeyBindings `toml:"hotkeys"`
+	}
+
+	want := config{Hotkeys: DefaultHotkeys()}
+	want.Hotkeys.Binding(HotkeyPause).Alt = input.Code{Type: input.MouseCtrl, MouseButton: sdl.ButtonMiddle}
+
+	buf := bytes.Buffer{}
+	if err := toml.NewEncoder(&buf).Encode(want); err != nil {
+		t.Fatal(err)
+	}
+	var got config
+	if _, err := toml.Decode(buf.String(), &got); err != nil {
+		t.Fatalf("decode: %v\n%s", err, buf.String())
+	}
+	if !slices.Equal(got.Hotkeys, want.Hotkeys) {
+		t.Errorf("round trip mismatch\ngot  %v\nwant %v", got.Hotkeys, want.Hotkeys)
+	}
+}
+
+func TestHotkeyBindingsPostLoad(t *testing.T) {
+	pause := input.Code{Type: input.MouseCtrl, MouseButton: sdl.ButtonRight}
+	hb := HotkeyBindings{
+		{Hotkey: HotkeyPause, Input: pause},
+		{Hotkey: HotkeyScreenshot}, // unbound
+	}
+	hb.PostLoad()
+
+	if len(hb) != int(HotkeyCount) {
+		t.Fatalf("got %d bindings, want %d", len(hb), HotkeyCount)
+	}
+	if got := hb.Binding(HotkeyPause).Input; got != pause {
+		t.Errorf("pause input = %v, want %v", got, pause)
+	}
+	if got := hb.Binding(HotkeyScreenshot).Input; got.Type != input.ControlNotSet {
+		t.Errorf("screenshot input = %v, want unbound", got)
+	}
+	if got, want := hb.Binding(HotkeyTapeStop), defaultBinding(HotkeyTapeStop); *got != want {
+		t.Errorf("tape stop = %v, want %v", *got, want)
+	}
+}
+
+func TestHotkeyTracker(t *testing.T) {
+	key := func(sc sdl.Scancode) input.Code {
+		return input.Code{Type: input.KeyboardCtrl, Scancode: sc}
+	}
+	button := input.Code{Type: input.ButtonCtrl, CtrlGUID: "guid", CtrlButton: sdl.CONTROLLER_BUTTON_START}
+
+	tracker := newHotkeyTracker(HotkeyBindings{
+		{Hotkey: HotkeyPause, Input: key(sdl.SCANCODE_P), Alt: button},
+		{Hotkey: HotkeyFastForwardHold, Input: key(sdl.SCANCODE_TAB)},
+	})
+
+	held := map[input.Code]bool{}
+	pressed := func(code input.Code) bool { return held[code] }
+
+	steps := []struct {
+		press, release []input.Code
+		want           []hotkeyEvent
+	}{
+		{
+			press: []input.Code{key(sdl.SCANCODE_P)},
+			want:  []hotkeyEvent{{HotkeyPause, true}},
+		},
+		{
+			// Still held, with the alternate input too.
+			press: []input.Code{button},
+		},
+		{
+			release: []input.Code{key(sdl.SCANCODE_P)},
+		},
+		{
+			press:   []input.Code{key(sdl.SCANCODE_TAB)},
+			release: []input.Code{button},
+			want:    []hotkeyEvent{{HotkeyPause, false}, {HotkeyFastForwardHold, true}},
+		},
+		{
+			release: []input.Code{key(sdl.SCANCODE_TAB)},
+			want:    []hotkeyEvent{{HotkeyFastForwardHold, false}},
+		},
+		{
+			// Unbound input.
+			press: []input.Code{key(sdl.SCANCODE_A)},
+		},
+	}
+
+	for i, step := range steps {
+		for _, code := range step.press {
+			held[code] = true
+		}
+		for _, code := range step.release {
+			held[code] = false
+		}
+		got := tracker.update(pressed, nil)
+		if !slices.Equal(got, step.want) {
+			t.Errorf("step %d: got events %v, want %v", i, got, step.want)
+		}
+	}
+}
@@ -8,14 +8,16 @@ const (
 	External IRQSource = 1 << iota
 	FrameCounter
 	DMC
+	FDSDisk
 
-	numSources = 3
+	numSources = 4
 )
 
 var irqSrcNames = [numSources]string{
 	"ext",
 	"fcnt",
 	"dmc",
+	"fds",
 }
 
 func (irq IRQSource) String() string {
@@ -4,63 +4,116 @@ import (
 	"nestor/hw/hwio"
 )
 
-type inputStateLoader interface {
-	LoadState() (uint8, uint8)
+// A PortDevice is a peripheral plugged in a controller port. The CPU reads it
+// through $4016 (port 1) or $4017 (port 2), and strobes all devices at once
+// with bit 0 of $4016 writes.
+type PortDevice interface {
+	// Strobe sets the state of the strobe (latch) line.
+	Strobe(on bool)
+
+	// Read returns the bits driven by the device on the data lines (D0-D4),
+	// and clocks its serial data out.
+	Read() uint8
+
+	// Peek is like Read but without side effects.
+	Peek() uint8
 }
 
-// InputPorts handles I/O with an InputDevice (such as standard NES controller
-// for example).
+// An ExpansionDevice is a peripheral plugged in the Famicom expansion port. It
+// sees all $4016 writes and drives bits 1-4 of both $4016 and $4017 reads.
+type ExpansionDevice interface {
+	// Write is called with the value written to $4016 (OUT0-OUT2).
+	Write(val uint8)
+
+	// Read returns the bits driven by the device on the given port (0 for
+	// $4016, 1 for $4017).
+	Read(port uint8) uint8
+
+	// Peek is like Read but without side effects.
+	Peek(port uint8) uint8
+}
+
+// InputPorts handles I/O with the devices plugged in the controller ports
+// (such as standard NES controllers for example).
 type InputPorts struct {
 	In hwio.Reg8 `hwio:"offset=0x16,pcb,rcb,wcb"`
 
-	provider           inputStateLoader
-	prevStrobe, strobe bool     // to observe strobe falling edge.
-	state              [2]uint8 // state shift registers.
+	ports [2]PortDevice   // devices plugged in each port, if any.
+	exp   ExpansionDevice // device plugged in the expansion port, if any.
+
+	mic microphoneLoader // Famicom controller 2 microphone, if any.
+
+	vs      *VsSystem       // non-nil on Vs. System arcade boards.
+	outHook func(val uint8) // optional, called on $4016 writes.
 }
 
 func (ip *InputPorts) initBus() {
 	hwio.MustInitRegs(ip)
 }
 
-func (ip *InputPorts) regval(port uint8) uint8 {
-	ret := ip.state[port] & 1
-	ip.state[port] >>= 1
-
-	// After 8 bits are read, all subsequent bits will report 1 on a standard
-	// NES controller, but third party and other controllers may report other
-	// values here.
-	ip.state[port] |= 0x80
+// device returns the device read through the given port.
+func (ip *InputPorts) device(port uint8) PortDevice {
+	if ip.vs != nil && ip.vs.SwapControllers {
+		port ^= 1
+	}
+	return ip.ports[port]
+}
 
-	// Emulate open bus behavior.
-	return 0x40 | ret
+func (ip *InputPorts) regval(port uint8) uint8 {
+	var ret uint8
+	if dev := ip.device(port); dev != nil {
+		ret = dev.Read()
+	}
+	if ip.exp != nil {
+		ret |= ip.exp.Read(port)
+	}
+	ret |= ip.micBit(port)
+	return ip.otherBits(port) | ret
 }
 
 // like regval but without side effects.
 func (ip *InputPorts) regvalPeek(port uint8) uint8 {
-	ret := ip.state[port] & 1
+	var ret uint8
+	if dev := ip.device(port); dev != nil {
+		ret = dev.Peek()
+	}
+	if ip.exp != nil {
+		ret |= ip.exp.Peek(port)
+	}
+	ret |= ip.micBit(port)
+	return ip.otherBits(port) | ret
+}
 
-	// Emulate open bus behavior.
-	return 0x40 | ret
+// micBit returns the bit driven by the Famicom microphone, on bit 2 of $4016.
+func (ip *InputPorts) micBit(port uint8) uint8 {
+	if port != 0 || ip.mic == nil || !ip.mic.MicrophoneState() {
+		return 0
+	}
+	return 0x04
 }
 
-// capture state of all connected input devices.
-func (ip *InputPorts) loadstate() {
-	if ip.provider == nil {
-		// No controller is connected.
-		ip.state[0] = 0x00
-		ip.state[1] = 0x00
-		return
+// otherBits returns the bits of the given port not driven by the controller.
+func (ip *InputPorts) otherBits(port uint8) uint8 {
+	if ip.vs != nil {
+		return ip.vs.portBits(port)
 	}
 
-	ip.state[0], ip.state[1] = ip.provider.LoadState()
+	// Emulate open bus behavior.
+	return 0x40
 }
 
 // In: $4016
 func (ip *InputPorts) WriteIN(old, val uint8) {
-	ip.prevStrobe = ip.strobe
-	ip.strobe = val&1 == 1
-	if ip.prevStrobe && !ip.strobe {
-		ip.loadstate()
+	for _, dev := range ip.ports {
+		if dev != nil {
+			dev.Strobe(val&1 == 1)
+		}
+	}
+	if ip.exp != nil {
+		ip.exp.Write(val)
+	}
+	if ip.outHook != nil {
+		ip.outHook(val)
 	}
 }
 
@@ -69,9 +122,6 @@ func (ip *InputPorts) PeekIN(_ uint8) uint8 {
 }
 
 func (ip *InputPorts) ReadIN(_ uint8) uint8 {
-	if ip.strobe {
-		ip.loadstate()
-	}
 	return ip.regval(0)
 }
 
@@ -81,9 +131,53 @@ func (ip *InputPorts) PeekOUT(_ uint8) uint8 {
 }
 
 func (ip *InputPorts) ReadOUT(_ uint8) uint8 {
-	if ip.strobe {
-		ip.loadstate()
+	return ip.regval(1)
+}
+
+type microphoneLoader interface {
+	MicrophoneState() bool
+}
+
+type padStateLoader interface {
+	PadState(pad int) uint8
+}
+
+// StdController is the standard NES controller, an 8-bit shift register
+// loaded with the state of the buttons while strobed.
+type StdController struct {
+	pad      int
+	provider padStateLoader
+	strobe   bool
+	state    uint8 // shift register
+}
+
+// NewStdController returns a controller reading the state of the given paddle
+// from provider.
+func NewStdController(pad int, provider padStateLoader) *StdController {
+	return &StdController{pad: pad, provider: provider}
+}
+
+func (c *StdController) Strobe(on bool) {
+	// Capture buttons state on strobe falling edge.
+	if c.strobe && !on {
+		c.state = c.provider.PadState(c.pad)
+	}
+	c.strobe = on
+}
+
+func (c *StdController) Read() uint8 {
+	if c.strobe {
+		c.state = c.provider.PadState(c.pad)
 	}
+	ret := c.state & 1
 
-	return ip.regval(1)
+	// After 8 bits are read, all subsequent bits will report 1 on a standard
+	// NES controller, but third party and other controllers may report other
+	// values here.
+	c.state = c.state>>1 | 0x80
+	return ret
+}
+
+func (c *StdController) Peek() uint8 {
+	return c.state & 1
 }
@@ -14,6 +14,7 @@ const (
 	KeyboardCtrl
 	ButtonCtrl
 	AxisCtrl
+	MouseCtrl
 )
 
 func (t ControlType) String() string {
@@ -24,12 +25,14 @@ func (t ControlType) String() string {
 		return "joy button"
 	case AxisCtrl:
 		return "joy axis"
+	case MouseCtrl:
+		return "mouse button"
 	}
 	return "not set"
 }
 
 // A Code describes the user input event (keyboard key, game controller
-// button/axis). Only one of these is valid.
+// button/axis, mouse button). Only one of these is valid.
 type Code struct {
 	Scancode sdl.Scancode
 
@@ -38,6 +41,8 @@ type Code struct {
 	CtrlAxis    sdl.GameControllerAxis
 	CtrlAxisDir int16
 
+	MouseButton sdl.Button
+
 	Type ControlType
 }
 
@@ -56,6 +61,8 @@ func (mc Code) Name() string {
 			axis += "-"
 		}
 		return axis
+	case MouseCtrl:
+		return mouseButtonName(mc.MouseButton)
 	}
 
 	return ""
@@ -71,6 +78,8 @@ func (mc Code) MarshalText() ([]byte, error) {
 		s = fmt.Sprintf("joybtn %s %s", name, mc.CtrlGUID)
 	case AxisCtrl:
 		s = fmt.Sprintf("joyaxis %s %s", name, mc.CtrlGUID)
+	case MouseCtrl:
+		s = fmt.Sprintf("mouse %s", name)
 	}
 
 	return []byte(s), nil
@@ -114,6 +123,18 @@ func (mc *Code) UnmarshalText(text []byte) error {
 		}
 		mc.Type = AxisCtrl
 
+	case strings.HasPrefix(s, "mouse"):
+		str := ""
+		if _, err := fmt.Sscanf(s, "mouse %s", &str); err != nil {
+			return fmt.Errorf("malformed mouse code: %s", s)
+		}
+
+		mc.MouseButton = mouseButtonFromName(str)
+		if mc.MouseButton == 0 {
+			return fmt.Errorf("unrecognized mouse button %q", str)
+		}
+		mc.Type = MouseCtrl
+
 	case strings.HasPrefix(s, "key"):
 		str := ""
 		if _, err := fmt.Sscanf(s, "key %s", &str); err != nil {
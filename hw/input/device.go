@@ -0,0 +1,114 @@
+package input
+
+import "fmt"
+
+// A Device is a peripheral plugged in a controller port.
+type Device uint8
+
+const (
+	DeviceController Device = iota // Standard controller
+	DeviceZapper                   // Zapper light gun
+	DeviceFourScore                // Four Score, takes both ports
+	DeviceVaus                     // Arkanoid Vaus controller
+	DevicePowerPad                 // Power Pad
+	DeviceSNESMouse                // SNES mouse
+
+	DeviceCount
+)
+
+var deviceNames = [DeviceCount]string{
+	"controller",
+	"zapper",
+	"four-score",
+	"vaus",
+	"power-pad",
+	"snes-mouse",
+}
+
+var deviceTitles = [DeviceCount]string{
+	"Controller",
+	"Zapper",
+	"Four Score",
+	"Arkanoid Vaus",
+	"Power Pad",
+	"SNES Mouse",
+}
+
+// String returns the user-friendly name of the device.
+func (d Device) String() string {
+	if d >= DeviceCount {
+		return fmt.Sprintf("Device(%d)", d)
+	}
+	return deviceTitles[d]
+}
+
+// UsesMouse reports whether the device is driven with the mouse.
+func (d Device) UsesMouse() bool {
+	switch d {
+	case DeviceZapper, DeviceVaus, DeviceSNESMouse:
+		return true
+	}
+	return false
+}
+
+func (d Device) MarshalText() ([]byte, error) {
+	if d >= DeviceCount {
+		return nil, fmt.Errorf("invalid device %d", d)
+	}
+	return []byte(deviceNames[d]), nil
+}
+
+func (d *Device) UnmarshalText(text []byte) error {
+	for i, name := range deviceNames {
+		if name == string(text) {
+			*d = Device(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unrecognized device %q", text)
+}
+
+// An ExpansionDevice is a peripheral plugged in the Famicom expansion port.
+type ExpansionDevice uint8
+
+const (
+	ExpansionNone           ExpansionDevice = iota // None
+	ExpansionFamilyKeyboard                        // Family BASIC keyboard
+
+	ExpansionDeviceCount
+)
+
+var expansionNames = [ExpansionDeviceCount]string{
+	"none",
+	"family-keyboard",
+}
+
+var expansionTitles = [ExpansionDeviceCount]string{
+	"None",
+	"Family BASIC Keyboard",
+}
+
+// String returns the user-friendly name of the expansion device.
+func (d ExpansionDevice) String() string {
+	if d >= ExpansionDeviceCount {
+		return fmt.Sprintf("ExpansionDevice(%d)", d)
+	}
+	return expansionTitles[d]
+}
+
+func (d ExpansionDevice) MarshalText() ([]byte, error) {
+	if d >= ExpansionDeviceCount {
+		return nil, fmt.Errorf("invalid expansion device %d", d)
+	}
+	return []byte(expansionNames[d]), nil
+}
+
+func (d *ExpansionDevice) UnmarshalText(text []byte) error {
+	for i, name := range expansionNames {
+		if name == string(text) {
+			*d = ExpansionDevice(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unrecognized expansion device %q", text)
+}
@@ -20,12 +20,15 @@ func TestInputCodeMarshalRoundTrip(t *testing.T) {
 		{"joybtn x 030000004c050000cc0900", &Code{Type: ButtonCtrl, CtrlButton: sdl.CONTROLLER_BUTTON_X, CtrlGUID: "030000004c050000cc0900"}},
 		{"joyaxis righttrigger+ 030000004c050000cc1212", &Code{Type: AxisCtrl, CtrlAxis: sdl.CONTROLLER_AXIS_TRIGGERRIGHT, CtrlAxisDir: 1, CtrlGUID: "030000004c050000cc1212"}},
 		{"joyaxis lefttrigger- 123400004c050000cc1212", &Code{Type: AxisCtrl, CtrlAxis: sdl.CONTROLLER_AXIS_TRIGGERLEFT, CtrlAxisDir: -1, CtrlGUID: "123400004c050000cc1212"}},
+		{"mouse left", &Code{Type: MouseCtrl, MouseButton: sdl.ButtonLeft}},
+		{"mouse x2", &Code{Type: MouseCtrl, MouseButton: sdl.ButtonX2}},
 
 		// unmarsal errors
 		{"key   ", nil},
 		{"joybtn foobar+ someguid", nil},
 		{"foocode Return", nil},
 		{"joybtn a", nil},
+		{"mouse wheel", nil},
 	}
 
 	for _, tt := range tests {
@@ -30,24 +30,76 @@ func (pd PaddleButton) String() string {
 // PaddlePreset holds the mapping configuration of a paddle.
 type PaddlePreset struct {
 	Buttons [PadButtonCount]Code `toml:"buttons"`
+
+	// AltButtons holds alternate mappings, so that each button can be
+	// pressed with 2 different inputs (a key and a joystick button, say).
+	AltButtons [PadButtonCount]Code `toml:"alt_buttons"`
+
+	// TurboA and TurboB are mapped to A and B, repeatedly pressed and
+	// released every TurboRate frames while held.
+	TurboA    Code `toml:"turbo_a"`
+	TurboB    Code `toml:"turbo_b"`
+	TurboRate int  `toml:"turbo_rate"`
+
+	Macros []Macro `toml:"macros"`
 }
 
+// DefaultTurboRate is the default number of frames turbo buttons stay pressed,
+// then released.
+const DefaultTurboRate = 2
+
 const numPresets = 8
 
 type Config struct {
 	Paddles [2]PaddleConfig          `toml:"paddles"`
 	Presets [numPresets]PaddlePreset `toml:"presets"`
+
+	// Ports holds the devices plugged in the controller ports, for the roms
+	// not specifying their own.
+	Ports [2]Device `toml:"ports"`
+
+	// Expansion is the device plugged in the Famicom expansion port, for the
+	// roms not specifying their own.
+	Expansion ExpansionDevice `toml:"expansion"`
+
+	// FourScore holds the configuration of paddles 3 and 4, plugged in the
+	// Four Score.
+	FourScore [2]PaddleConfig `toml:"four_score"`
+
+	// Famicom makes the controllers behave as the ones hardwired to the
+	// Famicom, the second one having a microphone.
+	Famicom bool `toml:"famicom"`
+
+	Zapper     ZapperConfig     `toml:"zapper"`
+	PowerPad   PowerPadConfig   `toml:"power_pad"`
+	Microphone MicrophoneConfig `toml:"microphone"`
+}
+
+// NumPaddles is the number of configurable paddles: 2 plus the 2 additional
+// ones plugged in the Four Score.
+const NumPaddles = 4
+
+// Paddle returns the configuration of the given paddle (0 to 3).
+func (cfg *Config) Paddle(pad int) *PaddleConfig {
+	if pad >= 2 {
+		return &cfg.FourScore[pad-2]
+	}
+	return &cfg.Paddles[pad]
 }
 
 func (cfg *Config) PostLoad() {
-	if cfg.Paddles[0].PaddlePreset >= numPresets {
-		cfg.Paddles[0].PaddlePreset = 0
+	for i := range cfg.Presets {
+		if cfg.Presets[i].TurboRate <= 0 {
+			cfg.Presets[i].TurboRate = DefaultTurboRate
+		}
 	}
-	if cfg.Paddles[1].PaddlePreset >= numPresets {
-		cfg.Paddles[1].PaddlePreset = 0
+	for pad := range NumPaddles {
+		padcfg := cfg.Paddle(pad)
+		if padcfg.PaddlePreset >= numPresets {
+			padcfg.PaddlePreset = 0
+		}
+		padcfg.Preset = &cfg.Presets[padcfg.PaddlePreset]
 	}
-	cfg.Paddles[0].Preset = &cfg.Presets[cfg.Paddles[0].PaddlePreset]
-	cfg.Paddles[1].Preset = &cfg.Presets[cfg.Paddles[1].PaddlePreset]
 }
 
 type PaddleConfig struct {
@@ -56,52 +108,183 @@ type PaddleConfig struct {
 	Preset       *PaddlePreset `toml:"-"` // points to the current preset
 }
 
+// ZapperConfig holds the configuration of the Zapper light gun, aimed with the
+// mouse.
+type ZapperConfig struct {
+	// Trigger is the mouse button, key or joystick button pulling the
+	// trigger. Defaults to the left mouse button.
+	Trigger Code `toml:"trigger"`
+}
+
+// PowerPadButtons is the number of buttons on the Power Pad mat.
+const PowerPadButtons = 12
+
+// PowerPadConfig holds the mapping of the Power Pad buttons, numbered as on
+// side B of the mat (1 to 4 on the top row, 9 to 12 on the bottom row).
+type PowerPadConfig struct {
+	Buttons [PowerPadButtons]Code `toml:"buttons"`
+}
+
+// MicrophoneConfig holds the configuration of the microphone of the Famicom
+// second controller. Sound is picked up while its hotkey is held, or when the
+// level captured by the host microphone reaches the threshold.
+type MicrophoneConfig struct {
+	Hotkey Code `toml:"hotkey"`
+
+	// Capture enables capturing the host microphone.
+	Capture bool `toml:"capture"`
+
+	// Device is the name of the audio input device to capture, the default
+	// one if empty.
+	Device string `toml:"device"`
+
+	// Threshold is the captured level, in percents of the full scale, from
+	// which sound is picked up. Defaults to DefaultMicThreshold.
+	Threshold int `toml:"threshold"`
+}
+
+// DefaultMicThreshold is the default level, in percents, from which the host
+// microphone picks up sound.
+const DefaultMicThreshold = 10
+
 type Provider struct {
 	keys     [2][8]sdl.Scancode
 	keystate []uint8
+	mouse    *Mouse
+	mic      *Microphone
+	micLoud  bool // host microphone level, sampled once per frame
+
+	frame  uint64                  // frames elapsed, for turbo buttons
+	macros [NumPaddles]macroPlayer // macros playing on each paddle
 
 	cfg Config
 }
 
-func NewProvider(cfg Config) *Provider {
+// NewProvider returns a Provider reading the keyboard, game controllers and,
+// for the devices driven with the mouse, the given mouse (may be nil). mic, if
+// not nil, is the host microphone captured for the Famicom microphone.
+func NewProvider(cfg Config, mouse *Mouse, mic *Microphone) *Provider {
 	var keystate []uint8
 	sdl.Do(func() { keystate = sdl.GetKeyboardState() })
-	return &Provider{keystate: keystate, cfg: cfg}
+	if cfg.Zapper.Trigger.Type == ControlNotSet {
+		cfg.Zapper.Trigger = Code{Type: MouseCtrl, MouseButton: sdl.ButtonLeft}
+	}
+	if cfg.Microphone.Threshold <= 0 {
+		cfg.Microphone.Threshold = DefaultMicThreshold
+	}
+	return &Provider{keystate: keystate, mouse: mouse, mic: mic, cfg: cfg}
 }
 
-func (ui *Provider) paddleState(idx int) uint8 {
-	padcfg := ui.cfg.Paddles[idx]
+// PadState returns the state of the buttons of the given paddle (0 to 3), as
+// read by the NES from the controller shift register (bit 0 is A).
+func (ui *Provider) PadState(pad int) uint8 {
+	padcfg := ui.cfg.Paddle(pad)
 	if !padcfg.Plugged {
 		// TODO: check this
 		return 0
 	}
 
-	preset := ui.cfg.Paddles[idx].Preset
+	preset := padcfg.Preset
 
 	state := uint8(0)
-	for i, code := range preset.Buttons {
-		pressed := uint8(0)
-		switch code.Type {
-		case KeyboardCtrl:
-			pressed = ui.keystate[code.Scancode]
-		case ButtonCtrl:
-			ctrl := Gamectrls.getByGUID(code.CtrlGUID)
-			if ctrl != nil {
-				pressed = ctrl.Button(code.CtrlButton)
-			}
-		case AxisCtrl:
-			ctrl := Gamectrls.getByGUID(code.CtrlGUID)
-			if ctrl != nil {
-				if ctrl.Axis(code.CtrlAxis) >= JoyAxisThreshold {
-					pressed = 1
-				}
-			}
+	for i := range PadButtonCount {
+		state |= (ui.pressed(preset.Buttons[i]) | ui.pressed(preset.AltButtons[i])) << i
+	}
+	if turboOn(ui.frame, preset.TurboRate) {
+		state |= ui.pressed(preset.TurboA)<<PadA | ui.pressed(preset.TurboB)<<PadB
+	}
+	return state | ui.macros[pad].state()
+}
+
+// turboOn reports whether turbo buttons are pressed during the given frame,
+// alternating every rate frames.
+func turboOn(frame uint64, rate int) bool {
+	rate = max(rate, 1)
+	return frame/uint64(rate)%2 == 0
+}
+
+// EndFrame must be called at the end of each emulated frame, it moves turbo
+// buttons and macros forward and samples the host microphone level.
+func (ui *Provider) EndFrame() {
+	ui.frame++
+	ui.micLoud = ui.mic != nil && ui.mic.Level() >= ui.cfg.Microphone.Threshold
+	for pad := range NumPaddles {
+		padcfg := ui.cfg.Paddle(pad)
+		if padcfg.Plugged && padcfg.Preset != nil {
+			ui.macros[pad].endFrame(padcfg.Preset.Macros, ui.pressed)
 		}
-		state |= pressed << i
+	}
+}
+
+// pressed returns 1 if the input identified by code is pressed, 0 otherwise.
+func (ui *Provider) pressed(code Code) uint8 {
+	if IsPressed(code, ui.keystate, ui.mouse) {
+		return 1
+	}
+	return 0
+}
+
+// IsPressed reports whether the input identified by code is pressed. keystate
+// is the SDL keyboard state, mouse buttons are read from mouse (may be nil).
+func IsPressed(code Code, keystate []uint8, mouse *Mouse) bool {
+	switch code.Type {
+	case KeyboardCtrl:
+		return keystate[code.Scancode] != 0
+	case ButtonCtrl:
+		ctrl := Gamectrls.getByGUID(code.CtrlGUID)
+		return ctrl != nil && ctrl.Button(code.CtrlButton) != 0
+	case AxisCtrl:
+		ctrl := Gamectrls.getByGUID(code.CtrlGUID)
+		return ctrl != nil && ctrl.Axis(code.CtrlAxis) >= JoyAxisThreshold
+	case MouseCtrl:
+		return mouse != nil && mouse.Button(code.MouseButton) != 0
+	}
+	return false
+}
+
+// ZapperState returns where the Zapper is aimed, in NES screen coordinates,
+// and whether its trigger is pulled. ok is false when aiming off the screen.
+func (ui *Provider) ZapperState() (x, y int, ok, trigger bool) {
+	trigger = ui.pressed(ui.cfg.Zapper.Trigger) != 0
+	if ui.mouse == nil {
+		return -1, -1, false, trigger
+	}
+	x, y, ok = ui.mouse.Position()
+	return x, y, ok, trigger
+}
+
+// VausState returns the horizontal mouse position, driving the Arkanoid Vaus
+// knob, and whether its button (left mouse button) is pressed.
+func (ui *Provider) VausState() (x int, ok, fire bool) {
+	if ui.mouse == nil {
+		return 0, false, false
+	}
+	x, _, ok = ui.mouse.Position()
+	return x, ok, ui.mouse.Button(sdl.ButtonLeft) != 0
+}
+
+// SNESMouseState returns the mouse motion since the last call and the state of
+// its left and right buttons.
+func (ui *Provider) SNESMouseState() (dx, dy int, left, right bool) {
+	if ui.mouse == nil {
+		return 0, 0, false, false
+	}
+	dx, dy = ui.mouse.Motion()
+	return dx, dy, ui.mouse.Button(sdl.ButtonLeft) != 0, ui.mouse.Button(sdl.ButtonRight) != 0
+}
+
+// PowerPadState returns the state of the Power Pad buttons, bit 0 is button 1.
+func (ui *Provider) PowerPadState() uint16 {
+	var state uint16
+	for i, code := range ui.cfg.PowerPad.Buttons {
+		state |= uint16(ui.pressed(code)) << i
 	}
 	return state
 }
 
-func (ui *Provider) LoadState() (uint8, uint8) {
-	return ui.paddleState(0), ui.paddleState(1)
+// MicrophoneState reports whether the Famicom microphone picks up sound, that
+// is, whether its hotkey is held or the host microphone was loud enough
+// during the last frame.
+func (ui *Provider) MicrophoneState() bool {
+	return ui.micLoud || ui.pressed(ui.cfg.Microphone.Hotkey) != 0
 }
@@ -0,0 +1,46 @@
+package input
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+func TestTurboOn(t *testing.T) {
+	var got []bool
+	for frame := range uint64(8) {
+		got = append(got, turboOn(frame, 2))
+	}
+	want := []bool{true, true, false, false, true, true, false, false}
+	if !slices.Equal(got, want) {
+		t.Errorf("turboOn(rate 2) = %v, want %v", got, want)
+	}
+}
+
+func TestPresetTOML(t *testing.T) {
+	var preset PaddlePreset
+	preset.Buttons[PadA] = Code{Type: KeyboardCtrl, Scancode: sdl.SCANCODE_W}
+	preset.AltButtons[PadA] = Code{Type: MouseCtrl, MouseButton: sdl.ButtonLeft}
+	preset.TurboA = Code{Type: KeyboardCtrl, Scancode: sdl.SCANCODE_E}
+	preset.TurboRate = 3
+	preset.Macros = []Macro{{
+		Key:      Code{Type: KeyboardCtrl, Scancode: sdl.SCANCODE_K},
+		Sequence: MacroSequence{1 << PadUp, 0, 1 << PadUp},
+	}}
+
+	buf, err := toml.Marshal(preset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got PaddlePreset
+	if _, err := toml.Decode(string(buf), &got); err != nil {
+		t.Fatalf("decode error: %v\n%s", err, buf)
+	}
+	if !reflect.DeepEqual(got, preset) {
+		t.Errorf("round-trip mismatch\ngot  %+v\nwant %+v\n%s", got, preset, buf)
+	}
+}
@@ -0,0 +1,57 @@
+package input
+
+import "github.com/veandco/go-sdl2/sdl"
+
+// familyKeyboard maps the Family BASIC keyboard matrix (9 rows of 2 columns of
+// 4 keys) to the host keyboard. Keys are positioned as on a japanese layout,
+// the keys not found on a US keyboard are mapped to the navigation keys.
+//
+// The 4 keys of a row and column are read on bits 1 to 4 of $4017, in order.
+var familyKeyboard = [9][2][4]sdl.Scancode{
+	{
+		{sdl.SCANCODE_F8, sdl.SCANCODE_RETURN, sdl.SCANCODE_RIGHTBRACKET, sdl.SCANCODE_BACKSLASH}, // F8 RETURN [ ]
+		{sdl.SCANCODE_RALT, sdl.SCANCODE_RSHIFT, sdl.SCANCODE_PAGEUP, sdl.SCANCODE_END},           // KANA RSHIFT ¥ STOP
+	},
+	{
+		{sdl.SCANCODE_F7, sdl.SCANCODE_LEFTBRACKET, sdl.SCANCODE_APOSTROPHE, sdl.SCANCODE_SEMICOLON}, // F7 @ : ;
+		{sdl.SCANCODE_PAGEDOWN, sdl.SCANCODE_SLASH, sdl.SCANCODE_MINUS, sdl.SCANCODE_EQUALS},         // _ / - ^
+	},
+	{
+		{sdl.SCANCODE_F6, sdl.SCANCODE_O, sdl.SCANCODE_L, sdl.SCANCODE_K},
+		{sdl.SCANCODE_PERIOD, sdl.SCANCODE_COMMA, sdl.SCANCODE_P, sdl.SCANCODE_0},
+	},
+	{
+		{sdl.SCANCODE_F5, sdl.SCANCODE_I, sdl.SCANCODE_U, sdl.SCANCODE_J},
+		{sdl.SCANCODE_M, sdl.SCANCODE_N, sdl.SCANCODE_9, sdl.SCANCODE_8},
+	},
+	{
+		{sdl.SCANCODE_F4, sdl.SCANCODE_Y, sdl.SCANCODE_G, sdl.SCANCODE_H},
+		{sdl.SCANCODE_B, sdl.SCANCODE_V, sdl.SCANCODE_7, sdl.SCANCODE_6},
+	},
+	{
+		{sdl.SCANCODE_F3, sdl.SCANCODE_T, sdl.SCANCODE_R, sdl.SCANCODE_D},
+		{sdl.SCANCODE_F, sdl.SCANCODE_C, sdl.SCANCODE_5, sdl.SCANCODE_4},
+	},
+	{
+		{sdl.SCANCODE_F2, sdl.SCANCODE_W, sdl.SCANCODE_S, sdl.SCANCODE_A},
+		{sdl.SCANCODE_X, sdl.SCANCODE_Z, sdl.SCANCODE_E, sdl.SCANCODE_3},
+	},
+	{
+		{sdl.SCANCODE_F1, sdl.SCANCODE_GRAVE, sdl.SCANCODE_Q, sdl.SCANCODE_LCTRL}, // F1 ESC Q CTR
+		{sdl.SCANCODE_LSHIFT, sdl.SCANCODE_LALT, sdl.SCANCODE_1, sdl.SCANCODE_2},  // LSHIFT GRPH 1 2
+	},
+	{
+		{sdl.SCANCODE_HOME, sdl.SCANCODE_UP, sdl.SCANCODE_RIGHT, sdl.SCANCODE_LEFT},          // CLR/HOME UP RIGHT LEFT
+		{sdl.SCANCODE_DOWN, sdl.SCANCODE_SPACE, sdl.SCANCODE_BACKSPACE, sdl.SCANCODE_INSERT}, // DOWN SPACE DEL INS
+	},
+}
+
+// FamilyKeyboardState returns the state of the 4 keys at the given row and
+// column of the Family BASIC keyboard matrix, 1 bits are pressed keys.
+func (ui *Provider) FamilyKeyboardState(row, col int) uint8 {
+	var state uint8
+	for i, sc := range familyKeyboard[row][col] {
+		state |= ui.keystate[sc] << i
+	}
+	return state
+}
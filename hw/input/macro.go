@@ -0,0 +1,155 @@
+package input
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A Macro plays back a sequence of button presses, frame by frame, on the
+// paddles using the preset it belongs to, when its key is pressed.
+type Macro struct {
+	Key      Code          `toml:"key"`
+	Sequence MacroSequence `toml:"sequence"`
+}
+
+// maxMacroFrames is the maximum duration of a macro, 1 minute.
+const maxMacroFrames = 60 * 60
+
+// A MacroSequence holds the state of the paddle buttons, one per frame (bit 0
+// is A, as in PadState).
+//
+// In text form, it's a list of space-separated steps. A step is a list of
+// buttons held together, joined with '+', or '-' for no button, optionally
+// followed by '*' and the number of frames it lasts (1 by default). For
+// example, the Konami code:
+//
+//	Up - Up - Down - Down - Left - Right - Left - Right - B - A
+//
+// or a fireball motion, holding each step for 2 frames:
+//
+//	Down*2 Down+Right*2 Right+A*2
+type MacroSequence []uint8
+
+func (seq MacroSequence) MarshalText() ([]byte, error) {
+	var steps []string
+	for i := 0; i < len(seq); {
+		n := 1
+		for i+n < len(seq) && seq[i+n] == seq[i] {
+			n++
+		}
+
+		step := formatButtons(seq[i])
+		if n > 1 {
+			step += "*" + strconv.Itoa(n)
+		}
+		steps = append(steps, step)
+		i += n
+	}
+	return []byte(strings.Join(steps, " ")), nil
+}
+
+func (seq *MacroSequence) UnmarshalText(text []byte) error {
+	var parsed MacroSequence
+	for _, step := range strings.Fields(string(text)) {
+		buttons, count, found := strings.Cut(step, "*")
+		n := 1
+		if found {
+			var err error
+			if n, err = strconv.Atoi(count); err != nil || n < 1 {
+				return fmt.Errorf("invalid frame count in macro step %q", step)
+			}
+		}
+
+		state, err := parseButtons(buttons)
+		if err != nil {
+			return err
+		}
+		if len(parsed)+n > maxMacroFrames {
+			return fmt.Errorf("macro too long (more than %d frames)", maxMacroFrames)
+		}
+		for range n {
+			parsed = append(parsed, state)
+		}
+	}
+	*seq = parsed
+	return nil
+}
+
+// formatButtons returns the names of the buttons set in state, joined with
+// '+', or '-' if none is.
+func formatButtons(state uint8) string {
+	var names []string
+	for btn := range PadButtonCount {
+		if state&(1<<btn) != 0 {
+			names = append(names, btn.String())
+		}
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, "+")
+}
+
+// parseButtons is the inverse of formatButtons, names are case-insensitive.
+func parseButtons(s string) (uint8, error) {
+	if s == "-" {
+		return 0, nil
+	}
+
+	var state uint8
+	for _, name := range strings.Split(s, "+") {
+		btn, ok := buttonFromName(name)
+		if !ok {
+			return 0, fmt.Errorf("unrecognized button %q in macro", name)
+		}
+		state |= 1 << btn
+	}
+	return state, nil
+}
+
+func buttonFromName(name string) (PaddleButton, bool) {
+	for btn := range PadButtonCount {
+		if strings.EqualFold(btn.String(), name) {
+			return btn, true
+		}
+	}
+	return 0, false
+}
+
+// macroPlayer plays back the macros of a paddle.
+type macroPlayer struct {
+	seq  MacroSequence // sequence being played, nil if none.
+	pos  int           // current frame in seq.
+	held []bool        // whether each macro key was held during the previous frame.
+}
+
+// state returns the state of the buttons pressed by the playing macro, if any.
+func (mp *macroPlayer) state() uint8 {
+	if mp.seq == nil {
+		return 0
+	}
+	return mp.seq[mp.pos]
+}
+
+// endFrame moves the playing macro to its next frame, then starts playing the
+// macro whose key has just been pressed, if none is playing.
+func (mp *macroPlayer) endFrame(macros []Macro, pressed func(Code) uint8) {
+	if mp.seq != nil {
+		mp.pos++
+		if mp.pos >= len(mp.seq) {
+			mp.seq = nil
+		}
+	}
+
+	if len(mp.held) != len(macros) {
+		mp.held = make([]bool, len(macros))
+	}
+	for i, m := range macros {
+		held := pressed(m.Key) != 0
+		if held && !mp.held[i] && mp.seq == nil && len(m.Sequence) > 0 {
+			mp.seq, mp.pos = m.Sequence, 0
+		}
+		mp.held[i] = held
+	}
+}
@@ -0,0 +1,83 @@
+package input
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMacroSequence(t *testing.T) {
+	const (
+		a     = 1 << PadA
+		b     = 1 << PadB
+		up    = 1 << PadUp
+		down  = 1 << PadDown
+		right = 1 << PadRight
+	)
+
+	tests := []struct {
+		text string
+		seq  MacroSequence
+		want string // marshaled text, if different
+	}{
+		{text: "", seq: nil},
+		{text: "Up - Up - B A", seq: MacroSequence{up, 0, up, 0, b, a}},
+		{text: "Down*2 Down+Right*2 A+Right*2", seq: MacroSequence{down, down, down | right, down | right, a | right, a | right}},
+		{text: "up  down+RIGHT -*3", seq: MacroSequence{up, down | right, 0, 0, 0}, want: "Up Down+Right -*3"},
+		{text: "A A*2", seq: MacroSequence{a, a, a}, want: "A*3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			var seq MacroSequence
+			if err := seq.UnmarshalText([]byte(tt.text)); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(seq, tt.seq) {
+				t.Fatalf("UnmarshalText(%q) = %v, want %v", tt.text, seq, tt.seq)
+			}
+
+			buf, err := seq.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tt.text
+			if tt.want != "" {
+				want = tt.want
+			}
+			if string(buf) != want {
+				t.Errorf("MarshalText() = %q, want %q", buf, want)
+			}
+		})
+	}
+
+	for _, text := range []string{"Up+Foo", "A*0", "A*x", "A+", "*2"} {
+		var seq MacroSequence
+		if err := seq.UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("UnmarshalText(%q) should fail", text)
+		}
+	}
+}
+
+func TestMacroPlayer(t *testing.T) {
+	key := Code{Type: KeyboardCtrl, Scancode: 4}
+	macros := []Macro{{Key: key, Sequence: MacroSequence{1, 0, 2}}}
+
+	var keyDown bool
+	pressed := func(code Code) uint8 {
+		if code == key && keyDown {
+			return 1
+		}
+		return 0
+	}
+
+	var mp macroPlayer
+	var got []uint8
+	// Key held for 5 frames, the macro plays once.
+	for frame := range 6 {
+		keyDown = frame < 5
+		mp.endFrame(macros, pressed)
+		got = append(got, mp.state())
+	}
+	if want := []uint8{1, 0, 2, 0, 0, 0}; !slices.Equal(got, want) {
+		t.Errorf("played %v, want %v", got, want)
+	}
+}
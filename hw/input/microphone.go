@@ -0,0 +1,82 @@
+package input
+
+import (
+	"encoding/binary"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	micSampleRate = 22050
+	micBufferSize = 512 // in samples
+)
+
+// Microphone captures the host microphone, through SDL audio input, and tracks
+// the level of the captured sound. It's meant to be used by a single goroutine.
+type Microphone struct {
+	dev   sdl.AudioDeviceID
+	buf   []byte
+	level int // peak level of the last captured samples, 0 to 32768
+}
+
+// OpenMicrophone starts capturing the audio input device with the given name,
+// or the default one if name is empty.
+func OpenMicrophone(name string) (*Microphone, error) {
+	if err := sdl.InitSubSystem(sdl.INIT_AUDIO); err != nil {
+		return nil, err
+	}
+
+	desired := sdl.AudioSpec{
+		Freq:     micSampleRate,
+		Format:   sdl.AUDIO_S16LSB,
+		Channels: 1,
+		Samples:  micBufferSize,
+	}
+	var obtained sdl.AudioSpec
+	dev, err := sdl.OpenAudioDevice(name, true, &desired, &obtained, 0)
+	if err != nil {
+		return nil, err
+	}
+	sdl.PauseAudioDevice(dev, false)
+
+	return &Microphone{dev: dev, buf: make([]byte, micBufferSize*2)}, nil
+}
+
+// Level returns the peak level, from 0 to 100, of the sound captured since the
+// last call. The previous level is returned if nothing has been captured in
+// the meantime.
+func (m *Microphone) Level() int {
+	var (
+		peak  int
+		fresh bool
+	)
+	for {
+		n, _ := sdl.DequeueAudio(m.dev, m.buf)
+		if n == 0 {
+			break
+		}
+		peak = max(peak, peakLevel(m.buf[:n]))
+		fresh = true
+	}
+	if fresh {
+		m.level = peak
+	}
+	return m.level * 100 / 32768
+}
+
+// Close stops capturing audio.
+func (m *Microphone) Close() {
+	sdl.CloseAudioDevice(m.dev)
+	sdl.QuitSubSystem(sdl.INIT_AUDIO)
+}
+
+// peakLevel returns the highest absolute value of the 16-bit little-endian
+// samples in buf.
+func peakLevel(buf []byte) int {
+	var peak int
+	for i := 0; i+1 < len(buf); i += 2 {
+		s := int(int16(binary.LittleEndian.Uint16(buf[i:])))
+		peak = max(peak, s, -s)
+	}
+	return peak
+}
@@ -0,0 +1,25 @@
+package input
+
+import "testing"
+
+func TestPeakLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		buf  []byte
+		want int
+	}{
+		{"empty", nil, 0},
+		{"silence", []byte{0, 0, 0, 0}, 0},
+		{"positive", []byte{0x10, 0x00, 0x00, 0x10, 0x20, 0x00}, 0x1000},
+		{"negative", []byte{0x00, 0xF0, 0x00, 0x10}, 0x1000},
+		{"full scale", []byte{0x00, 0x80, 0xFF, 0x7F}, 32768},
+		{"odd length", []byte{0x10, 0x00, 0xFF}, 0x10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := peakLevel(tt.buf); got != tt.want {
+				t.Errorf("peakLevel() = %#x, want %#x", got, tt.want)
+			}
+		})
+	}
+}
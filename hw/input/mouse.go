@@ -0,0 +1,100 @@
+package input
+
+import (
+	"sync/atomic"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Mouse holds the state of the mouse over the emulator window. It's updated by
+// the events loop and read by the emulated input devices (such as the Zapper).
+// Safe for concurrent use.
+type Mouse struct {
+	pos     atomic.Uint32 // x in high 16 bits, y in low 16 bits, ^0 if offscreen
+	buttons atomic.Uint32 // sdl.ButtonMask of the pressed buttons
+
+	dx, dy atomic.Int32 // motion accumulated since the last call to Motion
+}
+
+const offscreen = ^uint32(0)
+
+// NewMouse returns a Mouse pointing outside of the NES screen.
+func NewMouse() *Mouse {
+	m := &Mouse{}
+	m.pos.Store(offscreen)
+	return m
+}
+
+// SetPosition sets the mouse position, in NES screen coordinates.
+func (m *Mouse) SetPosition(x, y int) {
+	m.pos.Store(uint32(x)<<16 | uint32(y)&0xFFFF)
+}
+
+// SetOffscreen records that the mouse doesn't point at the NES screen.
+func (m *Mouse) SetOffscreen() {
+	m.pos.Store(offscreen)
+}
+
+// Position returns the mouse position in NES screen coordinates, ok is false if
+// the mouse doesn't point at the NES screen.
+func (m *Mouse) Position() (x, y int, ok bool) {
+	pos := m.pos.Load()
+	if pos == offscreen {
+		return -1, -1, false
+	}
+	return int(pos >> 16), int(pos & 0xFFFF), true
+}
+
+// AddMotion accumulates the relative motion of the mouse.
+func (m *Mouse) AddMotion(dx, dy int) {
+	m.dx.Add(int32(dx))
+	m.dy.Add(int32(dy))
+}
+
+// Motion returns, and resets, the mouse motion accumulated since the last
+// call.
+func (m *Mouse) Motion() (dx, dy int) {
+	return int(m.dx.Swap(0)), int(m.dy.Swap(0))
+}
+
+// SetButton sets the state of a mouse button.
+func (m *Mouse) SetButton(button sdl.Button, pressed bool) {
+	bit := uint32(button.Mask())
+	if pressed {
+		m.buttons.Or(bit)
+	} else {
+		m.buttons.And(^bit)
+	}
+}
+
+// Button returns 1 if the given mouse button is pressed, 0 otherwise.
+func (m *Mouse) Button(button sdl.Button) uint8 {
+	if m.buttons.Load()&uint32(button.Mask()) != 0 {
+		return 1
+	}
+	return 0
+}
+
+var mouseButtonNames = [...]string{
+	sdl.ButtonLeft:   "left",
+	sdl.ButtonMiddle: "middle",
+	sdl.ButtonRight:  "right",
+	sdl.ButtonX1:     "x1",
+	sdl.ButtonX2:     "x2",
+}
+
+func mouseButtonName(button sdl.Button) string {
+	if int(button) < len(mouseButtonNames) {
+		return mouseButtonNames[button]
+	}
+	return ""
+}
+
+func mouseButtonFromName(name string) sdl.Button {
+	for i, s := range mouseButtonNames {
+		if s != "" && s == name {
+			return sdl.Button(i)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,113 @@
+package hw
+
+import "testing"
+
+type fakeProvider struct {
+	pads     [4]uint8
+	vausX    int
+	vausFire bool
+	powerPad uint16
+	dx, dy   int
+	left     bool
+}
+
+func (p *fakeProvider) PadState(pad int) uint8 { return p.pads[pad] }
+func (p *fakeProvider) VausState() (int, bool, bool) {
+	return p.vausX, true, p.vausFire
+}
+func (p *fakeProvider) PowerPadState() uint16 { return p.powerPad }
+func (p *fakeProvider) SNESMouseState() (int, int, bool, bool) {
+	return p.dx, p.dy, p.left, false
+}
+
+// readPort strobes the devices then reads n times the given port, returning
+// the bits masked by mask.
+func readPort(ip *InputPorts, port uint8, n int, mask uint8) []uint8 {
+	ip.WriteIN(0, 1)
+	ip.WriteIN(1, 0)
+	bits := make([]uint8, n)
+	for i := range bits {
+		bits[i] = ip.regval(port) & mask
+	}
+	return bits
+}
+
+// serial returns the bits of val, LSB first, as read from data line d.
+func serial(val uint32, n int, d uint) []uint8 {
+	bits := make([]uint8, n)
+	for i := range bits {
+		bits[i] = uint8(val>>i&1) << d
+	}
+	return bits
+}
+
+func checkBits(t *testing.T, name string, got, want []uint8) {
+	t.Helper()
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s: read %d = %02X, want %02X\ngot  %02X\nwant %02X", name, i, got[i], want[i], got, want)
+			return
+		}
+	}
+}
+
+func TestPortDevices(t *testing.T) {
+	prov := &fakeProvider{pads: [4]uint8{0x81, 0x42, 0x24, 0x18}}
+
+	t.Run("controller", func(t *testing.T) {
+		var ip InputPorts
+		ip.ports[0] = NewStdController(0, prov)
+		ip.ports[1] = NewStdController(1, prov)
+		checkBits(t, "$4016", readPort(&ip, 0, 10, 0x1F), serial(0x381, 10, 0))
+		checkBits(t, "$4017", readPort(&ip, 1, 10, 0x1F), serial(0x342, 10, 0))
+	})
+
+	t.Run("four score", func(t *testing.T) {
+		var ip InputPorts
+		ip.ports[0], ip.ports[1] = NewFourScore(prov)
+		checkBits(t, "$4016", readPort(&ip, 0, 26, 0x1F), serial(0x3<<24|0x08<<16|0x24<<8|0x81, 26, 0))
+		checkBits(t, "$4017", readPort(&ip, 1, 26, 0x1F), serial(0x3<<24|0x04<<16|0x18<<8|0x42, 26, 0))
+	})
+
+	t.Run("vaus", func(t *testing.T) {
+		var ip InputPorts
+		ip.ports[1] = NewVaus(prov)
+		prov.vausX, prov.vausFire = 0, true
+
+		// Leftmost position is vausMin (0b01100010), inverted and MSB first,
+		// then 1s.
+		want := []uint8{0x18, 0x08, 0x08, 0x18, 0x18, 0x18, 0x08, 0x18, 0x18}
+		checkBits(t, "$4017", readPort(&ip, 1, 9, 0x18), want)
+
+		prov.vausX, prov.vausFire = NTSCWidth-1, false
+		// Rightmost position is vausMax (0b11110010).
+		want = []uint8{0x00, 0x00, 0x00, 0x00, 0x10, 0x10, 0x00, 0x10, 0x10}
+		checkBits(t, "$4017", readPort(&ip, 1, 9, 0x18), want)
+	})
+
+	t.Run("power pad", func(t *testing.T) {
+		var ip InputPorts
+		ip.ports[1] = NewPowerPad(prov)
+		prov.powerPad = 1<<(1-1) | 1<<(12-1) // buttons 1 and 12
+
+		// D4: 2, 1, 5, 9, 6, 10, 11, 7, then 1s
+		// D3: 4, 3, 12, 8, then 1s
+		want := []uint8{0x00, 0x10, 0x08, 0x00, 0x08, 0x08, 0x08, 0x08, 0x18}
+		checkBits(t, "$4017", readPort(&ip, 1, 9, 0x18), want)
+	})
+
+	t.Run("snes mouse", func(t *testing.T) {
+		var ip InputPorts
+		ip.ports[1] = NewSNESMouse(prov)
+		prov.dx, prov.dy, prov.left = -3, 5, true
+
+		var want []uint8
+		for _, b := range []uint8{0x00, 0x41, 0x05, 0x83} {
+			for i := 7; i >= 0; i-- {
+				want = append(want, b>>i&1)
+			}
+		}
+		want = append(want, 1, 1)
+		checkBits(t, "$4017", readPort(&ip, 1, 34, 0x1F), want)
+	})
+}
@@ -0,0 +1,139 @@
+package mappers
+
+import (
+	"nestor/hw/hwio"
+	"nestor/ines"
+)
+
+var Action53 = MapperDesc{
+	Name: "Action 53",
+	Load: loadAction53,
+}
+
+// action53 is the multicart board used by the Action 53 homebrew
+// compilations. It can emulate the banking of NROM, CNROM (with CHR-RAM),
+// BNROM, UNROM, AOROM and mapper 180 games, confined into an outer bank.
+type action53 struct {
+	*Base
+
+	reg    uint8 // selected register: $00, $01, $80 or $81
+	chr    uint8 // $00: CHR-RAM bank
+	inner  uint8 // $01: inner PRG bank
+	mode   uint8 // $80: mirroring, PRG bank mode and outer bank size
+	outer  uint8 // $81: outer PRG bank
+	chrram [32 * KB]byte
+
+	// $5000-$5FFF: register select.
+	RegSelect hwio.Device `hwio:"size=0x1000,wcb"`
+}
+
+func (m *action53) WriteREGSELECT(_ uint16, val uint8) {
+	// 7  bit  0
+	// ---- ----
+	// Sxxx xxxR
+	// |       |
+	// +-------+- Select register $00, $01, $80 or $81
+	m.reg = val & 0x81
+}
+
+func (m *action53) writeReg(_ uint16, val uint8) {
+	switch m.reg {
+	case 0x00:
+		// 7  bit  0
+		// ---- ----
+		// xxxM xxCC
+		//    |   ||
+		//    |   ++- Select 8 KB CHR RAM bank for PPU $0000-$1FFF
+		//    +------ One-screen page, if one-screen mirroring is selected
+		m.chr = val & 0x03
+		m.setOneScreenPage(val)
+	case 0x01:
+		// 7  bit  0
+		// ---- ----
+		// xxxM PPPP
+		//    | ||||
+		//    | ++++- Select inner PRG bank
+		//    +------ One-screen page, if one-screen mirroring is selected
+		m.inner = val & 0x0F
+		m.setOneScreenPage(val)
+	case 0x80:
+		// 7  bit  0
+		// ---- ----
+		// xxSS PBMM
+		//   || ||||
+		//   || ||++- Mirroring (0: one-screen A, 1: one-screen B,
+		//   || ||               2: vertical, 3: horizontal)
+		//   || |+--- In 16 KB mode, fixed bank location (0: $8000, 1: $C000)
+		//   || +---- PRG bank mode (0: 32 KB, 1: 16 KB)
+		//   ++------ Outer bank size (0: 32 KB, 1: 64 KB, 2: 128 KB, 3: 256 KB)
+		m.mode = val & 0x3F
+	case 0x81:
+		m.outer = val
+	}
+	m.remap()
+}
+
+// setOneScreenPage handles the mirroring bit of registers $00 and $01.
+func (m *action53) setOneScreenPage(val uint8) {
+	if m.mode&0x02 == 0 {
+		m.mode = m.mode&^0x01 | val>>4&0x01
+	}
+}
+
+func (m *action53) remap() {
+	switch m.mode & 0x03 {
+	case 0:
+		m.SetNTMirroring(ines.OnlyAScreen)
+	case 1:
+		m.SetNTMirroring(ines.OnlyBScreen)
+	case 2:
+		m.SetNTMirroring(ines.VertMirroring)
+	case 3:
+		m.SetNTMirroring(ines.HorzMirroring)
+	}
+
+	start := 8 * KB * int(m.chr)
+	m.PPU().Bus.Unmap(0x0000, 0x1FFF)
+	m.PPU().Bus.MapMemorySlice(0x0000, 0x1FFF, m.chrram[start:start+8*KB], false)
+
+	// Work in 16 KB units: the outer bank is a 32 KB bank, and the outer bank
+	// size mask covers the bits taken from the inner bank.
+	outer := int(m.outer) << 1
+	size := int(m.mode >> 4 & 0x03)
+
+	if m.mode&0x08 == 0 {
+		// 32 KB mode.
+		mask := 2<<size - 1
+		bank := outer&^mask | int(m.inner)<<1&mask
+		m.SelectPRGPage16KB(0, bank)
+		m.SelectPRGPage16KB(1, bank|1)
+		return
+	}
+
+	// 16 KB mode: one page is fixed to the first or last 16 KB of the outer
+	// bank, the other one is switchable.
+	mask := 2<<size - 1
+	bank := outer&^mask | int(m.inner)&mask
+	if m.mode&0x04 != 0 {
+		m.SelectPRGPage16KB(0, bank)
+		m.SelectPRGPage16KB(1, outer|1)
+	} else {
+		m.SelectPRGPage16KB(0, outer)
+		m.SelectPRGPage16KB(1, bank)
+	}
+}
+
+func loadAction53(b *Base) error {
+	m := &action53{
+		Base: b,
+		// The last bank holds the menu, reset vector included.
+		outer: 0xFF,
+	}
+	hwio.MustInitRegs(m)
+	b.Init(m.writeReg)
+
+	b.CPU().Bus.MapDevice(0x5000, &m.RegSelect)
+
+	m.remap()
+	return nil
+}
@@ -6,23 +6,62 @@ import (
 	"nestor/emu/log"
 	"nestor/hw"
 	"nestor/ines"
+	"nestor/unif"
 )
 
 var modMapper = log.NewModule("mapper")
 
-func Load(rom *ines.Rom, cpu *hw.CPU, ppu *hw.PPU) error {
-	desc, ok := All[rom.Mapper()]
+// Options holds user settings affecting cartridge hardware emulation.
+type Options struct {
+	// N163MixChannels mixes Namco 163 audio channels together rather than
+	// time-multiplexing them as the real hardware does.
+	N163MixChannels bool
+
+	// SavePath is the file in which the cartridge non-volatile memory
+	// (EEPROM, etc.) is persisted. Nothing is persisted if empty.
+	SavePath string
+}
+
+// Cartridge is a loaded cartridge board.
+type Cartridge interface {
+	// Save writes the cartridge non-volatile memory, if any, to the save
+	// file.
+	Save() error
+
+	// Status returns a short text describing the state of the cartridge
+	// hardware visible to the user (such as LEDs), or an empty string.
+	Status() string
+}
+
+// Load loads the cartridge board of rom, which must have been registered. The
+// board of roms converted from UNIF is first looked up by name, then by its
+// iNES mapper number if it has one.
+func Load(rom *ines.Rom, cpu *hw.CPU, ppu *hw.PPU, opts Options) (Cartridge, error) {
+	if rom.Board != "" {
+		if desc, ok := LookupUNIF(rom.Board); ok {
+			return LoadDesc(desc, rom, cpu, ppu, opts)
+		}
+		if _, _, ok := unif.Mapper(rom.Board); !ok {
+			return nil, fmt.Errorf("unsupported UNIF board %q", rom.Board)
+		}
+	}
+	desc, ok := Lookup(rom.Mapper())
 	if !ok {
-		return fmt.Errorf("unsupported mapper %d", rom.Mapper())
+		return nil, fmt.Errorf("unsupported mapper %d", rom.Mapper())
 	}
-	base, err := newbase(desc, rom, cpu, ppu)
+	return LoadDesc(desc, rom, cpu, ppu, opts)
+}
+
+// LoadDesc loads the cartridge board described by desc.
+func LoadDesc(desc MapperDesc, rom *ines.Rom, cpu *hw.CPU, ppu *hw.PPU, opts Options) (Cartridge, error) {
+	base, err := newBase(desc, rom, cpu, ppu, opts)
 	if err != nil {
-		return fmt.Errorf("mapper initialization failed: %w", err)
+		return nil, fmt.Errorf("mapper initialization failed: %w", err)
 	}
 	if err := desc.Load(base); err != nil {
-		return fmt.Errorf("failed to load mapper %s: %w", desc.Name, err)
+		return nil, fmt.Errorf("failed to load mapper %s: %w", desc.Name, err)
 	}
-	return nil
+	return base, nil
 }
 
 type ErrUnsuppportedPRGROMSize int
@@ -31,23 +70,122 @@ func (e ErrUnsuppportedPRGROMSize) Error() string {
 	return fmt.Sprintf("unsupported PRGROM size: %d bytes", int(e))
 }
 
+// alwaysBusConflicts is the HasBusConflicts function of boards which are
+// always subject to bus conflicts.
+func alwaysBusConflicts(*Base) bool { return true }
+
+// MapperDesc describes a cartridge board.
 type MapperDesc struct {
 	Name            string
-	Load            func(*base) error
+	Load            func(*Base) error // sets up the board hardware on top of Base
 	PRGROMbanksz    uint32
 	CHRROMbanksz    uint32
 	PRGRAMbanksz    uint32
-	HasBusConflicts func(*base) bool
+	HasBusConflicts func(*Base) bool // writes to registers are AND'ed with PRG-ROM (optional)
 
 	RegisterStart uint16 // defaults to 0x8000 if not set
 	RegisterEnd   uint16 // defaults to 0xFFFF if not set
 }
 
-var All = map[uint16]MapperDesc{
-	0:  NROM,
-	1:  MMC1,
-	2:  UxROM,
-	3:  CNROM,
-	7:  AxROM,
-	66: GxROM,
+var (
+	boards     = map[uint16]MapperDesc{}
+	unifBoards = map[string]MapperDesc{}
+)
+
+// Register makes a board available for the given iNES mapper number. It is
+// meant to be called from init functions, it panics if Register is called
+// twice for the same mapper number.
+func Register(mapper uint16, desc MapperDesc) {
+	if desc.Load == nil {
+		panic(fmt.Sprintf("mappers: Register of mapper %d with nil Load", mapper))
+	}
+	if _, dup := boards[mapper]; dup {
+		panic(fmt.Sprintf("mappers: Register called twice for mapper %d", mapper))
+	}
+	boards[mapper] = desc
+}
+
+// RegisterUNIF makes a board available for the given UNIF board name. The
+// name is normalized with unif.BoardName. Boards registered by name take
+// precedence over their iNES mapper number equivalent. It is meant to be
+// called from init functions, it panics if RegisterUNIF is called twice for
+// the same board name.
+func RegisterUNIF(board string, desc MapperDesc) {
+	board = unif.BoardName(board)
+	if desc.Load == nil {
+		panic(fmt.Sprintf("mappers: RegisterUNIF of board %q with nil Load", board))
+	}
+	if _, dup := unifBoards[board]; dup {
+		panic(fmt.Sprintf("mappers: RegisterUNIF called twice for board %q", board))
+	}
+	unifBoards[board] = desc
+}
+
+// Lookup returns the board registered for the given iNES mapper number.
+func Lookup(mapper uint16) (MapperDesc, bool) {
+	desc, ok := boards[mapper]
+	return desc, ok
+}
+
+// LookupUNIF returns the board registered for the given UNIF board name.
+func LookupUNIF(board string) (MapperDesc, bool) {
+	desc, ok := unifBoards[unif.BoardName(board)]
+	return desc, ok
+}
+
+func init() {
+	for mapper, desc := range builtin {
+		Register(mapper, desc)
+	}
+}
+
+// builtin lists the boards implemented in this package.
+var builtin = map[uint16]MapperDesc{
+	0:   NROM,
+	1:   MMC1,
+	2:   UxROM,
+	3:   CNROM,
+	7:   AxROM,
+	11:  ColorDreams,
+	13:  CPROM,
+	16:  BandaiFCG,
+	19:  Namco163,
+	21:  VRC4,
+	22:  VRC2,
+	23:  VRC2VRC4,
+	25:  VRC2VRC4,
+	28:  Action53,
+	30:  UNROM512,
+	32:  IremG101,
+	33:  TaitoTC0190,
+	34:  BNROM,
+	48:  TaitoTC0690,
+	65:  IremH3001,
+	66:  GxROM,
+	67:  Sunsoft3,
+	68:  Sunsoft4,
+	69:  FME7,
+	70:  Bandai74161,
+	71:  Camerica,
+	76:  NAMCOT3446,
+	78:  JalecoJF16,
+	87:  JalecoJF05,
+	88:  NAMCOT3443,
+	89:  Sunsoft2,
+	93:  Sunsoft2R,
+	94:  UN1ROM,
+	95:  NAMCOT3425,
+	97:  IremTAMS1,
+	99:  VsSystem,
+	111: GTROM,
+	140: JalecoJF11,
+	152: Bandai74161OneScreen,
+	153: BandaiLZ93D50SRAM,
+	154: NAMCOT3453,
+	155: MMC1A,
+	157: BandaiDatach,
+	159: BandaiLZ93D50,
+	180: UNROM180,
+	184: Sunsoft1,
+	206: Namco108,
 }
@@ -0,0 +1,307 @@
+package mappers
+
+import (
+	"nestor/hw"
+	"nestor/hw/hwdefs"
+	"nestor/hw/hwio"
+	"nestor/ines"
+)
+
+var (
+	BandaiFCG = MapperDesc{
+		Name: "Bandai FCG",
+		Load: loadBandai,
+	}
+	BandaiLZ93D50SRAM = MapperDesc{
+		Name: "Bandai LZ93D50 with SRAM",
+		Load: loadBandai,
+	}
+	BandaiDatach = MapperDesc{
+		Name: "Bandai Datach Joint ROM System",
+		Load: loadBandai,
+	}
+	BandaiLZ93D50 = MapperDesc{
+		Name: "Bandai LZ93D50 with 24C01",
+		Load: loadBandai,
+	}
+)
+
+// bandaiVariant describes one of the Bandai FCG/LZ93D50 boards.
+type bandaiVariant struct {
+	name string
+
+	// Register ranges the board responds to: the FCG-1/2 ASICs have their
+	// registers at $6000-$7FFF, the LZ93D50 at $8000-$FFFF.
+	regs6000, regs8000 bool
+
+	// The LZ93D50 IRQ counter is written through a latch, copied to the
+	// counter when the IRQ control register is written.
+	irqLatch bool
+
+	eeprom    *hw.EEPROM // 24C01 or 24C02 EEPROM on the cartridge (optional)
+	datach    *hw.EEPROM // 24C01 EEPROM in the Datach base unit
+	sram      bool       // 8 KB PRG-RAM, and PRG outer bank in CHR registers
+	chrBanked bool
+}
+
+// detectBandaiVariant returns the board variant for the given rom. For
+// mapper 16 with submapper 0 (i.e iNES 1.0 roms), both register ranges are
+// supported at once, along with a 24C02 EEPROM.
+func detectBandaiVariant(rom *ines.Rom) bandaiVariant {
+	switch rom.Mapper() {
+	case 153:
+		return bandaiVariant{
+			name:     "LZ93D50 with SRAM",
+			regs8000: true,
+			irqLatch: true,
+			sram:     true,
+		}
+	case 157:
+		return bandaiVariant{
+			name:     "Datach",
+			regs8000: true,
+			irqLatch: true,
+			eeprom:   hw.NewEEPROM24C02(),
+			datach:   hw.NewEEPROM24C01(),
+		}
+	case 159:
+		return bandaiVariant{
+			name:      "LZ93D50 with 24C01",
+			regs8000:  true,
+			irqLatch:  true,
+			eeprom:    hw.NewEEPROM24C01(),
+			chrBanked: true,
+		}
+	}
+
+	switch rom.SubMapper() {
+	case 4:
+		return bandaiVariant{name: "FCG-1/2", regs6000: true, chrBanked: true}
+	case 5:
+		v := bandaiVariant{name: "LZ93D50", regs8000: true, irqLatch: true, chrBanked: true}
+		if rom.PRGNVRAMSize() > 0 {
+			v.name = "LZ93D50 with 24C02"
+			v.eeprom = hw.NewEEPROM24C02()
+		}
+		return v
+	}
+	return bandaiVariant{
+		name:      "FCG-1/2 or LZ93D50",
+		regs6000:  true,
+		regs8000:  true,
+		eeprom:    hw.NewEEPROM24C02(),
+		chrBanked: true,
+	}
+}
+
+// bandai handles the Bandai FCG-1, FCG-2 and LZ93D50 boards (mappers 16, 153,
+// 157 and 159). Most of them save into serial EEPROMs rather than into
+// battery-backed RAM.
+type bandai struct {
+	*Base
+
+	variant bandaiVariant
+
+	chrbank [8]uint8
+	prgbank uint8
+
+	irqEnable  bool
+	irqCounter uint16
+	irqLatch   uint16
+
+	ramEnable bool // mapper 153 only
+	readSDA   bool // $6000-$7FFF reads return the EEPROM SDA line
+
+	// Reads of $6000-$7FFF return either the EEPROM data line or PRG-RAM.
+	PRG6000 hwio.Device `hwio:"size=0x2000,rcb,wcb"`
+}
+
+func (m *bandai) ReadPRG6000(addr uint16) uint8 {
+	if m.variant.sram {
+		if !m.ramEnable {
+			return uint8(addr >> 8) // open bus
+		}
+		return m.PRGRAM.Data[addr&0x1FFF]
+	}
+
+	// 7  bit  0
+	// ---- ----
+	// xxxE xxxx
+	//    |
+	//    +----- EEPROM SDA line (other bits are open bus)
+	val := uint8(addr>>8) &^ 0x10
+	if m.readSDA && m.sda() {
+		val |= 0x10
+	}
+	return val
+}
+
+func (m *bandai) WritePRG6000(addr uint16, val uint8) {
+	switch {
+	case m.variant.sram:
+		if m.ramEnable {
+			m.PRGRAM.Data[addr&0x1FFF] = val
+		}
+	case m.variant.regs6000:
+		m.writeReg(addr, val)
+	}
+}
+
+// sda returns the state of the SDA line, wired-AND of all EEPROMs.
+func (m *bandai) sda() bool {
+	sda := true
+	if m.variant.eeprom != nil {
+		sda = sda && m.variant.eeprom.Read()
+	}
+	if m.variant.datach != nil {
+		sda = sda && m.variant.datach.Read()
+	}
+	return sda
+}
+
+func (m *bandai) writeReg(addr uint16, val uint8) {
+	if addr >= 0x8000 && !m.variant.regs8000 {
+		return
+	}
+
+	switch reg := addr & 0x0F; {
+	case reg <= 0x7:
+		m.chrbank[reg] = val
+		if m.variant.chrBanked {
+			m.SelectCHRROMPage1KB(uint32(reg), int(val))
+		} else if m.variant.sram {
+			m.remapPRG()
+		}
+
+	case reg == 0x8:
+		m.prgbank = val & 0x0F
+		m.remapPRG()
+
+	case reg == 0x9:
+		switch val & 0x03 {
+		case 0:
+			m.SetNTMirroring(ines.VertMirroring)
+		case 1:
+			m.SetNTMirroring(ines.HorzMirroring)
+		case 2:
+			m.SetNTMirroring(ines.OnlyAScreen)
+		case 3:
+			m.SetNTMirroring(ines.OnlyBScreen)
+		}
+
+	case reg == 0xA:
+		// Writes to this register also acknowledge the IRQ.
+		m.irqEnable = val&0x01 != 0
+		if m.variant.irqLatch {
+			m.irqCounter = m.irqLatch
+		}
+		m.CPU().ClearIRQSource(hwdefs.External)
+
+	case reg == 0xB:
+		if m.variant.irqLatch {
+			m.irqLatch = m.irqLatch&0xFF00 | uint16(val)
+		} else {
+			m.irqCounter = m.irqCounter&0xFF00 | uint16(val)
+		}
+
+	case reg == 0xC:
+		if m.variant.irqLatch {
+			m.irqLatch = m.irqLatch&0x00FF | uint16(val)<<8
+		} else {
+			m.irqCounter = m.irqCounter&0x00FF | uint16(val)<<8
+		}
+
+	case reg == 0xD:
+		m.writeControl(val)
+	}
+}
+
+// writeControl handles writes to the EEPROM control register ($800D), or the
+// PRG-RAM enable register for mapper 153.
+//
+//	7  bit  0
+//	---- ----
+//	RDC. C...
+//	|||  |
+//	|||  +---- SCL line of the Datach 24C01 EEPROM
+//	||+------- SCL line of the cartridge EEPROM (or PRG-RAM enable)
+//	|+-------- SDA line
+//	+--------- Enable reading the SDA line at $6000-$7FFF
+func (m *bandai) writeControl(val uint8) {
+	if m.variant.sram {
+		m.ramEnable = val&0x20 != 0
+		return
+	}
+
+	sda := val&0x40 != 0
+	if m.variant.eeprom != nil {
+		m.variant.eeprom.Write(val&0x20 != 0, sda)
+	}
+	if m.variant.datach != nil {
+		m.variant.datach.Write(val&0x08 != 0, sda)
+	}
+	m.readSDA = val&0x80 != 0
+}
+
+func (m *bandai) remapPRG() {
+	// On mapper 153, bit 0 of any CHR register selects the 256 KB outer PRG
+	// bank (all of them are OR'ed).
+	var outer int
+	if m.variant.sram {
+		for _, bank := range m.chrbank {
+			outer |= int(bank&0x01) << 4
+		}
+	}
+
+	nbanks := len(m.Rom().PRGROM) / (16 * KB)
+	m.SelectPRGPage16KB(0, (outer|int(m.prgbank))%nbanks)
+	m.SelectPRGPage16KB(1, (outer|0x0F)%nbanks)
+}
+
+// tick is called on every CPU cycle.
+func (m *bandai) tick() {
+	if !m.irqEnable {
+		return
+	}
+	// The counter is checked before being decremented, so that the IRQ is
+	// triggered one cycle after reaching 0.
+	if m.irqCounter == 0 {
+		m.CPU().SetIRQSource(hwdefs.External)
+	}
+	m.irqCounter--
+}
+
+func loadBandai(b *Base) error {
+	m := &bandai{
+		Base:    b,
+		variant: detectBandaiVariant(b.Rom()),
+	}
+	hwio.MustInitRegs(m)
+	b.Init(m.writeReg)
+
+	modMapper.InfoZ("detected board").
+		String("mapper", m.Name()).
+		String("variant", m.variant.name).
+		End()
+
+	b.CPU().Bus.Unmap(0x6000, 0x7FFF)
+	b.CPU().Bus.MapDevice(0x6000, &m.PRG6000)
+	b.CPU().SetCycleHook(m.tick)
+
+	if m.variant.eeprom != nil {
+		b.Persist(m.variant.eeprom.Data)
+	}
+	if m.variant.datach != nil {
+		b.Persist(m.variant.datach.Data)
+	}
+	if m.variant.sram && b.Rom().HasPersistence() {
+		b.Persist(m.PRGRAM.Data)
+	}
+
+	b.SetNTMirroring(ines.VertMirroring)
+	m.remapPRG()
+	for i := range m.chrbank {
+		m.SelectCHRROMPage1KB(uint32(i), 0)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package mappers
+
+import "nestor/ines"
+
+// Bandai (and Taito) discrete logic boards, built around a 74161 latch.
+var (
+	Bandai74161 = MapperDesc{
+		Name:            "Bandai 74161/32",
+		Load:            loadBandai74161,
+		HasBusConflicts: alwaysBusConflicts,
+	}
+	Bandai74161OneScreen = MapperDesc{
+		Name:            "Bandai 74161/32 one-screen",
+		Load:            loadBandai74161,
+		HasBusConflicts: alwaysBusConflicts,
+	}
+)
+
+// Mappers 70 and 152, the latter adds one-screen mirroring control.
+func loadBandai74161(b *Base) error {
+	onescreen := b.Rom().Mapper() == 152
+
+	b.Init(func(_ uint16, val uint8) {
+		// 7  bit  0
+		// ---- ----
+		// MPPP CCCC
+		// |||| ||||
+		// |||| ++++- Select 8 KB CHR ROM bank for PPU $0000-$1FFF
+		// |+++------ Select 16 KB PRG ROM bank for CPU $8000-$BFFF
+		// +--------- Select 1 KB VRAM page for all 4 nametables (152 only,
+		//            mapper 70 uses bit 7 as a 4th PRG bank bit)
+		b.SelectCHRROMPage8KB(int(val & 0x0F))
+		if !onescreen {
+			b.SelectPRGPage16KB(0, int(val>>4))
+			return
+		}
+
+		b.SelectPRGPage16KB(0, int(val>>4&0x07))
+		if val&0x80 != 0 {
+			b.SetNTMirroring(ines.OnlyBScreen)
+		} else {
+			b.SetNTMirroring(ines.OnlyAScreen)
+		}
+	})
+
+	if onescreen {
+		b.SetNTMirroring(ines.OnlyAScreen)
+	} else {
+		b.SetNTMirroring(b.Rom().Mirroring())
+	}
+	b.SelectCHRROMPage8KB(0)
+	b.SelectPRGPage16KB(0, 0)
+	b.SelectPRGPage16KB(1, -1)
+	return nil
+}
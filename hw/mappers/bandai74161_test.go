@@ -0,0 +1,40 @@
+package mappers
+
+import (
+	"testing"
+
+	"nestor/ines"
+)
+
+func TestBandai74161(t *testing.T) {
+	t.Run("70", func(t *testing.T) {
+		loadTestRom(t, 70, 0, 128, 128).run(t, []busStep{
+			{
+				desc:  "banks",
+				write: []busVal{{0xFC00, 0x35}},
+				cpu:   []busVal{{0x8000, 3 * 16}},
+				ppu:   []busVal{{0x0000, 5 * 8}},
+			},
+			{
+				desc:  "bus conflict",
+				write: []busVal{{0x8001, 0x35}},
+				cpu:   []busVal{{0x8000, 0}},
+				ppu:   []busVal{{0x0000, 0}},
+			},
+		})
+	})
+
+	t.Run("152", func(t *testing.T) {
+		// 256 KB so that $FC00 holds $FF, and the mirroring bit gets through.
+		loadTestRom(t, 152, 0, 256, 128).run(t, []busStep{
+			{desc: "power up", mirror: ines.OnlyAScreen},
+			{
+				desc:   "banks and mirroring",
+				write:  []busVal{{0xFC00, 0xB5}},
+				cpu:    []busVal{{0x8000, 3 * 16}},
+				ppu:    []busVal{{0x0000, 5 * 8}},
+				mirror: ines.OnlyBScreen,
+			},
+		})
+	})
+}
@@ -1,14 +1,24 @@
 package mappers
 
 import (
+	"bytes"
 	"fmt"
+	"os"
 
 	"nestor/hw"
 	"nestor/hw/hwio"
 	"nestor/ines"
 )
 
-type base struct {
+// Base holds the hardware common to all cartridge boards: PRG-ROM and CHR-ROM
+// banking, PRG-RAM, nametable mapping and non-volatile memory. Each board
+// implementation embeds a *Base, given to MapperDesc.Load, and builds upon it.
+//
+// Boards raise IRQs through CPU().SetIRQSource(hwdefs.External), can be
+// clocked on every CPU cycle with CPU().SetCycleHook or on PPU A12 rising
+// edges with PPU().SetA12Hook, and can map their own devices onto
+// CPU().Bus and PPU().Bus.
+type Base struct {
 	rom *ines.Rom
 
 	cpu *hw.CPU
@@ -23,19 +33,33 @@ type base struct {
 	nametables [0x800]byte
 
 	desc MapperDesc
+	opts Options
 
-	// set by base.init
+	// Non-volatile memory areas, persisted in the save file (in that order).
+	nvmem [][]byte
+
+	// Self-flashable PRG-ROM, persisted after nvmem.
+	flash *hw.Flash
+
+	status string // see Cartridge.Status
+
+	// Writes to registers are AND'ed with the PRG-ROM byte at the same
+	// address, see MapperDesc.HasBusConflicts.
+	busConflicts bool
+
+	// set by Base.Init
 	registers hwio.Bitset
 	writeReg  func(addr uint16, value uint8) // optional
 }
 
-func newbase(desc MapperDesc, rom *ines.Rom, cpu *hw.CPU, ppu *hw.PPU) (*base, error) {
+func newBase(desc MapperDesc, rom *ines.Rom, cpu *hw.CPU, ppu *hw.PPU, opts Options) (*Base, error) {
 	if !ispow2(len(rom.PRGROM)) {
 		return nil, fmt.Errorf("only support PRGROM with power of 2 size, got %d", len(rom.PRGROM))
 	}
 
-	b := &base{
+	b := &Base{
 		desc: desc,
+		opts: opts,
 		rom:  rom,
 		cpu:  cpu,
 		ppu:  ppu,
@@ -50,10 +74,15 @@ func newbase(desc MapperDesc, rom *ines.Rom, cpu *hw.CPU, ppu *hw.PPU) (*base, e
 		end = uint(desc.RegisterEnd)
 	}
 	b.registers.SetRange(uint(start), uint(end))
+	if desc.HasBusConflicts != nil {
+		b.busConflicts = desc.HasBusConflicts(b)
+	}
 	return b, nil
 }
 
-func (b *base) init(writeReg func(uint16, uint8)) {
+// Init maps PRG-ROM, CHR-ROM (or CHR-RAM) and PRG-RAM onto the CPU and PPU
+// buses. writeReg, if not nil, is called on writes to the mapper registers.
+func (b *Base) Init(writeReg func(uint16, uint8)) {
 	// CPU mapping.
 	hwio.MustInitRegs(b)
 	b.cpu.Bus.MapBank(0x0000, b, 0)
@@ -85,10 +114,14 @@ func (b *base) init(writeReg func(uint16, uint8)) {
 	})
 }
 
-func (b *base) write(addr uint16, value uint8) {
+func (b *Base) write(addr uint16, value uint8) {
 	// is this a register write?
 	if b.registers.Test(uint(addr)) {
 		if b.writeReg != nil {
+			if b.busConflicts {
+				// The ROM drives the data bus at the same time as the CPU.
+				value &= b.PRGROM[addr&0x7FFF]
+			}
 			b.writeReg(addr, value)
 		}
 	}
@@ -96,16 +129,21 @@ func (b *base) write(addr uint16, value uint8) {
 
 const KB = 1 << 10
 
-// select what 32KB PRG ROM bank to use.
-func (b *base) selectPRGPage32KB(bank int) {
+// SelectPRGPage32KB selects what 32KB PRG ROM bank to use. Out of range banks
+// wrap around.
+func (b *Base) SelectPRGPage32KB(bank int) {
 	// TODO: what if instead of copying we were using
 	// table.MapMemorySlice. in this case we would avoid a copy, as well as
 	// define if the memory is read-only or read-write.
+	nbanks := max(len(b.rom.PRGROM)/(32*KB), 1)
+	bank %= nbanks
 	copy(b.PRGROM[:], b.rom.PRGROM[32*KB*(bank):])
 }
 
-// select what 16KB PRG ROM bank to use into which PRG 16KB page.
-func (b *base) selectPRGPage16KB(page uint32, bank int) {
+// SelectPRGPage16KB selects what 16KB PRG ROM bank to use into which PRG 16KB
+// page. Negative bank numbers are counted from the end of PRG ROM, and out of
+// range banks wrap around.
+func (b *Base) SelectPRGPage16KB(page uint32, bank int) {
 	if bank < 0 {
 		// TODO: should probably not be checked here and should not panic.
 		if len(b.rom.PRGROM)%(16*KB) != 0 {
@@ -113,6 +151,7 @@ func (b *base) selectPRGPage16KB(page uint32, bank int) {
 		}
 		bank += len(b.rom.PRGROM) / (16 * KB)
 	}
+	bank %= len(b.rom.PRGROM) / (16 * KB)
 
 	start := 16 * KB * page
 	end := 16 * KB * (page + 1)
@@ -125,20 +164,38 @@ func (b *base) selectPRGPage16KB(page uint32, bank int) {
 		Int("bank", bank).End()
 }
 
-// TODO: remove and use selectCHRROM... instead
-func (b *base) copyCHRROM(dest []byte, bank uint32) {
-	// Copy CHRROM bank to PPU memory.
-	// CHRROM is 8KB in size (when present).
-	start := min(uint32(len(b.rom.CHRROM)-1), bank*b.desc.CHRROMbanksz)
-	end := min(uint32(len(b.rom.CHRROM)), start+b.desc.CHRROMbanksz)
-	copy(dest, b.rom.CHRROM[start:end])
+// SelectPRGPage8KB selects what 8KB PRG ROM bank to use into which PRG 8KB
+// page. Negative bank numbers are counted from the end of PRG ROM, and out of
+// range banks wrap around.
+func (b *Base) SelectPRGPage8KB(page uint32, bank int) {
+	nbanks := len(b.rom.PRGROM) / (8 * KB)
+	if bank < 0 {
+		bank += nbanks
+	}
+	bank %= nbanks
+
+	start := 8 * KB * page
+	end := 8 * KB * (page + 1)
+	copy(b.PRGROM[start:end], b.rom.PRGROM[8*KB*bank:])
+
+	modMapper.DebugZ("Select 8 kB PRG page").
+		Hex16("bus.start", uint16(0x8000+start)).
+		Hex16("bus.end", uint16(-1+0x8000+end)).
+		Int("bank", bank).End()
 }
 
-// select what 8KB PRG ROM bank to use.
-func (b *base) selectCHRROMPage8KB(bank int) {
+// SelectCHRROMPage8KB selects what 8KB CHR ROM bank to use. Negative bank
+// numbers are counted from the end of CHR ROM, and out of range banks wrap
+// around.
+func (b *Base) SelectCHRROMPage8KB(bank int) {
+	nbanks := len(b.rom.CHRROM) / (8 * KB)
+	if nbanks == 0 {
+		return
+	}
 	if bank < 0 {
-		bank += len(b.rom.CHRROM) / (8 * KB)
+		bank += nbanks
 	}
+	bank %= nbanks
 
 	// b:bus r:rom
 	bstart, bend := 0, 8*KB
@@ -152,51 +209,213 @@ func (b *base) selectCHRROMPage8KB(bank int) {
 		Int("bank", bank).End()
 }
 
-// select what 4KB PRG ROM bank to use into which PRG 4KB page.
-func (b *base) selectCHRROMPage4KB(page uint32, bank int) {
+// SelectCHRROMPage4KB selects what 4KB CHR ROM bank to use into which CHR 4KB
+// page. Negative bank numbers are counted from the end of CHR ROM, and out of
+// range banks wrap around.
+func (b *Base) SelectCHRROMPage4KB(page uint32, bank int) {
+	nbanks := len(b.rom.CHRROM) / (4 * KB)
+	if nbanks == 0 {
+		return
+	}
+	if bank < 0 {
+		bank += nbanks
+	}
+	bank %= nbanks
+	copy(b.CHRROM[4*KB*page:4*KB*(page+1)], b.rom.CHRROM[4*KB*bank:])
+}
+
+// SelectCHRROMPage1KB selects what 1KB CHR ROM bank to use into which CHR 1KB
+// page. Negative bank numbers are counted from the end of CHR ROM, and out of
+// range banks wrap around.
+func (b *Base) SelectCHRROMPage1KB(page uint32, bank int) {
+	nbanks := len(b.rom.CHRROM) / KB
+	if nbanks == 0 {
+		return
+	}
 	if bank < 0 {
-		bank += len(b.rom.CHRROM) / (4 * KB)
+		bank += nbanks
 	}
+	bank %= nbanks
+	copy(b.CHRROM[KB*page:KB*(page+1)], b.rom.CHRROM[KB*bank:])
+}
+
+// Nametable is the memory backing one of the 4 nametable slots: a page of
+// the console internal VRAM (CIRAM), a page of CHR-ROM, CHR-RAM or any extra
+// RAM on the cartridge.
+type Nametable struct {
+	mem      []byte // 1 KB
+	readonly bool
+}
 
-	if len(b.rom.CHRROM) != 0 {
-		romoff := min(4*KB*bank, len(b.rom.CHRROM)-1)
-		copy(b.CHRROM[4*KB*page:], b.rom.CHRROM[romoff:])
+// CIRAM returns the CIRAM page (0 or 1) as a nametable.
+func (b *Base) CIRAM(page int) Nametable {
+	off := (page & 0x01) * KB
+	return Nametable{mem: b.nametables[off : off+KB]}
+}
+
+// CHRROMNametable returns the 1KB CHR-ROM bank as a read-only nametable.
+// Negative bank numbers are counted from the end of CHR ROM, and out of range
+// banks wrap around. Without CHR-ROM, the CIRAM page selected by the low bit
+// of bank is returned instead.
+func (b *Base) CHRROMNametable(bank int) Nametable {
+	nbanks := len(b.rom.CHRROM) / KB
+	if nbanks == 0 {
+		return b.CIRAM(bank)
+	}
+	if bank < 0 {
+		bank += nbanks
 	}
+	bank %= nbanks
+	return Nametable{mem: b.rom.CHRROM[KB*bank : KB*(bank+1)], readonly: true}
 }
 
-func (b *base) setNTMirroring(m ines.NTMirroring) {
-	A := b.nametables[:0x400]
-	B := b.nametables[0x400:0x800]
+// RAMNametable returns a 1KB area of CHR-RAM or extra cartridge RAM as a
+// nametable.
+func RAMNametable(mem []byte) Nametable {
+	return Nametable{mem: mem[:KB]}
+}
+
+// SetNTMirroring maps the CIRAM pages in the nametable slots according to m.
+func (b *Base) SetNTMirroring(m ines.NTMirroring) {
+	A, B := b.CIRAM(0), b.CIRAM(1)
 
 	switch m {
 	case ines.HorzMirroring:
-		b.remapNametables(A, A, B, B)
+		b.SetNametables(A, A, B, B)
 	case ines.VertMirroring:
-		b.remapNametables(A, B, A, B)
+		b.SetNametables(A, B, A, B)
 	case ines.OnlyAScreen:
-		b.remapNametables(A, A, A, A)
+		b.SetNametables(A, A, A, A)
 	case ines.OnlyBScreen:
-		b.remapNametables(B, B, B, B)
+		b.SetNametables(B, B, B, B)
 	default:
 		panic(fmt.Sprintf("unsupported mirroring %d", m))
 	}
 }
 
-func (b *base) remapNametables(nt1, nt2, nt3, nt4 []byte) {
-	// Unmap all nametables
-	b.ppu.Bus.Unmap(0x2000, 0x3EFF)
+// SetNametables maps the 4 nametable slots.
+func (b *Base) SetNametables(nt0, nt1, nt2, nt3 Nametable) {
+	b.SetNametable(0, nt0)
+	b.SetNametable(1, nt1)
+	b.SetNametable(2, nt2)
+	b.SetNametable(3, nt3)
+}
+
+// SetNametable maps a nametable into one of the 4 nametable slots (and its
+// mirror at $3000-$3EFF).
+func (b *Base) SetNametable(slot int, nt Nametable) {
+	start := 0x2000 + uint16(slot)*0x400
+	end := start + 0x3FF
+	mstart, mend := start+0x1000, min(end+0x1000, 0x3EFF)
+
+	b.ppu.Bus.Unmap(start, end)
+	b.ppu.Bus.Unmap(mstart, mend)
+	b.ppu.Bus.MapMemorySlice(start, end, nt.mem, nt.readonly)
+	b.ppu.Bus.MapMemorySlice(mstart, mend, nt.mem, nt.readonly)
+}
+
+// HasPRGRAM reports whether the cartridge has PRG-RAM, either volatile or
+// battery-backed. For iNES 1.0 roms, only the battery flag is available.
+func (b *Base) HasPRGRAM() bool {
+	return b.rom.PRGRAMSize()+b.rom.PRGNVRAMSize() > 0 || b.rom.HasPersistence()
+}
+
+// Persist registers mem as non-volatile memory, to be saved into the save
+// file. mem is restored from the save file, if it exists.
+func (b *Base) Persist(mem []byte) {
+	off := 0
+	for _, m := range b.nvmem {
+		off += len(m)
+	}
+	b.nvmem = append(b.nvmem, mem)
+
+	buf := b.readSaveFile()
+	if buf == nil {
+		return
+	}
+	if len(buf) < off+len(mem) {
+		modMapper.WarnZ("Save file too small, ignored").String("path", b.opts.SavePath).Int("size", len(buf)).End()
+		return
+	}
+	copy(mem, buf[off:])
+	modMapper.InfoZ("Restored non-volatile memory").String("path", b.opts.SavePath).Int("size", len(mem)).End()
+}
+
+// PersistFlash registers the flash memory f, holding PRG-ROM, as non-volatile
+// memory. Only the flashed sectors are saved, along with a checksum of the
+// original PRG-ROM, so that a save file made with another rom isn't restored.
+// It must be called after Persist, if the board has other non-volatile memory.
+func (b *Base) PersistFlash(f *hw.Flash) {
+	off := 0
+	for _, m := range b.nvmem {
+		off += len(m)
+	}
+	b.flash = f
+
+	buf := b.readSaveFile()
+	if len(buf) <= off {
+		return
+	}
+	if err := f.RestoreSave(buf[off:]); err != nil {
+		modMapper.WarnZ("Flash save ignored").String("path", b.opts.SavePath).Error("err", err).End()
+		return
+	}
+	modMapper.InfoZ("Restored flash memory").String("path", b.opts.SavePath).Int("size", len(buf)-off).End()
+}
 
-	b.ppu.Bus.MapMemorySlice(0x2000, 0x23FF, nt1, false)
-	b.ppu.Bus.MapMemorySlice(0x2400, 0x27FF, nt2, false)
-	b.ppu.Bus.MapMemorySlice(0x2800, 0x2BFF, nt3, false)
-	b.ppu.Bus.MapMemorySlice(0x2C00, 0x2FFF, nt4, false)
+// readSaveFile returns the content of the save file, or nil if there's none.
+func (b *Base) readSaveFile() []byte {
+	if b.opts.SavePath == "" {
+		return nil
+	}
+	buf, err := os.ReadFile(b.opts.SavePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			modMapper.WarnZ("Failed to read save file").String("path", b.opts.SavePath).Error("err", err).End()
+		}
+		return nil
+	}
+	return buf
+}
 
-	// Mirrors
-	b.ppu.Bus.MapMemorySlice(0x3000, 0x33FF, nt1, false)
-	b.ppu.Bus.MapMemorySlice(0x3400, 0x37FF, nt2, false)
-	b.ppu.Bus.MapMemorySlice(0x3800, 0x3BFF, nt3, false)
-	b.ppu.Bus.MapMemorySlice(0x3C00, 0x3EFF, nt4, false)
+// Save writes the non-volatile memory areas to the save file. Nothing is
+// written if there's nothing to save, such as an unmodified flash.
+func (b *Base) Save() error {
+	if b.opts.SavePath == "" {
+		return nil
+	}
+	buf := bytes.Join(b.nvmem, nil)
+	if b.flash != nil {
+		buf = append(buf, b.flash.SaveData()...)
+	}
+	if len(buf) == 0 {
+		return nil
+	}
+	return os.WriteFile(b.opts.SavePath, buf, 0644)
 }
 
+func (b *Base) Status() string { return b.status }
+
+// SetStatus sets the text returned by Status.
+func (b *Base) SetStatus(s string) { b.status = s }
+
+// Rom returns the rom the cartridge has been loaded from.
+func (b *Base) Rom() *ines.Rom { return b.rom }
+
+// CPU returns the CPU the cartridge is connected to.
+func (b *Base) CPU() *hw.CPU { return b.cpu }
+
+// PPU returns the PPU the cartridge is connected to.
+func (b *Base) PPU() *hw.PPU { return b.ppu }
+
+// Options returns the user settings of the cartridge.
+func (b *Base) Options() Options { return b.opts }
+
+// Desc returns the description of the mapper.
+func (b *Base) Desc() MapperDesc { return b.desc }
+
+// Name returns the name of the mapper, as found in its MapperDesc.
+func (b *Base) Name() string { return b.desc.Name }
+
 func ispow2(n int) bool  { return n&(n-1) == 0 }
 func u8tob(v uint8) bool { return v != 0 }
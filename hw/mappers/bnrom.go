@@ -0,0 +1,72 @@
+package mappers
+
+import "nestor/hw/hwio"
+
+// Mapper 34 covers 2 unrelated boards: BNROM and NINA-001.
+var BNROM = MapperDesc{
+	Name:            "BNROM/NINA-001",
+	Load:            loadBNROM,
+	HasBusConflicts: func(b *Base) bool { return !isNINA001(b) },
+}
+
+// isNINA001 reports whether the mapper 34 board is a NINA-001. NES 2.0
+// submappers tell them apart, otherwise only the NINA-001 has CHR-ROM banking.
+func isNINA001(b *Base) bool {
+	switch b.Rom().SubMapper() {
+	case 1:
+		return true
+	case 2:
+		return false
+	}
+	return len(b.Rom().CHRROM) > 8*KB
+}
+
+// nina001 has its registers at $7FFD-$7FFF, overlapping PRG-RAM.
+type nina001 struct {
+	*Base
+
+	PRG6000 hwio.Device `hwio:"size=0x2000,rcb,wcb"`
+}
+
+func (m *nina001) ReadPRG6000(addr uint16) uint8 {
+	return m.PRGRAM.Data[addr&0x1FFF]
+}
+
+func (m *nina001) WritePRG6000(addr uint16, val uint8) {
+	m.PRGRAM.Data[addr&0x1FFF] = val
+
+	switch addr {
+	case 0x7FFD:
+		// Select 32 KB PRG ROM bank for CPU $8000-$FFFF.
+		m.SelectPRGPage32KB(int(val & 0x01))
+	case 0x7FFE:
+		// Select 4 KB CHR ROM bank for PPU $0000-$0FFF.
+		m.SelectCHRROMPage4KB(0, int(val&0x0F))
+	case 0x7FFF:
+		// Select 4 KB CHR ROM bank for PPU $1000-$1FFF.
+		m.SelectCHRROMPage4KB(1, int(val&0x0F))
+	}
+}
+
+func loadBNROM(b *Base) error {
+	if !isNINA001(b) {
+		b.Init(func(_ uint16, val uint8) {
+			// Select 32 KB PRG ROM bank for CPU $8000-$FFFF.
+			b.SelectPRGPage32KB(int(val))
+		})
+		modMapper.InfoZ("detected board").String("mapper", b.Name()).String("variant", "BNROM").End()
+	} else {
+		m := &nina001{Base: b}
+		hwio.MustInitRegs(m)
+		b.Init(nil)
+		b.CPU().Bus.Unmap(0x6000, 0x7FFF)
+		b.CPU().Bus.MapDevice(0x6000, &m.PRG6000)
+		modMapper.InfoZ("detected board").String("mapper", b.Name()).String("variant", "NINA-001").End()
+	}
+
+	b.SetNTMirroring(b.Rom().Mirroring())
+	b.SelectPRGPage32KB(0)
+	b.SelectCHRROMPage4KB(0, 0)
+	b.SelectCHRROMPage4KB(1, 1)
+	return nil
+}
@@ -0,0 +1,21 @@
+package mappers
+
+import "testing"
+
+func TestBNROM(t *testing.T) {
+	t.Run("BNROM", func(t *testing.T) {
+		loadTestRom(t, 34, 2, 128, 0).run(t, []busStep{
+			{desc: "PRG bank", write: []busVal{{0xFC00, 0x03}}, cpu: []busVal{{0x8000, 3 * 32}}},
+			{desc: "bus conflict", write: []busVal{{0x8001, 0x02}}, cpu: []busVal{{0x8000, 0}}},
+		})
+	})
+
+	t.Run("NINA-001", func(t *testing.T) {
+		loadTestRom(t, 34, 1, 64, 64).run(t, []busStep{{
+			desc:  "registers overlap PRG-RAM",
+			write: []busVal{{0x7FFD, 0x01}, {0x7FFE, 0x05}, {0x7FFF, 0x09}},
+			cpu:   []busVal{{0x8000, 1 * 32}, {0x7FFE, 0x05}},
+			ppu:   []busVal{{0x0000, 5 * 4}, {0x1000, 9 * 4}},
+		}})
+	})
+}
@@ -0,0 +1,48 @@
+package mappers
+
+import "nestor/ines"
+
+// Camerica BF9093/BF9097 boards. Only the BF9097 (used by Fire Hawk) has
+// one-screen mirroring control.
+var Camerica = MapperDesc{
+	Name: "Camerica BF909x",
+	Load: loadCamerica,
+}
+
+func loadCamerica(b *Base) error {
+	b.Init(func(addr uint16, val uint8) {
+		switch {
+		case addr >= 0xC000:
+			// 7  bit  0
+			// ---- ----
+			// xxxx PPPP
+			//      ||||
+			//      ++++- Select 16 KB PRG ROM bank for CPU $8000-$BFFF
+			b.SelectPRGPage16KB(0, int(val&0x0F))
+
+		case addr >= 0x9000 && addr < 0xA000:
+			// 7  bit  0
+			// ---- ----
+			// xxxM xxxx
+			//    |
+			//    +------ Select 1 KB VRAM page for all 4 nametables
+			//
+			// BF9093 boards don't respond to writes here (and their games
+			// don't write here), so we support this for all roms, unless
+			// the NES 2.0 submapper tells otherwise.
+			if b.Rom().SubMapper() == 0 || b.Rom().SubMapper() == 1 {
+				if val&0x10 != 0 {
+					b.SetNTMirroring(ines.OnlyBScreen)
+				} else {
+					b.SetNTMirroring(ines.OnlyAScreen)
+				}
+			}
+		}
+	})
+
+	b.SetNTMirroring(b.Rom().Mirroring())
+	b.SelectCHRROMPage8KB(0)
+	b.SelectPRGPage16KB(0, 0)
+	b.SelectPRGPage16KB(1, -1)
+	return nil
+}
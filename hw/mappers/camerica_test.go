@@ -0,0 +1,16 @@
+package mappers
+
+import (
+	"testing"
+
+	"nestor/ines"
+)
+
+func TestCamerica(t *testing.T) {
+	loadTestRom(t, 71, 0, 256, 0).run(t, []busStep{
+		{desc: "PRG bank", write: []busVal{{0xC000, 0x05}}, cpu: []busVal{{0x8000, 5 * 16}}},
+		{desc: "no bus conflict", write: []busVal{{0xC001, 0x06}}, cpu: []busVal{{0x8000, 6 * 16}}},
+		{desc: "screen B", write: []busVal{{0x9000, 0x10}}, mirror: ines.OnlyBScreen},
+		{desc: "screen A", write: []busVal{{0x9000, 0x00}}, mirror: ines.OnlyAScreen},
+	})
+}
@@ -0,0 +1,26 @@
+package mappers
+
+var ColorDreams = MapperDesc{
+	Name:            "Color Dreams",
+	Load:            loadColorDreams,
+	HasBusConflicts: alwaysBusConflicts,
+}
+
+func loadColorDreams(b *Base) error {
+	b.Init(func(_ uint16, val uint8) {
+		// 7  bit  0
+		// ---- ----
+		// CCCC LLPP
+		// |||| ||||
+		// |||| ||++- Select 32 KB PRG ROM bank for CPU $8000-$FFFF
+		// |||| ++--- Used for lockout defeat
+		// ++++------ Select 8 KB CHR ROM bank for PPU $0000-$1FFF
+		b.SelectPRGPage32KB(int(val & 0x03))
+		b.SelectCHRROMPage8KB(int(val >> 4))
+	})
+
+	b.SetNTMirroring(b.Rom().Mirroring())
+	b.SelectPRGPage32KB(0)
+	b.SelectCHRROMPage8KB(0)
+	return nil
+}
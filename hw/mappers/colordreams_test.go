@@ -0,0 +1,13 @@
+package mappers
+
+import "testing"
+
+func TestColorDreams(t *testing.T) {
+	// $FC00 holds $1F, CHR bank 5 becomes 1.
+	loadTestRom(t, 11, 0, 128, 128).run(t, []busStep{{
+		desc:  "bus conflict",
+		write: []busVal{{0xFC00, 0x52}},
+		cpu:   []busVal{{0x8000, 2 * 32}},
+		ppu:   []busVal{{0x0000, 1 * 8}},
+	}})
+}
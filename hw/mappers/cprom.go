@@ -0,0 +1,40 @@
+package mappers
+
+var CPROM = MapperDesc{
+	Name:            "CPROM",
+	Load:            loadCPROM,
+	HasBusConflicts: alwaysBusConflicts,
+}
+
+// cprom has 16 KB of CHR-RAM, the first 4 KB are fixed at $0000-$0FFF and any
+// of the 4 pages can be mapped at $1000-$1FFF.
+type cprom struct {
+	*Base
+
+	chrram [16 * KB]byte
+}
+
+func (m *cprom) writeReg(_ uint16, val uint8) {
+	// 7  bit  0
+	// ---- ----
+	// xxxx xxCC
+	//        ||
+	//        ++- Select 4 KB CHR RAM bank for PPU $1000-$1FFF
+	bank := int(val & 0x03)
+	m.PPU().Bus.Unmap(0x1000, 0x1FFF)
+	m.PPU().Bus.MapMemorySlice(0x1000, 0x1FFF, m.chrram[4*KB*bank:4*KB*(bank+1)], false)
+}
+
+func loadCPROM(b *Base) error {
+	m := &cprom{Base: b}
+	b.Init(m.writeReg)
+
+	// Replace the 8 KB CHR area with 2 independent 4 KB pages.
+	b.PPU().Bus.Unmap(0x0000, 0x1FFF)
+	b.PPU().Bus.MapMemorySlice(0x0000, 0x0FFF, m.chrram[:4*KB], false)
+	m.writeReg(0x8000, 0)
+
+	b.SetNTMirroring(b.Rom().Mirroring())
+	b.SelectPRGPage32KB(0)
+	return nil
+}
@@ -0,0 +1,20 @@
+package mappers
+
+import "testing"
+
+func TestCPROM(t *testing.T) {
+	c := loadTestRom(t, 13, 0, 32, 0)
+
+	// At power up, the fixed page is also mapped at $1000.
+	c.ppu.Bus.Write8(0x1000, 0xAA)
+	c.run(t, []busStep{
+		{desc: "power up", ppu: []busVal{{0x0000, 0xAA}}},
+		{desc: "page 2", write: []busVal{{0xFC00, 0x02}}, ppu: []busVal{{0x1000, 0x00}}},
+	})
+
+	c.ppu.Bus.Write8(0x1000, 0x55)
+	c.run(t, []busStep{
+		{desc: "fixed page", ppu: []busVal{{0x0000, 0xAA}}},
+		{desc: "bus conflict", write: []busVal{{0x8001, 0x02}}, ppu: []busVal{{0x1000, 0xAA}}},
+	})
+}
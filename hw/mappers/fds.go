@@ -0,0 +1,460 @@
+package mappers
+
+import (
+	"fmt"
+
+	"nestor/fds"
+	"nestor/hw"
+	"nestor/hw/apu"
+	"nestor/hw/hwdefs"
+	"nestor/hw/hwio"
+	"nestor/ines"
+)
+
+// BIOSSize is the size of the FDS BIOS ROM.
+const BIOSSize = 0x2000
+
+// FDS emulates the Famicom Disk System: the RAM adapter, plugged in the
+// cartridge slot, and the disk drive.
+//
+// CPU memory map:
+//   - $4020-$4033: timer IRQ, disk drive control and status registers.
+//   - $4040-$4092: FDS audio registers.
+//   - $6000-$DFFF: 32 KB PRG-RAM.
+//   - $E000-$FFFF: 8 KB BIOS ROM.
+//
+// The PPU sees 8 KB of CHR-RAM, and nametable mirroring is controlled by
+// software.
+type FDS struct {
+	*Base
+
+	disk *fds.Disk
+	raw  [][]byte // disk sides as seen by the drive (with gaps and CRCs)
+	side int      // currently inserted disk side (-1 if none)
+
+	// Disk side to insert once insertDelay reaches 0 (-1 if none).
+	pendingSide int
+	insertDelay int
+
+	audio apu.FDSAudio
+
+	RAM  [0x8000]byte
+	BIOS [BIOSSize]byte
+
+	diskRegsEnabled  bool
+	soundRegsEnabled bool
+
+	// Timer IRQ.
+	irqReload  uint16
+	irqCounter uint16
+	irqEnabled bool
+	irqRepeat  bool
+
+	// $4025 control bits.
+	motorOn        bool
+	resetTransfer  bool
+	readMode       bool
+	crcControl     bool
+	diskReady      bool
+	diskIRQEnabled bool
+
+	writeData uint8
+	readData  uint8
+	extOutput uint8
+
+	// Drive state.
+	delay            int // CPU cycles before the next byte transfer
+	pos              int // position of the head in the raw disk side
+	endOfHead        bool
+	scanning         bool
+	gapEnded         bool
+	transferComplete bool
+	crc              uint16
+	prevCRCControl   bool
+
+	Regs  hwio.Device `hwio:"size=0x20,rcb,pcb,wcb"` // $4020-$403F
+	Wave  hwio.Device `hwio:"size=0x40,rcb,wcb"`     // $4040-$407F
+	Sound hwio.Device `hwio:"size=0x20,rcb,wcb"`     // $4080-$409F
+}
+
+// LoadFDS sets up the FDS RAM adapter, with the given BIOS and the first side
+// of the given disk inserted in the drive.
+func LoadFDS(disk *fds.Disk, bios []byte, cpu *hw.CPU, ppu *hw.PPU, opts Options) (*FDS, error) {
+	if len(bios) != BIOSSize {
+		return nil, fmt.Errorf("invalid FDS BIOS size: got %d bytes, want %d", len(bios), BIOSSize)
+	}
+	if len(disk.Sides) == 0 {
+		return nil, fmt.Errorf("disk has no sides")
+	}
+
+	m := &FDS{
+		Base: &Base{
+			rom:  &ines.Rom{},
+			cpu:  cpu,
+			ppu:  ppu,
+			opts: opts,
+		},
+		disk:        disk,
+		side:        0,
+		pendingSide: -1,
+		audio:       apu.NewFDSAudio(cpu.APU),
+	}
+	copy(m.BIOS[:], bios)
+	for _, side := range disk.Sides {
+		m.raw = append(m.raw, fds.AddGaps(side))
+	}
+	hwio.MustInitRegs(m)
+
+	// CPU mapping.
+	cpu.Bus.MapDevice(0x4020, &m.Regs)
+	cpu.Bus.MapDevice(0x4040, &m.Wave)
+	cpu.Bus.MapDevice(0x4080, &m.Sound)
+	cpu.Bus.MapMem(0x6000, &hwio.Mem{
+		Name:  "RAM",
+		Data:  m.RAM[:],
+		VSize: len(m.RAM),
+		Flags: hwio.MemFlagReadWrite,
+	})
+	cpu.Bus.MapMem(0xE000, &hwio.Mem{
+		Name:  "BIOS",
+		Data:  m.BIOS[:],
+		VSize: len(m.BIOS),
+		Flags: hwio.MemFlagReadOnly,
+	})
+	cpu.SetCycleHook(m.tick)
+
+	// PPU mapping.
+	ppu.Bus.MapMem(0x0000, &hwio.Mem{
+		Name:  "CHRRAM",
+		Data:  m.CHRROM[:],
+		VSize: len(m.CHRROM),
+		Flags: hwio.MemFlagReadWrite,
+	})
+	m.SetNTMirroring(ines.VertMirroring)
+
+	m.endOfHead = true
+	return m, nil
+}
+
+// NumSides returns the number of disk sides.
+func (m *FDS) NumSides() int { return len(m.raw) }
+
+// Side returns the disk side currently inserted, or about to be inserted, in
+// the drive. It returns -1 if no disk is inserted.
+func (m *FDS) Side() int {
+	if m.pendingSide != -1 {
+		return m.pendingSide
+	}
+	return m.side
+}
+
+// Delay, in CPU cycles, between ejecting a disk and inserting another one. The
+// BIOS and games need to see the drive empty for some time in order to notice
+// a disk change.
+const fdsInsertDelay = 1_000_000
+
+// InsertDisk ejects the current disk, if any, and inserts the given disk side
+// after a short delay.
+func (m *FDS) InsertDisk(side int) error {
+	if side < 0 || side >= len(m.raw) {
+		return fmt.Errorf("invalid disk side %d (disk has %d sides)", side, len(m.raw))
+	}
+	m.EjectDisk()
+	m.pendingSide = side
+	m.insertDelay = fdsInsertDelay
+	return nil
+}
+
+// EjectDisk ejects the disk from the drive.
+func (m *FDS) EjectDisk() {
+	m.pendingSide = -1
+	if m.side == -1 {
+		return
+	}
+	m.side = -1
+	m.endOfHead = true
+	m.scanning = false
+
+	modMapper.InfoZ("disk ejected").End()
+}
+
+// SaveDisk saves the disk writes, as a patch of the original disk image.
+func (m *FDS) SaveDisk() error {
+	for i := range m.raw {
+		m.disk.Sides[i] = fds.RemoveGaps(m.raw[i])
+	}
+	return m.disk.SaveWrites()
+}
+
+func (m *FDS) diskInserted() bool { return m.side != -1 }
+
+func (m *FDS) ReadREGS(addr uint16) uint8 {
+	val := m.PeekREGS(addr)
+	switch addr {
+	case 0x4030:
+		m.transferComplete = false
+		m.CPU().ClearIRQSource(hwdefs.External | hwdefs.FDSDisk)
+	case 0x4031:
+		m.transferComplete = false
+		m.CPU().ClearIRQSource(hwdefs.FDSDisk)
+	}
+	return val
+}
+
+func (m *FDS) PeekREGS(addr uint16) uint8 {
+	openbus := uint8(addr >> 8)
+	if !m.diskRegsEnabled {
+		return openbus
+	}
+
+	switch addr {
+	case 0x4030:
+		// 7  bit  0
+		// ---- ----
+		// I..C ..BT
+		// |  |   ||
+		// |  |   |+- Timer IRQ occurred
+		// |  |   +-- Byte transfer complete
+		// |  +------ CRC error
+		// +--------- Disk data read/write enable (unused)
+		val := openbus & 0x2C
+		if m.CPU().HasIRQSource(hwdefs.External) {
+			val |= 0x01
+		}
+		if m.transferComplete {
+			val |= 0x02
+		}
+		if m.crcControl && m.crc != 0 {
+			val |= 0x10
+		}
+		return val
+	case 0x4031:
+		return m.readData
+	case 0x4032:
+		// 7  bit  0
+		// ---- ----
+		// .... .WRS
+		//       |||
+		//       ||+- Disk not inserted
+		//       |+-- Disk not ready
+		//       +--- Disk write protected
+		val := openbus & 0xF8
+		if !m.diskInserted() {
+			val |= 0x07
+		} else if !m.scanning {
+			val |= 0x02
+		}
+		return val
+	case 0x4033:
+		// External connector input, bit 7 set means battery is good.
+		return 0x80
+	}
+	return openbus
+}
+
+func (m *FDS) WriteREGS(addr uint16, val uint8) {
+	if !m.diskRegsEnabled && addr >= 0x4024 {
+		return
+	}
+
+	switch addr {
+	case 0x4020:
+		m.irqReload = m.irqReload&0xFF00 | uint16(val)
+	case 0x4021:
+		m.irqReload = m.irqReload&0x00FF | uint16(val)<<8
+	case 0x4022:
+		// 7  bit  0
+		// ---- ----
+		// .... ..ER
+		//        ||
+		//        |+- Timer IRQ repeat
+		//        +-- Timer IRQ enabled
+		m.irqRepeat = val&0x01 != 0
+		m.irqEnabled = val&0x02 != 0 && m.diskRegsEnabled
+		if m.irqEnabled {
+			m.irqCounter = m.irqReload
+		} else {
+			m.CPU().ClearIRQSource(hwdefs.External)
+		}
+	case 0x4023:
+		// 7  bit  0
+		// ---- ----
+		// .... ..SD
+		//        ||
+		//        |+- Enable disk I/O registers
+		//        +-- Enable sound I/O registers
+		m.diskRegsEnabled = val&0x01 != 0
+		m.soundRegsEnabled = val&0x02 != 0
+		if !m.diskRegsEnabled {
+			m.irqEnabled = false
+			m.CPU().ClearIRQSource(hwdefs.External | hwdefs.FDSDisk)
+		}
+	case 0x4024:
+		m.writeData = val
+		m.transferComplete = false
+		m.CPU().ClearIRQSource(hwdefs.FDSDisk)
+	case 0x4025:
+		// 7  bit  0
+		// ---- ----
+		// IS.B MRTD
+		// || | ||||
+		// || | |||+- Drive motor control (1 = on)
+		// || | ||+-- Transfer reset
+		// || | |+--- Transfer mode (0 = write, 1 = read)
+		// || | +---- Mirroring (0 = vertical, 1 = horizontal)
+		// || +------ CRC control (transfer CRC)
+		// |+-------- Disk ready (0 = in gap, 1 = start of block)
+		// +--------- Disk transfer IRQ enable
+		m.motorOn = val&0x01 != 0
+		m.resetTransfer = val&0x02 != 0
+		m.readMode = val&0x04 != 0
+		if val&0x08 != 0 {
+			m.SetNTMirroring(ines.HorzMirroring)
+		} else {
+			m.SetNTMirroring(ines.VertMirroring)
+		}
+		m.crcControl = val&0x10 != 0
+		m.diskReady = val&0x40 != 0
+		m.diskIRQEnabled = val&0x80 != 0
+		m.CPU().ClearIRQSource(hwdefs.External | hwdefs.FDSDisk)
+	case 0x4026:
+		m.extOutput = val
+	}
+}
+
+func (m *FDS) ReadWAVE(addr uint16) uint8       { return m.ReadSOUND(addr) }
+func (m *FDS) WriteWAVE(addr uint16, val uint8) { m.WriteSOUND(addr, val) }
+func (m *FDS) WriteSOUND(addr uint16, val uint8) {
+	if m.soundRegsEnabled {
+		m.audio.WriteRegister(addr, val)
+	}
+}
+
+func (m *FDS) ReadSOUND(addr uint16) uint8 {
+	openbus := uint8(addr >> 8)
+	if !m.soundRegsEnabled {
+		return openbus
+	}
+	return m.audio.ReadRegister(addr, openbus)
+}
+
+// tick is called on every CPU cycle.
+func (m *FDS) tick() {
+	if m.irqEnabled {
+		if m.irqCounter == 0 {
+			m.CPU().SetIRQSource(hwdefs.External)
+			m.irqCounter = m.irqReload
+			if !m.irqRepeat {
+				m.irqEnabled = false
+			}
+		} else {
+			m.irqCounter--
+		}
+	}
+
+	if m.pendingSide != -1 {
+		m.insertDelay--
+		if m.insertDelay == 0 {
+			m.side = m.pendingSide
+			m.pendingSide = -1
+			modMapper.InfoZ("disk inserted").Int("side", m.side).End()
+		}
+	}
+
+	m.tickDrive()
+	m.audio.Clock()
+}
+
+// Drive timings, in CPU cycles.
+const (
+	fdsHeadReturnDelay = 50000 // time for the head to return to the start
+	fdsByteDelay       = 150   // time to transfer a byte (~96.4 kbit/s)
+)
+
+// tickDrive emulates the disk drive, which transfers a byte every
+// fdsByteDelay cycles while the motor is on.
+func (m *FDS) tickDrive() {
+	if !m.diskInserted() || !m.motorOn {
+		m.endOfHead = true
+		m.scanning = false
+		return
+	}
+	if m.resetTransfer && !m.scanning {
+		return
+	}
+	if m.endOfHead {
+		m.delay = fdsHeadReturnDelay
+		m.endOfHead = false
+		m.pos = 0
+		m.gapEnded = false
+		return
+	}
+	if m.delay > 0 {
+		m.delay--
+		return
+	}
+
+	m.scanning = true
+	raw := m.raw[m.side]
+
+	needIRQ := m.diskIRQEnabled
+	if m.readMode {
+		data := raw[m.pos]
+		if !m.prevCRCControl {
+			m.crc = fds.UpdateCRC(m.crc, data)
+		}
+
+		if !m.diskReady {
+			m.gapEnded = false
+			m.crc = 0
+		} else if data != 0 && !m.gapEnded {
+			// The block start mark is not transferred to the CPU.
+			m.gapEnded = true
+			needIRQ = false
+		}
+
+		if m.gapEnded {
+			m.transferComplete = true
+			m.readData = data
+			if needIRQ {
+				m.CPU().SetIRQSource(hwdefs.FDSDisk)
+			}
+		}
+	} else {
+		var data uint8
+		if !m.crcControl {
+			m.transferComplete = true
+			data = m.writeData
+			if needIRQ {
+				m.CPU().SetIRQSource(hwdefs.FDSDisk)
+			}
+		}
+		if !m.diskReady {
+			// Writing a gap.
+			data = 0
+			m.crc = 0
+		}
+		if !m.crcControl {
+			m.crc = fds.UpdateCRC(m.crc, data)
+		} else {
+			if !m.prevCRCControl {
+				// Finalize CRC computation.
+				m.crc = fds.UpdateCRC(m.crc, 0)
+				m.crc = fds.UpdateCRC(m.crc, 0)
+			}
+			data = uint8(m.crc)
+			m.crc >>= 8
+		}
+		raw[m.pos] = data
+		m.gapEnded = false
+	}
+	m.prevCRCControl = m.crcControl
+
+	m.pos++
+	if m.pos >= len(raw) {
+		// End of disk, the motor stops and the head returns to the start.
+		m.motorOn = false
+	} else {
+		m.delay = fdsByteDelay
+	}
+}
@@ -0,0 +1,171 @@
+package mappers
+
+import (
+	"nestor/hw/apu"
+	"nestor/hw/hwdefs"
+	"nestor/hw/hwio"
+	"nestor/ines"
+)
+
+var FME7 = MapperDesc{
+	Name: "Sunsoft FME-7",
+	Load: loadFME7,
+}
+
+// fme7 handles the Sunsoft FME-7 and 5A/5B mappers, which are functionally
+// identical, the 5B adding an expansion audio chip.
+type fme7 struct {
+	*Base
+
+	cmd uint8
+
+	chrbank [8]uint8
+	prgbank [4]uint8 // $6000, $8000, $A000, $C000 ($E000 is fixed)
+
+	ramSelect bool // $6000-$7FFF maps PRG-RAM rather than PRG-ROM
+	ramEnable bool
+	ntm       uint8
+
+	irqEnable     bool
+	counterEnable bool
+	counter       uint16
+
+	audio apu.Sunsoft5BAudio
+
+	// $6000-$7FFF maps either PRG-ROM or PRG-RAM.
+	PRG6000 hwio.Device `hwio:"size=0x2000,rcb,wcb"`
+}
+
+func (m *fme7) ReadPRG6000(addr uint16) uint8 {
+	addr &= 0x1FFF
+	if !m.ramSelect {
+		nbanks := len(m.Rom().PRGROM) / (8 * KB)
+		bank := int(m.prgbank[0]) % nbanks
+		return m.Rom().PRGROM[bank*8*KB+int(addr)]
+	}
+	if !m.ramEnable {
+		return uint8((0x6000 | addr) >> 8) // open bus
+	}
+	return m.PRGRAM.Data[addr]
+}
+
+func (m *fme7) WritePRG6000(addr uint16, val uint8) {
+	if m.ramSelect && m.ramEnable {
+		m.PRGRAM.Data[addr&0x1FFF] = val
+	}
+}
+
+func (m *fme7) writeReg(addr uint16, val uint8) {
+	switch addr & 0xE000 {
+	case 0x8000:
+		m.cmd = val & 0x0F
+	case 0xA000:
+		m.writeParam(val)
+	case 0xC000:
+		m.audio.SelectRegister(val)
+	case 0xE000:
+		m.audio.WriteRegister(val)
+	}
+}
+
+// writeParam writes the parameter register, its effect depends on the command
+// register.
+func (m *fme7) writeParam(val uint8) {
+	switch cmd := m.cmd; {
+	case cmd <= 0x7:
+		// Select 1 KB CHR bank at $0000 + cmd * $400.
+		m.chrbank[cmd] = val
+		m.SelectCHRROMPage1KB(uint32(cmd), int(val))
+
+	case cmd == 0x8:
+		// 7  bit  0
+		// ---- ----
+		// ERbB BBBB
+		// |||| ||||
+		// ||++-++++- Select 8 KB bank at $6000-$7FFF
+		// |+-------- RAM/ROM select (0 = ROM, 1 = RAM)
+		// +--------- RAM enable (0 = disabled, 1 = enabled)
+		m.prgbank[0] = val & 0x3F
+		m.ramSelect = val&0x40 != 0
+		m.ramEnable = val&0x80 != 0
+
+	case cmd <= 0xB:
+		// Select 8 KB PRG-ROM bank at $8000, $A000 or $C000.
+		m.prgbank[cmd-0x8] = val & 0x3F
+		m.SelectPRGPage8KB(uint32(cmd-0x9), int(m.prgbank[cmd-0x8]))
+
+	case cmd == 0xC:
+		m.setMirroring(val & 0x03)
+
+	case cmd == 0xD:
+		// 7  bit  0
+		// ---- ----
+		// C... ...T
+		// |       |
+		// |       +- IRQ enable
+		// +--------- IRQ counter enable
+		//
+		// Writes to this register also acknowledge the IRQ.
+		m.irqEnable = val&0x01 != 0
+		m.counterEnable = val&0x80 != 0
+		m.CPU().ClearIRQSource(hwdefs.External)
+
+	case cmd == 0xE:
+		m.counter = m.counter&0xFF00 | uint16(val)
+
+	case cmd == 0xF:
+		m.counter = m.counter&0x00FF | uint16(val)<<8
+	}
+}
+
+func (m *fme7) setMirroring(ntm uint8) {
+	if ntm == m.ntm {
+		return
+	}
+	m.ntm = ntm
+
+	switch m.ntm {
+	case 0:
+		m.SetNTMirroring(ines.VertMirroring)
+	case 1:
+		m.SetNTMirroring(ines.HorzMirroring)
+	case 2:
+		m.SetNTMirroring(ines.OnlyAScreen)
+	case 3:
+		m.SetNTMirroring(ines.OnlyBScreen)
+	}
+}
+
+// tick is called on every CPU cycle.
+func (m *fme7) tick() {
+	if m.counterEnable {
+		// The IRQ is triggered when the counter wraps from $0000 to $FFFF.
+		m.counter--
+		if m.counter == 0xFFFF && m.irqEnable {
+			m.CPU().SetIRQSource(hwdefs.External)
+		}
+	}
+	m.audio.Clock()
+}
+
+func loadFME7(b *Base) error {
+	m := &fme7{
+		Base:  b,
+		audio: apu.NewSunsoft5BAudio(b.CPU().APU),
+	}
+	hwio.MustInitRegs(m)
+	b.Init(m.writeReg)
+
+	b.CPU().Bus.Unmap(0x6000, 0x7FFF)
+	b.CPU().Bus.MapDevice(0x6000, &m.PRG6000)
+	b.CPU().SetCycleHook(m.tick)
+
+	b.SetNTMirroring(ines.VertMirroring)
+	for i := range 4 {
+		m.SelectPRGPage8KB(uint32(i), -1)
+	}
+	for i := range m.chrbank {
+		m.SelectCHRROMPage1KB(uint32(i), 0)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package mappers
+
+import (
+	"testing"
+
+	"nestor/hw/hwdefs"
+	"nestor/tests"
+)
+
+func TestFME7(t *testing.T) {
+	img := tests.MapperRom(69, 0, 256, 256)
+	tests.SetPRGRAMSize(img, 8, 0)
+	c := loadTestImage(t, img, Options{})
+
+	cmd := func(cmd, val uint8) []busVal {
+		return []busVal{{0x8000, cmd}, {0xA000, val}}
+	}
+
+	// $6000-$7FFF: PRG-ROM, disabled PRG-RAM (open bus), then PRG-RAM.
+	c.run(t, []busStep{
+		{desc: "PRG-ROM", write: cmd(0x8, 0x02), cpu: []busVal{{0x6000, 2 * 8}}},
+		{desc: "PRG-ROM is read-only", write: []busVal{{0x6000, 0xAA}}, cpu: []busVal{{0x6000, 2 * 8}}},
+		{desc: "PRG-RAM disabled", write: cmd(0x8, 0x40), cpu: []busVal{{0x6000, 0x60}}},
+		{desc: "PRG-RAM enabled", write: append(cmd(0x8, 0xC0), busVal{0x6000, 0xAA}), cpu: []busVal{{0x6000, 0xAA}}},
+	})
+
+	// The counter is decremented on every CPU cycle, the IRQ fires when it
+	// wraps from $0000 to $FFFF.
+	c.run(t, []busStep{{write: cmd(0xE, 0xE8)}, {write: cmd(0xF, 0x03)}, {write: cmd(0xD, 0x81)}})
+	if n := c.runUntilIRQ(2000); n < 996 || n > 1006 {
+		t.Fatalf("IRQ after %d CPU cycles, want 1001", n)
+	}
+
+	// Acknowledge, the counter keeps running and wraps again after 65536
+	// cycles.
+	c.run(t, []busStep{{write: cmd(0xD, 0x81)}})
+	if c.cpu.HasIRQSource(hwdefs.External) {
+		t.Fatalf("IRQ not acknowledged")
+	}
+	if n := c.runUntilIRQ(70000); n < 65500 || n > 65540 {
+		t.Fatalf("IRQ after %d CPU cycles, want ~65536", n)
+	}
+
+	// Counter running, IRQ disabled.
+	c.run(t, []busStep{{write: cmd(0xE, 0x00)}, {write: cmd(0xF, 0x01)}, {write: cmd(0xD, 0x80)}})
+	if n := c.runUntilIRQ(100000); n < 100000 {
+		t.Fatalf("unexpected IRQ after %d CPU cycles", n)
+	}
+}
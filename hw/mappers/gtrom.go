@@ -0,0 +1,92 @@
+package mappers
+
+import (
+	"fmt"
+
+	"nestor/hw"
+	"nestor/hw/hwio"
+)
+
+// GTROM is the Cheapocabra homebrew board by Membler Industries. Its PRG-ROM
+// is a self-flashable flash memory and it has 2 LEDs, shown in the window
+// title.
+var GTROM = MapperDesc{
+	Name: "GTROM",
+	Load: loadGTROM,
+}
+
+type gtrom struct {
+	*Base
+
+	flash   *hw.Flash
+	prgbank uint8
+
+	// 32 KB of CHR-RAM: 2 banks of pattern tables, followed by 2 banks of
+	// nametables (four-screen).
+	chrram [32 * KB]byte
+
+	// Register, at $5000-$5FFF and $7000-$7FFF.
+	Reg5000 hwio.Device `hwio:"size=0x1000,wcb=WriteREG"`
+	Reg7000 hwio.Device `hwio:"size=0x1000,wcb=WriteREG"`
+}
+
+func (m *gtrom) WriteREG(_ uint16, val uint8) {
+	// 7  bit  0
+	// ---- ----
+	// GRNC PPPP
+	// |||| ||||
+	// |||| ++++- Select 32 KB PRG ROM bank for CPU $8000-$FFFF
+	// |||+------ Select 8 KB CHR RAM bank for PPU $0000-$1FFF
+	// ||+------- Select 8 KB nametables bank for PPU $2000-$3EFF
+	// |+-------- Red LED (0: on)
+	// +--------- Green LED (0: on)
+	m.prgbank = val & 0x0F
+	m.SelectPRGPage32KB(int(m.prgbank))
+
+	chr := 8 * KB * int(val>>4&0x01)
+	m.PPU().Bus.Unmap(0x0000, 0x1FFF)
+	m.PPU().Bus.MapMemorySlice(0x0000, 0x1FFF, m.chrram[chr:chr+8*KB], false)
+
+	nt := 16*KB + 8*KB*int(val>>5&0x01)
+	for i := range 4 {
+		start := nt + i*KB
+		m.SetNametable(i, RAMNametable(m.chrram[start:]))
+	}
+
+	m.SetStatus(fmt.Sprintf("LEDs: red %s, green %s", ledState(val&0x40 == 0), ledState(val&0x80 == 0)))
+}
+
+func ledState(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+func (m *gtrom) writeFlash(addr uint16, val uint8) {
+	flashaddr := uint32(m.prgbank)<<15 | uint32(addr&0x7FFF)
+	if m.flash.Write(flashaddr, val) {
+		m.SelectPRGPage32KB(int(m.prgbank))
+	}
+}
+
+func loadGTROM(b *Base) error {
+	m := &gtrom{
+		Base:  b,
+		flash: hw.NewFlash(b.Rom().PRGROM),
+	}
+	hwio.MustInitRegs(m)
+	b.Init(m.writeFlash)
+
+	if b.Rom().HasPersistence() {
+		b.PersistFlash(m.flash)
+	}
+
+	// No PRG-RAM.
+	b.CPU().Bus.Unmap(0x6000, 0x7FFF)
+	b.CPU().Bus.MapDevice(0x5000, &m.Reg5000)
+	b.CPU().Bus.MapDevice(0x7000, &m.Reg7000)
+
+	m.WriteREG(0x5000, 0)
+	return nil
+}
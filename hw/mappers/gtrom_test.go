@@ -0,0 +1,54 @@
+package mappers
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"nestor/tests"
+)
+
+func TestGTROMFlashSave(t *testing.T) {
+	img := tests.MapperRom(111, 0, 512, 0)
+	img[6] |= 0x02 // battery
+	opts := Options{SavePath: filepath.Join(t.TempDir(), "gtrom.sav")}
+
+	// The flash is the PRG-ROM, each power up needs a fresh copy of the rom.
+	c := loadTestImage(t, bytes.Clone(img), opts)
+
+	// Nothing is saved until the flash is written.
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(opts.SavePath); !os.IsNotExist(err) {
+		t.Fatalf("save file written without flashing: %v", err)
+	}
+
+	// Program a byte in bank 3.
+	c.run(t, []busStep{{
+		desc:  "program",
+		write: []busVal{{0x5000, 0x03}, {0xD555, 0xAA}, {0xAAAA, 0x55}, {0xD555, 0xA0}, {0x9234, 0x00}},
+		cpu:   []busVal{{0x9234, 0x00}},
+	}})
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(opts.SavePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() > 8*1024 {
+		t.Errorf("save file is %d bytes, want only the flashed sector", fi.Size())
+	}
+
+	loadTestImage(t, bytes.Clone(img), opts).run(t, []busStep{
+		{desc: "restored", write: []busVal{{0x5000, 0x03}}, cpu: []busVal{{0x9234, 0x00}}},
+	})
+
+	// The save isn't applied to another rom.
+	img[16+3*32*1024+0x1234] ^= 0x01
+	loadTestImage(t, img, opts).run(t, []busStep{
+		{desc: "other rom", write: []busVal{{0x5000, 0x03}}, cpu: []busVal{{0x9234, img[16+3*32*1024+0x1234]}}},
+	})
+}
@@ -0,0 +1,224 @@
+package mappers
+
+import (
+	"nestor/hw/hwdefs"
+	"nestor/ines"
+)
+
+var IremTAMS1 = MapperDesc{
+	Name:            "Irem TAM-S1",
+	Load:            loadIremTAMS1,
+	HasBusConflicts: alwaysBusConflicts,
+}
+
+// Mapper 97, the last PRG bank is fixed at $8000-$BFFF and the switchable one
+// is at $C000-$FFFF.
+func loadIremTAMS1(b *Base) error {
+	b.Init(func(addr uint16, val uint8) {
+		if addr >= 0xC000 {
+			return
+		}
+		// 7  bit  0
+		// ---- ----
+		// Mxxx PPPP
+		// |    ||||
+		// |    ++++- Select 16 KB PRG ROM bank for CPU $C000-$FFFF
+		// +--------- Mirroring (0: horizontal, 1: vertical)
+		b.SelectPRGPage16KB(1, int(val&0x0F))
+		if val&0x80 != 0 {
+			b.SetNTMirroring(ines.VertMirroring)
+		} else {
+			b.SetNTMirroring(ines.HorzMirroring)
+		}
+	})
+
+	b.SetNTMirroring(b.Rom().Mirroring())
+	b.SelectCHRROMPage8KB(0)
+	b.SelectPRGPage16KB(0, -1)
+	b.SelectPRGPage16KB(1, 0)
+	return nil
+}
+
+var IremG101 = MapperDesc{
+	Name: "Irem G-101",
+	Load: loadIremG101,
+}
+
+// iremG101 is mapper 32. Submapper 1 (Major League) has its mirroring
+// hardwired to one-screen and no PRG mode.
+type iremG101 struct {
+	*Base
+
+	prgmode uint8
+	prgbank [2]uint8
+}
+
+func (m *iremG101) writeReg(addr uint16, val uint8) {
+	switch addr & 0xF000 {
+	case 0x8000:
+		m.prgbank[0] = val & 0x1F
+		m.remapPRG()
+	case 0x9000:
+		if m.Rom().SubMapper() == 1 {
+			return
+		}
+		// 7  bit  0
+		// ---- ----
+		// xxxx xxPM
+		//        ||
+		//        |+- Mirroring (0: vertical, 1: horizontal)
+		//        +-- PRG mode (0: $8000 swappable, $C000 fixed to the
+		//                      second-to-last bank; 1: the opposite)
+		m.prgmode = val >> 1 & 0x01
+		m.remapPRG()
+		if val&0x01 != 0 {
+			m.SetNTMirroring(ines.HorzMirroring)
+		} else {
+			m.SetNTMirroring(ines.VertMirroring)
+		}
+	case 0xA000:
+		m.prgbank[1] = val & 0x1F
+		m.remapPRG()
+	case 0xB000:
+		// Select 1 KB CHR bank at $0000 + (addr & 7) * $400.
+		m.SelectCHRROMPage1KB(uint32(addr&0x07), int(val))
+	}
+}
+
+func (m *iremG101) remapPRG() {
+	if m.prgmode == 0 {
+		m.SelectPRGPage8KB(0, int(m.prgbank[0]))
+		m.SelectPRGPage8KB(2, -2)
+	} else {
+		m.SelectPRGPage8KB(0, -2)
+		m.SelectPRGPage8KB(2, int(m.prgbank[0]))
+	}
+	m.SelectPRGPage8KB(1, int(m.prgbank[1]))
+	m.SelectPRGPage8KB(3, -1)
+}
+
+func loadIremG101(b *Base) error {
+	m := &iremG101{
+		Base:    b,
+		prgbank: [2]uint8{0, 1},
+	}
+	b.Init(m.writeReg)
+
+	if b.Rom().SubMapper() == 1 {
+		b.SetNTMirroring(ines.OnlyAScreen)
+	} else {
+		b.SetNTMirroring(b.Rom().Mirroring())
+	}
+	m.remapPRG()
+	for i := range 8 {
+		m.SelectCHRROMPage1KB(uint32(i), i)
+	}
+	return nil
+}
+
+var IremH3001 = MapperDesc{
+	Name: "Irem H3001",
+	Load: loadIremH3001,
+}
+
+// iremH3001 is mapper 65, it has a 16-bit CPU cycle IRQ counter.
+type iremH3001 struct {
+	*Base
+
+	prgmode uint8
+	prgbank [3]uint8 // $8000, $A000 and $C000
+
+	irqEnable bool
+	counter   uint16
+	reload    uint16
+}
+
+func (m *iremH3001) writeReg(addr uint16, val uint8) {
+	switch addr {
+	case 0x8000:
+		m.prgbank[0] = val
+		m.remapPRG()
+	case 0x9000:
+		// 7  bit  0
+		// ---- ----
+		// Pxxx xxxx
+		// |
+		// +--------- PRG mode (0: $8000 and $C000 as selected, 1: swapped)
+		m.prgmode = val >> 7
+		m.remapPRG()
+	case 0x9001:
+		// 7  bit  0
+		// ---- ----
+		// Mxxx xxxx
+		// |
+		// +--------- Mirroring (0: vertical, 1: horizontal)
+		if val&0x80 != 0 {
+			m.SetNTMirroring(ines.HorzMirroring)
+		} else {
+			m.SetNTMirroring(ines.VertMirroring)
+		}
+	case 0x9003:
+		// 7  bit  0
+		// ---- ----
+		// Exxx xxxx
+		// |
+		// +--------- IRQ enable, writing also acknowledges the IRQ.
+		m.irqEnable = val&0x80 != 0
+		m.CPU().ClearIRQSource(hwdefs.External)
+	case 0x9004:
+		// Reload the counter and acknowledge the IRQ.
+		m.counter = m.reload
+		m.CPU().ClearIRQSource(hwdefs.External)
+	case 0x9005:
+		m.reload = m.reload&0x00FF | uint16(val)<<8
+	case 0x9006:
+		m.reload = m.reload&0xFF00 | uint16(val)
+	case 0xA000:
+		m.prgbank[1] = val
+		m.remapPRG()
+	case 0xB000, 0xB001, 0xB002, 0xB003, 0xB004, 0xB005, 0xB006, 0xB007:
+		m.SelectCHRROMPage1KB(uint32(addr&0x07), int(val))
+	case 0xC000:
+		m.prgbank[2] = val
+		m.remapPRG()
+	}
+}
+
+func (m *iremH3001) remapPRG() {
+	lo, hi := m.prgbank[0], m.prgbank[2]
+	if m.prgmode != 0 {
+		lo, hi = hi, lo
+	}
+	m.SelectPRGPage8KB(0, int(lo))
+	m.SelectPRGPage8KB(1, int(m.prgbank[1]))
+	m.SelectPRGPage8KB(2, int(hi))
+	m.SelectPRGPage8KB(3, -1)
+}
+
+// tick is called on every CPU cycle.
+func (m *iremH3001) tick() {
+	if !m.irqEnable || m.counter == 0 {
+		return
+	}
+	// The counter stops once it reaches 0.
+	m.counter--
+	if m.counter == 0 {
+		m.CPU().SetIRQSource(hwdefs.External)
+	}
+}
+
+func loadIremH3001(b *Base) error {
+	m := &iremH3001{
+		Base:    b,
+		prgbank: [3]uint8{0x00, 0x01, 0xFE},
+	}
+	b.Init(m.writeReg)
+	b.CPU().SetCycleHook(m.tick)
+
+	b.SetNTMirroring(b.Rom().Mirroring())
+	m.remapPRG()
+	for i := range 8 {
+		m.SelectCHRROMPage1KB(uint32(i), i)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package mappers
+
+import (
+	"testing"
+
+	"nestor/hw/hwdefs"
+	"nestor/ines"
+)
+
+func TestIremTAMS1(t *testing.T) {
+	loadTestRom(t, 97, 0, 256, 0).run(t, []busStep{
+		{desc: "last bank fixed at $8000", cpu: []busVal{{0x8000, 15 * 16}, {0xC000, 0}}},
+		{desc: "vertical", write: []busVal{{0xBC00, 0x85}}, cpu: []busVal{{0xC000, 5 * 16}}, mirror: ines.VertMirroring},
+		{desc: "horizontal", write: []busVal{{0xBC00, 0x03}}, cpu: []busVal{{0xC000, 3 * 16}}, mirror: ines.HorzMirroring},
+		// $8000 holds $F0.
+		{desc: "bus conflict", write: []busVal{{0x8000, 0x05}}, cpu: []busVal{{0xC000, 0}}},
+		{desc: "no register at $C000-$FFFF", write: []busVal{{0xC000, 0x07}}, cpu: []busVal{{0xC000, 0}}},
+	})
+}
+
+func TestIremG101(t *testing.T) {
+	loadTestRom(t, 32, 0, 128, 128).run(t, []busStep{
+		{
+			desc:   "PRG mode 1, $8000 and $C000 swapped",
+			write:  []busVal{{0x8000, 3}, {0x9000, 0x03}},
+			cpu:    []busVal{{0x8000, 14 * 8}, {0xC000, 3 * 8}},
+			mirror: ines.HorzMirroring,
+		},
+		{desc: "PRG mode 0", write: []busVal{{0x9000, 0x00}}, cpu: []busVal{{0x8000, 3 * 8}}, mirror: ines.VertMirroring},
+		{
+			desc:  "CHR registers mirrored across $B000-$BFFF",
+			write: []busVal{{0xB005, 0x21}, {0xB00A, 0x42}},
+			ppu:   []busVal{{0x1400, 0x21}, {0x0800, 0x42}},
+		},
+	})
+
+	t.Run("major league", func(t *testing.T) {
+		loadTestRom(t, 32, 1, 128, 128).run(t, []busStep{
+			{desc: "power up", mirror: ines.OnlyAScreen},
+			{
+				desc:   "no PRG mode nor mirroring control",
+				write:  []busVal{{0x8000, 3}, {0x9000, 0x03}},
+				cpu:    []busVal{{0x8000, 3 * 8}, {0xC000, 14 * 8}},
+				mirror: ines.OnlyAScreen,
+			},
+		})
+	})
+}
+
+func TestIremH3001(t *testing.T) {
+	c := loadTestRom(t, 65, 0, 256, 256)
+	c.run(t, []busStep{
+		{
+			desc:  "PRG mode, $8000 and $C000 swapped",
+			write: []busVal{{0x8000, 4}, {0xC000, 6}, {0x9000, 0x80}},
+			cpu:   []busVal{{0x8000, 6 * 8}, {0xC000, 4 * 8}},
+		},
+		{desc: "horizontal", write: []busVal{{0x9001, 0x80}}, mirror: ines.HorzMirroring},
+		{desc: "vertical", write: []busVal{{0x9001, 0x00}}, mirror: ines.VertMirroring},
+	})
+
+	// IRQ fires after 1000 CPU cycles.
+	bus := c.cpu.Bus
+	bus.Write8(0x9005, 0x03)
+	bus.Write8(0x9006, 0xE8)
+	bus.Write8(0x9004, 0)
+	bus.Write8(0x9003, 0x80)
+
+	if n := c.runUntilIRQ(2000); n < 995 || n > 1005 {
+		t.Fatalf("IRQ after %d CPU cycles, want 1000", n)
+	}
+
+	// Acknowledge, the counter stays stopped at 0.
+	bus.Write8(0x9003, 0x80)
+	if c.cpu.HasIRQSource(hwdefs.External) {
+		t.Fatalf("IRQ not acknowledged")
+	}
+	if n := c.runUntilIRQ(100000); n < 100000 {
+		t.Fatalf("unexpected IRQ after %d CPU cycles", n)
+	}
+}
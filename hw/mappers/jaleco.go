@@ -0,0 +1,123 @@
+package mappers
+
+import (
+	"nestor/hw/hwio"
+	"nestor/ines"
+)
+
+// Jaleco (and Irem) discrete logic boards.
+var (
+	JalecoJF05 = MapperDesc{
+		Name: "Jaleco JF-05..JF-10",
+		Load: loadJalecoJF05,
+	}
+	JalecoJF11 = MapperDesc{
+		Name: "Jaleco JF-11/JF-14",
+		Load: loadJalecoJF11,
+	}
+	JalecoJF16 = MapperDesc{
+		Name:            "Jaleco JF-16/Irem Holy Diver",
+		Load:            loadJalecoJF16,
+		HasBusConflicts: alwaysBusConflicts,
+	}
+)
+
+// jalecoLatch is a write-only register at $6000-$7FFF.
+type jalecoLatch struct {
+	*Base
+
+	write func(uint8)
+
+	Latch hwio.Device `hwio:"size=0x2000,wcb"`
+}
+
+func (m *jalecoLatch) WriteLATCH(_ uint16, val uint8) { m.write(val) }
+
+func loadJalecoLatch(b *Base, write func(uint8)) {
+	m := &jalecoLatch{Base: b, write: write}
+	hwio.MustInitRegs(m)
+	b.Init(nil)
+
+	b.CPU().Bus.Unmap(0x6000, 0x7FFF)
+	b.CPU().Bus.MapDevice(0x6000, &m.Latch)
+
+	b.SetNTMirroring(b.Rom().Mirroring())
+	b.SelectPRGPage32KB(0)
+	b.SelectCHRROMPage8KB(0)
+}
+
+// Mapper 87.
+func loadJalecoJF05(b *Base) error {
+	loadJalecoLatch(b, func(val uint8) {
+		// 7  bit  0
+		// ---- ----
+		// xxxx xxLH
+		//        ||
+		//        ++- Select 8 KB CHR ROM bank for PPU $0000-$1FFF
+		//            (bits are swapped: L is the low bit, H the high bit)
+		b.SelectCHRROMPage8KB(int(val>>1&0x01 | val<<1&0x02))
+	})
+	return nil
+}
+
+// Mapper 140.
+func loadJalecoJF11(b *Base) error {
+	loadJalecoLatch(b, func(val uint8) {
+		// 7  bit  0
+		// ---- ----
+		// xxPP CCCC
+		//   || ||||
+		//   || ++++- Select 8 KB CHR ROM bank for PPU $0000-$1FFF
+		//   ++------ Select 32 KB PRG ROM bank for CPU $8000-$FFFF
+		b.SelectPRGPage32KB(int(val >> 4 & 0x03))
+		b.SelectCHRROMPage8KB(int(val & 0x0F))
+	})
+	return nil
+}
+
+// Mapper 78. The Jaleco JF-16 (Uchuusen: Cosmo Carrier) has one-screen
+// mirroring control, while Irem's Holy Diver board selects between horizontal
+// and vertical mirroring. Holy Diver iNES 1.0 dumps have the alternative
+// nametables bit set.
+func loadJalecoJF16(b *Base) error {
+	holyDiver := b.Rom().SubMapper() == 3 || (b.Rom().SubMapper() == 0 && b.Rom().HasAltNametables())
+	variant := "JF-16"
+	if holyDiver {
+		variant = "Holy Diver"
+	}
+	modMapper.InfoZ("detected board").String("mapper", b.Name()).String("variant", variant).End()
+
+	b.Init(func(_ uint16, val uint8) {
+		// 7  bit  0
+		// ---- ----
+		// CCCC MPPP
+		// |||| ||||
+		// |||| |+++- Select 16 KB PRG ROM bank for CPU $8000-$BFFF
+		// |||| +---- Mirroring (JF-16: one-screen A/B, Holy Diver: H/V)
+		// ++++------ Select 8 KB CHR ROM bank for PPU $0000-$1FFF
+		b.SelectPRGPage16KB(0, int(val&0x07))
+		b.SelectCHRROMPage8KB(int(val >> 4))
+
+		m := val&0x08 != 0
+		switch {
+		case holyDiver && m:
+			b.SetNTMirroring(ines.VertMirroring)
+		case holyDiver:
+			b.SetNTMirroring(ines.HorzMirroring)
+		case m:
+			b.SetNTMirroring(ines.OnlyBScreen)
+		default:
+			b.SetNTMirroring(ines.OnlyAScreen)
+		}
+	})
+
+	if holyDiver {
+		b.SetNTMirroring(ines.HorzMirroring)
+	} else {
+		b.SetNTMirroring(ines.OnlyAScreen)
+	}
+	b.SelectCHRROMPage8KB(0)
+	b.SelectPRGPage16KB(0, 0)
+	b.SelectPRGPage16KB(1, -1)
+	return nil
+}
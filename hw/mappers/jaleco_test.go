@@ -0,0 +1,39 @@
+package mappers
+
+import (
+	"testing"
+
+	"nestor/ines"
+)
+
+func TestJaleco(t *testing.T) {
+	t.Run("JF-05", func(t *testing.T) {
+		loadTestRom(t, 87, 0, 32, 32).run(t, []busStep{
+			{desc: "CHR bank bits swapped", write: []busVal{{0x6000, 0x01}}, ppu: []busVal{{0x0000, 2 * 8}}},
+			{desc: "CHR bank bits swapped", write: []busVal{{0x6000, 0x02}}, ppu: []busVal{{0x0000, 1 * 8}}},
+		})
+	})
+
+	t.Run("JF-16", func(t *testing.T) {
+		loadTestRom(t, 78, 0, 128, 128).run(t, []busStep{
+			{desc: "power up", mirror: ines.OnlyAScreen},
+			{
+				desc:   "banks and mirroring",
+				write:  []busVal{{0xFC00, 0x3D}},
+				cpu:    []busVal{{0x8000, 5 * 16}},
+				ppu:    []busVal{{0x0000, 3 * 8}},
+				mirror: ines.OnlyBScreen,
+			},
+			// $FC00 holds $7F, the CHR bank high bit is lost.
+			{desc: "bus conflict", write: []busVal{{0xFC00, 0xFF}}, ppu: []busVal{{0x0000, 7 * 8}}},
+		})
+	})
+
+	t.Run("Holy Diver", func(t *testing.T) {
+		loadTestRom(t, 78, 3, 128, 128).run(t, []busStep{
+			{desc: "power up", mirror: ines.HorzMirroring},
+			{desc: "vertical", write: []busVal{{0xFC00, 0x08}}, mirror: ines.VertMirroring},
+			{desc: "horizontal", write: []busVal{{0xFC00, 0x00}}, mirror: ines.HorzMirroring},
+		})
+	})
+}
@@ -0,0 +1,123 @@
+package mappers
+
+import (
+	"testing"
+
+	"nestor/hw"
+	"nestor/hw/hwdefs"
+	"nestor/ines"
+	"nestor/tests"
+)
+
+// testCart is a cartridge plugged into a bare CPU and PPU.
+type testCart struct {
+	Cartridge
+
+	cpu *hw.CPU
+	ppu *hw.PPU
+}
+
+// loadTestRom loads a rom generated by tests.MapperRom, in which each 1 KB
+// of PRG and CHR holds its index. Bus conflicts are checked by writing to
+// registers at addresses where PRG-ROM masks some of the bits written: odd
+// addresses hold 0 (high byte of the index) and $FC00 holds the index of the
+// last 1 KB of the bank mapped there.
+func loadTestRom(t *testing.T, mapper uint16, submapper uint8, prgKB, chrKB int) *testCart {
+	t.Helper()
+	return loadTestImage(t, tests.MapperRom(mapper, submapper, prgKB, chrKB), Options{})
+}
+
+func loadTestImage(t *testing.T, img []byte, opts Options) *testCart {
+	t.Helper()
+
+	rom, err := ines.Decode(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ppu := hw.NewPPU()
+	cpu := hw.NewCPU(ppu)
+	cpu.InitBus()
+
+	cart, err := Load(rom, cpu, ppu, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ppu.Reset()
+	cpu.Reset(hwdefs.HardReset)
+	return &testCart{Cartridge: cart, cpu: cpu, ppu: ppu}
+}
+
+type busVal struct {
+	addr uint16
+	val  uint8
+}
+
+// A busStep writes to the CPU bus, then checks the values read back from the
+// CPU and PPU buses and, if set, the nametable mirroring.
+type busStep struct {
+	desc   string
+	write  []busVal
+	cpu    []busVal
+	ppu    []busVal
+	mirror ines.NTMirroring
+}
+
+// run runs the steps in order, on the same cartridge.
+func (c *testCart) run(t *testing.T, steps []busStep) {
+	t.Helper()
+
+	for _, s := range steps {
+		for _, w := range s.write {
+			c.cpu.Bus.Write8(w.addr, w.val)
+		}
+		for _, r := range s.cpu {
+			if got := c.cpu.Bus.Read8(r.addr); got != r.val {
+				t.Errorf("%s: CPU $%04X = $%02X, want $%02X", s.desc, r.addr, got, r.val)
+			}
+		}
+		for _, r := range s.ppu {
+			if got := c.ppu.Bus.Read8(r.addr); got != r.val {
+				t.Errorf("%s: PPU $%04X = $%02X, want $%02X", s.desc, r.addr, got, r.val)
+			}
+		}
+		if s.mirror != 0 {
+			c.checkMirroring(t, s.desc, s.mirror)
+		}
+	}
+}
+
+// checkMirroring checks the nametable arrangement by writing a different value
+// into each nametable and reading them back.
+func (c *testCart) checkMirroring(t *testing.T, desc string, want ines.NTMirroring) {
+	t.Helper()
+
+	for i := range uint16(4) {
+		c.ppu.Bus.Write8(0x2000+i*0x400, uint8(i+1))
+	}
+
+	var vals [4]uint8
+	switch want {
+	case ines.HorzMirroring:
+		vals = [4]uint8{2, 2, 4, 4}
+	case ines.VertMirroring:
+		vals = [4]uint8{3, 4, 3, 4}
+	case ines.OnlyAScreen, ines.OnlyBScreen:
+		vals = [4]uint8{4, 4, 4, 4}
+	}
+	for i := range uint16(4) {
+		addr := 0x2000 + i*0x400
+		if got := c.ppu.Bus.Read8(addr); got != vals[i] {
+			t.Errorf("%s: %v, nametable $%04X = %d, want %d", desc, want, addr, got, vals[i])
+		}
+	}
+}
+
+// runUntilIRQ runs the CPU until the external IRQ source is set, or the max
+// number of cycles is reached. It returns the number of CPU cycles ran.
+func (c *testCart) runUntilIRQ(max int64) int64 {
+	start := c.cpu.Cycles
+	for c.cpu.Cycles-start < max && !c.cpu.HasIRQSource(hwdefs.External) {
+		c.cpu.Run(1)
+	}
+	return c.cpu.Cycles - start
+}
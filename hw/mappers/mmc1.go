@@ -1,18 +1,50 @@
 package mappers
 
 import (
+	"nestor/hw/hwio"
 	"nestor/ines"
 )
 
-var MMC1 = MapperDesc{
-	Name: "MMC1",
-	Load: loadMMC1,
-	// PRGROMbanksz: 0x8000,
-	// PRGRAMbanksz: 0x2000,
+var (
+	MMC1 = MapperDesc{
+		Name: "MMC1",
+		Load: loadMMC1,
+	}
+	// MMC1A is the first MMC1 revision, without the PRG-RAM disable bit.
+	MMC1A = MapperDesc{
+		Name: "MMC1A",
+		Load: loadMMC1,
+	}
+)
+
+// mmc1Board is the SxROM board an MMC1 is mounted on. Most boards only differ
+// by their ROM and RAM sizes, but some use the CHR bank registers lines for
+// other purposes.
+type mmc1Board uint8
+
+const (
+	sxrom    mmc1Board = iota // any board with nothing special
+	serom                     // SEROM/SHROM/SH1ROM: 32 KB PRG-ROM, no PRG banking
+	snrom                     // CHR A16 disables PRG-RAM
+	sorom                     // CHR A15 selects the 8 KB PRG-RAM bank (16 KB)
+	surom                     // CHR A16 selects the 256 KB PRG-ROM bank (512 KB)
+	sxrom512                  // SXROM: both SUROM PRG banking and 32 KB PRG-RAM
+)
+
+var mmc1BoardNames = [...]string{
+	sxrom:    "SxROM",
+	serom:    "SEROM",
+	snrom:    "SNROM",
+	sorom:    "SOROM",
+	surom:    "SUROM",
+	sxrom512: "SXROM",
 }
 
 type mmc1 struct {
-	*base
+	*Base
+
+	board mmc1Board
+	mmc1a bool // MMC1A: PRG-RAM always enabled, PRG bit 3 bypasses 16 KB mode
 
 	prevCycle int64
 
@@ -24,13 +56,20 @@ type mmc1 struct {
 	prgmode uint8
 	ntm     uint8
 
-	// CHR reg 0 bits
-	chrbank0 uint32
-	chrbank1 uint32
+	// CHR regs
+	chrbank0 uint8
+	chrbank1 uint8
+	lastCHR  uint8 // last CHR reg written, 0 or 1
 
 	// PRG reg bits
-	disableWRAM bool // TODO: unused for now
-	prgbank     uint32
+	disableWRAM bool
+	prgbank     uint8
+
+	wram     []byte // PRG-RAM, up to 32 KB, may be empty
+	wrambank int
+
+	// $6000-$7FFF: banked PRG-RAM.
+	PRG6000 hwio.Device `hwio:"size=0x2000,rcb,wcb"`
 }
 
 type shiftReg uint8
@@ -42,7 +81,7 @@ func (sr shiftReg) push(val uint8) shiftReg {
 }
 
 func (m *mmc1) WritePRGROM(addr uint16, val uint8) {
-	curCycle := m.cpu.CurrentCycle()
+	curCycle := m.CPU().CurrentCycle()
 	// Ignore consecutive cycle writes
 	resetbit := u8tob(val & 0x80)
 	if resetbit || curCycle-m.prevCycle >= 2 {
@@ -67,7 +106,30 @@ func (m *mmc1) WritePRGROM(addr uint16, val uint8) {
 			}
 		}
 	}
-	m.prevCycle = m.cpu.CurrentCycle()
+	m.prevCycle = m.CPU().CurrentCycle()
+}
+
+func (m *mmc1) ReadPRG6000(addr uint16) uint8 {
+	if !m.wramEnabled() {
+		return uint8((0x6000 | addr) >> 8) // open bus
+	}
+	return m.wram[m.wrambank*8*KB+int(addr&0x1FFF)]
+}
+
+func (m *mmc1) WritePRG6000(addr uint16, val uint8) {
+	if m.wramEnabled() {
+		m.wram[m.wrambank*8*KB+int(addr&0x1FFF)] = val
+	}
+}
+
+func (m *mmc1) wramEnabled() bool {
+	if len(m.wram) == 0 {
+		return false
+	}
+	if m.board == snrom && m.chrExtra()&0x10 != 0 {
+		return false
+	}
+	return m.mmc1a || !m.disableWRAM
 }
 
 func (m *mmc1) writeREG(addr uint16, val uint8) {
@@ -92,17 +154,17 @@ func (m *mmc1) writeCTRL(val uint8) {
 	if prevNT != m.ntm {
 		switch m.ntm {
 		case 0:
-			m.setNTMirroring(ines.OnlyAScreen)
+			m.SetNTMirroring(ines.OnlyAScreen)
 		case 1:
-			m.setNTMirroring(ines.OnlyBScreen)
+			m.SetNTMirroring(ines.OnlyBScreen)
 		case 2:
-			m.setNTMirroring(ines.VertMirroring)
+			m.SetNTMirroring(ines.VertMirroring)
 		case 3:
-			m.setNTMirroring(ines.HorzMirroring)
+			m.SetNTMirroring(ines.HorzMirroring)
 		}
 	}
 
-	modMapper.DebugZ("Write CTRL reg").String("mapper", m.desc.Name).
+	modMapper.DebugZ("Write CTRL reg").String("mapper", m.Name()).
 		Uint8("val", val).
 		Uint8("prgmode", m.prgmode).
 		Uint8("chrmode", m.chrmode).
@@ -110,57 +172,162 @@ func (m *mmc1) writeCTRL(val uint8) {
 }
 
 func (m *mmc1) writeCHR0(val uint8) {
-	modMapper.DebugZ("Write CHR0 reg").String("mapper", m.desc.Name).Uint8("val", val).End()
-	m.chrbank0 = uint32(val & 0b11111) // TODO: Adjust mask if CHRROM is larger
+	modMapper.DebugZ("Write CHR0 reg").String("mapper", m.Name()).Uint8("val", val).End()
+	m.chrbank0 = val & 0b11111
+	m.lastCHR = 0
 }
 
 func (m *mmc1) writeCHR1(val uint8) {
-	modMapper.DebugZ("Write CHR1 reg").String("mapper", m.desc.Name).Uint8("val", val).End()
-	m.chrbank1 = uint32(val & 0b11111) // TODO: Adjust mask if CHRROM is larger
+	modMapper.DebugZ("Write CHR1 reg").String("mapper", m.Name()).Uint8("val", val).End()
+	m.chrbank1 = val & 0b11111
+	m.lastCHR = 1
 }
 
 func (m *mmc1) writePRG(val uint8) {
-	modMapper.DebugZ("Write PRG reg").String("mapper", m.desc.Name).Uint8("val", val).End()
+	modMapper.DebugZ("Write PRG reg").String("mapper", m.Name()).Uint8("val", val).End()
 
 	// $E000-FFFF:  [...W PPPP]
-	// W = WRAM Disable (0=enabled, 1=disabled)
+	// W = WRAM Disable (0=enabled, 1=disabled), ignored on MMC1A
 	// P = PRG Reg
 	m.disableWRAM = u8tob(val & 0b1_0000)
-	m.prgbank = uint32(val & 0b1111)
-	if m.disableWRAM {
-		panic("disable WRAM not implemented")
+	m.prgbank = val & 0b1111
+}
+
+// chrExtra returns the CHR bank register whose upper lines are used by
+// SNROM, SOROM, SUROM and SXROM boards. In 4 KB CHR mode, the register in use
+// depends on PPU A12, games write the same value in both, we use the last
+// written one.
+func (m *mmc1) chrExtra() uint8 {
+	if m.chrmode == 1 && m.lastCHR == 1 {
+		return m.chrbank1
 	}
+	return m.chrbank0
 }
 
 func (m *mmc1) remap() {
+	m.remapPRG()
+
+	switch m.chrmode {
+	case 0:
+		// ignore low bit of bank number
+		m.SelectCHRROMPage4KB(0, int(m.chrbank0&0x1E))
+		m.SelectCHRROMPage4KB(1, int(m.chrbank0|0x01))
+	case 1:
+		m.SelectCHRROMPage4KB(0, int(m.chrbank0))
+		m.SelectCHRROMPage4KB(1, int(m.chrbank1))
+	}
+
+	switch m.board {
+	case sorom:
+		m.wrambank = int(m.chrExtra() >> 3 & 0x01)
+	case sxrom512:
+		m.wrambank = int(m.chrExtra() >> 2 & 0x03)
+	}
+	if nbanks := len(m.wram) / (8 * KB); nbanks > 0 {
+		m.wrambank %= nbanks
+	}
+}
+
+func (m *mmc1) remapPRG() {
+	if m.board == serom {
+		m.SelectPRGPage32KB(0)
+		return
+	}
+
+	// 16 KB banks, SUROM and SXROM select the 256 KB outer bank with CHR A16.
+	var outer int
+	if m.board == surom || m.board == sxrom512 {
+		outer = int(m.chrExtra() & 0x10)
+	}
+	bank := int(m.prgbank)
+
 	switch m.prgmode {
 	case 0, 1:
 		// ignore low bit of bank number
-		m.selectPRGPage32KB(int(m.prgbank & 0xFE))
+		m.SelectPRGPage16KB(0, outer|bank&0x0E)
+		m.SelectPRGPage16KB(1, outer|bank&0x0E|1)
 	case 2:
-		m.selectPRGPage16KB(0, 0)
-		m.selectPRGPage16KB(1, int(m.prgbank))
+		first := outer
+		if m.mmc1a {
+			// PRG A17 is taken from the register even for the fixed bank.
+			first |= bank & 0x08
+		}
+		m.SelectPRGPage16KB(0, first)
+		m.SelectPRGPage16KB(1, outer|bank)
 	case 3:
-		m.selectPRGPage16KB(0, int(m.prgbank))
-		m.selectPRGPage16KB(1, -1)
+		last := outer | 0x0F
+		if m.mmc1a {
+			last = outer | bank&0x08 | 0x07
+		}
+		m.SelectPRGPage16KB(0, outer|bank)
+		m.SelectPRGPage16KB(1, last)
+	}
+}
+
+// detectMMC1Board guesses the board from the rom sizes and submapper. iNES
+// 1.0 roms don't have PRG-RAM sizes, they're assumed to have 8 KB.
+func detectMMC1Board(rom *ines.Rom) (board mmc1Board, wramsz int) {
+	wramsz = 8 * KB
+	if rom.IsNES20() {
+		wramsz = rom.PRGRAMSize() + rom.PRGNVRAMSize()
 	}
 
-	switch m.chrmode {
-	case 0:
-		m.selectCHRROMPage8KB(int(m.chrbank0))
-	case 1:
-		m.selectCHRROMPage4KB(0, int(m.chrbank0))
-		m.selectCHRROMPage4KB(1, int(m.chrbank1))
+	switch {
+	case rom.SubMapper() == 5:
+		return serom, wramsz
+	case len(rom.PRGROM) > 256*KB && wramsz > 8*KB:
+		return sxrom512, wramsz
+	case len(rom.PRGROM) > 256*KB:
+		return surom, wramsz
+	case wramsz > 8*KB:
+		return sorom, wramsz
+	case len(rom.CHRROM) == 0 && wramsz > 0:
+		return snrom, wramsz
 	}
+	return sxrom, wramsz
 }
 
-func loadMMC1(b *base) error {
-	mmc1 := &mmc1{base: b}
+// nvram returns the battery-backed part of the PRG-RAM. Only SOROM and SXROM
+// boards can have both volatile and battery-backed RAM chips, in which case the
+// battery-backed one is in the last banks, with the size given by the NES 2.0
+// header. The other boards have a single chip, battery-backed as a whole: the
+// header RAM sizes can't tell which part is, iNES 1.0 headers don't have them
+// and NES 2.0 ones may have the battery-backed RAM in the volatile size.
+func (m *mmc1) nvram() []byte {
+	switch m.board {
+	case sorom, sxrom512:
+		if nvsz := m.Rom().PRGNVRAMSize(); nvsz > 0 && nvsz < len(m.wram) {
+			return m.wram[len(m.wram)-nvsz:]
+		}
+	}
+	return m.wram
+}
+
+func loadMMC1(b *Base) error {
+	board, wramsz := detectMMC1Board(b.Rom())
+	mmc1 := &mmc1{
+		Base:  b,
+		board: board,
+		mmc1a: b.Rom().Mapper() == 155,
+		wram:  make([]byte, wramsz),
+	}
+	hwio.MustInitRegs(mmc1)
+	b.Init(mmc1.WritePRGROM)
 
-	b.init(mmc1.WritePRGROM)
+	modMapper.InfoZ("detected board").
+		String("mapper", b.Name()).
+		String("variant", mmc1BoardNames[board]).
+		Int("prgram", wramsz).
+		End()
+
+	b.CPU().Bus.Unmap(0x6000, 0x7FFF)
+	b.CPU().Bus.MapDevice(0x6000, &mmc1.PRG6000)
+	if b.Rom().HasPersistence() {
+		b.Persist(mmc1.nvram())
+	}
 
 	// PPU mapping.
-	b.setNTMirroring(ines.OnlyAScreen)
+	b.SetNTMirroring(ines.OnlyAScreen)
 
 	// Mapper initialization.
 	// On powerup: bits 2,3 of $8000 are set (this ensures the $8000 is bank 0,
@@ -169,8 +336,7 @@ func loadMMC1(b *base) error {
 	mmc1.writeREG(0x8000, 0x0C)
 	mmc1.writeREG(0xA000, 0)
 	mmc1.writeREG(0xC000, 0)
-	mmc1.writeREG(0xE000, 0) // TODO: WRAM Disable: enabled by default for MMC1B
-	mmc1.disableWRAM = true  // TODO: always enabled on MMC1A
+	mmc1.writeREG(0xE000, 0) // PRG-RAM is enabled
 	mmc1.remap()
 	return nil
 }
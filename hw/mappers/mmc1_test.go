@@ -0,0 +1,155 @@
+package mappers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"nestor/tests"
+)
+
+// writeMMC1 writes a value into an MMC1 register through its serial port.
+func (c *testCart) writeMMC1(addr uint16, val uint8) {
+	for i := range 5 {
+		c.cpu.Bus.Write8(addr, val>>i&0x01)
+		// Writes on consecutive cycles are ignored.
+		c.cpu.Run(2)
+	}
+}
+
+func loadMMC1Rom(t *testing.T, mapper uint16, prgKB, chrKB, ramKB, nvramKB int) *testCart {
+	t.Helper()
+
+	img := tests.MapperRom(mapper, 0, prgKB, chrKB)
+	tests.SetPRGRAMSize(img, ramKB, nvramKB)
+	return loadTestImage(t, img, Options{})
+}
+
+func TestMMC1(t *testing.T) {
+	t.Run("SKROM", func(t *testing.T) {
+		c := loadMMC1Rom(t, 1, 256, 128, 0, 8)
+
+		c.run(t, []busStep{{desc: "PRG-RAM", write: []busVal{{0x6000, 0x42}}, cpu: []busVal{{0x6000, 0x42}}}})
+		c.writeMMC1(0xE000, 0x10)
+		c.run(t, []busStep{{desc: "PRG-RAM disabled", cpu: []busVal{{0x6000, 0x60}}}})
+		c.writeMMC1(0xE000, 0x00)
+		c.run(t, []busStep{{desc: "PRG-RAM enabled", cpu: []busVal{{0x6000, 0x42}}}})
+	})
+
+	t.Run("MMC1A", func(t *testing.T) {
+		c := loadMMC1Rom(t, 155, 256, 128, 8, 0)
+
+		c.cpu.Bus.Write8(0x6000, 0x42)
+		c.writeMMC1(0xE000, 0x10)
+		c.run(t, []busStep{{desc: "PRG-RAM can't be disabled", cpu: []busVal{{0x6000, 0x42}}}})
+
+		c.writeMMC1(0xE000, 0x02)
+		c.run(t, []busStep{{desc: "PRG bit 3 bypasses the fixed bank", cpu: []busVal{{0xC000, 7 * 16}}}})
+	})
+
+	t.Run("SNROM", func(t *testing.T) {
+		c := loadMMC1Rom(t, 1, 256, 0, 0, 8)
+
+		c.cpu.Bus.Write8(0x6000, 0x42)
+		c.writeMMC1(0xA000, 0x10)
+		c.run(t, []busStep{{desc: "CHR A16 disables PRG-RAM", cpu: []busVal{{0x6000, 0x60}}}})
+		c.writeMMC1(0xA000, 0x00)
+		c.run(t, []busStep{{desc: "PRG-RAM enabled", cpu: []busVal{{0x6000, 0x42}}}})
+	})
+
+	t.Run("SOROM", func(t *testing.T) {
+		c := loadMMC1Rom(t, 1, 256, 0, 8, 8)
+
+		// CHR A15 selects the PRG-RAM bank.
+		c.cpu.Bus.Write8(0x6000, 0x11)
+		c.writeMMC1(0xA000, 0x08)
+		c.run(t, []busStep{{desc: "bank 1", cpu: []busVal{{0x6000, 0x00}}}})
+		c.cpu.Bus.Write8(0x6000, 0x22)
+		c.writeMMC1(0xA000, 0x00)
+		c.run(t, []busStep{{desc: "bank 0", cpu: []busVal{{0x6000, 0x11}}}})
+
+		// In 4 KB CHR mode, the last written register is used.
+		c.writeMMC1(0x8000, 0x1C)
+		c.writeMMC1(0xC000, 0x08)
+		c.run(t, []busStep{{desc: "4 KB CHR mode", cpu: []busVal{{0x6000, 0x22}}}})
+	})
+
+	t.Run("SUROM", func(t *testing.T) {
+		c := loadMMC1Rom(t, 1, 512, 0, 0, 8)
+
+		// CHR A16 selects the 256 KB PRG bank, the fixed bank included.
+		c.writeMMC1(0xA000, 0x10)
+		c.writeMMC1(0xE000, 0x01)
+		c.run(t, []busStep{{
+			desc: "upper 256 KB",
+			cpu: []busVal{
+				{0x8000, 17 * 16 & 0xFF}, {0x8001, 17 * 16 >> 8},
+				{0xC000, 31 * 16 & 0xFF}, {0xC001, 31 * 16 >> 8},
+			},
+		}})
+	})
+
+	t.Run("SXROM", func(t *testing.T) {
+		c := loadMMC1Rom(t, 1, 512, 0, 0, 32)
+
+		// CHR A14-A13 select the PRG-RAM bank, CHR A16 the PRG bank.
+		for bank := range uint8(4) {
+			c.writeMMC1(0xA000, bank<<2)
+			c.cpu.Bus.Write8(0x6000, bank+1)
+		}
+		c.writeMMC1(0xA000, 0x10|2<<2)
+		c.run(t, []busStep{{desc: "RAM bank 2, upper 256 KB", cpu: []busVal{{0x6000, 3}, {0xC001, 31 * 16 >> 8}}}})
+	})
+
+	t.Run("SEROM", func(t *testing.T) {
+		c := loadTestRom(t, 1, 5, 32, 16)
+
+		c.writeMMC1(0xE000, 0x01)
+		c.run(t, []busStep{{desc: "32 KB PRG-ROM", cpu: []busVal{{0x8000, 0}, {0xC000, 16}}}})
+	})
+}
+
+func TestMMC1Save(t *testing.T) {
+	for _, tc := range []struct {
+		name                  string
+		prgKB, ramKB, nvramKB int
+		banks                 []uint8 // CHR bank 0 values selecting each PRG-RAM bank
+		saveKB                int
+		restored              []uint8 // PRG-RAM banks content after a power cycle
+	}{
+		// Battery-backed RAM in the volatile size of the NES 2.0 header.
+		{"SNROM", 256, 8, 0, []uint8{0x00}, 8, []uint8{0x11}},
+		{"SOROM", 256, 8, 8, []uint8{0x00, 0x08}, 8, []uint8{0x00, 0x22}},
+		{"SXROM", 512, 0, 32, []uint8{0x00, 0x04, 0x08, 0x0C}, 32, []uint8{0x11, 0x22, 0x33, 0x44}},
+		{"SXROM 8 KB volatile", 512, 8, 8, []uint8{0x00, 0x04}, 8, []uint8{0x00, 0x22}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			img := tests.MapperRom(1, 0, tc.prgKB, 0)
+			tests.SetPRGRAMSize(img, tc.ramKB, tc.nvramKB)
+			img[6] |= 0x02 // battery
+			opts := Options{SavePath: filepath.Join(t.TempDir(), "mmc1.sav")}
+
+			c := loadTestImage(t, img, opts)
+			for i, bank := range tc.banks {
+				c.writeMMC1(0xA000, bank)
+				c.cpu.Bus.Write8(0x6000, uint8(0x11*(i+1)))
+			}
+			if err := c.Save(); err != nil {
+				t.Fatal(err)
+			}
+			fi, err := os.Stat(opts.SavePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fi.Size() != int64(tc.saveKB*1024) {
+				t.Errorf("save file is %d bytes, want %d KB", fi.Size(), tc.saveKB)
+			}
+
+			c = loadTestImage(t, img, opts)
+			for i, bank := range tc.banks {
+				c.writeMMC1(0xA000, bank)
+				c.run(t, []busStep{{desc: "restored", cpu: []busVal{{0x6000, tc.restored[i]}}}})
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package mappers
+
+import "nestor/ines"
+
+// The Namco 108 (also known as Namco 118, Tengen MIMIC-1) is the predecessor
+// of the MMC3, without IRQ nor mirroring control. Some boards built around
+// it rewire its CHR address lines, giving different mapper numbers.
+var (
+	Namco108 = MapperDesc{
+		Name: "Namco 108",
+		Load: loadNamco108,
+	}
+	NAMCOT3446 = MapperDesc{
+		Name: "NAMCOT-3446",
+		Load: loadNamco108,
+	}
+	NAMCOT3443 = MapperDesc{
+		Name: "NAMCOT-3443",
+		Load: loadNamco108,
+	}
+	NAMCOT3425 = MapperDesc{
+		Name: "NAMCOT-3425",
+		Load: loadNamco108,
+	}
+	NAMCOT3453 = MapperDesc{
+		Name: "NAMCOT-3453",
+		Load: loadNamco108,
+	}
+)
+
+type namco108 struct {
+	*Base
+
+	mapper uint16
+	reg    uint8    // bank register selected for the next data write
+	banks  [8]uint8 // R0-R7
+}
+
+func (m *namco108) writeReg(addr uint16, val uint8) {
+	if m.mapper == 154 {
+		// 7  bit  0
+		// ---- ----
+		// xMxx xxxx
+		//  |
+		//  +-------- Select 1 KB VRAM page for all 4 nametables
+		//
+		// The mirroring bit is latched on any write to $8000-$FFFF.
+		if val&0x40 != 0 {
+			m.SetNTMirroring(ines.OnlyBScreen)
+		} else {
+			m.SetNTMirroring(ines.OnlyAScreen)
+		}
+	}
+
+	if addr >= 0xA000 {
+		return
+	}
+
+	if addr&0x01 == 0 {
+		// Bank select.
+		//
+		// 7  bit  0
+		// ---- ----
+		// xxxx xRRR
+		//       |||
+		//       +++- Specify which bank register to update on next write
+		//            to Bank Data register
+		m.reg = val & 0x07
+		return
+	}
+
+	// Bank data.
+	m.banks[m.reg] = val & 0x3F
+	if m.reg >= 6 {
+		m.remapPRG()
+	} else {
+		m.remapCHR()
+	}
+}
+
+func (m *namco108) remapPRG() {
+	// R6 and R7 select 8 KB banks at $8000 and $A000. $C000-$FFFF is fixed
+	// to the last 16 KB.
+	m.SelectPRGPage8KB(0, int(m.banks[6]&0x0F))
+	m.SelectPRGPage8KB(1, int(m.banks[7]&0x0F))
+	m.SelectPRGPage8KB(2, -2)
+	m.SelectPRGPage8KB(3, -1)
+}
+
+func (m *namco108) remapCHR() {
+	if m.mapper == 76 {
+		// R2-R5 select 2 KB banks, R0 and R1 are unused.
+		for i := range 4 {
+			bank := int(m.banks[2+i]) << 1
+			m.SelectCHRROMPage1KB(uint32(2*i), bank)
+			m.SelectCHRROMPage1KB(uint32(2*i+1), bank|1)
+		}
+		return
+	}
+
+	// R0 and R1 select 2 KB banks at $0000 and $0800 (low bit ignored), R2-R5
+	// select 1 KB banks at $1000-$1FFF.
+	r0, r1 := int(m.banks[0]&0x3E), int(m.banks[1]&0x3E)
+	hi := [4]int{int(m.banks[2]), int(m.banks[3]), int(m.banks[4]), int(m.banks[5])}
+
+	switch m.mapper {
+	case 88, 154:
+		// PPU A12 drives CHR A16: the lower pattern table is taken from the
+		// first 64 KB of CHR-ROM and the upper one from the next 64 KB.
+		for i := range hi {
+			hi[i] |= 0x40
+		}
+	case 95:
+		// Bit 5 of R0 and R1 isn't a CHR line, it selects the CIRAM page
+		// for the left and right nametables.
+		r0 &= 0x1F
+		r1 &= 0x1F
+		m.remapNametables95()
+	}
+
+	m.SelectCHRROMPage1KB(0, r0)
+	m.SelectCHRROMPage1KB(1, r0|1)
+	m.SelectCHRROMPage1KB(2, r1)
+	m.SelectCHRROMPage1KB(3, r1|1)
+	for i, bank := range hi {
+		m.SelectCHRROMPage1KB(uint32(4+i), bank)
+	}
+}
+
+func (m *namco108) remapNametables95() {
+	left := m.CIRAM(int(m.banks[0] >> 5))
+	right := m.CIRAM(int(m.banks[1] >> 5))
+	m.SetNametables(left, left, right, right)
+}
+
+func loadNamco108(b *Base) error {
+	m := &namco108{
+		Base:   b,
+		mapper: b.Rom().Mapper(),
+	}
+	b.Init(m.writeReg)
+
+	switch m.mapper {
+	case 154:
+		b.SetNTMirroring(ines.OnlyAScreen)
+	default:
+		b.SetNTMirroring(b.Rom().Mirroring())
+	}
+
+	// Power-on bank registers are unspecified, start with the first 8 KB of
+	// CHR-ROM and the first 16 KB of PRG-ROM.
+	m.banks = [8]uint8{0, 2, 4, 5, 6, 7, 0, 1}
+	if m.mapper == 76 {
+		m.banks = [8]uint8{0, 0, 0, 1, 2, 3, 0, 1}
+	}
+	m.remapPRG()
+	m.remapCHR()
+	return nil
+}
@@ -0,0 +1,77 @@
+package mappers
+
+import (
+	"testing"
+
+	"nestor/ines"
+)
+
+func TestNamco108(t *testing.T) {
+	bank := func(reg, val uint8) []busVal {
+		return []busVal{{0x8000, reg}, {0x8001, val}}
+	}
+
+	t.Run("206", func(t *testing.T) {
+		loadTestRom(t, 206, 0, 128, 64).run(t, []busStep{
+			{
+				desc:  "R0 and R1 ignore the low bit",
+				write: append(bank(0, 5), bank(1, 9)...),
+				ppu:   []busVal{{0x0000, 4}, {0x0400, 5}, {0x0800, 8}, {0x0C00, 9}},
+			},
+			{
+				desc:   "no registers at $A000-$FFFF",
+				write:  append(bank(6, 3), busVal{0xA000, 6}, busVal{0xA001, 7}),
+				cpu:    []busVal{{0x8000, 3 * 8}},
+				mirror: ines.HorzMirroring,
+			},
+		})
+	})
+
+	t.Run("NAMCOT-3446", func(t *testing.T) {
+		loadTestRom(t, 76, 0, 128, 128).run(t, []busStep{{
+			desc:  "R2-R5 select 2 KB banks",
+			write: append(bank(2, 3), bank(5, 9)...),
+			ppu:   []busVal{{0x0000, 6}, {0x0400, 7}, {0x1800, 18}, {0x1C00, 19}},
+		}})
+	})
+
+	t.Run("NAMCOT-3443", func(t *testing.T) {
+		loadTestRom(t, 88, 0, 128, 128).run(t, []busStep{{
+			desc:  "upper pattern table from the second 64 KB",
+			write: append(bank(0, 0x42), bank(2, 0x01)...),
+			ppu:   []busVal{{0x0000, 0x02}, {0x1000, 0x41}},
+		}})
+	})
+
+	t.Run("NAMCOT-3453", func(t *testing.T) {
+		loadTestRom(t, 154, 0, 128, 128).run(t, []busStep{
+			{desc: "power up", mirror: ines.OnlyAScreen},
+			{desc: "screen B", write: []busVal{{0xC000, 0x40}}, mirror: ines.OnlyBScreen},
+			{
+				desc:   "screen A",
+				write:  bank(2, 0x01),
+				ppu:    []busVal{{0x1000, 0x41}},
+				mirror: ines.OnlyAScreen,
+			},
+		})
+	})
+
+	t.Run("NAMCOT-3425", func(t *testing.T) {
+		c := loadTestRom(t, 95, 0, 128, 32)
+
+		// Bit 5 of R0 and R1 select the CIRAM page at $2000 and $2800.
+		c.run(t, []busStep{{
+			desc:  "CHR bank",
+			write: append(bank(0, 0x22), bank(1, 0x04)...),
+			ppu:   []busVal{{0x0000, 0x02}},
+		}})
+		for i := range uint16(4) {
+			c.ppu.Bus.Write8(0x2000+i*0x400, uint8(i+1))
+		}
+		c.run(t, []busStep{
+			{desc: "R0 page 1, R1 page 0", ppu: []busVal{{0x2000, 2}, {0x2800, 4}}},
+			{desc: "R1 page 1", write: bank(1, 0x24), ppu: []busVal{{0x2800, 2}}},
+			{desc: "R0 page 0", write: bank(0, 0x02), ppu: []busVal{{0x2000, 4}}},
+		})
+	})
+}
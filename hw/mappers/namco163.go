@@ -0,0 +1,223 @@
+package mappers
+
+import (
+	"nestor/hw/apu"
+	"nestor/hw/hwdefs"
+	"nestor/hw/hwio"
+)
+
+var Namco163 = MapperDesc{
+	Name: "Namco 163",
+	Load: loadNamco163,
+}
+
+type namco163 struct {
+	*Base
+
+	audio apu.Namco163Audio
+
+	prgbank [3]uint8
+	chrbank [8]uint8
+	ntbank  [4]uint8
+
+	// When set, CHR banks $E0-$FF select CHR ROM rather than CIRAM, for the
+	// low ($0000-$0FFF) and high ($1000-$1FFF) pattern tables.
+	noCIRAM [2]bool
+
+	// PRG-RAM write protection ($F800).
+	writeProtect uint8
+
+	irqCounter uint16 // 15 bits
+	irqEnable  bool
+
+	Data    hwio.Device `hwio:"size=0x800,rcb,pcb,wcb"` // $4800-$4FFF
+	IRQLo   hwio.Device `hwio:"size=0x800,rcb,wcb"`     // $5000-$57FF
+	IRQHi   hwio.Device `hwio:"size=0x800,rcb,wcb"`     // $5800-$5FFF
+	PRG6000 hwio.Device `hwio:"size=0x2000,rcb,wcb"`    // $6000-$7FFF
+}
+
+func (m *namco163) ReadDATA(addr uint16) uint8       { return m.audio.ReadData() }
+func (m *namco163) PeekDATA(addr uint16) uint8       { return m.audio.PeekData() }
+func (m *namco163) WriteDATA(addr uint16, val uint8) { m.audio.WriteData(val) }
+
+func (m *namco163) ReadIRQLO(addr uint16) uint8 {
+	return uint8(m.irqCounter)
+}
+
+func (m *namco163) WriteIRQLO(addr uint16, val uint8) {
+	m.irqCounter = m.irqCounter&0x7F00 | uint16(val)
+	m.CPU().ClearIRQSource(hwdefs.External)
+}
+
+func (m *namco163) ReadIRQHI(addr uint16) uint8 {
+	val := uint8(m.irqCounter >> 8)
+	if m.irqEnable {
+		val |= 0x80
+	}
+	return val
+}
+
+// 7  bit  0
+// ---- ----
+// EIII IIII
+// |||| ||||
+// |+++-++++- High 7 bits of IRQ counter
+// +--------- IRQ enable
+func (m *namco163) WriteIRQHI(addr uint16, val uint8) {
+	m.irqCounter = m.irqCounter&0x00FF | uint16(val&0x7F)<<8
+	m.irqEnable = val&0x80 != 0
+	m.CPU().ClearIRQSource(hwdefs.External)
+}
+
+func (m *namco163) ReadPRG6000(addr uint16) uint8 {
+	return m.PRGRAM.Data[addr&0x1FFF]
+}
+
+// PRG-RAM is writable when the upper 4 bits of $F800 are 0100, and the 2KB
+// window containing addr is not write-protected by one of the low 4 bits.
+func (m *namco163) WritePRG6000(addr uint16, val uint8) {
+	addr &= 0x1FFF
+	if m.writeProtect&0xF0 != 0x40 {
+		return
+	}
+	if m.writeProtect&(1<<(addr>>11)) != 0 {
+		return
+	}
+	m.PRGRAM.Data[addr] = val
+}
+
+func (m *namco163) writeReg(addr uint16, val uint8) {
+	switch reg := addr & 0xF800; {
+	case reg <= 0xB800:
+		// Select 1 KB CHR bank at $0000-$1FFF.
+		i := (reg - 0x8000) >> 11
+		m.chrbank[i] = val
+		m.remapCHR(int(i))
+
+	case reg <= 0xD800:
+		// Select 1 KB nametable at $2000-$2FFF.
+		i := (reg - 0xC000) >> 11
+		m.ntbank[i] = val
+		m.remapNT(int(i))
+
+	case reg == 0xE000:
+		// 7  bit  0
+		// ---- ----
+		// .SPP PPPP
+		//  ||| ||||
+		//  |++-++++- Select 8 KB PRG ROM bank at $8000-$9FFF
+		//  +-------- Disable sound if set
+		m.prgbank[0] = val & 0x3F
+		m.audio.SetDisabled(val&0x40 != 0)
+		m.SelectPRGPage8KB(0, int(m.prgbank[0]))
+
+	case reg == 0xE800:
+		// 7  bit  0
+		// ---- ----
+		// HLPP PPPP
+		// |||| ||||
+		// ||++-++++- Select 8 KB PRG ROM bank at $A000-$BFFF
+		// |+-------- Disable CIRAM for low pattern table ($0000-$0FFF)
+		// +--------- Disable CIRAM for high pattern table ($1000-$1FFF)
+		m.prgbank[1] = val & 0x3F
+		m.SelectPRGPage8KB(1, int(m.prgbank[1]))
+		m.noCIRAM[0] = val&0x40 != 0
+		m.noCIRAM[1] = val&0x80 != 0
+		for i := range m.chrbank {
+			m.remapCHR(i)
+		}
+
+	case reg == 0xF000:
+		// Select 8 KB PRG ROM bank at $C000-$DFFF.
+		m.prgbank[2] = val & 0x3F
+		m.SelectPRGPage8KB(2, int(m.prgbank[2]))
+
+	case reg == 0xF800:
+		// Both PRG-RAM write protection and the address port of the sound
+		// internal RAM.
+		m.writeProtect = val
+		m.audio.WriteAddress(val)
+	}
+}
+
+// remapCHR maps the 1 KB CHR page i, either from CHR ROM or from CIRAM (banks
+// $E0-$FF, unless disabled for that pattern table).
+func (m *namco163) remapCHR(i int) {
+	start := uint16(i) * KB
+	end := start + KB - 1
+	m.PPU().Bus.Unmap(start, end)
+
+	bank := m.chrbank[i]
+	if bank >= 0xE0 && !m.noCIRAM[i/4] {
+		m.PPU().Bus.MapMemorySlice(start, end, m.CIRAM(int(bank)).mem, false)
+		return
+	}
+
+	m.SelectCHRROMPage1KB(uint32(i), int(bank))
+	chrram := len(m.Rom().CHRROM) == 0
+	m.PPU().Bus.MapMemorySlice(start, end, m.CHRROM[start:end+1], !chrram)
+}
+
+// remapNT maps the nametable slot i, either from CIRAM (banks $E0-$FF) or
+// from CHR ROM.
+func (m *namco163) remapNT(i int) {
+	bank := m.ntbank[i]
+	if bank >= 0xE0 || len(m.Rom().CHRROM) == 0 {
+		m.SetNametable(i, m.CIRAM(int(bank)))
+		return
+	}
+
+	m.SetNametable(i, m.CHRROMNametable(int(bank)))
+}
+
+// tick is called on every CPU cycle.
+func (m *namco163) tick() {
+	// The IRQ counter counts up to $7FFF, at which point it triggers an IRQ
+	// and stops counting.
+	if m.irqEnable && m.irqCounter < 0x7FFF {
+		m.irqCounter++
+		if m.irqCounter == 0x7FFF {
+			m.CPU().SetIRQSource(hwdefs.External)
+		}
+	}
+	m.audio.Clock()
+}
+
+func loadNamco163(b *Base) error {
+	m := &namco163{
+		Base:  b,
+		audio: apu.NewNamco163Audio(b.CPU().APU, b.Options().N163MixChannels),
+	}
+	hwio.MustInitRegs(m)
+	b.Init(m.writeReg)
+
+	b.CPU().Bus.MapDevice(0x4800, &m.Data)
+	b.CPU().Bus.MapDevice(0x5000, &m.IRQLo)
+	b.CPU().Bus.MapDevice(0x5800, &m.IRQHi)
+	b.CPU().Bus.Unmap(0x6000, 0x7FFF)
+	b.CPU().Bus.MapDevice(0x6000, &m.PRG6000)
+	b.CPU().SetCycleHook(m.tick)
+
+	// The internal RAM, holding the sound registers and samples, is
+	// battery-backed along with the PRG-RAM.
+	if b.Rom().HasPersistence() {
+		b.Persist(m.PRGRAM.Data)
+		b.Persist(m.audio.RAM[:])
+	}
+
+	// Pattern tables are mapped in 1 KB pages since any of them can point to
+	// CIRAM.
+	b.PPU().Bus.Unmap(0x0000, 0x1FFF)
+	for i := range m.chrbank {
+		m.remapCHR(i)
+	}
+	for i := range m.ntbank {
+		m.ntbank[i] = 0xE0 | uint8(i)&0x01 // vertical mirroring
+		m.remapNT(i)
+	}
+
+	for i := range 4 {
+		m.SelectPRGPage8KB(uint32(i), -1)
+	}
+	return nil
+}
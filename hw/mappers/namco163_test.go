@@ -0,0 +1,39 @@
+package mappers
+
+import (
+	"path/filepath"
+	"testing"
+
+	"nestor/ines"
+	"nestor/tests"
+)
+
+func TestNamco163Save(t *testing.T) {
+	img := tests.MapperRom(19, 0, 128, 128)
+	img[6] |= 0x02 // battery
+	opts := Options{SavePath: filepath.Join(t.TempDir(), "n163.sav")}
+
+	c := loadTestImage(t, img, opts)
+	c.run(t, []busStep{
+		{desc: "internal RAM", write: []busVal{{0xF800, 0x05}, {0x4800, 0xA5}}},
+		{desc: "PRG-RAM, writes enabled", write: []busVal{{0xF800, 0x40}, {0x7123, 0x5A}}},
+	})
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both are restored at power up.
+	loadTestImage(t, img, opts).run(t, []busStep{
+		{desc: "PRG-RAM", cpu: []busVal{{0x7123, 0x5A}}},
+		{desc: "internal RAM", write: []busVal{{0xF800, 0x05}}, cpu: []busVal{{0x4800, 0xA5}}},
+	})
+}
+
+func TestNamco163NametablesWithoutCHRROM(t *testing.T) {
+	// Enabling CHR-ROM nametables on a CHR-RAM cart falls back to CIRAM.
+	loadTestRom(t, 19, 0, 128, 0).run(t, []busStep{{
+		desc:   "CIRAM",
+		write:  []busVal{{0xC000, 0x00}, {0xC800, 0x01}, {0xD000, 0x00}, {0xD800, 0x01}},
+		mirror: ines.VertMirroring,
+	}})
+}
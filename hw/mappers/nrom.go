@@ -6,19 +6,19 @@ var NROM = MapperDesc{
 	CHRROMbanksz: 0x2000,
 }
 
-func loadNROM(b *base) error {
-	b.init(nil)
+func loadNROM(b *Base) error {
+	b.Init(nil)
 
-	b.setNTMirroring(b.rom.Mirroring())
-	b.selectCHRROMPage8KB(0)
-	switch len(b.rom.PRGROM) {
+	b.SetNTMirroring(b.Rom().Mirroring())
+	b.SelectCHRROMPage8KB(0)
+	switch len(b.Rom().PRGROM) {
 	case 16 * KB:
-		b.selectPRGPage16KB(0, 0)
-		b.selectPRGPage16KB(1, 0) // mirror
+		b.SelectPRGPage16KB(0, 0)
+		b.SelectPRGPage16KB(1, 0) // mirror
 	case 32 * KB:
-		b.selectPRGPage32KB(0)
+		b.SelectPRGPage32KB(0)
 	default:
-		return ErrUnsuppportedPRGROMSize(len(b.rom.PRGROM))
+		return ErrUnsuppportedPRGROMSize(len(b.Rom().PRGROM))
 	}
 
 	// TODO: handle ROMS with CHRRAM
@@ -0,0 +1,439 @@
+package mappers
+
+import (
+	"fmt"
+	"time"
+
+	"nestor/hw"
+	"nestor/hw/apu"
+	"nestor/hw/hwio"
+	"nestor/ines"
+	"nestor/nsf"
+)
+
+// NSF is a synthetic cartridge playing a NSF (NES Sound Format) music rip. A
+// small driver program, mapped at $4100, initializes the console and calls
+// the tune INIT routine, then calls its PLAY routine at the rate given by the
+// NSF header.
+//
+// CPU memory map:
+//   - $4040-$409F: FDS audio registers.
+//   - $4100-$41FF: driver program and play flag.
+//   - $4800-$4FFF: Namco 163 audio data port.
+//   - $5000-$5015: MMC5 audio registers.
+//   - $5205-$5206: MMC5 multiplier.
+//   - $5C00-$5FF5: MMC5 ExRAM.
+//   - $5FF6-$5FFF: bank registers ($5FF6-$5FF7 for FDS tunes only).
+//   - $6000-$7FFF: 8 KB RAM (banked for FDS tunes).
+//   - $8000-$FFFF: program data, in 4 KB banks (RAM for FDS tunes), and other
+//     expansion audio registers. The interrupt vectors point to the driver.
+type NSF struct {
+	*Base
+
+	file *nsf.File
+	song int
+
+	// Program data, padded so that it's split into 4 KB banks.
+	image []byte
+	// Initial bank of each 4 KB page of $6000-$FFFF.
+	initBanks [10]int
+
+	RAM    [0x2000]byte // $6000-$7FFF
+	ExRAM  [0x400]byte  // $5C00-$5FFF
+	driver [0x80]byte   // $4100-$417F
+
+	// Play routine timing, both periods and timer are expressed in units of
+	// 1/1e9 CPU cycles so that any rate can be honored precisely.
+	playPeriod  int64
+	playTimer   int64
+	playPending bool
+
+	cycles int64 // CPU cycles since the start of the track
+
+	mul [2]uint8 // MMC5 multiplier operands
+
+	vrc6 apu.VRC6Audio
+	vrc7 apu.VRC7Audio
+	fds  apu.FDSAudio
+	mmc5 apu.MMC5Audio
+	n163 apu.Namco163Audio
+	s5b  apu.Sunsoft5BAudio
+
+	PlayFlag hwio.Device `hwio:"size=0x80,rcb,pcb"`       // $4180-$41FF
+	FDSWave  hwio.Device `hwio:"size=0x40,rcb,wcb"`       // $4040-$407F
+	FDSSound hwio.Device `hwio:"size=0x20,rcb,wcb"`       // $4080-$409F
+	N163Data hwio.Device `hwio:"size=0x800,rcb,pcb,wcb"`  // $4800-$4FFF
+	Regs     hwio.Device `hwio:"size=0x1000,rcb,pcb,wcb"` // $5000-$5FFF
+	Vectors  hwio.Device `hwio:"size=0x10,rcb,pcb,wcb"`   // $FFF0-$FFFF
+}
+
+// Driver program layout.
+const (
+	nsfDriverAddr = 0x4100
+	nsfRTIAddr    = 0x417F
+	nsfPlayFlag   = 0x4180
+)
+
+// LoadNSF sets up the synthetic cartridge playing the given NSF file, the
+// starting song being selected.
+func LoadNSF(f *nsf.File, cpu *hw.CPU, ppu *hw.PPU, opts Options) (*NSF, error) {
+	m := &NSF{
+		Base: &Base{
+			rom:  &ines.Rom{},
+			cpu:  cpu,
+			ppu:  ppu,
+			opts: opts,
+		},
+		file: f,
+	}
+
+	fdsMode := f.Expansion&nsf.FDS != 0
+	switch {
+	case f.Banked():
+		pad := int(f.LoadAddr & 0x0FFF)
+		m.image = append(make([]byte, pad), f.Data...)
+		for i, bank := range f.Banks {
+			m.initBanks[2+i] = int(bank)
+		}
+		if fdsMode {
+			m.initBanks[0] = int(f.Banks[6])
+			m.initBanks[1] = int(f.Banks[7])
+		}
+	default:
+		// Non-banked tunes are loaded as is at the load address, we make
+		// it look like a banked one, with consecutive banks.
+		base := uint16(0x8000)
+		if fdsMode {
+			base = 0x6000
+		}
+		m.image = append(make([]byte, f.LoadAddr-base), f.Data...)
+		for i := range m.initBanks {
+			m.initBanks[i] = i - 2 + int(0x8000-base)/0x1000
+		}
+		if len(m.image) > 0x10000-int(base) {
+			return nil, fmt.Errorf("program data too big: %d bytes at $%04X", len(f.Data), f.LoadAddr)
+		}
+	}
+
+	period := f.PlayPeriod()
+	if period <= 0 {
+		return nil, fmt.Errorf("invalid play period: %s", period)
+	}
+	m.playPeriod = period.Nanoseconds() * hw.NTSCCPUClock
+
+	hwio.MustInitRegs(m)
+
+	// CPU mapping.
+	cpu.Bus.MapDevice(0x4040, &m.FDSWave)
+	cpu.Bus.MapDevice(0x4080, &m.FDSSound)
+	cpu.Bus.MapMem(0x4100, &hwio.Mem{
+		Name:  "Driver",
+		Data:  m.driver[:],
+		VSize: len(m.driver),
+		Flags: hwio.MemFlagReadOnlyNoLog,
+	})
+	cpu.Bus.MapDevice(0x4180, &m.PlayFlag)
+	cpu.Bus.MapDevice(0x4800, &m.N163Data)
+	cpu.Bus.MapDevice(0x5000, &m.Regs)
+	cpu.Bus.MapMem(0x6000, &hwio.Mem{
+		Name:  "RAM",
+		Data:  m.RAM[:],
+		VSize: len(m.RAM),
+		Flags: hwio.MemFlagReadWrite,
+	})
+	cpu.Bus.MapMem(0x8000, &hwio.Mem{
+		Name:    "PRGROM",
+		Data:    m.PRGROM[:],
+		VSize:   0x7FF0,
+		Flags:   hwio.MemFlagReadOnlyNoLog,
+		WriteCb: m.writePRG,
+	})
+	cpu.Bus.MapDevice(0xFFF0, &m.Vectors)
+	cpu.SetCycleHook(m.tick)
+
+	// PPU mapping.
+	ppu.Bus.MapMem(0x0000, &hwio.Mem{
+		Name:  "CHRRAM",
+		Data:  m.CHRROM[:],
+		VSize: len(m.CHRROM),
+		Flags: hwio.MemFlagReadWrite,
+	})
+	m.SetNTMirroring(ines.VertMirroring)
+
+	m.SetTrack(f.StartSong)
+	return m, nil
+}
+
+// File returns the NSF file being played.
+func (m *NSF) File() *nsf.File { return m.file }
+
+// Track returns the song being played (0-based).
+func (m *NSF) Track() int { return m.song }
+
+// Elapsed returns the time the current song has been playing for.
+func (m *NSF) Elapsed() time.Duration {
+	return time.Duration(m.cycles * int64(time.Second) / hw.NTSCCPUClock)
+}
+
+// SetTrack prepares the cartridge to play the given song (0-based). The
+// console must then be reset for the driver to initialize the song.
+func (m *NSF) SetTrack(song int) {
+	m.song = song
+	m.driver = nsfDriver(uint8(song), m.region(), m.file.InitAddr, m.file.PlayAddr)
+
+	clear(m.RAM[:])
+	clear(m.ExRAM[:])
+	clear(m.PRGROM[:])
+	for page, bank := range m.initBanks {
+		if page >= 2 || m.fdsMode() {
+			m.selectBank(page, bank)
+		}
+	}
+
+	mixer := m.CPU().APU
+	m.vrc6 = apu.NewVRC6Audio(mixer)
+	m.vrc7 = apu.NewVRC7Audio(mixer)
+	m.fds = apu.NewFDSAudio(mixer)
+	m.mmc5 = apu.NewMMC5Audio(mixer)
+	m.n163 = apu.NewNamco163Audio(mixer, m.Options().N163MixChannels)
+	m.s5b = apu.NewSunsoft5BAudio(mixer)
+
+	m.mul = [2]uint8{}
+	m.cycles = 0
+	m.playTimer = 0
+	m.playPending = false
+
+	modMapper.InfoZ("NSF song selected").Int("song", song+1).End()
+}
+
+// Status shows the song being played, its title and the elapsed time.
+func (m *NSF) Status() string {
+	title := m.file.Track(m.song).Label
+	if title == "" {
+		title = m.file.Title
+	}
+	elapsed := m.Elapsed()
+	return fmt.Sprintf("Track %d/%d - %s - %d:%02d", m.song+1, m.file.Songs, title,
+		int(elapsed.Minutes()), int(elapsed.Seconds())%60)
+}
+
+func (m *NSF) fdsMode() bool {
+	return m.file.Expansion&nsf.FDS != 0
+}
+
+// region returns the value given to the INIT routine in the X register: 0 for
+// NTSC, 1 for PAL.
+func (m *NSF) region() uint8 {
+	if m.file.IsPAL() {
+		return 1
+	}
+	return 0
+}
+
+// selectBank copies a 4 KB bank of the program data into a page of
+// $6000-$FFFF (page 0 being $6000-$6FFF). Banks outside of the program data
+// are filled with zeroes.
+func (m *NSF) selectBank(page, bank int) {
+	var dst []byte
+	if page < 2 {
+		dst = m.RAM[page*0x1000 : (page+1)*0x1000]
+	} else {
+		dst = m.PRGROM[(page-2)*0x1000 : (page-1)*0x1000]
+	}
+
+	off := bank * 0x1000
+	n := 0
+	if off >= 0 && off < len(m.image) {
+		n = copy(dst, m.image[off:])
+	}
+	clear(dst[n:])
+}
+
+// nsfDriver assembles the driver program, which clears the RAM and resets
+// the APU before calling the INIT routine, and then calls PLAY each time the
+// play flag is raised:
+//
+//	      SEI
+//	      CLD
+//	      LDX #$FF
+//	      TXS
+//	      LDA #$00
+//	      TAX
+//	clr:  STA $0000,X ... STA $0700,X
+//	      INX
+//	      BNE clr
+//	      STA $4000 ... STA $4013
+//	      LDA #$0F
+//	      STA $4015
+//	      LDA #$40
+//	      STA $4017
+//	      LDA #song
+//	      LDX #region
+//	      JSR init
+//	wait: LDA $4180
+//	      BEQ wait
+//	      JSR play
+//	      JMP wait
+//
+// APU registers are written one by one since indexed writes would also read
+// them, which isn't allowed.
+func nsfDriver(song, region uint8, init, play uint16) [0x80]byte {
+	code := []byte{0x78, 0xD8, 0xA2, 0xFF, 0x9A, 0xA9, 0x00, 0xAA}
+	clr := len(code)
+	for page := range uint8(8) {
+		code = append(code, 0x9D, 0x00, page)
+	}
+	code = append(code, 0xE8, 0xD0, uint8(clr-len(code)-2))
+	for reg := range uint8(0x14) {
+		code = append(code, 0x8D, reg, 0x40)
+	}
+	code = append(code,
+		0xA9, 0x0F, 0x8D, 0x15, 0x40,
+		0xA9, 0x40, 0x8D, 0x17, 0x40,
+		0xA9, song, 0xA2, region,
+		0x20, uint8(init), uint8(init>>8),
+	)
+	wait := nsfDriverAddr + len(code)
+	code = append(code,
+		0xAD, nsfPlayFlag&0xFF, nsfPlayFlag>>8,
+		0xF0, 0xFB,
+		0x20, uint8(play), uint8(play>>8),
+		0x4C, uint8(wait), uint8(wait>>8),
+	)
+
+	var prg [0x80]byte
+	copy(prg[:], code)
+	prg[nsfRTIAddr&0x7F] = 0x40 // RTI
+	return prg
+}
+
+// tick is called on every CPU cycle.
+func (m *NSF) tick() {
+	m.cycles++
+	m.playTimer += 1e9
+	if m.playTimer >= m.playPeriod {
+		m.playTimer -= m.playPeriod
+		m.playPending = true
+	}
+
+	exp := m.file.Expansion
+	if exp&nsf.VRC6 != 0 {
+		m.vrc6.Clock()
+	}
+	if exp&nsf.VRC7 != 0 {
+		m.vrc7.Clock()
+	}
+	if exp&nsf.FDS != 0 {
+		m.fds.Clock()
+	}
+	if exp&nsf.MMC5 != 0 {
+		m.mmc5.Clock()
+	}
+	if exp&nsf.N163 != 0 {
+		m.n163.Clock()
+	}
+	if exp&nsf.Sunsoft5B != 0 {
+		m.s5b.Clock()
+	}
+}
+
+// Reading the play flag clears it.
+func (m *NSF) ReadPLAYFLAG(addr uint16) uint8 {
+	val := m.PeekPLAYFLAG(addr)
+	m.playPending = false
+	return val
+}
+
+func (m *NSF) PeekPLAYFLAG(addr uint16) uint8 {
+	if m.playPending {
+		return 1
+	}
+	return 0
+}
+
+// The NMI and IRQ vectors point to a RTI instruction, the reset vector to
+// the driver.
+func (m *NSF) ReadVECTORS(addr uint16) uint8 { return m.PeekVECTORS(addr) }
+func (m *NSF) PeekVECTORS(addr uint16) uint8 {
+	if addr < 0xFFFA {
+		return m.PRGROM[addr&0x7FFF]
+	}
+	vec := uint16(nsfRTIAddr)
+	if addr&0xFFFE == 0xFFFC {
+		vec = nsfDriverAddr
+	}
+	return uint8(vec >> (8 * (addr & 1)))
+}
+
+func (m *NSF) WriteVECTORS(addr uint16, val uint8) { m.writePRG(addr, val) }
+
+func (m *NSF) ReadFDSWAVE(addr uint16) uint8        { return m.ReadFDSSOUND(addr) }
+func (m *NSF) WriteFDSWAVE(addr uint16, val uint8)  { m.WriteFDSSOUND(addr, val) }
+func (m *NSF) WriteFDSSOUND(addr uint16, val uint8) { m.fds.WriteRegister(addr, val) }
+func (m *NSF) ReadFDSSOUND(addr uint16) uint8 {
+	return m.fds.ReadRegister(addr, uint8(addr>>8))
+}
+
+func (m *NSF) ReadN163DATA(addr uint16) uint8       { return m.n163.ReadData() }
+func (m *NSF) PeekN163DATA(addr uint16) uint8       { return m.n163.PeekData() }
+func (m *NSF) WriteN163DATA(addr uint16, val uint8) { m.n163.WriteData(val) }
+
+func (m *NSF) ReadREGS(addr uint16) uint8 { return m.PeekREGS(addr) }
+func (m *NSF) PeekREGS(addr uint16) uint8 {
+	switch {
+	case addr == 0x5010 || addr == 0x5015:
+		return m.mmc5.ReadRegister(addr)
+	case addr == 0x5205:
+		return uint8(uint16(m.mul[0]) * uint16(m.mul[1]))
+	case addr == 0x5206:
+		return uint8(uint16(m.mul[0]) * uint16(m.mul[1]) >> 8)
+	case addr >= 0x5C00 && addr <= 0x5FF5:
+		return m.ExRAM[addr&0x3FF]
+	}
+	return uint8(addr >> 8)
+}
+
+func (m *NSF) WriteREGS(addr uint16, val uint8) {
+	switch {
+	case addr <= 0x5015:
+		m.mmc5.WriteRegister(addr, val)
+	case addr == 0x5205 || addr == 0x5206:
+		m.mul[addr-0x5205] = val
+	case addr >= 0x5C00 && addr <= 0x5FF5:
+		m.ExRAM[addr&0x3FF] = val
+	case addr >= 0x5FF8 && m.file.Banked():
+		m.selectBank(int(addr-0x5FF8)+2, int(val))
+	case addr >= 0x5FF6 && m.fdsMode() && m.file.Banked():
+		m.selectBank(int(addr-0x5FF6), int(val))
+	}
+}
+
+// writePRG handles writes to $8000-$FFFF, which hold the registers of most
+// expansion audio chips. For FDS tunes, that's also RAM.
+func (m *NSF) writePRG(addr uint16, val uint8) {
+	if m.fdsMode() {
+		m.PRGROM[addr&0x7FFF] = val
+	}
+
+	exp := m.file.Expansion
+	switch {
+	case exp&nsf.VRC7 != 0 && addr == 0x9010:
+		m.vrc7.SelectRegister(val)
+	case exp&nsf.VRC7 != 0 && addr == 0x9030:
+		m.vrc7.WriteRegister(val)
+	case exp&nsf.VRC6 != 0 && addr >= 0x9000 && addr <= 0xB002:
+		m.vrc6.WriteRegister(addr, val)
+	}
+
+	if exp&nsf.Sunsoft5B != 0 {
+		switch addr & 0xE000 {
+		case 0xC000:
+			m.s5b.SelectRegister(val)
+		case 0xE000:
+			m.s5b.WriteRegister(val)
+		}
+	}
+	if exp&nsf.N163 != 0 && addr >= 0xF800 {
+		m.n163.WriteAddress(val)
+	}
+}
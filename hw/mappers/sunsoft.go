@@ -0,0 +1,106 @@
+package mappers
+
+import (
+	"nestor/hw/hwio"
+	"nestor/ines"
+)
+
+// Sunsoft-1 and Sunsoft-2 discrete boards.
+var (
+	Sunsoft1 = MapperDesc{
+		Name: "Sunsoft-1",
+		Load: loadSunsoft1,
+	}
+	Sunsoft2 = MapperDesc{
+		Name:            "Sunsoft-2 (Sunsoft-3 board)",
+		Load:            loadSunsoft2,
+		HasBusConflicts: alwaysBusConflicts,
+	}
+	Sunsoft2R = MapperDesc{
+		Name:            "Sunsoft-2 (Sunsoft-3R board)",
+		Load:            loadSunsoft2R,
+		HasBusConflicts: alwaysBusConflicts,
+	}
+)
+
+// sunsoft1 has its register at $6000-$7FFF.
+type sunsoft1 struct {
+	*Base
+
+	Latch hwio.Device `hwio:"size=0x2000,wcb"`
+}
+
+func (m *sunsoft1) WriteLATCH(_ uint16, val uint8) {
+	// 7  bit  0
+	// ---- ----
+	// xHHH xLLL
+	//  |||  |||
+	//  |||  +++- Select 4 KB CHR ROM bank for PPU $0000-$0FFF
+	//  +++------ Select 4 KB CHR ROM bank for PPU $1000-$1FFF
+	//
+	// The upper bank always has its most significant bit set, the chip
+	// drives it high.
+	m.SelectCHRROMPage4KB(0, int(val&0x07))
+	m.SelectCHRROMPage4KB(1, int(val>>4&0x07|0x04))
+}
+
+// Mapper 184.
+func loadSunsoft1(b *Base) error {
+	m := &sunsoft1{Base: b}
+	hwio.MustInitRegs(m)
+	b.Init(nil)
+
+	b.CPU().Bus.Unmap(0x6000, 0x7FFF)
+	b.CPU().Bus.MapDevice(0x6000, &m.Latch)
+
+	b.SetNTMirroring(b.Rom().Mirroring())
+	b.SelectPRGPage32KB(0)
+	m.WriteLATCH(0x6000, 0)
+	return nil
+}
+
+// Mapper 89.
+func loadSunsoft2(b *Base) error {
+	b.Init(func(_ uint16, val uint8) {
+		// 7  bit  0
+		// ---- ----
+		// CPPP MCCC
+		// |||| ||||
+		// |||| |+++- Select 8 KB CHR ROM bank for PPU $0000-$1FFF (low bits)
+		// |||| +---- Select 1 KB VRAM page for all 4 nametables
+		// |+++------ Select 16 KB PRG ROM bank for CPU $8000-$BFFF
+		// +--------- Select 8 KB CHR ROM bank (high bit)
+		b.SelectPRGPage16KB(0, int(val>>4&0x07))
+		b.SelectCHRROMPage8KB(int(val>>4&0x08 | val&0x07))
+		if val&0x08 != 0 {
+			b.SetNTMirroring(ines.OnlyBScreen)
+		} else {
+			b.SetNTMirroring(ines.OnlyAScreen)
+		}
+	})
+
+	b.SetNTMirroring(ines.OnlyAScreen)
+	b.SelectCHRROMPage8KB(0)
+	b.SelectPRGPage16KB(0, 0)
+	b.SelectPRGPage16KB(1, -1)
+	return nil
+}
+
+// Mapper 93.
+func loadSunsoft2R(b *Base) error {
+	b.Init(func(_ uint16, val uint8) {
+		// 7  bit  0
+		// ---- ----
+		// xPPP xxxE
+		//  |||    |
+		//  |||    +- CHR RAM enable (not emulated, always enabled)
+		//  +++------ Select 16 KB PRG ROM bank for CPU $8000-$BFFF
+		b.SelectPRGPage16KB(0, int(val>>4&0x07))
+	})
+
+	b.SetNTMirroring(b.Rom().Mirroring())
+	b.SelectCHRROMPage8KB(0)
+	b.SelectPRGPage16KB(0, 0)
+	b.SelectPRGPage16KB(1, -1)
+	return nil
+}
@@ -0,0 +1,204 @@
+package mappers
+
+import (
+	"nestor/hw/hwdefs"
+	"nestor/hw/hwio"
+	"nestor/ines"
+)
+
+var (
+	Sunsoft3 = MapperDesc{
+		Name: "Sunsoft-3",
+		Load: loadSunsoft3,
+	}
+	Sunsoft4 = MapperDesc{
+		Name: "Sunsoft-4",
+		Load: loadSunsoft4,
+	}
+)
+
+// sunsoftMirroring decodes the mirroring register of Sunsoft-3 and Sunsoft-4.
+var sunsoftMirroring = [4]ines.NTMirroring{
+	ines.VertMirroring,
+	ines.HorzMirroring,
+	ines.OnlyAScreen,
+	ines.OnlyBScreen,
+}
+
+// sunsoft3 is mapper 67, it has 2 KB CHR banks and a 16-bit CPU cycle IRQ
+// counter.
+type sunsoft3 struct {
+	*Base
+
+	irqEnable bool
+	irqToggle bool // next write to $C800 is the low byte
+	counter   uint16
+}
+
+func (m *sunsoft3) writeReg(addr uint16, val uint8) {
+	switch addr & 0xF800 {
+	case 0x8000:
+		// IRQ acknowledge.
+		m.CPU().ClearIRQSource(hwdefs.External)
+	case 0x8800, 0x9800, 0xA800, 0xB800:
+		// Select 2 KB CHR ROM bank for PPU $0000, $0800, $1000 or $1800.
+		page := uint32(addr>>12&0x03) * 2
+		m.SelectCHRROMPage1KB(page, int(val)<<1)
+		m.SelectCHRROMPage1KB(page+1, int(val)<<1|1)
+	case 0xC800:
+		// IRQ counter, high byte first then low byte.
+		if m.irqToggle {
+			m.counter = m.counter&0xFF00 | uint16(val)
+		} else {
+			m.counter = m.counter&0x00FF | uint16(val)<<8
+		}
+		m.irqToggle = !m.irqToggle
+	case 0xD800:
+		// 7  bit  0
+		// ---- ----
+		// xxxE xxxx
+		//    |
+		//    +------ IRQ enable, writing also resets the $C800 write toggle.
+		m.irqEnable = val&0x10 != 0
+		m.irqToggle = false
+	case 0xE800:
+		m.SetNTMirroring(sunsoftMirroring[val&0x03])
+	case 0xF800:
+		m.SelectPRGPage16KB(0, int(val))
+	}
+}
+
+// tick is called on every CPU cycle.
+func (m *sunsoft3) tick() {
+	if !m.irqEnable {
+		return
+	}
+	// The IRQ is triggered when the counter wraps from $0000 to $FFFF, the
+	// counter is then paused.
+	m.counter--
+	if m.counter == 0xFFFF {
+		m.irqEnable = false
+		m.CPU().SetIRQSource(hwdefs.External)
+	}
+}
+
+func loadSunsoft3(b *Base) error {
+	m := &sunsoft3{Base: b}
+	b.Init(m.writeReg)
+	b.CPU().SetCycleHook(m.tick)
+
+	b.SetNTMirroring(b.Rom().Mirroring())
+	b.SelectPRGPage16KB(0, 0)
+	b.SelectPRGPage16KB(1, -1)
+	b.SelectCHRROMPage8KB(0)
+	return nil
+}
+
+// sunsoft4 is mapper 68. Its nametables can be mapped either from CIRAM or
+// from the last 128 KB of CHR ROM.
+type sunsoft4 struct {
+	*Base
+
+	ntbank    [2]uint8 // CHR ROM 1 KB banks used as nametables
+	ntm       uint8    // mirroring
+	chrNT     bool     // nametables from CHR ROM
+	ramEnable bool
+
+	// $6000-$7FFF: PRG-RAM, which can be disabled.
+	PRG6000 hwio.Device `hwio:"size=0x2000,rcb,wcb"`
+}
+
+func (m *sunsoft4) ReadPRG6000(addr uint16) uint8 {
+	if !m.ramEnable {
+		return uint8((0x6000 | addr) >> 8) // open bus
+	}
+	return m.PRGRAM.Data[addr&0x1FFF]
+}
+
+func (m *sunsoft4) WritePRG6000(addr uint16, val uint8) {
+	if m.ramEnable {
+		m.PRGRAM.Data[addr&0x1FFF] = val
+	}
+}
+
+func (m *sunsoft4) writeReg(addr uint16, val uint8) {
+	switch addr & 0xF000 {
+	case 0x8000, 0x9000, 0xA000, 0xB000:
+		// Select 2 KB CHR ROM bank for PPU $0000, $0800, $1000 or $1800.
+		page := uint32(addr>>12&0x03) * 2
+		m.SelectCHRROMPage1KB(page, int(val)<<1)
+		m.SelectCHRROMPage1KB(page+1, int(val)<<1|1)
+	case 0xC000, 0xD000:
+		// Select 1 KB CHR ROM bank used as nametable A or B. The chip drives
+		// the most significant bit high.
+		m.ntbank[addr>>12&0x01] = val | 0x80
+		m.remapNT()
+	case 0xE000:
+		// 7  bit  0
+		// ---- ----
+		// xxxN xxMM
+		//    |   ||
+		//    |   ++- Mirroring (0: vertical, 1: horizontal,
+		//    |                  2: one-screen A, 3: one-screen B)
+		//    +------ Nametables source (0: CIRAM, 1: CHR ROM)
+		m.ntm = val & 0x03
+		m.chrNT = val&0x10 != 0
+		m.remapNT()
+	case 0xF000:
+		// 7  bit  0
+		// ---- ----
+		// xxxE PPPP
+		//    | ||||
+		//    | ++++- Select 16 KB PRG ROM bank for CPU $8000-$BFFF
+		//    +------ PRG RAM enable
+		m.SelectPRGPage16KB(0, int(val&0x0F))
+		m.ramEnable = val&0x10 != 0
+	}
+}
+
+func (m *sunsoft4) remapNT() {
+	if !m.chrNT {
+		m.SetNTMirroring(sunsoftMirroring[m.ntm])
+		return
+	}
+
+	// The 2 nametable banks take the place of CIRAM pages A and B.
+	A := m.CHRROMNametable(int(m.ntbank[0]))
+	B := m.CHRROMNametable(int(m.ntbank[1]))
+	switch m.ntm {
+	case 0:
+		m.SetNametables(A, B, A, B)
+	case 1:
+		m.SetNametables(A, A, B, B)
+	case 2:
+		m.SetNametables(A, A, A, A)
+	case 3:
+		m.SetNametables(B, B, B, B)
+	}
+}
+
+func loadSunsoft4(b *Base) error {
+	m := &sunsoft4{
+		Base:   b,
+		ntbank: [2]uint8{0x80, 0x80},
+	}
+	hwio.MustInitRegs(m)
+	b.Init(m.writeReg)
+
+	b.CPU().Bus.Unmap(0x6000, 0x7FFF)
+	b.CPU().Bus.MapDevice(0x6000, &m.PRG6000)
+	if b.Rom().HasPersistence() {
+		b.Persist(m.PRGRAM.Data)
+	}
+
+	m.ntm = 1
+	if b.Rom().Mirroring() == ines.VertMirroring {
+		m.ntm = 0
+	}
+	m.remapNT()
+
+	b.SelectPRGPage16KB(0, 0)
+	b.SelectPRGPage16KB(1, -1)
+	b.SelectCHRROMPage8KB(0)
+	return nil
+}
@@ -0,0 +1,65 @@
+package mappers
+
+import (
+	"testing"
+
+	"nestor/hw/hwdefs"
+	"nestor/ines"
+)
+
+func TestSunsoft3(t *testing.T) {
+	c := loadTestRom(t, 67, 0, 128, 128)
+	c.run(t, []busStep{
+		{desc: "screen B", write: []busVal{{0xE800, 0x03}}, mirror: ines.OnlyBScreen},
+	})
+
+	// IRQ fires when the counter wraps, after 1001 CPU cycles.
+	bus := c.cpu.Bus
+	bus.Write8(0xD800, 0x00)
+	bus.Write8(0xC800, 0x03)
+	bus.Write8(0xC800, 0xE8)
+	bus.Write8(0xD800, 0x10)
+
+	if n := c.runUntilIRQ(2000); n < 996 || n > 1006 {
+		t.Fatalf("IRQ after %d CPU cycles, want 1001", n)
+	}
+
+	bus.Write8(0x8000, 0)
+	if c.cpu.HasIRQSource(hwdefs.External) {
+		t.Fatalf("IRQ not acknowledged")
+	}
+	// The counter is paused after an IRQ.
+	if n := c.runUntilIRQ(100000); n < 100000 {
+		t.Fatalf("unexpected IRQ after %d CPU cycles", n)
+	}
+}
+
+func TestSunsoft4(t *testing.T) {
+	c := loadTestRom(t, 68, 0, 128, 256)
+	c.run(t, []busStep{
+		{desc: "PRG-RAM disabled", write: []busVal{{0x6000, 0x42}}, cpu: []busVal{{0x6000, 0x60}}},
+		{desc: "PRG-RAM enabled", write: []busVal{{0xF000, 0x12}, {0x6000, 0x42}}, cpu: []busVal{{0x6000, 0x42}}},
+		{desc: "CIRAM nametables", write: []busVal{{0xE000, 0x00}}, mirror: ines.VertMirroring},
+		{
+			desc:  "CHR-ROM nametables, from the upper 128 KB",
+			write: []busVal{{0xC000, 0x05}, {0xD000, 0x06}, {0xE000, 0x11}},
+			ppu:   []busVal{{0x2000, 0x85}, {0x2400, 0x85}, {0x2800, 0x86}, {0x2C00, 0x86}, {0x3000, 0x85}},
+		},
+	})
+
+	c.ppu.Bus.Write8(0x2000, 0xFF)
+	c.run(t, []busStep{
+		{desc: "CHR-ROM nametables are read-only", ppu: []busVal{{0x2000, 0x85}}},
+		{desc: "one-screen B", write: []busVal{{0xE000, 0x13}}, ppu: []busVal{{0x2000, 0x86}}},
+		{desc: "CIRAM preserved", write: []busVal{{0xE000, 0x00}}, ppu: []busVal{{0x2000, 3}, {0x2400, 4}}},
+	})
+
+	t.Run("without CHR-ROM", func(t *testing.T) {
+		// Enabling CHR-ROM nametables on a CHR-RAM cart falls back to CIRAM.
+		loadTestRom(t, 68, 0, 128, 0).run(t, []busStep{{
+			desc:   "CIRAM",
+			write:  []busVal{{0xC000, 0x00}, {0xD000, 0x01}, {0xE000, 0x10}},
+			mirror: ines.VertMirroring,
+		}})
+	})
+}
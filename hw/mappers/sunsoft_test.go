@@ -0,0 +1,31 @@
+package mappers
+
+import (
+	"testing"
+
+	"nestor/ines"
+)
+
+func TestSunsoftDiscrete(t *testing.T) {
+	t.Run("Sunsoft-1", func(t *testing.T) {
+		loadTestRom(t, 184, 0, 32, 32).run(t, []busStep{{
+			desc:  "upper CHR bank has bit 2 set",
+			write: []busVal{{0x6000, 0x21}},
+			ppu:   []busVal{{0x0000, 1 * 4}, {0x1000, 6 * 4}},
+		}})
+	})
+
+	t.Run("Sunsoft-2", func(t *testing.T) {
+		loadTestRom(t, 89, 0, 128, 128).run(t, []busStep{
+			{
+				desc:   "banks and mirroring",
+				write:  []busVal{{0xFC00, 0x3A}},
+				cpu:    []busVal{{0x8000, 3 * 16}},
+				ppu:    []busVal{{0x0000, 2 * 8}},
+				mirror: ines.OnlyBScreen,
+			},
+			// $FC00 holds $7F, the CHR bank high bit (bit 7) is lost.
+			{desc: "bus conflict", write: []busVal{{0xFC00, 0xFF}}, ppu: []busVal{{0x0000, 7 * 8}}},
+		})
+	})
+}
@@ -0,0 +1,150 @@
+package mappers
+
+import (
+	"nestor/hw/hwdefs"
+	"nestor/ines"
+)
+
+var (
+	TaitoTC0190 = MapperDesc{
+		Name: "Taito TC0190",
+		Load: loadTaitoTC0190,
+	}
+	TaitoTC0690 = MapperDesc{
+		Name: "Taito TC0690",
+		Load: loadTaitoTC0690,
+	}
+)
+
+// taito handles the Taito TC0190 (mapper 33) and its successor the TC0690
+// (mapper 48), which adds a scanline IRQ counter and moves the mirroring
+// control to its own register.
+type taito struct {
+	*Base
+
+	tc0690 bool
+
+	// IRQ, TC0690 only. The counter works as the MMC3 one, clocked by PPU A12
+	// rising edges, but the IRQ is only asserted a few CPU cycles after the
+	// counter reaches 0.
+	irqEnable bool
+	irqReload bool // reload the counter on next clock
+	irqLatch  uint8
+	irqDelay  uint8 // CPU cycles left before asserting the IRQ
+	counter   uint8
+}
+
+// taitoIRQDelay is the number of CPU cycles between the counter reaching 0 and
+// the IRQ being asserted.
+const taitoIRQDelay = 4
+
+func (m *taito) writeReg(addr uint16, val uint8) {
+	switch addr & 0xE003 {
+	case 0x8000:
+		// 7  bit  0
+		// ---- ----
+		// xMPP PPPP
+		//  ||| ||||
+		//  |++-++++- Select 8 KB PRG ROM bank for CPU $8000-$9FFF
+		//  +-------- Mirroring (0: vertical, 1: horizontal), TC0190 only
+		m.SelectPRGPage8KB(0, int(val&0x3F))
+		if !m.tc0690 {
+			m.setMirroring(val)
+		}
+	case 0x8001:
+		m.SelectPRGPage8KB(1, int(val&0x3F))
+	case 0x8002, 0x8003:
+		// Select 2 KB CHR ROM bank for PPU $0000-$07FF or $0800-$0FFF.
+		page := 2 * uint32(addr&0x01)
+		m.SelectCHRROMPage1KB(page, int(val)<<1)
+		m.SelectCHRROMPage1KB(page+1, int(val)<<1|1)
+	case 0xA000, 0xA001, 0xA002, 0xA003:
+		// Select 1 KB CHR ROM bank for PPU $1000-$1FFF.
+		m.SelectCHRROMPage1KB(4+uint32(addr&0x03), int(val))
+	}
+
+	if !m.tc0690 {
+		return
+	}
+
+	switch addr & 0xE003 {
+	case 0xC000:
+		// The counter reload value is written inverted.
+		m.irqLatch = val ^ 0xFF
+	case 0xC001:
+		m.counter = 0
+		m.irqReload = true
+	case 0xC002:
+		m.irqEnable = true
+	case 0xC003:
+		m.irqEnable = false
+		m.irqDelay = 0
+		m.CPU().ClearIRQSource(hwdefs.External)
+	case 0xE000:
+		m.setMirroring(val)
+	}
+}
+
+func (m *taito) setMirroring(val uint8) {
+	if val&0x40 != 0 {
+		m.SetNTMirroring(ines.HorzMirroring)
+	} else {
+		m.SetNTMirroring(ines.VertMirroring)
+	}
+}
+
+// clockCounter is called on PPU A12 rising edges.
+func (m *taito) clockCounter() {
+	if m.counter == 0 || m.irqReload {
+		m.counter = m.irqLatch
+		m.irqReload = false
+	} else {
+		m.counter--
+	}
+
+	if m.counter == 0 && m.irqEnable {
+		m.irqDelay = taitoIRQDelay
+	}
+}
+
+// tick is called on every CPU cycle.
+func (m *taito) tick() {
+	if m.irqDelay == 0 {
+		return
+	}
+	m.irqDelay--
+	if m.irqDelay == 0 {
+		m.CPU().SetIRQSource(hwdefs.External)
+	}
+}
+
+func loadTaitoTC0190(b *Base) error {
+	return loadTaito(b, false)
+}
+
+func loadTaitoTC0690(b *Base) error {
+	return loadTaito(b, true)
+}
+
+func loadTaito(b *Base, tc0690 bool) error {
+	m := &taito{
+		Base:   b,
+		tc0690: tc0690,
+	}
+	b.Init(m.writeReg)
+
+	if tc0690 {
+		b.PPU().SetA12Hook(m.clockCounter)
+		b.CPU().SetCycleHook(m.tick)
+	}
+
+	b.SetNTMirroring(b.Rom().Mirroring())
+	m.SelectPRGPage8KB(0, 0)
+	m.SelectPRGPage8KB(1, 1)
+	m.SelectPRGPage8KB(2, -2)
+	m.SelectPRGPage8KB(3, -1)
+	for i := range 8 {
+		m.SelectCHRROMPage1KB(uint32(i), i)
+	}
+	return nil
+}
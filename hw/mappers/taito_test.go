@@ -0,0 +1,69 @@
+package mappers
+
+import (
+	"testing"
+
+	"nestor/hw/hwdefs"
+	"nestor/ines"
+)
+
+func TestTaitoTC0190(t *testing.T) {
+	loadTestRom(t, 33, 0, 128, 256).run(t, []busStep{
+		{desc: "horizontal", write: []busVal{{0x8000, 0x45}}, cpu: []busVal{{0x8000, 5 * 8}}, mirror: ines.HorzMirroring},
+		{desc: "vertical", write: []busVal{{0x8000, 0x05}}, mirror: ines.VertMirroring},
+		{
+			desc:  "2 KB banks at $0000 and $0800, 1 KB banks at $1000-$1FFF",
+			write: []busVal{{0x8002, 0x03}, {0x8003, 0x10}, {0xA000, 0x30}, {0xA003, 0x33}},
+			ppu: []busVal{
+				{0x0000, 0x06}, {0x0400, 0x07},
+				{0x0800, 0x20}, {0x0C00, 0x21},
+				{0x1000, 0x30}, {0x1C00, 0x33},
+			},
+		},
+	})
+}
+
+func TestTaitoTC0690(t *testing.T) {
+	c := loadTestRom(t, 48, 0, 128, 256)
+	c.run(t, []busStep{
+		{
+			desc:   "PRG register doesn't control mirroring",
+			write:  []busVal{{0xE000, 0x00}, {0x8000, 0x45}},
+			cpu:    []busVal{{0x8000, 5 * 8}},
+			mirror: ines.VertMirroring,
+		},
+		{desc: "horizontal", write: []busVal{{0xE000, 0x40}}, mirror: ines.HorzMirroring},
+	})
+
+	// Wait for vblank, then enable rendering with sprites fetched from $1000.
+	for c.ppu.Scanline != 241 {
+		c.cpu.Run(1)
+	}
+	bus := c.cpu.Bus
+	bus.Write8(0x2000, 0x08)
+	bus.Write8(0x2001, 0x18)
+
+	// The counter is reloaded with 10 (written inverted) on the pre-render
+	// scanline, then reaches 0 on scanline 9.
+	bus.Write8(0xC000, 10^0xFF)
+	bus.Write8(0xC001, 0)
+	bus.Write8(0xC002, 0)
+
+	c.runUntilIRQ(29781)
+	if !c.cpu.HasIRQSource(hwdefs.External) {
+		t.Fatalf("no IRQ")
+	}
+	// The counter is clocked at cycle 257, the IRQ is delayed by 4 CPU cycles.
+	if sl, cyc := c.ppu.Scanline, c.ppu.Cycle; sl != 9 || cyc < 257+12 || cyc > 257+24 {
+		t.Errorf("IRQ at scanline %d cycle %d, want scanline 9 cycle ~269", sl, cyc)
+	}
+
+	// Acknowledge and disable.
+	bus.Write8(0xC003, 0)
+	if c.cpu.HasIRQSource(hwdefs.External) {
+		t.Fatalf("IRQ not acknowledged")
+	}
+	if n := c.runUntilIRQ(29781); n < 29781 {
+		t.Fatalf("unexpected IRQ after %d CPU cycles", n)
+	}
+}
@@ -0,0 +1,103 @@
+package mappers
+
+import (
+	"nestor/hw"
+	"nestor/ines"
+)
+
+// UNROM512 is the RetroUSB UNROM-512 homebrew board. Boards with the battery
+// flag set have a self-flashable PRG-ROM, used for saves, and no bus conflicts.
+var UNROM512 = MapperDesc{
+	Name:            "UNROM-512",
+	Load:            loadUNROM512,
+	HasBusConflicts: func(b *Base) bool { return !b.Rom().HasPersistence() },
+}
+
+type unrom512 struct {
+	*Base
+
+	flash  *hw.Flash // nil if not self-flashable
+	chrram [32 * KB]byte
+
+	prgbank uint8
+	chrbank uint8
+
+	onescreen bool // mirroring is controlled by the register
+}
+
+func (m *unrom512) writeReg(addr uint16, val uint8) {
+	if m.flash != nil && addr < 0xC000 {
+		// Flashable boards have their register at $C000-$FFFF, writes to
+		// $8000-$BFFF go to the flash.
+		flashaddr := uint32(m.prgbank)<<14 | uint32(addr&0x3FFF)
+		if m.flash.Write(flashaddr, val) {
+			m.remapPRG()
+		}
+		return
+	}
+
+	// 7  bit  0
+	// ---- ----
+	// MCCP PPPP
+	// |||| ||||
+	// |||+-++++- Select 16 KB PRG ROM bank for CPU $8000-$BFFF
+	// |++------- Select 8 KB CHR RAM bank for PPU $0000-$1FFF
+	// +--------- Select 1 KB VRAM page for all 4 nametables (one-screen boards)
+	m.prgbank = val & 0x1F
+	m.remapPRG()
+
+	if chrbank := val >> 5 & 0x03; chrbank != m.chrbank {
+		m.chrbank = chrbank
+		m.remapCHR()
+	}
+
+	if m.onescreen {
+		if val&0x80 != 0 {
+			m.SetNTMirroring(ines.OnlyBScreen)
+		} else {
+			m.SetNTMirroring(ines.OnlyAScreen)
+		}
+	}
+}
+
+func (m *unrom512) remapPRG() {
+	m.SelectPRGPage16KB(0, int(m.prgbank))
+	m.SelectPRGPage16KB(1, -1)
+}
+
+func (m *unrom512) remapCHR() {
+	start := 8 * KB * int(m.chrbank)
+	m.PPU().Bus.Unmap(0x0000, 0x1FFF)
+	m.PPU().Bus.MapMemorySlice(0x0000, 0x1FFF, m.chrram[start:start+8*KB], false)
+}
+
+func loadUNROM512(b *Base) error {
+	m := &unrom512{Base: b}
+	b.Init(m.writeReg)
+
+	if b.Rom().HasPersistence() {
+		m.flash = hw.NewFlash(b.Rom().PRGROM)
+		b.PersistFlash(m.flash)
+	}
+
+	// The header mirroring and alternative nametables bits select between
+	// horizontal, vertical, one-screen (switchable) and four-screen
+	// mirroring. In the later case, the last 8 KB of CHR RAM are used as
+	// nametables.
+	switch {
+	case b.Rom().HasAltNametables() && b.Rom().Mirroring() == ines.VertMirroring:
+		for i := range 4 {
+			start := 24*KB + i*KB
+			b.SetNametable(i, RAMNametable(m.chrram[start:]))
+		}
+	case b.Rom().HasAltNametables():
+		m.onescreen = true
+		b.SetNTMirroring(ines.OnlyAScreen)
+	default:
+		b.SetNTMirroring(b.Rom().Mirroring())
+	}
+
+	m.remapPRG()
+	m.remapCHR()
+	return nil
+}
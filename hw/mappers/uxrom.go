@@ -1,29 +1,42 @@
 package mappers
 
 var UxROM = MapperDesc{
-	Name:         "UxROM",
-	Load:         loadUxROM,
-	PRGROMbanksz: 0x4000,
-	CHRROMbanksz: 0x2000,
+	Name:            "UxROM",
+	Load:            loadUxROM,
+	PRGROMbanksz:    0x4000,
+	CHRROMbanksz:    0x2000,
+	HasBusConflicts: func(b *Base) bool { return b.Rom().SubMapper() == 2 },
+}
+
+// UN1ROM only differs from UNROM in the register bits selecting the bank.
+var UN1ROM = MapperDesc{
+	Name:            "UN1ROM",
+	Load:            loadUN1ROM,
+	PRGROMbanksz:    0x4000,
+	CHRROMbanksz:    0x2000,
+	HasBusConflicts: alwaysBusConflicts,
+}
+
+// UNROM180 is an UNROM variant, used by Crazy Climber, where the first PRG
+// bank is fixed at $8000-$BFFF and the switchable one is at $C000-$FFFF.
+var UNROM180 = MapperDesc{
+	Name:            "UNROM (74HC08)",
+	Load:            loadUNROM180,
+	PRGROMbanksz:    0x4000,
+	CHRROMbanksz:    0x2000,
+	HasBusConflicts: alwaysBusConflicts,
 }
 
 type uxrom struct {
-	*base
+	*Base
 
-	prgbank      uint32
-	bankmask     uint8
-	busConflicts bool
+	prgbank  uint32
+	bankmask uint8
 }
 
 func (m *uxrom) WritePRGROM(addr uint16, val uint8) {
-	old := val
-	if m.busConflicts {
-		old = m.cpu.Bus.Peek8(addr)
-		val &= old
-	}
 	modMapper.DebugZ("WritePRGROM").
 		Hex16("addr", addr).
-		Hex8("old", old).
 		Hex8("val", val).
 		Hex8("bank", val&m.bankmask).
 		Bool("conflicts", m.busConflicts).
@@ -36,20 +49,53 @@ func (m *uxrom) WritePRGROM(addr uint16, val uint8) {
 	//      ++++- Select 16 KB PRG ROM bank for CPU $8000-$BFFF
 	//            (UNROM uses bits 2-0; UOROM uses bits 3-0)
 	m.prgbank = uint32(val & m.bankmask)
-	m.selectPRGPage16KB(0, int(m.prgbank))
+	m.SelectPRGPage16KB(0, int(m.prgbank))
 }
 
-func loadUxROM(b *base) error {
+func loadUxROM(b *Base) error {
 	uxrom := &uxrom{
-		base:         b,
-		busConflicts: b.rom.SubMapper() == 2,
-		bankmask:     uint8(len(b.rom.PRGROM)>>14) - 1,
+		Base:     b,
+		bankmask: uint8(len(b.Rom().PRGROM)>>14) - 1,
 	}
-	b.init(uxrom.WritePRGROM)
+	b.Init(uxrom.WritePRGROM)
+
+	b.SetNTMirroring(b.Rom().Mirroring())
+	b.SelectCHRROMPage8KB(0)
+	b.SelectPRGPage16KB(0, 0)
+	b.SelectPRGPage16KB(1, -1)
+	return nil
+}
+
+func loadUN1ROM(b *Base) error {
+	b.Init(func(_ uint16, val uint8) {
+		// 7  bit  0
+		// ---- ----
+		// xxxP PPxx
+		//    | ||
+		//    +-++--- Select 16 KB PRG ROM bank for CPU $8000-$BFFF
+		b.SelectPRGPage16KB(0, int(val>>2)&0x07)
+	})
+
+	b.SetNTMirroring(b.Rom().Mirroring())
+	b.SelectCHRROMPage8KB(0)
+	b.SelectPRGPage16KB(0, 0)
+	b.SelectPRGPage16KB(1, -1)
+	return nil
+}
+
+func loadUNROM180(b *Base) error {
+	b.Init(func(_ uint16, val uint8) {
+		// 7  bit  0
+		// ---- ----
+		// xxxx xPPP
+		//       |||
+		//       +++- Select 16 KB PRG ROM bank for CPU $C000-$FFFF
+		b.SelectPRGPage16KB(1, int(val&0x07))
+	})
 
-	b.setNTMirroring(b.rom.Mirroring())
-	b.selectCHRROMPage8KB(0)
-	b.selectPRGPage16KB(0, 0)
-	b.selectPRGPage16KB(1, -1)
+	b.SetNTMirroring(b.Rom().Mirroring())
+	b.SelectCHRROMPage8KB(0)
+	b.SelectPRGPage16KB(0, 0)
+	b.SelectPRGPage16KB(1, 0)
 	return nil
 }
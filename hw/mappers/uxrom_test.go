@@ -0,0 +1,21 @@
+package mappers
+
+import "testing"
+
+func TestUN1ROM(t *testing.T) {
+	loadTestRom(t, 94, 0, 128, 0).run(t, []busStep{
+		{desc: "PRG bank", write: []busVal{{0xFC00, 0x0C}}, cpu: []busVal{{0x8000, 3 * 16}}},
+		{desc: "bus conflict", write: []busVal{{0x8001, 0x0C}}, cpu: []busVal{{0x8000, 0}}},
+	})
+}
+
+func TestUNROM180(t *testing.T) {
+	loadTestRom(t, 180, 0, 128, 0).run(t, []busStep{
+		{
+			desc:  "first bank fixed at $8000",
+			write: []busVal{{0xBC00, 0x05}},
+			cpu:   []busVal{{0x8000, 0}, {0xC000, 5 * 16}},
+		},
+		{desc: "bus conflict", write: []busVal{{0x8001, 0x05}}, cpu: []busVal{{0xC000, 0}}},
+	})
+}
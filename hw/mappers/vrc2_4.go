@@ -0,0 +1,261 @@
+package mappers
+
+import (
+	"nestor/hw/hwio"
+	"nestor/ines"
+)
+
+var (
+	VRC4 = MapperDesc{
+		Name: "VRC4",
+		Load: loadVRC2_4,
+	}
+	VRC2 = MapperDesc{
+		Name: "VRC2",
+		Load: loadVRC2_4,
+	}
+	VRC2VRC4 = MapperDesc{
+		Name: "VRC2/VRC4",
+		Load: loadVRC2_4,
+	}
+)
+
+// vrcVariant describes one of the VRC2/VRC4 boards. Each board connects
+// different CPU address lines to the VRC A0 and A1 pins, which select the
+// register within a $x000-$x003 group.
+type vrcVariant struct {
+	name string
+	vrc4 bool
+
+	// CPU address lines wired to the VRC A0 and A1 pins. When more than one
+	// line is set, they're OR'ed together. This allows to support multiple
+	// boards at once when the exact board is unknown (iNES 1.0 roms).
+	a0, a1 uint16
+
+	// VRC2a ignores the low bit of CHR bank numbers.
+	chrShift uint8
+}
+
+// translate converts a CPU address into a VRC register address, in the
+// $x000-$x003 range.
+func (v *vrcVariant) translate(addr uint16) uint16 {
+	reg := addr & 0xF000
+	if addr&v.a0 != 0 {
+		reg |= 0x01
+	}
+	if addr&v.a1 != 0 {
+		reg |= 0x02
+	}
+	return reg
+}
+
+// detectVRCVariant returns the VRC2/VRC4 variant for the given mapper and NES
+// 2.0 submapper numbers. For submapper 0 (i.e iNES 1.0 roms), both wirings
+// sharing the same mapper number are supported at once and the board is
+// emulated as a VRC4, which is a superset of VRC2.
+func detectVRCVariant(mapper uint16, submapper uint8) vrcVariant {
+	const (
+		A0 = 1 << iota
+		A1
+		A2
+		A3
+		A4
+		A5
+		A6
+		A7
+	)
+
+	switch mapper {
+	case 21:
+		switch submapper {
+		case 1:
+			return vrcVariant{name: "VRC4a", vrc4: true, a0: A1, a1: A2}
+		case 2:
+			return vrcVariant{name: "VRC4c", vrc4: true, a0: A6, a1: A7}
+		}
+		return vrcVariant{name: "VRC4a/VRC4c", vrc4: true, a0: A1 | A6, a1: A2 | A7}
+	case 22:
+		return vrcVariant{name: "VRC2a", a0: A1, a1: A0, chrShift: 1}
+	case 23:
+		switch submapper {
+		case 1:
+			return vrcVariant{name: "VRC4f", vrc4: true, a0: A0, a1: A1}
+		case 2:
+			return vrcVariant{name: "VRC4e", vrc4: true, a0: A2, a1: A3}
+		case 3:
+			return vrcVariant{name: "VRC2b", a0: A0, a1: A1}
+		}
+		return vrcVariant{name: "VRC2b/VRC4e/VRC4f", vrc4: true, a0: A0 | A2, a1: A1 | A3}
+	case 25:
+		switch submapper {
+		case 1:
+			return vrcVariant{name: "VRC4b", vrc4: true, a0: A1, a1: A0}
+		case 2:
+			return vrcVariant{name: "VRC4d", vrc4: true, a0: A3, a1: A2}
+		case 3:
+			return vrcVariant{name: "VRC2c", a0: A1, a1: A0}
+		}
+		return vrcVariant{name: "VRC2c/VRC4b/VRC4d", vrc4: true, a0: A1 | A3, a1: A0 | A2}
+	}
+	panic("not a VRC2/VRC4 mapper")
+}
+
+type vrc2_4 struct {
+	*Base
+
+	variant vrcVariant
+	irq     vrcIRQ
+
+	prgmode uint8
+	prgbank [2]uint8
+	chrbank [8]uint16
+	ntm     uint8
+
+	// VRC2 boards without PRG-RAM expose the microwire interface latch
+	// (originally used for a serial EEPROM) at $6000-$6FFF.
+	Latch hwio.Device `hwio:"size=0x1000,rcb,wcb"`
+	latch uint8
+}
+
+func (m *vrc2_4) ReadLATCH(addr uint16) uint8 {
+	// Only bit 0 is driven, other bits are open bus.
+	return uint8(addr>>8)&0xFE | m.latch
+}
+
+func (m *vrc2_4) WriteLATCH(addr uint16, val uint8) {
+	m.latch = val & 0x01
+}
+
+func (m *vrc2_4) writeReg(addr uint16, val uint8) {
+	reg := m.variant.translate(addr)
+
+	switch {
+	case reg <= 0x8003:
+		// 7  bit  0
+		// ---------
+		// ...P PPPP
+		//    | ||||
+		//    +-++++- Select 8 KB PRG bank at $8000 (or $C000 in swap mode)
+		m.prgbank[0] = val & 0x1F
+		m.remapPRG()
+
+	case reg <= 0x9003:
+		if !m.variant.vrc4 {
+			// VRC2 mirroring control: 0 = vertical, 1 = horizontal.
+			m.setMirroring(val & 0x01)
+			return
+		}
+		switch reg {
+		case 0x9000:
+			m.setMirroring(val & 0x03)
+		case 0x9002:
+			// 7  bit  0
+			// ---------
+			// .... ..MW
+			//        ||
+			//        |+- WRAM control (ignored, PRG-RAM is always enabled)
+			//        +-- PRG swap mode
+			m.prgmode = (val >> 1) & 0x01
+			m.remapPRG()
+		}
+
+	case reg <= 0xA003:
+		// Select 8 KB PRG bank at $A000.
+		m.prgbank[1] = val & 0x1F
+		m.remapPRG()
+
+	case reg <= 0xE003:
+		// Each 1 KB CHR bank number is split into a low and a high part:
+		//  - $B000/$B001: CHR bank 0 low/high ($0000-$03FF)
+		//  - $B002/$B003: CHR bank 1 low/high ($0400-$07FF)
+		//  - $C000/$C001: CHR bank 2 low/high ($0800-$0BFF)
+		//  ...
+		//  - $E002/$E003: CHR bank 7 low/high ($1C00-$1FFF)
+		i := (reg>>12-0xB)*2 + (reg>>1)&0x01
+		if reg&0x01 == 0 {
+			m.chrbank[i] = m.chrbank[i]&0x1F0 | uint16(val&0x0F)
+		} else {
+			m.chrbank[i] = m.chrbank[i]&0x00F | uint16(val&0x1F)<<4
+		}
+		m.SelectCHRROMPage1KB(uint32(i), int(m.chrbank[i]>>m.variant.chrShift))
+
+	default:
+		if !m.variant.vrc4 {
+			return
+		}
+		switch reg {
+		case 0xF000:
+			m.irq.writeLatchNibble(val, false)
+		case 0xF001:
+			m.irq.writeLatchNibble(val, true)
+		case 0xF002:
+			m.irq.writeControl(val)
+		case 0xF003:
+			m.irq.acknowledge()
+		}
+	}
+}
+
+func (m *vrc2_4) setMirroring(ntm uint8) {
+	if ntm == m.ntm {
+		return
+	}
+	m.ntm = ntm
+
+	switch m.ntm {
+	case 0:
+		m.SetNTMirroring(ines.VertMirroring)
+	case 1:
+		m.SetNTMirroring(ines.HorzMirroring)
+	case 2:
+		m.SetNTMirroring(ines.OnlyAScreen)
+	case 3:
+		m.SetNTMirroring(ines.OnlyBScreen)
+	}
+}
+
+func (m *vrc2_4) remapPRG() {
+	// $8000 and $C000 are swapped in PRG swap mode.
+	if m.prgmode == 0 {
+		m.SelectPRGPage8KB(0, int(m.prgbank[0]))
+		m.SelectPRGPage8KB(2, -2)
+	} else {
+		m.SelectPRGPage8KB(0, -2)
+		m.SelectPRGPage8KB(2, int(m.prgbank[0]))
+	}
+	m.SelectPRGPage8KB(1, int(m.prgbank[1]))
+	m.SelectPRGPage8KB(3, -1)
+}
+
+func (m *vrc2_4) remapCHR() {
+	for i, bank := range m.chrbank {
+		m.SelectCHRROMPage1KB(uint32(i), int(bank>>m.variant.chrShift))
+	}
+}
+
+func loadVRC2_4(b *Base) error {
+	m := &vrc2_4{
+		Base:    b,
+		variant: detectVRCVariant(b.Rom().Mapper(), b.Rom().SubMapper()),
+		irq:     vrcIRQ{cpu: b.CPU()},
+	}
+	hwio.MustInitRegs(m)
+	b.Init(m.writeReg)
+
+	modMapper.InfoZ("detected board").
+		String("mapper", m.Name()).
+		String("variant", m.variant.name).
+		End()
+
+	if m.variant.vrc4 {
+		b.CPU().SetCycleHook(m.irq.tick)
+	} else if !b.HasPRGRAM() {
+		b.CPU().Bus.Unmap(0x6000, 0x7FFF)
+		b.CPU().Bus.MapDevice(0x6000, &m.Latch)
+	}
+
+	b.SetNTMirroring(ines.VertMirroring)
+	m.remapPRG()
+	m.remapCHR()
+	return nil
+}
@@ -0,0 +1,153 @@
+package mappers
+
+import (
+	"testing"
+
+	"nestor/hw/hwdefs"
+	"nestor/ines"
+)
+
+func TestVRC2_4(t *testing.T) {
+	// CPU address lines wired to the VRC A0 and A1 pins, for each board. iNES
+	// 1.0 roms (submapper 0) must work with both wirings of their mapper.
+	wirings := []struct {
+		name      string
+		mapper    uint16
+		submapper uint8
+		a0, a1    uint16
+		chrShift  uint8
+	}{
+		{"VRC4a", 21, 1, 0x02, 0x04, 0},
+		{"VRC4c", 21, 2, 0x40, 0x80, 0},
+		{"21/VRC4a", 21, 0, 0x02, 0x04, 0},
+		{"21/VRC4c", 21, 0, 0x40, 0x80, 0},
+		{"VRC2a", 22, 0, 0x02, 0x01, 1},
+		{"VRC4f", 23, 1, 0x01, 0x02, 0},
+		{"VRC4e", 23, 2, 0x04, 0x08, 0},
+		{"VRC2b", 23, 3, 0x01, 0x02, 0},
+		{"23/VRC4f", 23, 0, 0x01, 0x02, 0},
+		{"23/VRC4e", 23, 0, 0x04, 0x08, 0},
+		{"VRC4b", 25, 1, 0x02, 0x01, 0},
+		{"VRC4d", 25, 2, 0x08, 0x04, 0},
+		{"VRC2c", 25, 3, 0x02, 0x01, 0},
+		{"25/VRC4b", 25, 0, 0x02, 0x01, 0},
+		{"25/VRC4d", 25, 0, 0x08, 0x04, 0},
+	}
+	for _, w := range wirings {
+		t.Run(w.name, func(t *testing.T) {
+			loadTestRom(t, w.mapper, w.submapper, 256, 256).run(t, []busStep{
+				{
+					// $B000-$B003: CHR bank 0 low/high, CHR bank 1 low/high.
+					desc: "CHR banks",
+					write: []busVal{
+						{0xB000, 0x05},
+						{0xB000 | w.a0, 0x01},
+						{0xB000 | w.a1, 0x06},
+						{0xB000 | w.a0 | w.a1, 0x02},
+					},
+					ppu: []busVal{{0x0000, 0x15 >> w.chrShift}, {0x0400, 0x26 >> w.chrShift}},
+				},
+				{
+					desc:  "$A000 ignores A0 and A1",
+					write: []busVal{{0xA000 | w.a0 | w.a1, 0x05}},
+					cpu:   []busVal{{0xA000, 5 * 8}},
+				},
+			})
+		})
+	}
+
+	t.Run("VRC4a ignores A6", func(t *testing.T) {
+		// $B040 is $B000 on VRC4a, the high part of CHR bank 0 isn't written.
+		loadTestRom(t, 21, 1, 256, 256).run(t, []busStep{{
+			desc:  "$B040",
+			write: []busVal{{0xB000, 0x05}, {0xB040, 0x01}},
+			ppu:   []busVal{{0x0000, 0x01}},
+		}})
+	})
+
+	t.Run("mirroring", func(t *testing.T) {
+		loadTestRom(t, 23, 1, 256, 256).run(t, []busStep{
+			{desc: "VRC4 vertical", write: []busVal{{0x9000, 0}}, mirror: ines.VertMirroring},
+			{desc: "VRC4 horizontal", write: []busVal{{0x9000, 1}}, mirror: ines.HorzMirroring},
+			{desc: "VRC4 screen A", write: []busVal{{0x9000, 2}}, mirror: ines.OnlyAScreen},
+			{desc: "VRC4 screen B", write: []busVal{{0x9000, 3}}, mirror: ines.OnlyBScreen},
+		})
+
+		// VRC2 only has vertical and horizontal mirroring.
+		loadTestRom(t, 23, 3, 256, 256).run(t, []busStep{
+			{desc: "VRC2 horizontal", write: []busVal{{0x9000, 3}}, mirror: ines.HorzMirroring},
+		})
+	})
+
+	t.Run("VRC2 latch", func(t *testing.T) {
+		// Only bit 0 is driven.
+		loadTestRom(t, 22, 0, 256, 256).run(t, []busStep{
+			{desc: "set", write: []busVal{{0x6000, 0xFF}}, cpu: []busVal{{0x6000, 0x61}}},
+			{desc: "clear", write: []busVal{{0x6000, 0x00}}, cpu: []busVal{{0x6100, 0x60}}},
+		})
+	})
+
+	t.Run("IRQ scanline mode", func(t *testing.T) {
+		c := loadTestRom(t, 23, 1, 256, 256)
+		bus := c.cpu.Bus
+
+		// Latch $F6: the counter wraps after 10 scanlines, 1136⅔ CPU cycles.
+		bus.Write8(0xF000, 0x06)
+		bus.Write8(0xF001, 0x0F)
+		bus.Write8(0xF002, 0x03)
+
+		if n := c.runUntilIRQ(2000); n < 1130 || n > 1143 {
+			t.Fatalf("IRQ after %d CPU cycles, want ~1137", n)
+		}
+
+		// The prescaler isn't reset on acknowledge, so the error doesn't
+		// accumulate: 10 more IRQs take exactly 11366⅔ cycles, even when
+		// acknowledged in the middle of a scanline.
+		start := c.cpu.Cycles
+		for i := range 10 {
+			for end := c.cpu.Cycles + 57; c.cpu.Cycles < end; {
+				c.cpu.Run(1)
+			}
+			bus.Write8(0xF003, 0)
+			if c.cpu.HasIRQSource(hwdefs.External) {
+				t.Fatalf("IRQ %d not acknowledged", i)
+			}
+			c.runUntilIRQ(2000)
+		}
+		if n := c.cpu.Cycles - start; n < 11360 || n > 11373 {
+			t.Fatalf("10 IRQs after %d CPU cycles, want ~11367", n)
+		}
+	})
+
+	t.Run("IRQ cycle mode", func(t *testing.T) {
+		c := loadTestRom(t, 23, 1, 256, 256)
+		bus := c.cpu.Bus
+
+		// Latch $00: the counter wraps after 256 CPU cycles.
+		bus.Write8(0xF000, 0x00)
+		bus.Write8(0xF001, 0x00)
+		bus.Write8(0xF002, 0x06)
+
+		if n := c.runUntilIRQ(2000); n < 250 || n > 262 {
+			t.Fatalf("IRQ after %d CPU cycles, want 256", n)
+		}
+
+		// Acknowledge with 'enable after acknowledgement' clear disables IRQs.
+		bus.Write8(0xF003, 0)
+		if n := c.runUntilIRQ(100000); n < 100000 {
+			t.Fatalf("unexpected IRQ after %d CPU cycles", n)
+		}
+	})
+
+	t.Run("VRC2 has no IRQ", func(t *testing.T) {
+		c := loadTestRom(t, 23, 3, 256, 256)
+		bus := c.cpu.Bus
+
+		bus.Write8(0xF000, 0x00)
+		bus.Write8(0xF001, 0x00)
+		bus.Write8(0xF002, 0x06)
+		if n := c.runUntilIRQ(100000); n < 100000 {
+			t.Fatalf("unexpected IRQ after %d CPU cycles", n)
+		}
+	})
+}
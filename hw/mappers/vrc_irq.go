@@ -0,0 +1,93 @@
+package mappers
+
+import (
+	"nestor/hw"
+	"nestor/hw/hwdefs"
+)
+
+// vrcIRQ is the IRQ counter found in Konami VRC4, VRC6 and VRC7. It's an 8-bit
+// up-counter clocked by the CPU, either on every cycle (cycle mode) or through
+// a prescaler dividing the CPU clock by 113⅔ so that it's clocked once per
+// scanline (scanline mode).
+type vrcIRQ struct {
+	cpu *hw.CPU
+
+	latch     uint8
+	counter   uint8
+	prescaler int16
+
+	enabled        bool
+	enableAfterAck bool
+	cycleMode      bool
+}
+
+// tick must be called on every CPU cycle.
+func (irq *vrcIRQ) tick() {
+	if !irq.enabled {
+		return
+	}
+	if irq.cycleMode {
+		irq.clock()
+		return
+	}
+
+	// The prescaler is decremented by 3 each CPU cycle, and clocks the counter
+	// each time it goes past 0, that is every 341 PPU cycles.
+	irq.prescaler -= 3
+	if irq.prescaler <= 0 {
+		irq.prescaler += 341
+		irq.clock()
+	}
+}
+
+func (irq *vrcIRQ) clock() {
+	if irq.counter == 0xFF {
+		irq.counter = irq.latch
+		irq.cpu.SetIRQSource(hwdefs.External)
+	} else {
+		irq.counter++
+	}
+}
+
+// writeLatch sets the whole reload value.
+func (irq *vrcIRQ) writeLatch(val uint8) {
+	irq.latch = val
+}
+
+// writeLatchNibble sets either the low or the high 4 bits of the reload value
+// (VRC4 splits the latch between 2 registers).
+func (irq *vrcIRQ) writeLatchNibble(val uint8, hi bool) {
+	if hi {
+		irq.latch = (irq.latch & 0x0F) | (val&0x0F)<<4
+	} else {
+		irq.latch = (irq.latch & 0xF0) | val&0x0F
+	}
+}
+
+// writeControl handles writes to the IRQ control register.
+//
+//	7  bit  0
+//	---- ----
+//	.... .MEA
+//	      |||
+//	      ||+- IRQ enable after acknowledgement
+//	      |+-- IRQ enable (1 = enabled)
+//	      +--- IRQ mode (1 = cycle mode, 0 = scanline mode)
+func (irq *vrcIRQ) writeControl(val uint8) {
+	irq.enableAfterAck = val&0x01 != 0
+	irq.enabled = val&0x02 != 0
+	irq.cycleMode = val&0x04 != 0
+
+	if irq.enabled {
+		irq.counter = irq.latch
+		irq.prescaler = 341
+	}
+	irq.cpu.ClearIRQSource(hwdefs.External)
+}
+
+// acknowledge clears the pending IRQ and restores the enable flag
+// from the 'enable after acknowledgement' control bit.
+func (irq *vrcIRQ) acknowledge() {
+	irq.enabled = irq.enableAfterAck
+	irq.cpu.ClearIRQSource(hwdefs.External)
+}
@@ -0,0 +1,47 @@
+package mappers
+
+import "nestor/hw/hwio"
+
+// Mapper 99: the Vs. System default board. The 8 KB CHR-ROM bank is selected
+// by the OUT2 pin of the controller port ($4016 bit 2), the 40 KB PRG-ROM
+// variant (Vs. Gumshoe) isn't supported.
+var VsSystem = MapperDesc{
+	Name: "Vs. System",
+	Load: loadVsSystem,
+}
+
+type vsSystem struct {
+	*Base
+
+	RAM  [0x800]byte  // $6000-$7FFF, mirrored
+	VRAM [0x1000]byte // four-screen nametables, on the main board
+}
+
+func loadVsSystem(b *Base) error {
+	m := &vsSystem{Base: b}
+	b.Init(nil)
+
+	b.CPU().Bus.Unmap(0x6000, 0x7FFF)
+	b.CPU().Bus.MapMem(0x6000, &hwio.Mem{
+		Name:  "RAM",
+		Data:  m.RAM[:],
+		VSize: 0x2000,
+	})
+	if b.Rom().HasPersistence() {
+		b.Persist(m.RAM[:])
+	}
+
+	b.CPU().SetOutputHook(func(val uint8) {
+		b.SelectCHRROMPage8KB(int(val >> 2 & 0x01))
+	})
+
+	b.SetNametables(
+		RAMNametable(m.VRAM[0x000:]),
+		RAMNametable(m.VRAM[0x400:]),
+		RAMNametable(m.VRAM[0x800:]),
+		RAMNametable(m.VRAM[0xC00:]),
+	)
+	b.SelectPRGPage32KB(0)
+	b.SelectCHRROMPage8KB(0)
+	return nil
+}
@@ -14,6 +14,7 @@ import (
 
 	"nestor/emu/log"
 	"nestor/hw/input"
+	"nestor/hw/shaders"
 )
 
 const (
@@ -48,6 +49,18 @@ type OutputConfig struct {
 
 	// Shader name for additional video processing effects.
 	Shader string
+
+	// Inputs bound to the hotkeys.
+	Hotkeys HotkeyBindings
+
+	// Called, from the polling goroutine, when a hotkey is pressed or
+	// released. The fullscreen and shader hotkeys are handled by the output.
+	OnHotkey func(hk Hotkey, pressed bool)
+
+	// Mouse, if not nil, tracks the mouse over the NES screen (for the
+	// Zapper, Arkanoid Vaus, etc.). The mouse cursor is then shown as a
+	// crosshair.
+	Mouse *input.Mouse
 }
 
 // A Frame holds the audio/video buffers the emulator
@@ -55,6 +68,10 @@ type OutputConfig struct {
 type Frame struct {
 	Video []byte
 	_     []byte // TODO: Audio
+
+	// Status is a short text shown in the window title, along with the frame
+	// rate (such as the state of cartridge LEDs).
+	Status string
 }
 
 type Output struct {
@@ -65,6 +82,8 @@ type Output struct {
 
 	fpscounter int
 	fpsclock   uint64
+	fps        int
+	status     string
 
 	videoEnabled bool
 	window       *window
@@ -117,6 +136,12 @@ func (out *Output) EnableVideo(enable bool) error {
 		out.window = window
 		out.videoEnabled = true
 
+		if out.cfg.Mouse != nil {
+			sdl.Do(func() {
+				sdl.SetCursor(sdl.CreateSystemCursor(sdl.SYSTEM_CURSOR_CROSSHAIR))
+			})
+		}
+
 	case !enable && out.videoEnabled:
 		err := out.window.Close()
 		if err != nil {
@@ -193,6 +218,21 @@ func (out *Output) EndFrame(frame Frame) {
 	out.framech <- frame
 }
 
+// TryEndFrame is like EndFrame, but drops the frame rather than waiting for the
+// previous one to be rendered. It reports whether the frame has been sent.
+func (out *Output) TryEndFrame(frame Frame) bool {
+	select {
+	case out.framech <- frame:
+		out.framecounter++
+		return true
+	default:
+		// The next frame reuses this video buffer, since the other ones may
+		// still be rendering.
+		out.framebufidx = (out.framebufidx + out.cfg.NumVideoBuffers - 1) % out.cfg.NumVideoBuffers
+		return false
+	}
+}
+
 // Stop output flow.
 func (out *Output) Close() {
 	log.ModEmu.DebugZ("Terminating output streams").End()
@@ -257,6 +297,14 @@ func (out *Output) render() {
 	}
 }
 
+func (out *Output) title() string {
+	title := fmt.Sprintf("%s - %d FPS", out.cfg.Title, out.fps)
+	if out.status != "" {
+		title += " - " + out.status
+	}
+	return title
+}
+
 // Poll reports whether input polling is ongoing.
 // (i.e false if user requested to quit)
 // Safe for concurrent use.
@@ -267,7 +315,19 @@ func (out *Output) Poll() bool {
 func (out *Output) poll() {
 	defer out.wg.Done()
 
+	var keystate []uint8
+	sdl.Do(func() { keystate = sdl.GetKeyboardState() })
+	pressed := func(code input.Code) bool {
+		return input.IsPressed(code, keystate, out.cfg.Mouse)
+	}
+
+	// Ignore the inputs already pressed at startup.
+	hotkeys := newHotkeyTracker(out.cfg.Hotkeys)
+	sdl.Do(func() { hotkeys.update(pressed, nil) })
+
+	var events []hotkeyEvent
 	for out.Poll() {
+		events = events[:0]
 		sdl.Do(func() {
 			for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
 				switch e := event.(type) {
@@ -279,16 +339,92 @@ func (out *Output) poll() {
 						return
 					}
 				case sdl.WindowEvent:
-					if e.Event == sdl.WINDOWEVENT_RESIZED {
+					switch e.Event {
+					case sdl.WINDOWEVENT_SIZE_CHANGED:
 						width, height := e.Data1, e.Data2
 						out.window.scaleViewport(width, height)
+					case sdl.WINDOWEVENT_LEAVE:
+						if out.cfg.Mouse != nil {
+							out.cfg.Mouse.SetOffscreen()
+						}
+					}
+				case sdl.MouseMotionEvent:
+					if out.cfg.Mouse != nil {
+						out.moveMouse(e.X, e.Y)
+						out.cfg.Mouse.AddMotion(int(e.XRel), int(e.YRel))
+					}
+				case sdl.MouseButtonEvent:
+					if out.cfg.Mouse != nil {
+						out.moveMouse(e.X, e.Y)
+						out.cfg.Mouse.SetButton(sdl.Button(e.Button), e.State == sdl.PRESSED)
 					}
 				case sdl.ControllerDeviceEvent:
 					input.Gamectrls.UpdateDevices(e)
 				}
 			}
+			events = hotkeys.update(pressed, events)
 		})
+
+		// Hotkeys are handled out of the main thread, so that handlers can
+		// call sdl.Do.
+		for _, ev := range events {
+			out.handleHotkey(ev.hotkey, ev.pressed)
+		}
+	}
+}
+
+func (out *Output) handleHotkey(hk Hotkey, pressed bool) {
+	switch {
+	case hk == HotkeyFullscreen && pressed:
+		out.toggleFullscreen()
+	case hk == HotkeyNextShader && pressed:
+		out.nextShader()
+	case out.cfg.OnHotkey != nil:
+		out.cfg.OnHotkey(hk, pressed)
+	}
+}
+
+func (out *Output) toggleFullscreen() {
+	if !out.videoEnabled {
+		return
+	}
+	var err error
+	sdl.Do(func() { err = out.window.toggleFullscreen() })
+	if err != nil {
+		log.ModEmu.WarnZ("Failed to toggle fullscreen").Error("err", err).End()
+	}
+}
+
+// nextShader switches to the shader following the current one.
+func (out *Output) nextShader() {
+	if !out.videoEnabled {
+		return
+	}
+	names := shaders.Names()
+	i := slices.Index(names, out.cfg.Shader)
+	name := names[(i+1)%len(names)]
+
+	var err error
+	sdl.Do(func() { err = out.window.setShader(name) })
+	if err != nil {
+		log.ModEmu.WarnZ("Failed to switch shader").String("shader", name).Error("err", err).End()
+		return
+	}
+	out.cfg.Shader = name
+	log.ModEmu.InfoZ("Switched shader").String("shader", name).End()
+}
+
+// moveMouse updates the mouse position from window coordinates.
+func (out *Output) moveMouse(winx, winy int32) {
+	if out.window == nil {
+		return
+	}
+	x, y, ok := out.window.screenCoords(winx, winy)
+	if !ok {
+		out.cfg.Mouse.SetOffscreen()
+		return
 	}
+	out.cfg.Mouse.SetPosition(x, y)
 }
 
 func (out *Output) Screenshot() *image.RGBA {
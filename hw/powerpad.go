@@ -0,0 +1,61 @@
+package hw
+
+type powerPadStateLoader interface {
+	PowerPadState() uint16
+}
+
+// PowerPad is the Power Pad (or Family Trainer) mat, its 12 buttons are read
+// through 2 shift registers.
+//
+//	$4016/$4017 read: ---H L---
+//	                     | +---- buttons 4, 3, 12, 8, then 1s
+//	                     +------ buttons 2, 1, 5, 9, 6, 10, 11, 7, then 1s
+type PowerPad struct {
+	provider powerPadStateLoader
+	strobe   bool
+	hi, lo   uint8 // D4 and D3 shift registers
+}
+
+// Order in which the buttons are shifted out on D4 and D3.
+var (
+	powerPadD4 = [8]uint8{2, 1, 5, 9, 6, 10, 11, 7}
+	powerPadD3 = [4]uint8{4, 3, 12, 8}
+)
+
+// NewPowerPad returns a Power Pad reading the state of its buttons from
+// provider.
+func NewPowerPad(provider powerPadStateLoader) *PowerPad {
+	return &PowerPad{provider: provider}
+}
+
+func (pp *PowerPad) load() {
+	state := pp.provider.PowerPadState()
+	pp.hi, pp.lo = 0, 0xF0
+	for i, btn := range powerPadD4 {
+		pp.hi |= uint8(state>>(btn-1)&1) << i
+	}
+	for i, btn := range powerPadD3 {
+		pp.lo |= uint8(state>>(btn-1)&1) << i
+	}
+}
+
+func (pp *PowerPad) Strobe(on bool) {
+	if pp.strobe && !on {
+		pp.load()
+	}
+	pp.strobe = on
+}
+
+func (pp *PowerPad) Read() uint8 {
+	if pp.strobe {
+		pp.load()
+	}
+	ret := pp.Peek()
+	pp.hi = pp.hi>>1 | 0x80
+	pp.lo = pp.lo>>1 | 0x80
+	return ret
+}
+
+func (pp *PowerPad) Peek() uint8 {
+	return (pp.hi&1)<<4 | (pp.lo&1)<<3
+}
@@ -43,6 +43,9 @@ type PPU struct {
 
 	framebuf []uint32 // RGBA framebuffer
 
+	model   PPUModel
+	palette *[64]uint32 // ABGR colors of the PPU model
+
 	oddFrame      bool
 	preventVblank bool
 
@@ -56,6 +59,11 @@ type PPU struct {
 	openBusDecayBuf [8]uint32
 
 	bg bgregs
+
+	// A12 line watching, for scanline counting mappers.
+	a12Hook func()
+	a12     bool
+	a12Low  uint64 // master clock at which A12 went low
 }
 
 func NewPPU() *PPU {
@@ -64,6 +72,7 @@ func NewPPU() *PPU {
 		// Throwaway frame buffer for the first PPU cycles,
 		// before one is provided for the frame.
 		framebuf: make([]uint32, 256*240),
+		palette:  &nesPalette,
 	}
 
 	hwio.MustInitRegs(p)
@@ -181,8 +190,6 @@ func (p *PPU) doScanline(sm scanlineMode) {
 			}
 		case 257:
 			p.evalSprites()
-		case 321:
-			p.loadSprites()
 		}
 
 		switch {
@@ -197,13 +204,13 @@ func (p *PPU) doScanline(sm scanlineMode) {
 				p.bg.addrLatch = p.ntAddr()
 				p.refillShifters()
 			case 2:
-				p.bg.nt = p.ReadVRAM(p.bg.addrLatch)
+				p.bg.nt = p.fetchVRAM(p.bg.addrLatch)
 
 			// attribute table
 			case 3:
 				p.bg.addrLatch = p.atAddr()
 			case 4:
-				p.bg.at = p.ReadVRAM(p.bg.addrLatch)
+				p.bg.at = p.fetchVRAM(p.bg.addrLatch)
 				if p.vramAddr.coarsey()&2 != 0 {
 					p.bg.at >>= 4
 				}
@@ -215,24 +222,28 @@ func (p *PPU) doScanline(sm scanlineMode) {
 			case 5:
 				p.bg.addrLatch = p.bgAddr()
 			case 6:
-				p.bg.bglo = p.ReadVRAM(p.bg.addrLatch)
+				p.bg.bglo = p.fetchVRAM(p.bg.addrLatch)
 
 			// high background byte
 			case 7:
 				p.bg.addrLatch += 8
 			case 0:
-				p.bg.bghi = p.ReadVRAM(p.bg.addrLatch)
+				p.bg.bghi = p.fetchVRAM(p.bg.addrLatch)
 				p.horzScroll()
 			}
 
 		case p.Cycle == 256:
 			p.renderPixel()
-			p.bg.bghi = p.ReadVRAM(p.bg.addrLatch)
+			p.bg.bghi = p.fetchVRAM(p.bg.addrLatch)
 			p.vertScroll()
 		case p.Cycle == 257:
 			p.renderPixel()
 			p.refillShifters()
 			p.horzUpdate()
+
+			// Sprite patterns for the next scanline are fetched during cycles
+			// 257-320, we fetch them all at once.
+			p.loadSprites()
 		case p.Cycle >= 280 && p.Cycle <= 304:
 			if sm == preRender {
 				p.vertUpdate()
@@ -260,9 +271,9 @@ func (p *PPU) doScanline(sm scanlineMode) {
 
 		// 'garbage' fetches
 		case p.Cycle == 338:
-			p.bg.nt = p.ReadVRAM(p.bg.addrLatch)
+			p.bg.nt = p.fetchVRAM(p.bg.addrLatch)
 		case p.Cycle == 340:
-			p.bg.nt = p.ReadVRAM(p.bg.addrLatch)
+			p.bg.nt = p.fetchVRAM(p.bg.addrLatch)
 			if sm == preRender && p.isRenderingEnabled() && p.oddFrame {
 				p.Cycle++
 			}
@@ -423,7 +434,7 @@ func (p *PPU) renderPixel() {
 			paddr += uint16(palette)
 		}
 		pidx := p.ReadVRAM(0x3F00 + paddr)
-		colu32 := nesPalette[pidx]
+		colu32 := p.palette[pidx&0x3F]
 
 		// TODO: emphasis not tested yet.
 		// const m = 0x80 | 0x40 | 0x20
@@ -526,6 +537,10 @@ func (p *PPU) Peek8(addr uint16) uint8 {
 		}
 		openBusMask = 0x1F
 		ret = uint8(tmp)
+		if id, ok := p.model.statusID(); ok {
+			openBusMask = 0x00
+			ret |= id
+		}
 	case OAMDATA:
 		ret = p.oamMem[p.oamAddr]
 		openBusMask = 0x00
@@ -557,7 +572,17 @@ func (p *PPU) Read8(addr uint16) uint8 {
 func (p *PPU) Write8(addr uint16, val uint8) {
 	p.setOpenBus(0xFF, val)
 
-	switch ppuregFromAddr(addr) {
+	reg := ppuregFromAddr(addr)
+	if p.model.swapsCtrlMask() {
+		switch reg {
+		case PPUCTRL:
+			reg = PPUMASK
+		case PPUMASK:
+			reg = PPUCTRL
+		}
+	}
+
+	switch reg {
 	case PPUCTRL:
 		p.WritePPUCTRL(val)
 	case PPUMASK:
@@ -614,6 +639,9 @@ func (p *PPU) PeekPPUSTATUS() uint8 {
 		ret.setVblank(false)
 	}
 
+	if id, ok := p.model.statusID(); ok {
+		return uint8(ret) | id
+	}
 	return uint8(ret) | (p.openBus & openBusMask)
 }
 
@@ -635,6 +663,10 @@ func (p *PPU) ReadPPUSTATUS() uint8 {
 		// anyway, causing NMI to not occur that frame.
 		p.preventVblank = true
 	}
+	if id, ok := p.model.statusID(); ok {
+		// 2C05 PPUs return their ID instead of the open bus.
+		return p.applyOpenBus(0x00, uint8(ret)|id)
+	}
 	const openBusMask = 0x1F
 	return p.applyOpenBus(openBusMask, uint8(ret))
 }
@@ -677,6 +709,7 @@ func (p *PPU) ReadPPUDATA() uint8 {
 	// Reading VRAM is too slow so the actual data
 	// will be returned at the next read.
 	val := p.ppudataBuf
+	p.watchA12(p.vramAddr.addr())
 	p.ppudataBuf = p.ReadVRAM(p.vramAddr.addr())
 
 	if p.busAddr&0x3FFF >= 0x3F00 {
@@ -723,11 +756,49 @@ func (p *PPU) ReadVRAM(addr uint16) uint8 {
 	return p.Bus.Read8(addr)
 }
 
+// fetchVRAM reads VRAM for rendering.
+func (p *PPU) fetchVRAM(addr uint16) uint8 {
+	if p.isRenderingEnabled() {
+		p.watchA12(addr)
+	}
+	return p.ReadVRAM(addr)
+}
+
 func (p *PPU) WriteVRAM(addr uint16, val uint8) {
 	p.busAddr = addr
+	p.watchA12(addr)
 	p.Bus.Write8(addr, val)
 }
 
+// a12LowClocks is the minimum number of master clock cycles A12 must have
+// been low for a rising edge to be reported. This filters out the quick
+// toggles happening while fetching background tiles, as mappers do (the MMC3
+// waits for 3 falling edges of M2).
+const a12LowClocks = 10 * ntscDivider
+
+// SetA12Hook sets a function to call on every filtered rising edge of the PPU
+// address line A12, used by mappers counting scanlines.
+func (p *PPU) SetA12Hook(hook func()) {
+	p.a12Hook = hook
+}
+
+func (p *PPU) watchA12(addr uint16) {
+	if p.a12Hook == nil {
+		return
+	}
+
+	a12 := addr&0x1000 != 0
+	switch {
+	case a12 && !p.a12:
+		if p.masterClock-p.a12Low >= a12LowClocks {
+			p.a12Hook()
+		}
+	case !a12 && p.a12:
+		p.a12Low = p.masterClock
+	}
+	p.a12 = a12
+}
+
 // ABGR format. Convenient for little endian since it has the same memory layout
 // as RGBA struct.
 //
@@ -899,6 +970,9 @@ func (p *PPU) loadSprites() {
 		}
 		addr += uint16(sprY + (sprY & 8)) // Select the second tile if on 8x16.
 
+		if p.isRenderingEnabled() {
+			p.watchA12(addr)
+		}
 		p.oam[i].dataL = p.Bus.Read8(addr)
 		p.oam[i].dataH = p.Bus.Read8(addr + 8)
 	}
@@ -0,0 +1,120 @@
+package hw
+
+// PPUModel is the PPU chip variant. The Vs. System and Playchoice arcade
+// boards use RGB PPUs, which output their own palette, some of them with
+// scrambled color indices (2C04) or swapped registers (2C05).
+type PPUModel uint8
+
+const (
+	PPU2C02      PPUModel = iota // NES/Famicom
+	PPU2C03                      // RGB PPU
+	PPU2C04_0001                 // RGB PPU, scrambled palette
+	PPU2C04_0002                 // RGB PPU, scrambled palette
+	PPU2C04_0003                 // RGB PPU, scrambled palette
+	PPU2C04_0004                 // RGB PPU, scrambled palette
+	PPU2C05_01                   // RGB PPU, swapped $2000/$2001, ID $1B
+	PPU2C05_02                   // RGB PPU, swapped $2000/$2001, ID $3D
+	PPU2C05_03                   // RGB PPU, swapped $2000/$2001, ID $1C
+	PPU2C05_04                   // RGB PPU, swapped $2000/$2001, ID $1B
+	PPU2C05_05                   // RGB PPU, swapped $2000/$2001
+)
+
+// SetModel sets the PPU chip variant, 2C02 by default.
+func (p *PPU) SetModel(model PPUModel) {
+	p.model = model
+	p.palette = ppuPalettes[model]
+}
+
+// Model returns the PPU chip variant.
+func (p *PPU) Model() PPUModel { return p.model }
+
+// swapsCtrlMask reports whether PPUCTRL and PPUMASK are swapped, that is
+// PPUCTRL is at $2001 and PPUMASK at $2000.
+func (m PPUModel) swapsCtrlMask() bool {
+	return m >= PPU2C05_01 && m <= PPU2C05_05
+}
+
+// statusID returns the value which the 2C05 PPUs return in the low 5 bits of
+// PPUSTATUS, in place of the open bus, which games check as a copy
+// protection.
+func (m PPUModel) statusID() (uint8, bool) {
+	switch m {
+	case PPU2C05_01, PPU2C05_04:
+		return 0x1B, true
+	case PPU2C05_02:
+		return 0x3D & 0x1F, true
+	case PPU2C05_03:
+		return 0x1C, true
+	}
+	return 0, false
+}
+
+// rgbPalette is the palette of the RGB PPUs, as 3-bit per component RGB
+// values.
+var rgbPalette = [64]uint16{
+	0o333, 0o014, 0o006, 0o326, 0o403, 0o503, 0o510, 0o420, 0o320, 0o120, 0o031, 0o040, 0o022, 0o000, 0o000, 0o000,
+	0o555, 0o036, 0o027, 0o407, 0o507, 0o704, 0o700, 0o630, 0o430, 0o140, 0o040, 0o053, 0o044, 0o000, 0o000, 0o000,
+	0o777, 0o357, 0o447, 0o637, 0o707, 0o737, 0o740, 0o750, 0o660, 0o360, 0o070, 0o276, 0o077, 0o000, 0o000, 0o000,
+	0o777, 0o567, 0o657, 0o757, 0o747, 0o755, 0o764, 0o772, 0o773, 0o572, 0o473, 0o276, 0o467, 0o000, 0o000, 0o000,
+}
+
+// The 2C04 PPUs have the same colors as the 2C03, in a different order. These
+// tables give the 2C03 color of each 2C04 color index.
+var (
+	lut2C04_0001 = [64]uint8{
+		0x35, 0x23, 0x16, 0x22, 0x1C, 0x09, 0x1D, 0x15, 0x20, 0x00, 0x27, 0x05, 0x04, 0x28, 0x08, 0x20,
+		0x21, 0x3E, 0x1F, 0x29, 0x3C, 0x32, 0x36, 0x12, 0x3F, 0x2B, 0x2E, 0x1E, 0x3D, 0x2D, 0x24, 0x01,
+		0x0E, 0x31, 0x33, 0x2A, 0x2C, 0x0C, 0x1B, 0x14, 0x2E, 0x07, 0x34, 0x06, 0x13, 0x02, 0x26, 0x2E,
+		0x2E, 0x19, 0x10, 0x0A, 0x39, 0x03, 0x37, 0x17, 0x0F, 0x11, 0x0B, 0x0D, 0x38, 0x25, 0x18, 0x3A,
+	}
+	lut2C04_0002 = [64]uint8{
+		0x2E, 0x27, 0x18, 0x39, 0x3A, 0x25, 0x1C, 0x31, 0x16, 0x13, 0x38, 0x34, 0x20, 0x23, 0x3C, 0x0B,
+		0x0F, 0x21, 0x06, 0x3D, 0x1B, 0x29, 0x1E, 0x22, 0x1D, 0x24, 0x0E, 0x2B, 0x32, 0x08, 0x2E, 0x03,
+		0x04, 0x36, 0x26, 0x33, 0x11, 0x1F, 0x10, 0x02, 0x14, 0x3F, 0x00, 0x09, 0x12, 0x2E, 0x28, 0x20,
+		0x3E, 0x0D, 0x2A, 0x17, 0x0C, 0x01, 0x15, 0x19, 0x2E, 0x2C, 0x07, 0x37, 0x35, 0x05, 0x0A, 0x2D,
+	}
+	lut2C04_0003 = [64]uint8{
+		0x14, 0x25, 0x3A, 0x10, 0x0B, 0x20, 0x31, 0x09, 0x01, 0x2E, 0x36, 0x08, 0x15, 0x3D, 0x3E, 0x3C,
+		0x22, 0x1C, 0x05, 0x12, 0x19, 0x18, 0x17, 0x1B, 0x00, 0x03, 0x2E, 0x02, 0x16, 0x06, 0x34, 0x35,
+		0x23, 0x0F, 0x0E, 0x37, 0x0D, 0x27, 0x26, 0x20, 0x29, 0x04, 0x21, 0x24, 0x11, 0x2D, 0x2E, 0x1F,
+		0x2C, 0x1E, 0x39, 0x33, 0x07, 0x2A, 0x28, 0x1D, 0x0A, 0x2E, 0x32, 0x38, 0x13, 0x2B, 0x3F, 0x0C,
+	}
+	lut2C04_0004 = [64]uint8{
+		0x18, 0x03, 0x1C, 0x28, 0x2E, 0x35, 0x01, 0x17, 0x10, 0x1F, 0x2A, 0x0E, 0x36, 0x37, 0x0B, 0x39,
+		0x25, 0x1E, 0x12, 0x34, 0x2E, 0x1D, 0x06, 0x26, 0x3E, 0x1B, 0x22, 0x19, 0x04, 0x2E, 0x3A, 0x21,
+		0x05, 0x0A, 0x07, 0x02, 0x13, 0x14, 0x00, 0x15, 0x0C, 0x3D, 0x11, 0x0F, 0x0D, 0x38, 0x2D, 0x24,
+		0x33, 0x20, 0x08, 0x16, 0x3F, 0x2B, 0x20, 0x3C, 0x2E, 0x27, 0x23, 0x31, 0x29, 0x32, 0x2C, 0x09,
+	}
+)
+
+// ppuPalettes holds the ABGR palette of each PPU model.
+var ppuPalettes = func() map[PPUModel]*[64]uint32 {
+	rgb := func(lut *[64]uint8) *[64]uint32 {
+		var pal [64]uint32
+		for i := range pal {
+			idx := uint8(i)
+			if lut != nil {
+				idx = lut[i]
+			}
+			c := rgbPalette[idx]
+			r, g, b := c>>6&7, c>>3&7, c&7
+			pal[i] = 0xFF000000 | uint32(b*255/7)<<16 | uint32(g*255/7)<<8 | uint32(r*255/7)
+		}
+		return &pal
+	}
+
+	pal2C03 := rgb(nil)
+	return map[PPUModel]*[64]uint32{
+		PPU2C02:      &nesPalette,
+		PPU2C03:      pal2C03,
+		PPU2C04_0001: rgb(&lut2C04_0001),
+		PPU2C04_0002: rgb(&lut2C04_0002),
+		PPU2C04_0003: rgb(&lut2C04_0003),
+		PPU2C04_0004: rgb(&lut2C04_0004),
+		PPU2C05_01:   pal2C03,
+		PPU2C05_02:   pal2C03,
+		PPU2C05_03:   pal2C03,
+		PPU2C05_04:   pal2C03,
+		PPU2C05_05:   pal2C03,
+	}
+}()
@@ -2,6 +2,8 @@ package hw
 
 import (
 	"testing"
+
+	"nestor/hw/hwio"
 )
 
 func TestPPUScroll(t *testing.T) {
@@ -71,3 +73,139 @@ func TestPPUScroll(t *testing.T) {
 		t.Errorf("v != t")
 	}
 }
+
+func TestPPUSpriteRendering(t *testing.T) {
+	ppu := NewPPU()
+	cpu := NewCPU(ppu)
+	cpu.InitBus()
+	ppu.CPU = cpu
+	ppu.Reset()
+
+	// Pattern tables and nametables. Tile 1 is a diagonal line, from the
+	// top-left to the bottom-right corner.
+	var chr [0x2000]byte
+	var nt [0x1000]byte
+	for row := range 8 {
+		chr[16+row] = 0x80 >> row
+	}
+	ppu.Bus.MapMem(0x0000, &hwio.Mem{Name: "chr", Data: chr[:], VSize: len(chr)})
+	ppu.Bus.MapMem(0x2000, &hwio.Mem{Name: "nt", Data: nt[:], VSize: len(nt)})
+
+	// Sprite 0 displayed on scanlines 50-57, sprite 1 on scanlines 100-107,
+	// flipped vertically. All other sprites are hidden.
+	for i := range ppu.oamMem {
+		ppu.oamMem[i] = 0xFF
+	}
+	copy(ppu.oamMem[0:], []byte{49, 1, 0x00, 100})
+	copy(ppu.oamMem[4:], []byte{99, 1, 0x80, 20})
+	ppu.Palettes.Data[0x00] = 0x0F
+	ppu.Palettes.Data[0x11] = 0x30
+
+	ppu.WritePPUMASK(0b0001_1110)
+	for range 2 * NumCycles * NumScanlines {
+		ppu.Tick()
+	}
+
+	bg, spr := nesPalette[0x0F], nesPalette[0x30]
+	check := func(x, y int, want uint32) {
+		t.Helper()
+		if got := ppu.framebuf[y*256+x]; got != want {
+			t.Errorf("pixel (%d,%d) = %08x, want %08x", x, y, got, want)
+		}
+	}
+	for row := range 8 {
+		for col := range 8 {
+			want := bg
+			if col == row {
+				want = spr
+			}
+			check(100+col, 50+row, want)
+
+			want = bg
+			if col == 7-row {
+				want = spr
+			}
+			check(20+col, 100+row, want)
+		}
+	}
+	// Nothing above and below.
+	for col := range 8 {
+		check(100+col, 49, bg)
+		check(100+col, 58, bg)
+		check(20+col, 99, bg)
+		check(20+col, 108, bg)
+	}
+}
+
+func TestPPUModelPalette(t *testing.T) {
+	ppu := NewPPU()
+	if got := ppu.palette[0x01]; got != nesPalette[0x01] {
+		t.Errorf("2C02 color $01 = %08X, want %08X", got, nesPalette[0x01])
+	}
+
+	ppu.SetModel(PPU2C03)
+	// $01 is 0,1,4 (RGB 3-bit), as ABGR.
+	if got, want := ppu.palette[0x01], uint32(0xFF912400); got != want {
+		t.Errorf("2C03 color $01 = %08X, want %08X", got, want)
+	}
+
+	// The 2C04-0001 color $00 is the 2C03 color $35.
+	want := ppu.palette[0x35]
+	ppu.SetModel(PPU2C04_0001)
+	if got := ppu.palette[0x00]; got != want {
+		t.Errorf("2C04-0001 color $00 = %08X, want %08X", got, want)
+	}
+}
+
+func TestPPUA12Edges(t *testing.T) {
+	ppu := NewPPU()
+	cpu := NewCPU(ppu)
+	cpu.InitBus()
+	ppu.CPU = cpu
+	ppu.Reset()
+
+	type edge struct {
+		scanline int
+		cycle    uint32
+	}
+	var edges []edge
+	ppu.SetA12Hook(func() {
+		edges = append(edges, edge{ppu.Scanline, ppu.Cycle})
+	})
+
+	runFrame := func() {
+		edges = edges[:0]
+		for range NumCycles * NumScanlines {
+			ppu.Tick()
+			ppu.masterClock += ntscDivider
+		}
+	}
+
+	// Background at $0000 and sprites at $1000, the setup expected by
+	// MMC3-like scanline counters: A12 rises once per rendered scanline,
+	// when sprite patterns are fetched.
+	ppu.WritePPUCTRL(0b0000_1000)
+	ppu.WritePPUMASK(0b0001_1000)
+	runFrame()
+	runFrame()
+
+	if len(edges) != 241 {
+		t.Fatalf("got %d A12 edges per frame, want 241", len(edges))
+	}
+	for i, e := range edges {
+		want := i
+		if i == 240 {
+			want = 261 // pre-render scanline
+		}
+		if e.scanline != want || e.cycle != 257 {
+			t.Errorf("edge %d at scanline %d cycle %d, want scanline %d cycle 257", i, e.scanline, e.cycle, want)
+		}
+	}
+
+	// No edges with rendering disabled.
+	ppu.WritePPUMASK(0)
+	runFrame()
+	if len(edges) != 0 {
+		t.Errorf("got %d A12 edges with rendering disabled, want 0", len(edges))
+	}
+}
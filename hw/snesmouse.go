@@ -0,0 +1,67 @@
+package hw
+
+type snesMouseStateLoader interface {
+	SNESMouseState() (dx, dy int, left, right bool)
+}
+
+// SNESMouse is the Super NES mouse, used by some homebrew games. It reports 32
+// bits, MSB first, on D0:
+//
+//	byte 0: 0
+//	byte 1: RLss 0001 (right/left buttons, sensitivity, signature)
+//	byte 2: vertical motion (bit 7 set when moving up)
+//	byte 3: horizontal motion (bit 7 set when moving left)
+//
+// Clocking the mouse while strobed cycles its sensitivity.
+type SNESMouse struct {
+	provider    snesMouseStateLoader
+	strobe      bool
+	sensitivity uint8
+	state       uint32 // shift register
+}
+
+// NewSNESMouse returns a SNES mouse reading the mouse motion from provider.
+func NewSNESMouse(provider snesMouseStateLoader) *SNESMouse {
+	return &SNESMouse{provider: provider}
+}
+
+func (m *SNESMouse) load() {
+	dx, dy, left, right := m.provider.SNESMouseState()
+	status := m.sensitivity<<4 | 0x01
+	if left {
+		status |= 0x40
+	}
+	if right {
+		status |= 0x80
+	}
+	m.state = uint32(status)<<16 | uint32(snesMouseMotion(dy))<<8 | uint32(snesMouseMotion(dx))
+}
+
+// snesMouseMotion encodes a displacement as sign and magnitude.
+func snesMouseMotion(d int) uint8 {
+	if d < 0 {
+		return 0x80 | uint8(min(-d, 0x7F))
+	}
+	return uint8(min(d, 0x7F))
+}
+
+func (m *SNESMouse) Strobe(on bool) {
+	if m.strobe && !on {
+		m.load()
+	}
+	m.strobe = on
+}
+
+func (m *SNESMouse) Read() uint8 {
+	if m.strobe {
+		m.sensitivity = (m.sensitivity + 1) % 3
+		m.load()
+	}
+	ret := m.Peek()
+	m.state = m.state<<1 | 1
+	return ret
+}
+
+func (m *SNESMouse) Peek() uint8 {
+	return uint8(m.state >> 31)
+}
@@ -0,0 +1,62 @@
+package hw
+
+type vausStateLoader interface {
+	VausState() (x int, ok, fire bool)
+}
+
+// Vaus is the Arkanoid controller: a knob, driven by the horizontal mouse
+// position, and a button. The knob potentiometer position is latched on
+// strobe, then read serially.
+//
+//	$4017 read: ---P F---
+//	               | +---- fire button (1: pressed)
+//	               +------ knob position, inverted, MSB first
+type Vaus struct {
+	provider vausStateLoader
+	strobe   bool
+	pos      uint8 // last knob position
+	state    uint8 // shift register
+}
+
+// Range of the knob position, as read by the game.
+const (
+	vausMin = 0x62
+	vausMax = 0xF2
+)
+
+// NewVaus returns an Arkanoid controller reading the mouse from provider.
+func NewVaus(provider vausStateLoader) *Vaus {
+	return &Vaus{provider: provider, pos: (vausMin + vausMax) / 2}
+}
+
+func (v *Vaus) load() {
+	// The knob stays where it was while the mouse is out of the screen.
+	if x, ok, _ := v.provider.VausState(); ok {
+		v.pos = uint8(vausMin + x*(vausMax-vausMin)/(NTSCWidth-1))
+	}
+	v.state = v.pos
+}
+
+func (v *Vaus) Strobe(on bool) {
+	if v.strobe && !on {
+		v.load()
+	}
+	v.strobe = on
+}
+
+func (v *Vaus) Read() uint8 {
+	if v.strobe {
+		v.load()
+	}
+	ret := v.Peek()
+	v.state <<= 1
+	return ret
+}
+
+func (v *Vaus) Peek() uint8 {
+	ret := ^v.state >> 3 & 0x10
+	if _, _, fire := v.provider.VausState(); fire {
+		ret |= 0x08
+	}
+	return ret
+}
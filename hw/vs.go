@@ -0,0 +1,70 @@
+package hw
+
+// VsSystem is the Vs. System arcade hardware read by the CPU through the
+// controller ports, alongside the joysticks serial data: the coin slots, the
+// service button and the DIP switches.
+//
+//	$4016 read: PCCD DS0B
+//	            |||| || +- joystick serial data
+//	            |||| |+--- service button
+//	            |||+-+---- DIP switches 1-2
+//	            |++------- coin slots 1-2
+//	            +--------- 0 on the primary CPU
+//	$4017 read: DDDD DD0B
+//	            |||| || +- joystick serial data
+//	            ++++-++--- DIP switches 3-8
+type VsSystem struct {
+	// DIPSwitches holds the state of the 8 DIP switches, bit 0 is switch 1.
+	DIPSwitches uint8
+
+	// SwapControllers swaps the joysticks read from $4016 and $4017, for
+	// the games expecting the first player on the right.
+	SwapControllers bool
+
+	coins   [2]int // frames during which each coin slot stays active
+	service int    // frames during which the service button stays pressed
+}
+
+// vsCoinFrames is the number of frames during which a coin, or the service
+// button, is seen by the game. Too short or too long pulses are rejected by
+// some games.
+const vsCoinFrames = 4
+
+// InsertCoin inserts a coin in the given slot (0 or 1).
+func (vs *VsSystem) InsertCoin(slot int) {
+	vs.coins[slot&1] = vsCoinFrames
+}
+
+// PressService presses the service button, which usually adds a credit.
+func (vs *VsSystem) PressService() {
+	vs.service = vsCoinFrames
+}
+
+// EndFrame releases the coin slots and the service button once they've been
+// active long enough.
+func (vs *VsSystem) EndFrame() {
+	for i := range vs.coins {
+		vs.coins[i] = max(vs.coins[i]-1, 0)
+	}
+	vs.service = max(vs.service-1, 0)
+}
+
+// portBits returns the bits read from the given controller port, other than
+// the joystick serial data.
+func (vs *VsSystem) portBits(port uint8) uint8 {
+	if port == 1 {
+		return vs.DIPSwitches & 0xFC
+	}
+
+	val := (vs.DIPSwitches & 0x03) << 3
+	if vs.service > 0 {
+		val |= 0x04
+	}
+	if vs.coins[0] > 0 {
+		val |= 0x20
+	}
+	if vs.coins[1] > 0 {
+		val |= 0x40
+	}
+	return val
+}
@@ -20,6 +20,8 @@ type window struct {
 	ubo      uint32
 	context  sdl.GLContext
 	cfg      OutputConfig
+
+	viewport sdl.Rect // in window coordinates, set by scaleViewport
 }
 
 // create an opengl window that renders an unique texture
@@ -78,19 +80,9 @@ func _newWindow(cfg OutputConfig) (*window, error) {
 	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, cfg.Width, cfg.Height, 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(&texbuf[0]))
 	gl.GenerateMipmap(gl.TEXTURE_2D)
 
-	vert, err := shaders.Compile(cfg.Shader, shaders.Vertex)
-	if err != nil {
-		return nil, fmt.Errorf("vertex shader %q compilation: %s", cfg.Shader, err)
-	}
-
-	frag, err := shaders.Compile(cfg.Shader, shaders.Fragment)
-	if err != nil {
-		return nil, fmt.Errorf("fragment shader %q compilation: %s", cfg.Shader, err)
-	}
-
-	prog, err := shaders.LinkProgram(vert, frag)
+	prog, err := compileProgram(cfg.Shader)
 	if err != nil {
-		return nil, fmt.Errorf("shader program link: %s", err)
+		return nil, err
 	}
 
 	var uniforms uniforms
@@ -128,9 +120,51 @@ func _newWindow(cfg OutputConfig) (*window, error) {
 		vao:      VAO,
 		context:  context,
 		cfg:      cfg,
+		viewport: sdl.Rect{W: winw, H: winh},
 	}, nil
 }
 
+// compileProgram compiles and links the shader program with the given name.
+func compileProgram(shader string) (uint32, error) {
+	vert, err := shaders.Compile(shader, shaders.Vertex)
+	if err != nil {
+		return 0, fmt.Errorf("vertex shader %q compilation: %s", shader, err)
+	}
+
+	frag, err := shaders.Compile(shader, shaders.Fragment)
+	if err != nil {
+		return 0, fmt.Errorf("fragment shader %q compilation: %s", shader, err)
+	}
+
+	prog, err := shaders.LinkProgram(vert, frag)
+	if err != nil {
+		return 0, fmt.Errorf("shader program link: %s", err)
+	}
+	return prog, nil
+}
+
+// setShader replaces the shader program with the one with the given name.
+func (w *window) setShader(shader string) error {
+	prog, err := compileProgram(shader)
+	if err != nil {
+		return err
+	}
+
+	gl.DeleteProgram(w.prog)
+	w.prog = prog
+	w.uniforms.getLocations(prog)
+	return nil
+}
+
+// toggleFullscreen switches between windowed and (desktop) fullscreen modes.
+func (w *window) toggleFullscreen() error {
+	var flags uint32
+	if w.GetFlags()&sdl.WINDOW_FULLSCREEN_DESKTOP != sdl.WINDOW_FULLSCREEN_DESKTOP {
+		flags = uint32(sdl.WINDOW_FULLSCREEN_DESKTOP)
+	}
+	return w.SetFullscreen(flags)
+}
+
 type uniforms struct {
 	// vertex+fragment
 	textureSize int32
@@ -205,6 +239,22 @@ func (w *window) scaleViewport(winw, winh int32) {
 	offy := (winh - vph) / 2
 
 	gl.Viewport(offx, offy, vpw, vph)
+	w.viewport = sdl.Rect{X: offx, Y: offy, W: vpw, H: vph}
+}
+
+// screenCoords maps window coordinates to NES screen coordinates, reversing
+// the transform applied by scaleViewport. ok is false outside of the NES
+// screen (i.e in the black bars around the viewport).
+func (w *window) screenCoords(winx, winy int32) (x, y int, ok bool) {
+	vp := w.viewport
+	winx -= vp.X
+	winy -= vp.Y
+	if winx < 0 || winy < 0 || winx >= vp.W || winy >= vp.H {
+		return -1, -1, false
+	}
+	x = int(winx * w.cfg.Width / vp.W)
+	y = int(winy * w.cfg.Height / vp.H)
+	return x, y, true
 }
 
 func (w *window) Close() error {
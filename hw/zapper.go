@@ -0,0 +1,90 @@
+package hw
+
+type zapperStateLoader interface {
+	ZapperState() (x, y int, ok, trigger bool)
+}
+
+// Zapper is the NES light gun. Its photodiode senses the light emitted by the
+// CRT beam around where it's aimed, which we emulate by looking at the pixels
+// the PPU has drawn around the aim point, in the current frame.
+//
+//	$4016/$4017 read: ---T L--0
+//	                     | |  +- serial data (always 0)
+//	                     | +---- light sense (0: light detected)
+//	                     +------ trigger (1: pulled)
+type Zapper struct {
+	ppu      *PPU
+	provider zapperStateLoader
+}
+
+const (
+	// zapperRadius is the distance, in pixels, from the aim point at which
+	// the photodiode still senses light.
+	zapperRadius = 2
+
+	// zapperLightLines is the number of scanlines during which a pixel keeps
+	// being sensed after having been drawn (CRT phosphor persistence and
+	// photodiode response time).
+	zapperLightLines = 20
+
+	// zapperBrightness is the minimum brightness (average of the RGB
+	// components) of a pixel for the photodiode to sense it.
+	zapperBrightness = 0x55
+)
+
+// NewZapper returns a Zapper sensing the light of the pixels drawn by ppu. Aim
+// and trigger are read from provider.
+func NewZapper(ppu *PPU, provider zapperStateLoader) *Zapper {
+	return &Zapper{ppu: ppu, provider: provider}
+}
+
+// Strobe does nothing, the Zapper isn't a serial device.
+func (z *Zapper) Strobe(on bool) {}
+
+func (z *Zapper) Read() uint8 {
+	return z.Peek()
+}
+
+func (z *Zapper) Peek() uint8 {
+	x, y, ok, trigger := z.provider.ZapperState()
+
+	var val uint8
+	if !ok || !z.senseLight(x, y) {
+		val |= 0x08
+	}
+	if trigger {
+		val |= 0x10
+	}
+	return val
+}
+
+// senseLight reports whether a bright enough pixel, around (x, y), has
+// recently been drawn by the PPU.
+func (z *Zapper) senseLight(x, y int) bool {
+	scanline := z.ppu.Scanline
+	if scanline < 0 || scanline >= NTSCHeight {
+		return false
+	}
+	cycle := int(z.ppu.Cycle)
+
+	for py := max(y-zapperRadius, 0); py <= min(y+zapperRadius, NTSCHeight-1); py++ {
+		if py > scanline || scanline-py > zapperLightLines {
+			continue // not drawn yet or already faded.
+		}
+		for px := max(x-zapperRadius, 0); px <= min(x+zapperRadius, NTSCWidth-1); px++ {
+			if py == scanline && px > cycle-2 {
+				break // not drawn yet.
+			}
+			if brightness(z.ppu.framebuf[py*NTSCWidth+px]) >= zapperBrightness {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// brightness returns the average of the RGB components of an ABGR color.
+func brightness(col uint32) uint32 {
+	r, g, b := col&0xFF, col>>8&0xFF, col>>16&0xFF
+	return (r + g + b) / 3
+}
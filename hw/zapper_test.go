@@ -0,0 +1,91 @@
+package hw
+
+import "testing"
+
+type fakeZapperState struct {
+	x, y        int
+	ok, trigger bool
+}
+
+func (s fakeZapperState) ZapperState() (x, y int, ok, trigger bool) {
+	return s.x, s.y, s.ok, s.trigger
+}
+
+func TestZapper(t *testing.T) {
+	ppu := NewPPU()
+	// A white target at (100, 50), on a black screen.
+	ppu.framebuf[50*NTSCWidth+100] = nesPalette[0x30]
+
+	tests := []struct {
+		name     string
+		state    fakeZapperState
+		scanline int
+		cycle    uint32
+		want     uint8
+	}{
+		{
+			name:     "before target is drawn",
+			state:    fakeZapperState{x: 100, y: 50, ok: true},
+			scanline: 40, cycle: 100,
+			want: 0x08,
+		},
+		{
+			name:     "same scanline, before target pixel",
+			state:    fakeZapperState{x: 100, y: 50, ok: true},
+			scanline: 50, cycle: 90,
+			want: 0x08,
+		},
+		{
+			name:     "same scanline, after target pixel",
+			state:    fakeZapperState{x: 100, y: 50, ok: true},
+			scanline: 50, cycle: 120,
+			want: 0x00,
+		},
+		{
+			name:     "few scanlines after target",
+			state:    fakeZapperState{x: 100, y: 50, ok: true, trigger: true},
+			scanline: 60, cycle: 0,
+			want: 0x10,
+		},
+		{
+			name:     "close to target",
+			state:    fakeZapperState{x: 102, y: 49, ok: true},
+			scanline: 55, cycle: 0,
+			want: 0x00,
+		},
+		{
+			name:     "target faded",
+			state:    fakeZapperState{x: 100, y: 50, ok: true},
+			scanline: 80, cycle: 0,
+			want: 0x08,
+		},
+		{
+			name:     "vblank",
+			state:    fakeZapperState{x: 100, y: 50, ok: true},
+			scanline: 241, cycle: 0,
+			want: 0x08,
+		},
+		{
+			name:     "missed target",
+			state:    fakeZapperState{x: 120, y: 50, ok: true},
+			scanline: 55, cycle: 0,
+			want: 0x08,
+		},
+		{
+			name:     "offscreen",
+			state:    fakeZapperState{x: -1, y: -1, trigger: true},
+			scanline: 55, cycle: 0,
+			want: 0x18,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := NewZapper(ppu, tt.state)
+			ppu.Scanline, ppu.Cycle = tt.scanline, tt.cycle
+			if got := z.Read(); got != tt.want {
+				t.Errorf("Read() = %02X, want %02X", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package ines
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// nes20db.xml.gz is the gzip compressed cartridge database, in the NES 2.0
+// XML format, as maintained by the NesDev community. It's fetched by go
+// generate, from the file or URL set in the NES20DB environment variable, and
+// left untouched when NES20DB isn't set.
+//
+//go:generate go run ./dbgen/gen_nes20db.go -out nes20db.xml.gz
+//go:embed nes20db.xml.gz
+var nes20db []byte
+
+// Game is a cartridge database entry. It describes the hardware of a
+// cartridge, identified by the checksums of its PRG-ROM and CHR-ROM.
+type Game struct {
+	Title  string // file name of the dump, without extension
+	Region Region
+
+	CRC32 uint32 // CRC32 of PRG-ROM followed by CHR-ROM
+	SHA1  string // SHA-1 (hex) of PRG-ROM followed by CHR-ROM, may be empty
+
+	PRGROMSize int
+	CHRROMSize int
+	PRGRAMSize int
+	PRGNVRAM   int
+	CHRRAMSize int
+	CHRNVRAM   int
+
+	Mapper    uint16
+	SubMapper uint8
+	Mirroring string // "H", "V" or "4"
+	Battery   bool
+
+	ConsoleType uint8 // NES 2.0 console type
+	VsHardware  uint8
+	VsPPU       uint8
+	MiscROMs    uint8
+	Expansion   uint8 // NES 2.0 default expansion device
+}
+
+// DB is a cartridge database.
+type DB struct {
+	games map[uint32][]*Game // indexed by CRC32
+}
+
+// Len returns the number of games in the database.
+func (db *DB) Len() int {
+	n := 0
+	for _, games := range db.games {
+		n += len(games)
+	}
+	return n
+}
+
+// Lookup returns the game having the given PRG-ROM and CHR-ROM, or nil if it's
+// not in the database. The CRC32 is used as key, the SHA-1, if present in the
+// database, has to match as well.
+func (db *DB) Lookup(prgrom, chrrom []byte) *Game {
+	crc := crc32.Update(crc32.ChecksumIEEE(prgrom), crc32.IEEETable, chrrom)
+	games := db.games[crc]
+	if len(games) == 0 {
+		return nil
+	}
+
+	h := sha1.New()
+	h.Write(prgrom)
+	h.Write(chrrom)
+	sum := hex.EncodeToString(h.Sum(nil))
+	for _, g := range games {
+		if g.SHA1 == "" || strings.EqualFold(g.SHA1, sum) {
+			return g
+		}
+	}
+	return nil
+}
+
+// DefaultDB returns the embedded cartridge database.
+var DefaultDB = sync.OnceValue(func() *DB {
+	zr, err := gzip.NewReader(bytes.NewReader(nes20db))
+	if err != nil {
+		panic(fmt.Sprintf("embedded cartridge database: %v", err))
+	}
+	db, err := ParseDB(zr)
+	if err != nil {
+		panic(fmt.Sprintf("embedded cartridge database: %v", err))
+	}
+	return db
+})
+
+// XML representation of the NES 2.0 database.
+type (
+	xmlDB struct {
+		Games []xmlGame `xml:"game"`
+	}
+
+	xmlGame struct {
+		Comment   string     `xml:",comment"`
+		ROM       xmlROM     `xml:"rom"`
+		PRGROM    xmlROM     `xml:"prgrom"`
+		CHRROM    *xmlROM    `xml:"chrrom"`
+		PRGRAM    *xmlSize   `xml:"prgram"`
+		PRGNVRAM  *xmlSize   `xml:"prgnvram"`
+		CHRRAM    *xmlSize   `xml:"chrram"`
+		CHRNVRAM  *xmlSize   `xml:"chrnvram"`
+		MiscROM   *xmlMisc   `xml:"miscrom"`
+		PCB       xmlPCB     `xml:"pcb"`
+		Console   xmlConsole `xml:"console"`
+		Vs        *xmlVs     `xml:"vs"`
+		Expansion *xmlSize   `xml:"expansion"`
+	}
+
+	xmlROM struct {
+		Size  int    `xml:"size,attr"`
+		CRC32 string `xml:"crc32,attr"`
+		SHA1  string `xml:"sha1,attr"`
+	}
+
+	xmlSize struct {
+		Size int `xml:"size,attr"`
+		Type int `xml:"type,attr"`
+	}
+
+	xmlMisc struct {
+		Number int `xml:"number,attr"`
+	}
+
+	xmlPCB struct {
+		Mapper    int    `xml:"mapper,attr"`
+		SubMapper int    `xml:"submapper,attr"`
+		Mirroring string `xml:"mirroring,attr"`
+		Battery   int    `xml:"battery,attr"`
+	}
+
+	xmlConsole struct {
+		Type   int `xml:"type,attr"`
+		Region int `xml:"region,attr"`
+	}
+
+	xmlVs struct {
+		Hardware int `xml:"hardware,attr"`
+		PPU      int `xml:"ppu,attr"`
+	}
+)
+
+// ParseDB parses a cartridge database in the NES 2.0 XML format.
+func ParseDB(r io.Reader) (*DB, error) {
+	var xdb xmlDB
+	if err := xml.NewDecoder(r).Decode(&xdb); err != nil {
+		return nil, err
+	}
+
+	db := &DB{games: make(map[uint32][]*Game, len(xdb.Games))}
+	for i, xg := range xdb.Games {
+		crc, err := strconv.ParseUint(xg.ROM.CRC32, 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("game %d: invalid rom crc32 %q", i, xg.ROM.CRC32)
+		}
+
+		g := &Game{
+			Title:       gameTitle(xg.Comment),
+			Region:      Region(xg.Console.Region & 0x03),
+			CRC32:       uint32(crc),
+			SHA1:        xg.ROM.SHA1,
+			PRGROMSize:  xg.PRGROM.Size,
+			Mapper:      uint16(xg.PCB.Mapper),
+			SubMapper:   uint8(xg.PCB.SubMapper),
+			Mirroring:   xg.PCB.Mirroring,
+			Battery:     xg.PCB.Battery != 0,
+			ConsoleType: uint8(xg.Console.Type),
+		}
+		if xg.CHRROM != nil {
+			g.CHRROMSize = xg.CHRROM.Size
+		}
+		if xg.PRGRAM != nil {
+			g.PRGRAMSize = xg.PRGRAM.Size
+		}
+		if xg.PRGNVRAM != nil {
+			g.PRGNVRAM = xg.PRGNVRAM.Size
+		}
+		if xg.CHRRAM != nil {
+			g.CHRRAMSize = xg.CHRRAM.Size
+		}
+		if xg.CHRNVRAM != nil {
+			g.CHRNVRAM = xg.CHRNVRAM.Size
+		}
+		if xg.MiscROM != nil {
+			g.MiscROMs = uint8(xg.MiscROM.Number)
+		}
+		if xg.Vs != nil {
+			g.VsHardware = uint8(xg.Vs.Hardware)
+			g.VsPPU = uint8(xg.Vs.PPU)
+		}
+		if xg.Expansion != nil {
+			g.Expansion = uint8(xg.Expansion.Type)
+		}
+		db.games[g.CRC32] = append(db.games[g.CRC32], g)
+	}
+	return db, nil
+}
+
+// gameTitle extracts the game title from the comment preceding each game in
+// the database, which holds the path of the dump, e.g:
+//
+//	<!-- Licensed\Super Mario Bros. (World).nes -->
+func gameTitle(comment string) string {
+	title := strings.TrimSpace(comment)
+	if i := strings.LastIndexAny(title, `\/`); i != -1 {
+		title = title[i+1:]
+	}
+	return strings.TrimSuffix(title, ".nes")
+}
+
+// header returns the NES 2.0 header describing the game. The trainer flag,
+// which depends on the file layout rather than on the cartridge, is taken
+// from the file header.
+func (g *Game) header(file *header) header {
+	var raw [16]byte
+	copy(raw[:], Magic)
+
+	nprg, nchr := g.PRGROMSize/0x4000, g.CHRROMSize/0x2000
+	raw[4] = uint8(nprg)
+	raw[5] = uint8(nchr)
+	raw[6] = uint8(g.Mapper&0x0F)<<4 | file.raw[6]&0x04
+	raw[7] = uint8(g.Mapper&0xF0) | 0x08 | g.ConsoleType&0x03
+	raw[8] = g.SubMapper<<4 | uint8(g.Mapper>>8&0x0F)
+	raw[9] = uint8(nchr>>8&0x0F)<<4 | uint8(nprg>>8&0x0F)
+	raw[10] = sizeShift(g.PRGNVRAM)<<4 | sizeShift(g.PRGRAMSize)
+	raw[11] = sizeShift(g.CHRNVRAM)<<4 | sizeShift(g.CHRRAMSize)
+	raw[12] = uint8(g.Region)
+	raw[13] = g.VsHardware<<4 | g.VsPPU&0x0F
+	raw[14] = g.MiscROMs & 0x03
+	raw[15] = g.Expansion & 0x3F
+
+	switch g.Mirroring {
+	case "V":
+		raw[6] |= 0x01
+	case "4":
+		raw[6] |= 0x08
+	}
+	if g.Battery {
+		raw[6] |= 0x02
+	}
+
+	var hdr header
+	hdr.decode(raw[:])
+	return hdr
+}
+
+// sizeShift is the inverse of shiftSize, sizes which aren't a power of 2 are
+// rounded up.
+func sizeShift(size int) uint8 {
+	if size <= 0 {
+		return 0
+	}
+	shift := bits.Len(uint(size-1)) - 6
+	return uint8(max(shift, 1))
+}
@@ -0,0 +1,140 @@
+package ines
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"testing"
+
+	"nestor/tests"
+)
+
+func TestDefaultDB(t *testing.T) {
+	// Ensures the embedded database is valid.
+	db := DefaultDB()
+	if db.Len() == 0 {
+		t.Fatal("embedded database is empty, run 'go generate ./ines' with NES20DB set")
+	}
+
+	// Every game must correct an iNES 1.0 header having a wrong mapper,
+	// mirroring and no battery flag.
+	var file header
+	if err := file.decode([]byte(Magic + "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")); err != nil {
+		t.Fatal(err)
+	}
+	for _, games := range db.games {
+		for _, g := range games {
+			if g.PRGROMSize%0x4000 != 0 || g.CHRROMSize%0x2000 != 0 {
+				// Can't be stored in an iNES file.
+				continue
+			}
+			hdr := g.header(&file)
+			if !hdr.IsNES20() {
+				t.Fatalf("%s: corrected header isn't NES 2.0", g.Title)
+			}
+			if hdr.Mapper() != g.Mapper || hdr.SubMapper() != g.SubMapper {
+				t.Errorf("%s: mapper %d.%d, want %d.%d", g.Title, hdr.Mapper(), hdr.SubMapper(), g.Mapper, g.SubMapper)
+			}
+			if hdr.nslotsPRGROM()*0x4000 != g.PRGROMSize || hdr.nslotsCHRROM()*0x2000 != g.CHRROMSize {
+				t.Errorf("%s: PRG/CHR sizes %d/%d slots, want %d/%d bytes", g.Title,
+					hdr.nslotsPRGROM(), hdr.nslotsCHRROM(), g.PRGROMSize, g.CHRROMSize)
+			}
+			if hdr.HasPersistence() != g.Battery {
+				t.Errorf("%s: HasPersistence() = %t, want %t", g.Title, hdr.HasPersistence(), g.Battery)
+			}
+		}
+	}
+}
+
+func TestDBOverride(t *testing.T) {
+	// iNES 1.0 rom with a wrong mapper, mirroring, and missing battery flag.
+	img := tests.MapperRom(0, 0, 32, 8)
+	img[7] = 0
+
+	data := img[16:]
+	sum := sha1.Sum(data)
+	xml := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<nes20db>
+	<game>
+		<!-- Licensed\Test Game (Europe).nes -->
+		<rom size="%[1]d" crc32="%08[2]X" sha1="%[3]X"/>
+		<prgrom size="32768" crc32="00000000" sha1="00"/>
+		<chrrom size="8192" crc32="00000000" sha1="00"/>
+		<prgnvram size="8192"/>
+		<pcb mapper="1" submapper="5" mirroring="V" battery="1"/>
+		<console type="0" region="1"/>
+	</game>
+	<game>
+		<!-- Same CRC32, other SHA-1 -->
+		<rom size="%[1]d" crc32="%08[2]X" sha1="0000000000000000000000000000000000000000"/>
+		<prgrom size="32768" crc32="00000000" sha1="00"/>
+		<chrrom size="8192" crc32="00000000" sha1="00"/>
+		<pcb mapper="2" submapper="0" mirroring="H" battery="0"/>
+		<console type="0" region="0"/>
+	</game>
+</nes20db>
+`, len(data), crc32.ChecksumIEEE(data), sum)
+
+	db, err := ParseDB(strings.NewReader(xml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Len() != 2 {
+		t.Fatalf("db.Len() = %d, want 2", db.Len())
+	}
+
+	rom, err := decode(img, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rom.Game == nil {
+		t.Fatalf("rom not found in database")
+	}
+	if rom.Game.Title != "Test Game (Europe)" {
+		t.Errorf("Game.Title = %q, want %q", rom.Game.Title, "Test Game (Europe)")
+	}
+
+	if !rom.IsNES20() {
+		t.Errorf("IsNES20() = false, want true")
+	}
+	if got := rom.Mapper(); got != 1 {
+		t.Errorf("Mapper() = %d, want 1", got)
+	}
+	if got := rom.SubMapper(); got != 5 {
+		t.Errorf("SubMapper() = %d, want 5", got)
+	}
+	if got := rom.Mirroring(); got != VertMirroring {
+		t.Errorf("Mirroring() = %s, want %s", got, VertMirroring)
+	}
+	if !rom.HasPersistence() {
+		t.Errorf("HasPersistence() = false, want true")
+	}
+	if got := rom.PRGNVRAMSize(); got != 8192 {
+		t.Errorf("PRGNVRAMSize() = %d, want 8192", got)
+	}
+	if got := rom.Region(); got != PAL {
+		t.Errorf("Region() = %s, want %s", got, PAL)
+	}
+	if got := rom.fileHeader.Mapper(); got != 0 {
+		t.Errorf("file header Mapper() = %d, want 0", got)
+	}
+
+	var buf bytes.Buffer
+	rom.PrintInfos(&buf)
+	for _, want := range []string{"Test Game (Europe)", "file header", "database"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("PrintInfos output doesn't contain %q:\n%s", want, buf.String())
+		}
+	}
+
+	// A rom which isn't in the database is left untouched.
+	img[16] ^= 0xFF
+	if rom, err = decode(img, db); err != nil {
+		t.Fatal(err)
+	}
+	if rom.Game != nil || rom.IsNES20() {
+		t.Errorf("rom unexpectedly found in database")
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// gen_nes20db fetches the NES 2.0 XML database, from a file or an URL, and
+// stores it gzip compressed, ready to be embedded.
+
+func main() {
+	log.SetFlags(0)
+	inf := flag.String("in", os.Getenv("NES20DB"), "path or URL of the NES 2.0 XML database (default $NES20DB)")
+	outf := flag.String("out", "nes20db.xml.gz", "output file")
+	flag.Parse()
+
+	// Don't break 'go generate' for the other generators of the package.
+	if *inf == "" {
+		log.Printf("NES20DB not set, %s left untouched", *outf)
+		return
+	}
+
+	buf, err := fetch(*inf)
+	if err != nil {
+		log.Fatalf("failed to fetch database: %s", err)
+	}
+
+	// Check the database before storing it.
+	var db struct {
+		XMLName xml.Name   `xml:"nes20db"`
+		Games   []struct{} `xml:"game"`
+	}
+	if err := xml.Unmarshal(buf, &db); err != nil {
+		log.Fatalf("invalid database: %s", err)
+	}
+	if len(db.Games) == 0 {
+		log.Fatal("invalid database: no games")
+	}
+
+	var gz bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&gz, gzip.BestCompression)
+	zw.Write(buf)
+	if err := zw.Close(); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*outf, gz.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("%s: %d games, %d bytes", *outf, len(db.Games), gz.Len())
+}
+
+func fetch(loc string) ([]byte, error) {
+	if !strings.HasPrefix(loc, "http://") && !strings.HasPrefix(loc, "https://") {
+		return os.ReadFile(loc)
+	}
+
+	resp, err := http.Get(loc)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", loc, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
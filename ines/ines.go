@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 )
 
 type Rom struct {
@@ -16,6 +17,21 @@ type Rom struct {
 	CHRROM  []uint8 // CHRROM data (size is a multiple of 8k)
 
 	Name string
+
+	// Board is the normalized UNIF board name, for roms converted from UNIF
+	// files, or empty.
+	Board string
+
+	// OneScreen is the hard-wired one-screen mirroring (OnlyAScreen or
+	// OnlyBScreen) of roms converted from UNIF files, which the header can't
+	// express, or 0.
+	OneScreen NTMirroring
+
+	// Game is the cartridge database entry matching the rom, or nil. When
+	// set, the header has been rebuilt from the database and fileHeader is
+	// the header found in the file.
+	Game       *Game
+	fileHeader header
 }
 
 func yn(b bool) string {
@@ -27,30 +43,62 @@ func yn(b bool) string {
 
 func (rom *Rom) PrintInfos(w io.Writer) {
 	fmt.Fprintf(w, "%s\n", rom.Name)
-	fmt.Fprintf(w, "|iNES2.0                | % 14s |\n", yn(rom.IsNES20()))
-	if rom.IsNES20() {
-		fmt.Fprintf(w, "|Region                 | % 14s |\n", rom.Region())
+	if rom.Game != nil {
+		fmt.Fprintf(w, "|Database title         | % 14s |\n", rom.Game.Title)
+		fmt.Fprintf(w, "|Database region        | % 14s |\n", rom.Game.Region)
 	}
-	fmt.Fprintf(w, "|Mapper                 | % 14d |\n", rom.Mapper())
-	if rom.IsNES20() {
-		fmt.Fprintf(w, "|Submapper              | % 14d |\n", rom.SubMapper())
+	if rom.Board != "" {
+		fmt.Fprintf(w, "|UNIF board             | % 14s |\n", rom.Board)
 	}
-	fmt.Fprintf(w, "|PRG ROM                | % 8d x 16k |\n", rom.nslotsPRGROM())
-	fmt.Fprintf(w, "|CHR ROM                | % 9d x 8k |\n", rom.nslotsCHRROM())
-	if rom.IsNES20() {
-		fmt.Fprintf(w, "|PRG RAM                | % 13dk |\n", rom.PRGRAMSize()/1024)
-		fmt.Fprintf(w, "|PRG NVRAM              | % 13dk |\n", rom.PRGNVRAMSize()/1024)
-		fmt.Fprintf(w, "|CHR RAM                | % 13dk |\n", rom.CHRRAMSize()/1024)
-		fmt.Fprintf(w, "|CHR NVRAM              | % 13dk |\n", rom.CHRNVRAMSize()/1024)
+
+	// When the rom is in the database, both the values found in the file
+	// header and the corrected ones are shown.
+	hdrs := []*header{&rom.header}
+	if rom.Game != nil {
+		hdrs = []*header{&rom.fileHeader, &rom.header}
+		fmt.Fprintf(w, "|                       |    file header |       database |\n")
 	}
-	if rom.IsNES20() {
-		fmt.Fprintf(w, "|Bus conflicts          | % 14s |\n", yn(rom.HasBusConflicts()))
+	// NES 2.0 only values are shown as "-" for iNES 1.0 headers, and the row
+	// is skipped entirely if there's no NES 2.0 header at all.
+	row := func(label string, nes20 bool, value func(*header) string) {
+		if nes20 && !slices.ContainsFunc(hdrs, (*header).IsNES20) {
+			return
+		}
+		fmt.Fprintf(w, "|%-23s|", label)
+		for _, hdr := range hdrs {
+			v := "-"
+			if !nes20 || hdr.IsNES20() {
+				v = value(hdr)
+			}
+			fmt.Fprintf(w, " % 14s |", v)
+		}
+		fmt.Fprintln(w)
 	}
 
-	fmt.Fprintf(w, "|Nametable mirroring    | % 14s |\n", rom.Mirroring())
-	fmt.Fprintf(w, "|Alternative nametable  | % 14s |\n", yn(rom.HasAltNametables()))
-	fmt.Fprintf(w, "|Trainer                | % 14s |\n", yn(rom.HasTrainer()))
-	fmt.Fprintf(w, "|Persistent             | % 14s |\n", yn(rom.HasPersistence()))
+	itoa := func(v int) string { return fmt.Sprint(v) }
+	kb := func(v int) string { return fmt.Sprintf("%dk", v/1024) }
+
+	row("iNES2.0", false, func(h *header) string { return yn(h.IsNES20()) })
+	row("Region", true, func(h *header) string { return h.Region().String() })
+	row("Console", false, func(h *header) string { return h.ConsoleType().String() })
+	if slices.ContainsFunc(hdrs, func(h *header) bool { return h.ConsoleType() == VsSystem }) {
+		row("Vs. PPU", false, func(h *header) string { return h.VsPPU().String() })
+		row("Vs. hardware", true, func(h *header) string { return h.VsHardware().String() })
+	}
+	row("Input device", true, func(h *header) string { return h.ExpansionDevice().String() })
+	row("Mapper", false, func(h *header) string { return itoa(int(h.Mapper())) })
+	row("Submapper", true, func(h *header) string { return itoa(int(h.SubMapper())) })
+	row("PRG ROM", false, func(h *header) string { return fmt.Sprintf("%d x 16k", h.nslotsPRGROM()) })
+	row("CHR ROM", false, func(h *header) string { return fmt.Sprintf("%d x 8k", h.nslotsCHRROM()) })
+	row("PRG RAM", true, func(h *header) string { return kb(h.PRGRAMSize()) })
+	row("PRG NVRAM", true, func(h *header) string { return kb(h.PRGNVRAMSize()) })
+	row("CHR RAM", true, func(h *header) string { return kb(h.CHRRAMSize()) })
+	row("CHR NVRAM", true, func(h *header) string { return kb(h.CHRNVRAMSize()) })
+	row("Bus conflicts", true, func(h *header) string { return yn(h.HasBusConflicts()) })
+	row("Nametable mirroring", false, func(h *header) string { return h.Mirroring().String() })
+	row("Alternative nametable", false, func(h *header) string { return yn(h.HasAltNametables()) })
+	row("Trainer", false, func(h *header) string { return yn(h.HasTrainer()) })
+	row("Persistent", false, func(h *header) string { return yn(h.HasPersistence()) })
 }
 
 // ReadRom loads a rom from an iNES file.
@@ -68,8 +116,13 @@ func ReadRom(path string) (*Rom, error) {
 	return rom, nil
 }
 
-// Decode the give buffer into a rom file.
+// Decode the give buffer into a rom file. If the rom is found in the embedded
+// cartridge database, the header is corrected with the database values.
 func Decode(buf []byte) (*Rom, error) {
+	return decode(buf, DefaultDB())
+}
+
+func decode(buf []byte, db *DB) (*Rom, error) {
 	rom := new(Rom)
 
 	// header
@@ -104,6 +157,13 @@ func Decode(buf []byte) (*Rom, error) {
 	rom.CHRROM = buf[off : off+chrRomSize]
 	off += chrRomSize
 
+	if g := db.Lookup(rom.PRGROM, rom.CHRROM); g != nil {
+		if g.PRGROMSize == len(rom.PRGROM) && g.CHRROMSize == len(rom.CHRROM) {
+			rom.Game = g
+			rom.fileHeader = rom.header
+			rom.header = g.header(&rom.fileHeader)
+		}
+	}
 	return rom, nil
 }
 
@@ -133,14 +193,23 @@ func (hdr *header) decode(p []byte) error {
 	if hdr.IsNES20() {
 		hdr.prgromsz |= int(hdr.raw[9]&0x0F) << 8
 		hdr.chrromsz |= int(hdr.raw[9] & 0xF0)
-		hdr.prgramsz = 64 << int(hdr.raw[10]&0x0F)
-		hdr.prgnvramsz = 64 << int(hdr.raw[10]>>4)
-		hdr.chrramsz = 64 << int(hdr.raw[11]&0x0F)
-		hdr.chrnvramsz = 64 << int(hdr.raw[11]>>4)
+		hdr.prgramsz = shiftSize(hdr.raw[10] & 0x0F)
+		hdr.prgnvramsz = shiftSize(hdr.raw[10] >> 4)
+		hdr.chrramsz = shiftSize(hdr.raw[11] & 0x0F)
+		hdr.chrnvramsz = shiftSize(hdr.raw[11] >> 4)
 	}
 	return nil
 }
 
+// shiftSize decodes a NES 2.0 RAM size shift count. A zero shift count means
+// there's no RAM at all.
+func shiftSize(shift uint8) int {
+	if shift == 0 {
+		return 0
+	}
+	return 64 << int(shift)
+}
+
 // nslotsPRGROM returns the number of 16kB slots of PRGROM.
 func (hdr *header) nslotsPRGROM() int {
 	return hdr.prgromsz
@@ -187,16 +256,175 @@ const (
 
 func (hdr *header) Region() Region {
 	if hdr.IsNES20() {
-		return Region(hdr.raw[10] & 0x03)
+		return Region(hdr.raw[12] & 0x03)
 	}
 	return Unspecified
 }
 
+//go:generate go run golang.org/x/tools/cmd/stringer -type=ConsoleType -linecomment
+
+// ConsoleType is the console the rom runs on.
+type ConsoleType byte
+
+const (
+	NES        ConsoleType = iota // NES/Famicom
+	VsSystem                      // Vs. System
+	Playchoice                    // Playchoice-10
+	Extended                      // Extended
+)
+
+// ConsoleType returns the console the rom runs on, iNES 1.0 headers can't
+// tell the extended console types.
+func (hdr *header) ConsoleType() ConsoleType {
+	if hdr.IsNES20() {
+		return ConsoleType(hdr.raw[7] & 0x03)
+	}
+	switch {
+	case hdr.raw[7]&0x01 != 0:
+		return VsSystem
+	case hdr.raw[7]&0x02 != 0:
+		return Playchoice
+	}
+	return NES
+}
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type=VsPPU -linecomment
+
+// VsPPU is the PPU model of a Vs. System arcade board.
+type VsPPU byte
+
+const (
+	RP2C03B     VsPPU = iota // RP2C03B
+	RP2C03G                  // RP2C03G
+	RP2C04_0001              // RP2C04-0001
+	RP2C04_0002              // RP2C04-0002
+	RP2C04_0003              // RP2C04-0003
+	RP2C04_0004              // RP2C04-0004
+	RC2C03B                  // RC2C03B
+	RC2C03C                  // RC2C03C
+	RC2C05_01                // RC2C05-01
+	RC2C05_02                // RC2C05-02
+	RC2C05_03                // RC2C05-03
+	RC2C05_04                // RC2C05-04
+	RC2C05_05                // RC2C05-05
+)
+
+func (p VsPPU) MarshalText() ([]byte, error) {
+	if p > RC2C05_05 {
+		return nil, fmt.Errorf("invalid Vs. PPU %d", p)
+	}
+	return []byte(p.String()), nil
+}
+
+func (p *VsPPU) UnmarshalText(text []byte) error {
+	for m := RP2C03B; m <= RC2C05_05; m++ {
+		if m.String() == string(text) {
+			*p = m
+			return nil
+		}
+	}
+	return fmt.Errorf("unrecognized Vs. PPU %q", text)
+}
+
+// VsPPU returns the PPU model of Vs. System roms. iNES 1.0 headers don't
+// specify it, RP2C03B is assumed.
+func (hdr *header) VsPPU() VsPPU {
+	if hdr.IsNES20() {
+		return VsPPU(hdr.raw[13] & 0x0F)
+	}
+	return RP2C03B
+}
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type=VsHardware -linecomment
+
+// VsHardware is the hardware type of a Vs. System arcade board, either the
+// single or the dual CPU system, along with the protection used by some
+// games.
+type VsHardware byte
+
+const (
+	VsUnisystem          VsHardware = iota // Unisystem
+	VsRBIBaseball                          // RBI Baseball
+	VsTKOBoxing                            // TKO Boxing
+	VsSuperXevious                         // Super Xevious
+	VsIceClimberJapan                      // Ice Climber JP
+	VsDualSystem                           // Dual System
+	VsRaidOnBungelingBay                   // Bungeling Bay
+)
+
+// VsHardware returns the hardware type of Vs. System roms.
+func (hdr *header) VsHardware() VsHardware {
+	if hdr.IsNES20() {
+		return VsHardware(hdr.raw[13] >> 4)
+	}
+	return VsUnisystem
+}
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type=ExpansionDevice -linecomment
+
+// ExpansionDevice is the default input device of NES 2.0 roms, plugged in the
+// controller ports or the Famicom expansion port.
+type ExpansionDevice byte
+
+const (
+	ExpUnspecified          ExpansionDevice = iota // Unspecified
+	ExpStdControllers                              // Controllers
+	ExpFourScore                                   // Four Score
+	ExpFamicomFourPlayers                          // Famicom 4P
+	ExpVsSystem4016                                // Vs. System
+	ExpVsSystem4017                                // Vs. System P2
+	ExpReserved                                    // Reserved
+	ExpVsZapper                                    // Vs. Zapper
+	ExpZapper                                      // Zapper
+	ExpTwoZappers                                  // Two Zappers
+	ExpBandaiHyperShot                             // Hyper Shot
+	ExpPowerPadA                                   // Power Pad A
+	ExpPowerPadB                                   // Power Pad B
+	ExpFamilyTrainerA                              // Trainer A
+	ExpFamilyTrainerB                              // Trainer B
+	ExpVausNES                                     // Vaus
+	ExpVausFamicom                                 // Vaus (FC)
+	ExpTwoVausDataRecorder                         // Two Vaus
+	ExpKonamiHyperShot                             // Konami HS
+	ExpCoconutsPachinko                            // Pachinko
+	ExpPunchingBag                                 // Punching Bag
+	ExpJissenMahjong                               // Mahjong
+	ExpPartyTap                                    // Party Tap
+	ExpOekaKidsTablet                              // Oeka Kids
+	ExpBarcodeBattler                              // Barcode Battler
+	ExpMiraclePiano                                // Miracle Piano
+	ExpPokkunMoguraa                               // Pokkun Moguraa
+	ExpTopRider                                    // Top Rider
+	ExpDoubleFisted                                // Double-Fisted
+	ExpFamicom3D                                   // Famicom 3D
+	ExpDoremikkoKeyboard                           // Doremikko
+	ExpROBGyromite                                 // R.O.B. Gyro
+	ExpDataRecorder                                // Data Recorder
+	ExpTurboFile                                   // Turbo File
+	ExpBattleBox                                   // Battle Box
+	ExpFamilyBASICKeyboard                         // Family BASIC
+	ExpPEC586Keyboard                              // PEC-586
+	ExpBit79Keyboard                               // Bit-79
+	ExpSuborKeyboard                               // Subor Keyboard
+	ExpSuborKeyboardMouse                          // Subor Mouse
+	ExpSuborKeyboardMouse24                        // Subor Mouse 24
+	ExpSNESMouse                                   // SNES Mouse
+	ExpMulticart                                   // Multicart
+)
+
+// ExpansionDevice returns the default input device of NES 2.0 roms.
+func (hdr *header) ExpansionDevice() ExpansionDevice {
+	if hdr.IsNES20() {
+		return ExpansionDevice(hdr.raw[15] & 0x3F)
+	}
+	return ExpUnspecified
+}
+
 // Mapper returns the mapper number.
 func (hdr *header) Mapper() uint16 {
 	base := uint16(hdr.raw[7]&0xF0) | uint16(hdr.raw[6]>>4)
 	if hdr.IsNES20() {
-		return uint16(hdr.raw[8]&0x0F) | base
+		return uint16(hdr.raw[8]&0x0F)<<8 | base
 	}
 	return base
 }
@@ -233,6 +461,15 @@ func (hdr *header) Mirroring() NTMirroring {
 	return HorzMirroring
 }
 
+// Mirroring returns the hard-wired nametable mirroring, from the header or
+// OneScreen when set.
+func (rom *Rom) Mirroring() NTMirroring {
+	if rom.OneScreen != 0 {
+		return rom.OneScreen
+	}
+	return rom.header.Mirroring()
+}
+
 // HasPersistence indicates the presence of persistent saved memory in the rom.
 // The original cartridge contained battery-backed PRG RAM ($6000-7FFF) or other
 // persistent memory.
@@ -39,3 +39,67 @@ func TestRomOpen(t *testing.T) {
 		})
 	}
 }
+
+func TestNES20Mapper(t *testing.T) {
+	for _, mapper := range []uint16{0, 1, 255, 256, 4000} {
+		rom, err := Decode(tests.MapperRom(mapper, 3, 32, 8))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rom.Mapper(); got != mapper {
+			t.Errorf("Mapper() = %d, want %d", got, mapper)
+		}
+		if got := rom.SubMapper(); got != 3 {
+			t.Errorf("mapper %d: SubMapper() = %d, want 3", mapper, got)
+		}
+	}
+}
+
+func TestVsSystem(t *testing.T) {
+	buf := tests.MapperRom(99, 0, 32, 8)
+	buf[7] |= 0x01         // Vs. System
+	buf[13] = 0x5<<4 | 0x9 // Vs. Dual System, RC2C05-02
+	rom, err := Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rom.ConsoleType(); got != VsSystem {
+		t.Errorf("ConsoleType() = %s, want %s", got, VsSystem)
+	}
+	if got := rom.VsPPU(); got != RC2C05_02 {
+		t.Errorf("VsPPU() = %s, want %s", got, RC2C05_02)
+	}
+	if got := rom.VsHardware(); got != VsDualSystem {
+		t.Errorf("VsHardware() = %s, want %s", got, VsDualSystem)
+	}
+
+	// iNES 1.0
+	buf[7] &^= 0x0C
+	if rom, err = Decode(buf); err != nil {
+		t.Fatal(err)
+	}
+	if rom.ConsoleType() != VsSystem || rom.VsPPU() != RP2C03B {
+		t.Errorf("iNES 1.0: ConsoleType(), VsPPU() = %s, %s, want %s, %s", rom.ConsoleType(), rom.VsPPU(), VsSystem, RP2C03B)
+	}
+}
+
+func TestExpansionDevice(t *testing.T) {
+	buf := tests.MapperRom(0, 0, 2, 1)
+	buf[15] = 0x0F // Arkanoid Vaus
+	rom, err := Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rom.ExpansionDevice(); got != ExpVausNES {
+		t.Errorf("ExpansionDevice() = %s, want %s", got, ExpVausNES)
+	}
+
+	// iNES 1.0
+	buf[7] &^= 0x0C
+	if rom, err = Decode(buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := rom.ExpansionDevice(); got != ExpUnspecified {
+		t.Errorf("iNES 1.0: ExpansionDevice() = %s, want %s", got, ExpUnspecified)
+	}
+}
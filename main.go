@@ -6,8 +6,11 @@ import (
 	"runtime/debug"
 	"slices"
 
+	"nestor/fds"
 	"nestor/ines"
+	"nestor/patch"
 	"nestor/ui"
+	"nestor/unif"
 )
 
 func main() {
@@ -19,23 +22,98 @@ func main() {
 	case guiMode:
 		ui.RunApp(&cfg)
 	case romInfosMode:
-		romInfosMain(args.RomInfos.RomPath)
+		romInfosMain(args.RomInfos)
 	case runMode:
 		emuMain(args.Run, &cfg)
+	case nsfMode:
+		nsfMain(args.NSF, &cfg)
 	case captureMode:
 		captureMain(args.Capture)
 	case versionMode:
 		versionMain()
+	case patchCreateMode:
+		patchCreateMain(args.Patch.Create)
+	case patchApplyMode:
+		patchApplyMain(args.Patch.Apply)
 	}
 }
 
-func romInfosMain(romPath string) {
-	rom, err := ines.ReadRom(romPath)
+func romInfosMain(args RomInfos) {
+	rf, err := openRomFile(args.RomPath, args.Entry, args.Patch)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error opening ROM: %s", err)
+		os.Exit(1)
+	}
+	buf, err := rf.read()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error reading ROM: %s", err)
 		os.Exit(1)
 	}
-	rom.PrintInfos(os.Stdout)
+
+	switch {
+	case isDiskImage(rf.name()):
+		disk, err := fds.Decode(buf)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading disk image: %s", err)
+			os.Exit(1)
+		}
+		disk.Name = rf.name()
+		disk.PrintInfos(os.Stdout)
+	case isNSF(rf.name()):
+		f, err := rf.readNSF()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading NSF file: %s", err)
+			os.Exit(1)
+		}
+		f.PrintInfos(os.Stdout)
+	case isUNIF(rf.name()):
+		f, err := unif.Decode(buf)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading UNIF ROM: %s", err)
+			os.Exit(1)
+		}
+		f.Name = rf.name()
+		f.PrintInfos(os.Stdout)
+	default:
+		rom, err := ines.Decode(buf)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading ROM: %s", err)
+			os.Exit(1)
+		}
+		rom.Name = rf.name()
+		rom.PrintInfos(os.Stdout)
+	}
+}
+
+func patchCreateMain(args PatchCreate) {
+	format, ok := patch.FormatOf(args.Output)
+	if args.Format != "" {
+		format, ok = patch.ParseFormat(args.Format)
+	}
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown patch format, use --format or a .ips, .bps or .ups extension")
+		os.Exit(1)
+	}
+
+	orig, err := os.ReadFile(args.Orig)
+	checkf(err, "failed to read original ROM")
+	mod, err := os.ReadFile(args.Mod)
+	checkf(err, "failed to read modified ROM")
+
+	buf, err := patch.Create(format, orig, mod)
+	checkf(err, "failed to create patch")
+	checkf(os.WriteFile(args.Output, buf, 0644), "failed to write patch")
+}
+
+func patchApplyMain(args PatchApply) {
+	rom, err := os.ReadFile(args.RomPath)
+	checkf(err, "failed to read ROM")
+	p, err := os.ReadFile(args.Patch)
+	checkf(err, "failed to read patch")
+
+	buf, err := patch.Apply(rom, p)
+	checkf(err, "failed to apply patch")
+	checkf(os.WriteFile(args.Output, buf, 0644), "failed to write patched ROM")
 }
 
 func versionMain() {
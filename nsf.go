@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/veandco/go-sdl2/sdl"
+
+	"nestor/emu"
+	"nestor/ui"
+)
+
+// nsfMain plays a NSF file, or renders one of its tracks to a WAV file.
+func nsfMain(args NSF, cfg *ui.Config) {
+	rf, err := openRomFile(args.Path, args.Entry, "")
+	checkf(err, "failed to open NSF file")
+	f, err := rf.readNSF()
+	checkf(err, "failed to read NSF file")
+
+	if args.Track != 0 {
+		if args.Track < 1 || args.Track > f.Songs {
+			fatalf("invalid track %d, %s has %d tracks", args.Track, f.Name, f.Songs)
+		}
+		f.StartSong = args.Track - 1
+	}
+	f.PrintInfos(os.Stdout)
+
+	if args.WAV != "" {
+		out, err := os.Create(args.WAV)
+		checkf(err, "failed to create WAV file")
+		err = emu.RenderNSF(f, f.StartSong, args.Duration, args.Fade, out, cfg.Config)
+		checkf(err, "failed to render track %d", f.StartSong+1)
+		checkf(out.Close(), "failed to write WAV file")
+		fmt.Printf("Track %d written to %s\n", f.StartSong+1, args.WAV)
+		return
+	}
+
+	var exitcode int
+	sdl.Main(func() {
+		cfg.Video.Monitor = args.Monitor
+		emulator, err := emu.LaunchNSF(f, cfg.Config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to start emulator: %v\n", err)
+			exitcode = 1
+			return
+		}
+		emulator.Run()
+	})
+	os.Exit(exitcode)
+}
@@ -0,0 +1,417 @@
+// package nsf implements a Reader for NES Sound Format files, in the NSF or
+// NSFe formats. These hold the music code and data ripped from NES games, with
+// the addresses of the routines to call to initialize and play a song.
+package nsf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	Magic     = "NESM\x1a"
+	MagicNSFe = "NSFE"
+)
+
+const headerSize = 0x80
+
+// Expansion is a bitfield of the expansion audio chips used by a file.
+type Expansion uint8
+
+const (
+	VRC6 Expansion = 1 << iota
+	VRC7
+	FDS
+	MMC5
+	N163
+	Sunsoft5B
+)
+
+func (e Expansion) String() string {
+	names := [...]string{"VRC6", "VRC7", "FDS", "MMC5", "N163", "5B"}
+
+	var chips []string
+	for i, name := range names {
+		if e&(1<<i) != 0 {
+			chips = append(chips, name)
+		}
+	}
+	if len(chips) == 0 {
+		return "none"
+	}
+	return strings.Join(chips, ",")
+}
+
+// Region flags.
+const (
+	RegionPAL  = 0x01 // PAL tune
+	RegionDual = 0x02 // tune supports both NTSC and PAL
+)
+
+// Track holds the metadata of a track, only available in NSFe files.
+type Track struct {
+	Label    string
+	Duration time.Duration // 0 if unknown
+	Fade     time.Duration // fade out duration, 0 if unknown
+}
+
+// File is a decoded NSF or NSFe file.
+type File struct {
+	Version   uint8
+	Songs     int // number of songs
+	StartSong int // song to play first, 0-based
+
+	LoadAddr uint16 // address at which Data is loaded (non-banked tunes)
+	InitAddr uint16 // address of the INIT routine
+	PlayAddr uint16 // address of the PLAY routine
+
+	Title     string
+	Artist    string
+	Copyright string
+	Ripper    string
+
+	SpeedNTSC uint16 // play routine period for NTSC, in microseconds
+	SpeedPAL  uint16 // play routine period for PAL, in microseconds
+
+	Banks     [8]uint8 // initial bank of each 4 KB page of $8000-$FFFF
+	Region    uint8
+	Expansion Expansion
+
+	Data []byte // program data
+
+	// NSFe only, both can be empty.
+	Tracks   []Track // per song metadata, indexed by song number
+	Playlist []int   // songs to play, in order
+
+	Name string // file name
+}
+
+// ReadFile loads a NSF or NSFe file.
+func ReadFile(path string) (*File, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := Decode(buf)
+	if err != nil {
+		return nil, err
+	}
+	f.Name = filepath.Base(path)
+	return f, nil
+}
+
+// Decode decodes the given buffer into a NSF file, its format (NSF or NSFe) is
+// detected from its magic number.
+func Decode(buf []byte) (*File, error) {
+	switch {
+	case bytes.HasPrefix(buf, []byte(Magic)):
+		return decodeNSF(buf)
+	case bytes.HasPrefix(buf, []byte(MagicNSFe)):
+		return decodeNSFe(buf)
+	}
+	return nil, fmt.Errorf("invalid magic number")
+}
+
+func decodeNSF(buf []byte) (*File, error) {
+	if len(buf) < headerSize {
+		return nil, fmt.Errorf("too small, needs %d bytes", headerSize)
+	}
+
+	f := &File{
+		Version:   buf[0x05],
+		Songs:     int(buf[0x06]),
+		StartSong: max(int(buf[0x07])-1, 0),
+		LoadAddr:  binary.LittleEndian.Uint16(buf[0x08:]),
+		InitAddr:  binary.LittleEndian.Uint16(buf[0x0A:]),
+		PlayAddr:  binary.LittleEndian.Uint16(buf[0x0C:]),
+		Title:     cstring(buf[0x0E:0x2E]),
+		Artist:    cstring(buf[0x2E:0x4E]),
+		Copyright: cstring(buf[0x4E:0x6E]),
+		SpeedNTSC: binary.LittleEndian.Uint16(buf[0x6E:]),
+		SpeedPAL:  binary.LittleEndian.Uint16(buf[0x78:]),
+		Region:    buf[0x7A] & 0x03,
+		Expansion: Expansion(buf[0x7B] & 0x3F),
+	}
+	copy(f.Banks[:], buf[0x70:0x78])
+
+	data := buf[headerSize:]
+	// NSF2 may store the program data length, followed by NSFe metadata.
+	if f.Version >= 2 {
+		size := int(buf[0x7D]) | int(buf[0x7E])<<8 | int(buf[0x7F])<<16
+		if size != 0 && size <= len(data) {
+			data = data[:size]
+		}
+	}
+	f.Data = data
+
+	if err := f.check(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func decodeNSFe(buf []byte) (*File, error) {
+	f := &File{Version: 1}
+
+	var (
+		hasInfo bool
+		times   []time.Duration
+		fades   []time.Duration
+		labels  []string
+	)
+
+	off := len(MagicNSFe)
+	for off < len(buf) {
+		if len(buf) < off+8 {
+			return nil, fmt.Errorf("truncated chunk header at offset %d", off)
+		}
+		size := int(binary.LittleEndian.Uint32(buf[off:]))
+		id := string(buf[off+4 : off+8])
+		off += 8
+		if size > len(buf)-off {
+			return nil, fmt.Errorf("truncated %s chunk at offset %d", id, off-8)
+		}
+		data := buf[off : off+size]
+		off += size
+
+		switch id {
+		case "INFO":
+			if len(data) < 9 {
+				return nil, fmt.Errorf("INFO chunk too small: %d bytes", len(data))
+			}
+			hasInfo = true
+			f.LoadAddr = binary.LittleEndian.Uint16(data[0:])
+			f.InitAddr = binary.LittleEndian.Uint16(data[2:])
+			f.PlayAddr = binary.LittleEndian.Uint16(data[4:])
+			f.Region = data[6] & 0x03
+			f.Expansion = Expansion(data[7] & 0x3F)
+			f.Songs = int(data[8])
+			if len(data) > 9 {
+				f.StartSong = int(data[9])
+			}
+			// NSFe always uses the standard rates, unless there's a RATE
+			// chunk.
+			f.SpeedNTSC, f.SpeedPAL = 16639, 19997
+		case "DATA":
+			f.Data = data
+		case "BANK":
+			copy(f.Banks[:], data)
+		case "RATE":
+			if len(data) >= 2 {
+				f.SpeedNTSC = binary.LittleEndian.Uint16(data)
+			}
+			if len(data) >= 4 {
+				f.SpeedPAL = binary.LittleEndian.Uint16(data[2:])
+			}
+		case "auth":
+			strs := cstrings(data)
+			for i, s := range []*string{&f.Title, &f.Artist, &f.Copyright, &f.Ripper} {
+				if i < len(strs) {
+					*s = strs[i]
+				}
+			}
+		case "plst":
+			for _, song := range data {
+				f.Playlist = append(f.Playlist, int(song))
+			}
+		case "time":
+			times = durations(data)
+		case "fade":
+			fades = durations(data)
+		case "tlbl":
+			labels = cstrings(data)
+		case "NEND":
+			off = len(buf)
+		default:
+			// Chunks starting with an uppercase letter are mandatory.
+			if id[0] >= 'A' && id[0] <= 'Z' {
+				return nil, fmt.Errorf("unsupported mandatory chunk %q", id)
+			}
+		}
+	}
+
+	if !hasInfo {
+		return nil, fmt.Errorf("missing INFO chunk")
+	}
+
+	if len(times)+len(fades)+len(labels) > 0 {
+		f.Tracks = make([]Track, f.Songs)
+		for i := range f.Tracks {
+			if i < len(times) {
+				f.Tracks[i].Duration = times[i]
+			}
+			if i < len(fades) {
+				f.Tracks[i].Fade = fades[i]
+			}
+			if i < len(labels) {
+				f.Tracks[i].Label = labels[i]
+			}
+		}
+	}
+
+	if err := f.check(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) check() error {
+	if len(f.Data) == 0 {
+		return fmt.Errorf("missing program data")
+	}
+	if f.Songs == 0 {
+		return fmt.Errorf("no songs")
+	}
+	if f.StartSong >= f.Songs {
+		f.StartSong = 0
+	}
+	if !f.Banked() {
+		lo := uint16(0x8000)
+		if f.Expansion&FDS != 0 {
+			lo = 0x6000
+		}
+		if f.LoadAddr < lo {
+			return fmt.Errorf("invalid load address $%04X", f.LoadAddr)
+		}
+	}
+	for _, song := range f.Playlist {
+		if song >= f.Songs {
+			return fmt.Errorf("invalid song %d in playlist", song)
+		}
+	}
+	return nil
+}
+
+// Banked reports whether the tune uses bankswitching.
+func (f *File) Banked() bool {
+	return f.Banks != [8]uint8{}
+}
+
+// IsPAL reports whether the tune is meant to be played at the PAL rate only.
+func (f *File) IsPAL() bool {
+	return f.Region&(RegionPAL|RegionDual) == RegionPAL
+}
+
+// PlayPeriod returns the period at which the PLAY routine should be called.
+func (f *File) PlayPeriod() time.Duration {
+	speed := f.SpeedNTSC
+	if f.IsPAL() {
+		speed = f.SpeedPAL
+	}
+	if speed == 0 {
+		// Use the rate of vertical blanking.
+		if f.IsPAL() {
+			return time.Second / 50
+		}
+		return time.Second * 1000 / 60099
+	}
+	return time.Duration(speed) * time.Microsecond
+}
+
+// Track returns the metadata of the given song.
+func (f *File) Track(song int) Track {
+	if song < len(f.Tracks) {
+		return f.Tracks[song]
+	}
+	return Track{}
+}
+
+// Order returns the songs in playing order, that's the playlist if there's
+// one, all songs otherwise.
+func (f *File) Order() []int {
+	if len(f.Playlist) > 0 {
+		return f.Playlist
+	}
+	order := make([]int, f.Songs)
+	for i := range order {
+		order[i] = i
+	}
+	return order
+}
+
+func (f *File) PrintInfos(w io.Writer) {
+	region := "NTSC"
+	switch {
+	case f.Region&RegionDual != 0:
+		region = "NTSC/PAL"
+	case f.IsPAL():
+		region = "PAL"
+	}
+
+	fmt.Fprintf(w, "%s\n", f.Name)
+	fmt.Fprintf(w, "|NSF version            | % 14d |\n", f.Version)
+	fmt.Fprintf(w, "|Title                  | % 14s |\n", f.Title)
+	fmt.Fprintf(w, "|Artist                 | % 14s |\n", f.Artist)
+	fmt.Fprintf(w, "|Copyright              | % 14s |\n", f.Copyright)
+	if f.Ripper != "" {
+		fmt.Fprintf(w, "|Ripper                 | % 14s |\n", f.Ripper)
+	}
+	fmt.Fprintf(w, "|Songs                  | % 14d |\n", f.Songs)
+	fmt.Fprintf(w, "|Starting song          | % 14d |\n", f.StartSong+1)
+	fmt.Fprintf(w, "|Load address           |          $%04X |\n", f.LoadAddr)
+	fmt.Fprintf(w, "|Init address           |          $%04X |\n", f.InitAddr)
+	fmt.Fprintf(w, "|Play address           |          $%04X |\n", f.PlayAddr)
+	fmt.Fprintf(w, "|Play period            | % 14s |\n", f.PlayPeriod())
+	fmt.Fprintf(w, "|Region                 | % 14s |\n", region)
+	fmt.Fprintf(w, "|Bankswitching          | % 14s |\n", yn(f.Banked()))
+	fmt.Fprintf(w, "|Expansion audio        | % 14s |\n", f.Expansion)
+	fmt.Fprintf(w, "|Data size              | % 13dk |\n", len(f.Data)/1024)
+
+	for i, t := range f.Tracks {
+		if t.Label == "" && t.Duration == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "  %3d. %s", i+1, t.Label)
+		if t.Duration != 0 {
+			fmt.Fprintf(w, " (%s)", t.Duration)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// cstring returns the null-terminated string at the start of b.
+func cstring(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i != -1 {
+		b = b[:i]
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// cstrings returns the sequence of null-terminated strings in b.
+func cstrings(b []byte) []string {
+	var strs []string
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, 0)
+		if i == -1 {
+			i = len(b)
+		}
+		strs = append(strs, strings.TrimSpace(string(b[:i])))
+		b = b[min(i+1, len(b)):]
+	}
+	return strs
+}
+
+// durations decodes a sequence of signed 32-bit durations in milliseconds,
+// negative values meaning unknown.
+func durations(b []byte) []time.Duration {
+	var ds []time.Duration
+	for ; len(b) >= 4; b = b[4:] {
+		ms := int32(binary.LittleEndian.Uint32(b))
+		ds = append(ds, time.Duration(max(ms, 0))*time.Millisecond)
+	}
+	return ds
+}
+
+func yn(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,109 @@
+package nsf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	"nestor/tests"
+)
+
+func TestDecodeNSF(t *testing.T) {
+	img := tests.NSF(5, uint8(VRC6|N163), [8]uint8{0, 1, 2, 3, 4, 5, 6, 7}, 0x8003, []byte{0x60, 0, 0, 0x60})
+
+	f, err := Decode(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Songs != 5 || f.StartSong != 0 {
+		t.Errorf("Songs, StartSong = %d, %d, want 5, 0", f.Songs, f.StartSong)
+	}
+	if f.InitAddr != 0x8000 || f.PlayAddr != 0x8003 {
+		t.Errorf("InitAddr, PlayAddr = $%04X, $%04X, want $8000, $8003", f.InitAddr, f.PlayAddr)
+	}
+	if f.Title != "Test Song" || f.Artist != "Test Artist" {
+		t.Errorf("Title, Artist = %q, %q", f.Title, f.Artist)
+	}
+	if !f.Banked() {
+		t.Errorf("Banked() = false, want true")
+	}
+	if f.Expansion.String() != "VRC6,N163" {
+		t.Errorf("Expansion = %s, want VRC6,N163", f.Expansion)
+	}
+	if got := f.PlayPeriod(); got != 16667*time.Microsecond {
+		t.Errorf("PlayPeriod() = %s, want 16.667ms", got)
+	}
+	if len(f.Data) != 4 {
+		t.Errorf("len(Data) = %d, want 4", len(f.Data))
+	}
+
+	var buf bytes.Buffer
+	f.PrintInfos(&buf)
+	if !strings.Contains(buf.String(), "VRC6,N163") {
+		t.Errorf("PrintInfos output doesn't contain expansion audio:\n%s", buf.String())
+	}
+
+	if _, err := Decode(img[:0x40]); err == nil {
+		t.Errorf("Decode succeeded on truncated header")
+	}
+}
+
+func chunk(id string, data []byte) []byte {
+	buf := binary.LittleEndian.AppendUint32(nil, uint32(len(data)))
+	return append(append(buf, id...), data...)
+}
+
+func TestDecodeNSFe(t *testing.T) {
+	info := []byte{
+		0x00, 0x80, // load
+		0x00, 0x80, // init
+		0x03, 0x80, // play
+		0x00,       // NTSC
+		uint8(FDS), // expansion
+		3,          // songs
+		1,          // starting song
+	}
+	times := binary.LittleEndian.AppendUint32(nil, 90000)
+	times = binary.LittleEndian.AppendUint32(times, 0xFFFFFFFF) // unknown
+
+	img := []byte(MagicNSFe)
+	img = append(img, chunk("INFO", info)...)
+	img = append(img, chunk("DATA", []byte{0x60, 0, 0, 0x60})...)
+	img = append(img, chunk("auth", []byte("Title\x00Artist\x00Copyright\x00Ripper\x00"))...)
+	img = append(img, chunk("time", times)...)
+	img = append(img, chunk("tlbl", []byte("Intro\x00Level 1\x00Boss\x00"))...)
+	img = append(img, chunk("plst", []byte{2, 0})...)
+	img = append(img, chunk("xtra", []byte{1, 2, 3})...) // optional, skipped
+	img = append(img, chunk("NEND", nil)...)
+
+	f, err := Decode(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Songs != 3 || f.StartSong != 1 {
+		t.Errorf("Songs, StartSong = %d, %d, want 3, 1", f.Songs, f.StartSong)
+	}
+	if f.Expansion != FDS {
+		t.Errorf("Expansion = %s, want FDS", f.Expansion)
+	}
+	if f.Title != "Title" || f.Ripper != "Ripper" {
+		t.Errorf("Title, Ripper = %q, %q", f.Title, f.Ripper)
+	}
+	if got := f.Track(0); got.Label != "Intro" || got.Duration != 90*time.Second {
+		t.Errorf("Track(0) = %+v", got)
+	}
+	if got := f.Track(1); got.Label != "Level 1" || got.Duration != 0 {
+		t.Errorf("Track(1) = %+v", got)
+	}
+	if got := f.Order(); len(got) != 2 || got[0] != 2 || got[1] != 0 {
+		t.Errorf("Order() = %v, want [2 0]", got)
+	}
+
+	// Unknown mandatory chunks are rejected.
+	bad := append(bytes.Clone(img[:len(img)-8]), chunk("ABCD", nil)...)
+	if _, err := Decode(bad); err == nil {
+		t.Errorf("Decode succeeded with an unknown mandatory chunk")
+	}
+}
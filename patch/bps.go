@@ -0,0 +1,134 @@
+package patch
+
+import (
+	"fmt"
+	"hash/crc32"
+)
+
+// BPS patch format:
+//
+//	"BPS1"
+//	varint source size, varint target size
+//	varint metadata size, metadata
+//	actions: varint (length-1)<<2 | command, followed by the command data
+//	CRC32 of the source, of the target and of the patch (little endian)
+const bpsMagic = "BPS1"
+
+// BPS commands.
+const (
+	bpsSourceRead = iota // copy from source, at the current output offset
+	bpsTargetRead        // copy from the patch
+	bpsSourceCopy        // copy from source, at a relative offset
+	bpsTargetCopy        // copy from target, at a relative offset
+)
+
+// CreateBPS creates a BPS patch which transforms orig into mod. The patch is
+// linear: bytes are either read from orig at the same offset, or from the
+// patch itself. This is enough for the vast majority of rom hacks which do not
+// move data around.
+func CreateBPS(orig, mod []byte) []byte {
+	buf := []byte(bpsMagic)
+	buf = appendVarint(buf, uint64(len(orig)))
+	buf = appendVarint(buf, uint64(len(mod)))
+	buf = appendVarint(buf, 0) // no metadata
+
+	same := func(off int) bool { return off < len(orig) && orig[off] == mod[off] }
+
+	for off := 0; off < len(mod); {
+		start := off
+		cmd := bpsTargetRead
+		if same(off) {
+			cmd = bpsSourceRead
+		}
+		for off < len(mod) && same(off) == (cmd == bpsSourceRead) {
+			off++
+		}
+		buf = appendVarint(buf, uint64(off-start-1)<<2|uint64(cmd))
+		if cmd == bpsTargetRead {
+			buf = append(buf, mod[start:off]...)
+		}
+	}
+	return appendFooter(buf, orig, mod)
+}
+
+// ApplyBPS applies a BPS patch to src. The checksum of src is verified before,
+// and the checksum of the patched data after applying the patch.
+func ApplyBPS(src, patch []byte) ([]byte, error) {
+	body, ft, err := splitFooter(patch, bpsMagic)
+	if err != nil {
+		return nil, fmt.Errorf("BPS: %w", err)
+	}
+	if crc32.ChecksumIEEE(src) != ft.src {
+		return nil, fmt.Errorf("BPS: source file %w", ErrChecksum)
+	}
+
+	r := &patchReader{buf: body}
+	srcSize := r.varint()
+	dstSize := r.varint()
+	r.bytes(int(r.varint())) // skip metadata
+	if r.err != nil {
+		return nil, fmt.Errorf("BPS: %w", r.err)
+	}
+	if srcSize != uint64(len(src)) {
+		return nil, fmt.Errorf("BPS: source size is %d, want %d", len(src), srcSize)
+	}
+	if dstSize > maxSize {
+		return nil, fmt.Errorf("BPS: invalid target size %d", dstSize)
+	}
+
+	dst := make([]byte, 0, dstSize)
+	var srcRel, dstRel int
+	for !r.eof() {
+		n := r.varint()
+		cmd, length := n&3, int(n>>2)+1
+		if uint64(len(dst)+length) > dstSize {
+			return nil, fmt.Errorf("BPS: output overflows target size")
+		}
+
+		switch cmd {
+		case bpsSourceRead:
+			off := len(dst)
+			if off+length > len(src) {
+				return nil, fmt.Errorf("BPS: source read out of bounds")
+			}
+			dst = append(dst, src[off:off+length]...)
+		case bpsTargetRead:
+			dst = append(dst, r.bytes(length)...)
+		case bpsSourceCopy:
+			srcRel += relOffset(r.varint())
+			if srcRel < 0 || srcRel+length > len(src) {
+				return nil, fmt.Errorf("BPS: source copy out of bounds")
+			}
+			dst = append(dst, src[srcRel:srcRel+length]...)
+			srcRel += length
+		case bpsTargetCopy:
+			dstRel += relOffset(r.varint())
+			if dstRel < 0 || dstRel >= len(dst) {
+				return nil, fmt.Errorf("BPS: target copy out of bounds")
+			}
+			// Byte by byte since source and destination may overlap.
+			for range length {
+				dst = append(dst, dst[dstRel])
+				dstRel++
+			}
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("BPS: %w", r.err)
+	}
+	if uint64(len(dst)) != dstSize {
+		return nil, fmt.Errorf("BPS: target size is %d, want %d", len(dst), dstSize)
+	}
+	if crc32.ChecksumIEEE(dst) != ft.dst {
+		return nil, fmt.Errorf("BPS: target file %w", ErrChecksum)
+	}
+	return dst, nil
+}
+
+// relOffset decodes a signed relative offset: the sign is in bit 0.
+func relOffset(v uint64) int {
+	if v&1 != 0 {
+		return -int(v >> 1)
+	}
+	return int(v >> 1)
+}
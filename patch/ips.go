@@ -0,0 +1,122 @@
+package patch
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// IPS patch format:
+//
+//	"PATCH"
+//	records: 3-byte offset (big endian), 2-byte size, <size> bytes of data.
+//	         (or 3-byte offset, 0 size, 2-byte run length, 1 byte to repeat)
+//	"EOF"
+//	optional 3-byte size, to which the patched file is truncated.
+const (
+	ipsHeader = "PATCH"
+	ipsFooter = "EOF"
+	ipsEOF    = 0x454F46 // offset that would be confused with the footer
+	ipsMaxOff = 1<<24 - 1
+)
+
+// CreateIPS creates an IPS patch which transforms orig into mod. IPS offsets
+// are 24-bit so mod can't be larger than 16 MB.
+func CreateIPS(orig, mod []byte) ([]byte, error) {
+	if len(mod) > ipsMaxOff {
+		return nil, fmt.Errorf("file too large for an IPS patch (%d bytes)", len(mod))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(ipsHeader)
+
+	// Bytes past the end of orig always differ.
+	differ := func(off int) bool { return off >= len(orig) || orig[off] != mod[off] }
+
+	for off := 0; off < len(mod); {
+		if !differ(off) {
+			off++
+			continue
+		}
+		start := off
+		if start == ipsEOF {
+			start--
+		}
+		end := off
+		for end < len(mod) && end-start < 0xFFFF && differ(end) {
+			end++
+		}
+		size := end - start
+		buf.Write([]byte{byte(start >> 16), byte(start >> 8), byte(start), byte(size >> 8), byte(size)})
+		buf.Write(mod[start:end])
+		off = end
+	}
+
+	buf.WriteString(ipsFooter)
+	if len(mod) < len(orig) {
+		n := len(mod)
+		buf.Write([]byte{byte(n >> 16), byte(n >> 8), byte(n)})
+	}
+	return buf.Bytes(), nil
+}
+
+// ApplyIPS applies an IPS patch to src. Records writing past the end of src
+// extend it.
+func ApplyIPS(src, patch []byte) ([]byte, error) {
+	if !bytes.HasPrefix(patch, []byte(ipsHeader)) {
+		return nil, fmt.Errorf("invalid IPS header")
+	}
+	patch = patch[len(ipsHeader):]
+	buf := bytes.Clone(src)
+
+	grow := func(size int) {
+		if size > len(buf) {
+			buf = append(buf, make([]byte, size-len(buf))...)
+		}
+	}
+
+	for {
+		if len(patch) < 3 {
+			return nil, fmt.Errorf("truncated IPS patch")
+		}
+		if string(patch[:3]) == ipsFooter {
+			patch = patch[3:]
+			break
+		}
+		if len(patch) < 5 {
+			return nil, fmt.Errorf("truncated IPS record")
+		}
+		off := int(patch[0])<<16 | int(patch[1])<<8 | int(patch[2])
+		size := int(patch[3])<<8 | int(patch[4])
+		patch = patch[5:]
+
+		if size == 0 {
+			// RLE record.
+			if len(patch) < 3 {
+				return nil, fmt.Errorf("truncated IPS RLE record")
+			}
+			size = int(patch[0])<<8 | int(patch[1])
+			grow(off + size)
+			for i := range size {
+				buf[off+i] = patch[2]
+			}
+			patch = patch[3:]
+			continue
+		}
+
+		if len(patch) < size {
+			return nil, fmt.Errorf("truncated IPS record")
+		}
+		grow(off + size)
+		copy(buf[off:], patch[:size])
+		patch = patch[size:]
+	}
+
+	// Truncation extension.
+	if len(patch) >= 3 {
+		n := int(patch[0])<<16 | int(patch[1])<<8 | int(patch[2])
+		if n < len(buf) {
+			buf = buf[:n]
+		}
+	}
+	return buf, nil
+}
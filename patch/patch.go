@@ -0,0 +1,170 @@
+// package patch applies and creates rom patches in the IPS, BPS and UPS
+// formats.
+package patch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"path/filepath"
+	"strings"
+)
+
+// Format is a patch file format.
+type Format string
+
+const (
+	IPS Format = "ips"
+	BPS Format = "bps"
+	UPS Format = "ups"
+)
+
+// Formats lists the supported patch formats, their names are also the
+// extensions of the patch files.
+var Formats = []Format{IPS, BPS, UPS}
+
+// ParseFormat returns the format with the given name (case insensitive).
+func ParseFormat(name string) (Format, bool) {
+	f := Format(strings.ToLower(name))
+	switch f {
+	case IPS, BPS, UPS:
+		return f, true
+	}
+	return "", false
+}
+
+// FormatOf returns the format of a patch file, based on its extension.
+func FormatOf(path string) (Format, bool) {
+	ext := filepath.Ext(path)
+	if ext == "" {
+		return "", false
+	}
+	return ParseFormat(ext[1:])
+}
+
+// ErrChecksum is returned when applying a BPS or UPS patch to a file it's not
+// been created for.
+var ErrChecksum = errors.New("checksum mismatch")
+
+// Apply applies patch to src and returns the patched data, src is left
+// untouched. The patch format is detected from its header.
+func Apply(src, patch []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(patch, []byte(ipsHeader)):
+		return ApplyIPS(src, patch)
+	case bytes.HasPrefix(patch, []byte(bpsMagic)):
+		return ApplyBPS(src, patch)
+	case bytes.HasPrefix(patch, []byte(upsMagic)):
+		return ApplyUPS(src, patch)
+	}
+	return nil, fmt.Errorf("unknown patch format")
+}
+
+// Create creates a patch in the given format, which transforms orig into mod.
+func Create(format Format, orig, mod []byte) ([]byte, error) {
+	switch format {
+	case IPS:
+		return CreateIPS(orig, mod)
+	case BPS:
+		return CreateBPS(orig, mod), nil
+	case UPS:
+		return CreateUPS(orig, mod), nil
+	}
+	return nil, fmt.Errorf("unknown patch format %q", format)
+}
+
+// BPS and UPS share the same variable-length integer encoding and the same
+// footer, made of the CRC32 of the source, the target and the patch itself.
+
+func appendVarint(buf []byte, v uint64) []byte {
+	for {
+		x := byte(v & 0x7F)
+		v >>= 7
+		if v == 0 {
+			return append(buf, 0x80|x)
+		}
+		buf = append(buf, x)
+		v--
+	}
+}
+
+// patchReader reads the body of a BPS or UPS patch.
+type patchReader struct {
+	buf []byte
+	off int
+	err error
+}
+
+func (r *patchReader) eof() bool { return r.err != nil || r.off >= len(r.buf) }
+
+func (r *patchReader) byte() byte {
+	if r.off >= len(r.buf) {
+		r.err = errTruncated
+		return 0
+	}
+	r.off++
+	return r.buf[r.off-1]
+}
+
+func (r *patchReader) bytes(n int) []byte {
+	if n < 0 || n > len(r.buf)-r.off {
+		r.err = errTruncated
+		return nil
+	}
+	r.off += n
+	return r.buf[r.off-n : r.off]
+}
+
+func (r *patchReader) varint() uint64 {
+	var v, shift uint64 = 0, 1
+	for range 10 {
+		x := r.byte()
+		if r.err != nil {
+			return 0
+		}
+		v += uint64(x&0x7F) * shift
+		if x&0x80 != 0 {
+			return v
+		}
+		shift <<= 7
+		v += shift
+	}
+	r.err = errors.New("invalid variable-length integer")
+	return 0
+}
+
+var errTruncated = errors.New("truncated patch")
+
+// maxSize is the maximum size of a patched file, which protects against
+// allocating huge buffers for corrupted patches.
+const maxSize = 64 << 20
+
+// footer holds the checksums found at the end of BPS and UPS patches.
+type footer struct {
+	src, dst uint32
+}
+
+// splitFooter verifies the patch checksum and returns the patch body (without
+// magic number) and its footer.
+func splitFooter(patch []byte, magic string) ([]byte, footer, error) {
+	if len(patch) < len(magic)+12 {
+		return nil, footer{}, errTruncated
+	}
+	end := len(patch) - 12
+	ft := footer{
+		src: binary.LittleEndian.Uint32(patch[end:]),
+		dst: binary.LittleEndian.Uint32(patch[end+4:]),
+	}
+	if crc32.ChecksumIEEE(patch[:end+8]) != binary.LittleEndian.Uint32(patch[end+8:]) {
+		return nil, footer{}, fmt.Errorf("corrupted patch: %w", ErrChecksum)
+	}
+	return patch[len(magic):end], ft, nil
+}
+
+func appendFooter(buf, src, dst []byte) []byte {
+	buf = binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(src))
+	buf = binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(dst))
+	return binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf))
+}
@@ -0,0 +1,185 @@
+package patch
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func testData(size int) []byte {
+	buf := make([]byte, size)
+	for i := range buf {
+		buf[i] = byte(i*7 + i>>8)
+	}
+	return buf
+}
+
+func TestIPS(t *testing.T) {
+	orig := bytes.Repeat([]byte{0x11}, 1000)
+	mod := bytes.Clone(orig)
+	mod[0] = 0x22
+	copy(mod[500:], []byte{1, 2, 3, 4, 5})
+	mod[999] = 0x33
+
+	patch, err := CreateIPS(orig, mod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := ApplyIPS(orig, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, mod) {
+		t.Errorf("patched buffer differs")
+	}
+}
+
+// A record at offset 0x454F46 would be read as the "EOF" footer.
+func TestIPSEOFOffset(t *testing.T) {
+	orig := make([]byte, ipsEOF+16)
+	mod := bytes.Clone(orig)
+	mod[ipsEOF] = 1
+
+	patch, err := CreateIPS(orig, mod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ApplyIPS(orig, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, mod) {
+		t.Errorf("patched buffer differs")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	orig := testData(0x50000)
+
+	grown := append(testData(0x50000), testData(0x8000)...)
+	grown[0x10] ^= 0xFF
+	for i := 0x1000; i < 0x12000; i++ {
+		grown[i] = 0xEA // longer than an IPS record
+	}
+
+	shrunk := bytes.Clone(orig[:0x40000])
+	shrunk[0x3FFFF] = 0
+
+	rle := bytes.Clone(orig)
+	for i := 0x8000; i < 0x8100; i++ {
+		rle[i] = 0xFF
+	}
+
+	tests := []struct {
+		name string
+		mod  []byte
+	}{
+		{"same", bytes.Clone(orig)},
+		{"grown", grown},
+		{"shrunk", shrunk},
+		{"rle", rle},
+	}
+	for _, format := range Formats {
+		for _, tt := range tests {
+			t.Run(string(format)+"/"+tt.name, func(t *testing.T) {
+				patch, err := Create(format, orig, tt.mod)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := Apply(orig, patch)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, tt.mod) {
+					t.Errorf("patched data differs (len: %d, want %d)", len(got), len(tt.mod))
+				}
+			})
+		}
+	}
+}
+
+func TestIPSRLE(t *testing.T) {
+	patch := []byte("PATCH" +
+		"\x00\x00\x02\x00\x00\x00\x04\xAA" + // RLE: 4 x 0xAA at offset 2
+		"\x00\x00\x08\x00\x02\x01\x02" + // 2 bytes at offset 8, extends the file
+		"EOF")
+	got, err := ApplyIPS(make([]byte, 4), patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0, 0, 0xAA, 0xAA, 0xAA, 0xAA, 0, 0, 1, 2}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got % x, want % x", got, want)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	orig := testData(0x4000)
+	mod := bytes.Clone(orig)
+	mod[100] = 0
+
+	other := bytes.Clone(orig)
+	other[200] ^= 1
+
+	for _, format := range []Format{BPS, UPS} {
+		t.Run(string(format), func(t *testing.T) {
+			patch, _ := Create(format, orig, mod)
+			if _, err := Apply(other, patch); !errors.Is(err, ErrChecksum) {
+				t.Errorf("applying to another source: got error %v, want %v", err, ErrChecksum)
+			}
+
+			patch[len(patch)/2] ^= 0xFF
+			if _, err := Apply(orig, patch); !errors.Is(err, ErrChecksum) {
+				t.Errorf("applying corrupted patch: got error %v, want %v", err, ErrChecksum)
+			}
+		})
+	}
+}
+
+// BPS patches created by other tools use SourceCopy and TargetCopy commands.
+func TestBPSCopy(t *testing.T) {
+	src := []byte("abcdefgh")
+	want := []byte("efghxyxyxyab")
+
+	body := []byte(bpsMagic)
+	body = appendVarint(body, uint64(len(src)))
+	body = appendVarint(body, uint64(len(want)))
+	body = appendVarint(body, 0)
+	body = appendVarint(body, 3<<2|bpsSourceCopy) // "efgh"
+	body = appendVarint(body, 4<<1)
+	body = appendVarint(body, 1<<2|bpsTargetRead) // "xy"
+	body = append(body, "xy"...)
+	body = appendVarint(body, 3<<2|bpsTargetCopy) // "xyxy"
+	body = appendVarint(body, 4<<1)
+	body = appendVarint(body, 1<<2|bpsSourceCopy) // "ab"
+	body = appendVarint(body, 8<<1|1)
+	patch := appendFooter(body, src, want)
+
+	got, err := ApplyBPS(src, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct {
+		path string
+		want Format
+		ok   bool
+	}{
+		{"game.ips", IPS, true},
+		{"/roms/Game (Translated).BPS", BPS, true},
+		{"game.ups", UPS, true},
+		{"game.nes", "", false},
+		{"ips", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := FormatOf(tt.path)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("FormatOf(%q) = %q, %t, want %q, %t", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
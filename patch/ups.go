@@ -0,0 +1,92 @@
+package patch
+
+import (
+	"fmt"
+	"hash/crc32"
+)
+
+// UPS patch format:
+//
+//	"UPS1"
+//	varint source size, varint target size
+//	hunks: varint number of bytes to skip, bytes to XOR, 0x00 terminator
+//	CRC32 of the source, of the target and of the patch (little endian)
+const upsMagic = "UPS1"
+
+// CreateUPS creates an UPS patch which transforms orig into mod.
+func CreateUPS(orig, mod []byte) []byte {
+	buf := []byte(upsMagic)
+	buf = appendVarint(buf, uint64(len(orig)))
+	buf = appendVarint(buf, uint64(len(mod)))
+
+	xor := func(off int) byte {
+		if off < len(orig) {
+			return orig[off] ^ mod[off]
+		}
+		return mod[off]
+	}
+
+	pos := 0
+	for off := 0; off < len(mod); off++ {
+		if xor(off) == 0 {
+			continue
+		}
+		buf = appendVarint(buf, uint64(off-pos))
+		for ; off < len(mod) && xor(off) != 0; off++ {
+			buf = append(buf, xor(off))
+		}
+		buf = append(buf, 0)
+		pos = off + 1
+	}
+	return appendFooter(buf, orig, mod)
+}
+
+// ApplyUPS applies an UPS patch to src. The checksum of src is verified before,
+// and the checksum of the patched data after applying the patch.
+func ApplyUPS(src, patch []byte) ([]byte, error) {
+	body, ft, err := splitFooter(patch, upsMagic)
+	if err != nil {
+		return nil, fmt.Errorf("UPS: %w", err)
+	}
+	if crc32.ChecksumIEEE(src) != ft.src {
+		return nil, fmt.Errorf("UPS: source file %w", ErrChecksum)
+	}
+
+	r := &patchReader{buf: body}
+	srcSize := r.varint()
+	dstSize := r.varint()
+	if r.err != nil {
+		return nil, fmt.Errorf("UPS: %w", r.err)
+	}
+	if srcSize != uint64(len(src)) {
+		return nil, fmt.Errorf("UPS: source size is %d, want %d", len(src), srcSize)
+	}
+	if dstSize > maxSize {
+		return nil, fmt.Errorf("UPS: invalid target size %d", dstSize)
+	}
+
+	dst := make([]byte, dstSize)
+	copy(dst, src)
+	off := 0
+	for !r.eof() {
+		off += int(r.varint())
+		for {
+			x := r.byte()
+			if r.err != nil || x == 0 {
+				break
+			}
+			if off < len(dst) {
+				dst[off] ^= x
+			}
+			off++
+		}
+		off++
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("UPS: %w", r.err)
+	}
+	if crc32.ChecksumIEEE(dst) != ft.dst {
+		return nil, fmt.Errorf("UPS: target file %w", ErrChecksum)
+	}
+	return dst, nil
+}
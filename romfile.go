@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"nestor/archive"
+	"nestor/ines"
+	"nestor/nsf"
+	"nestor/patch"
+	"nestor/ui"
+	"nestor/unif"
+)
+
+// romFile is a rom, or a disk image, possibly stored in an archive.
+type romFile struct {
+	path  string // path of the rom, or of the archive containing it
+	entry string // archive entry, empty if path isn't an archive
+	patch string // path of the patch to apply to the rom, if any
+}
+
+// openRomFile returns the rom file at path. If path is an archive and entry
+// is empty, the rom entry is picked by its extension, the user is asked to
+// choose if there are several of them.
+//
+// patchPath is the patch to apply to the rom. If empty, a patch named after
+// the rom is looked for in the rom directory (see findPatch).
+func openRomFile(path, entry, patchPath string) (romFile, error) {
+	rf := romFile{path: path, entry: entry, patch: patchPath}
+	if archive.IsArchive(path) && entry == "" {
+		entries, err := archive.List(path)
+		if err != nil {
+			return romFile{}, err
+		}
+		roms := ui.RomEntries(entries)
+		switch len(roms) {
+		case 0:
+			return romFile{}, fmt.Errorf("no rom found in %s", filepath.Base(path))
+		case 1:
+			rf.entry = roms[0].Name
+		default:
+			if rf.entry, err = promptEntry(os.Stdin, os.Stderr, roms); err != nil {
+				return romFile{}, err
+			}
+		}
+	}
+
+	if rf.patch == "" {
+		rf.patch = rf.findPatch()
+	}
+	return rf, nil
+}
+
+// promptEntry asks the user to choose one of the archive entries.
+func promptEntry(r io.Reader, w io.Writer, entries []archive.Entry) (string, error) {
+	fmt.Fprintf(w, "Several roms found in the archive:\n")
+	for i, e := range entries {
+		fmt.Fprintf(w, "  %d) %s\n", i+1, e.Name)
+	}
+
+	sc := bufio.NewScanner(r)
+	for {
+		fmt.Fprintf(w, "Choose a rom [1-%d]: ", len(entries))
+		if !sc.Scan() {
+			if err := sc.Err(); err != nil {
+				return "", err
+			}
+			return "", fmt.Errorf("no rom chosen")
+		}
+		i, err := strconv.Atoi(strings.TrimSpace(sc.Text()))
+		if err == nil && i >= 1 && i <= len(entries) {
+			return entries[i-1].Name, nil
+		}
+	}
+}
+
+// name returns the file name of the rom.
+func (rf romFile) name() string {
+	if rf.entry != "" {
+		return filepath.Base(rf.entry)
+	}
+	return filepath.Base(rf.path)
+}
+
+// findPatch returns the path of the patch file having the same name as the
+// rom, in the rom directory (or the archive directory), with a .ips, .bps or
+// .ups extension. It returns an empty string if there's none.
+func (rf romFile) findPatch() string {
+	name := rf.name()
+	base := filepath.Join(filepath.Dir(rf.path), strings.TrimSuffix(name, filepath.Ext(name)))
+	for _, format := range patch.Formats {
+		path := base + "." + string(format)
+		if fi, err := os.Stat(path); err == nil && fi.Mode().IsRegular() {
+			return path
+		}
+	}
+	return ""
+}
+
+// savePath returns the path of the file in which the game saves are
+// persisted. For roms stored in archives, saves are stored alongside the
+// archive, named after the rom. Patched roms have their own saves, the patch
+// name is appended to the rom name.
+func (rf romFile) savePath() string {
+	return rf.basePath() + ".sav"
+}
+
+// tapePath returns the path of the WAV file the data recorder plays and
+// records tapes to, named like the saves.
+func (rf romFile) tapePath() string {
+	return rf.basePath() + ".wav"
+}
+
+// basePath returns the path of the files associated to the rom, without
+// extension.
+func (rf romFile) basePath() string {
+	path := rf.path
+	if rf.entry != "" {
+		path = filepath.Join(filepath.Dir(rf.path), rf.name())
+	}
+	if rf.patch != "" {
+		pname := filepath.Base(rf.patch)
+		path += "." + strings.TrimSuffix(pname, filepath.Ext(pname))
+	}
+	return path
+}
+
+// read returns the rom file content, patched if there's a patch to apply.
+func (rf romFile) read() ([]byte, error) {
+	var (
+		buf []byte
+		err error
+	)
+	if rf.entry != "" {
+		buf, err = archive.ReadFile(rf.path, rf.entry)
+	} else {
+		buf, err = os.ReadFile(rf.path)
+	}
+	if err != nil || rf.patch == "" {
+		return buf, err
+	}
+
+	p, err := os.ReadFile(rf.patch)
+	if err != nil {
+		return nil, err
+	}
+	if buf, err = patch.Apply(buf, p); err != nil {
+		return nil, fmt.Errorf("failed to apply patch %s: %w", filepath.Base(rf.patch), err)
+	}
+	return buf, nil
+}
+
+// readRom loads the rom, either in the iNES/NES 2.0 or the UNIF format.
+func (rf romFile) readRom() (*ines.Rom, error) {
+	buf, err := rf.read()
+	if err != nil {
+		return nil, err
+	}
+
+	var rom *ines.Rom
+	if isUNIF(rf.name()) {
+		f, err := unif.Decode(buf)
+		if err != nil {
+			return nil, err
+		}
+		f.Name = rf.name()
+		if rom, err = f.Rom(); err != nil {
+			return nil, err
+		}
+	} else {
+		if rom, err = ines.Decode(buf); err != nil {
+			return nil, err
+		}
+	}
+	rom.Name = rf.name()
+	return rom, nil
+}
+
+// readNSF loads the NSF or NSFe music file.
+func (rf romFile) readNSF() (*nsf.File, error) {
+	buf, err := rf.read()
+	if err != nil {
+		return nil, err
+	}
+	f, err := nsf.Decode(buf)
+	if err != nil {
+		return nil, err
+	}
+	f.Name = rf.name()
+	return f, nil
+}
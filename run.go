@@ -1,17 +1,21 @@
 package main
 
 import (
+	"cmp"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"runtime/pprof"
+	"strings"
 
 	"github.com/veandco/go-sdl2/sdl"
 
 	"nestor/emu"
+	"nestor/emu/log"
 	"nestor/emu/rpc"
+	"nestor/fds"
 	"nestor/hw/input"
-	"nestor/ines"
 	"nestor/ui"
 )
 
@@ -19,13 +23,6 @@ import (
 func emuMain(args Run, cfg *ui.Config) {
 	var exitcode int
 	sdl.Main(func() {
-		rom, err := ines.ReadRom(args.RomPath)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "error reading ROM: %s", err)
-			exitcode = 1
-			return
-		}
-
 		var traceout io.WriteCloser
 		if args.Trace != nil {
 			traceout = args.Trace
@@ -34,8 +31,23 @@ func emuMain(args Run, cfg *ui.Config) {
 
 		cfg.TraceOut = traceout
 		cfg.Video.Monitor = args.Monitor
+		if args.FDSBIOS != "" {
+			cfg.FDS.BIOSPath = args.FDSBIOS
+		}
 
-		emulator, err := emu.Launch(rom, cfg.Config)
+		rf, err := openRomFile(args.RomPath, args.Entry, args.Patch)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to open rom: %v\n", err)
+			exitcode = 1
+			return
+		}
+		if rf.patch != "" {
+			log.ModEmu.Infof("Applying patch %s", rf.patch)
+		}
+
+		cfg.TapePath = cmp.Or(args.Tape, rf.tapePath())
+		cfg.ScreenshotPath = rf.basePath()
+		emulator, err := launch(rf, cfg.Config)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "failed to start emulator: %v\n", err)
 			exitcode = 1
@@ -68,6 +80,63 @@ func emuMain(args Run, cfg *ui.Config) {
 	os.Exit(exitcode)
 }
 
+// launch loads the rom, the Famicom Disk System image or the NSF file, and
+// launches the emulator.
+func launch(rf romFile, cfg emu.Config) (*emu.Emulator, error) {
+	if isNSF(rf.name()) {
+		f, err := rf.readNSF()
+		if err != nil {
+			return nil, fmt.Errorf("error reading NSF file: %s", err)
+		}
+		return emu.LaunchNSF(f, cfg)
+	}
+	if isDiskImage(rf.name()) {
+		buf, err := rf.read()
+		if err != nil {
+			return nil, fmt.Errorf("error reading disk image: %s", err)
+		}
+		disk, err := fds.LoadDisk(buf, rf.name(), rf.savePath())
+		if err != nil {
+			return nil, fmt.Errorf("error reading disk image: %s", err)
+		}
+		return emu.LaunchFDS(disk, cfg)
+	}
+
+	rom, err := rf.readRom()
+	if err != nil {
+		return nil, fmt.Errorf("error reading ROM: %s", err)
+	}
+	cfg.SavePath = rf.savePath()
+	return emu.Launch(rom, cfg)
+}
+
+// isUNIF reports whether path is a rom in the UNIF format.
+func isUNIF(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".unf", ".unif":
+		return true
+	}
+	return false
+}
+
+// isDiskImage reports whether path is a Famicom Disk System image.
+func isDiskImage(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".fds", ".qd":
+		return true
+	}
+	return false
+}
+
+// isNSF reports whether path is a NSF or NSFe music file.
+func isNSF(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".nsf", ".nsfe":
+		return true
+	}
+	return false
+}
+
 func captureMain(args Capture) {
 	var (
 		code input.Code
@@ -0,0 +1,25 @@
+package tests
+
+import "encoding/binary"
+
+// NSF builds a NSF file image, with the given number of songs, expansion audio
+// chips, initial banks and program data, loaded at $8000. The INIT routine is
+// at $8000, the PLAY routine at the given address. The play rate is 60Hz.
+func NSF(songs int, expansion uint8, banks [8]uint8, play uint16, data []byte) []byte {
+	hdr := make([]byte, 0x80)
+	copy(hdr, "NESM\x1A")
+	hdr[0x05] = 1
+	hdr[0x06] = uint8(songs)
+	hdr[0x07] = 1
+	binary.LittleEndian.PutUint16(hdr[0x08:], 0x8000)
+	binary.LittleEndian.PutUint16(hdr[0x0A:], 0x8000)
+	binary.LittleEndian.PutUint16(hdr[0x0C:], play)
+	copy(hdr[0x0E:], "Test Song")
+	copy(hdr[0x2E:], "Test Artist")
+	copy(hdr[0x4E:], "2026 Nestor")
+	binary.LittleEndian.PutUint16(hdr[0x6E:], 16667)
+	copy(hdr[0x70:], banks[:])
+	binary.LittleEndian.PutUint16(hdr[0x78:], 20000)
+	hdr[0x7B] = expansion
+	return append(hdr, data...)
+}
@@ -0,0 +1,64 @@
+package tests
+
+// MapperRom builds an NES 2.0 rom image, meant to test the banking and IRQs of
+// the given mapper.
+//
+// Each 1 KB of PRG-ROM and CHR-ROM is filled with its index, as a little-endian
+// 16-bit number, so that the bank mapped at any address can be identified by
+// reading it. The end of the last PRG-ROM bank holds a program which disables
+// interrupts and loops forever, the reset vector points to it.
+func MapperRom(mapper uint16, submapper uint8, prgKB, chrKB int) []byte {
+	const kb = 1024
+
+	hdr := []byte{
+		'N', 'E', 'S', 0x1A,
+		uint8(prgKB / 16),
+		uint8(chrKB / 8),
+		uint8(mapper&0x0F) << 4,
+		uint8(mapper&0xF0) | 0x08, // NES 2.0
+		uint8(submapper)<<4 | uint8(mapper>>8&0x0F),
+		0, 0, 0, 0, 0, 0, 0,
+	}
+
+	prg := make([]byte, prgKB*kb)
+	fillBanks(prg)
+	chr := make([]byte, chrKB*kb)
+	fillBanks(chr)
+
+	// $FFF0: SEI
+	// $FFF1: JMP $FFF1
+	end := prg[len(prg)-16:]
+	copy(end, []byte{0x78, 0x4C, 0xF1, 0xFF})
+	// NMI, RESET and IRQ vectors.
+	copy(end[10:], []byte{0xF1, 0xFF, 0xF0, 0xFF, 0xF1, 0xFF})
+
+	return append(append(hdr, prg...), chr...)
+}
+
+// SetPRGRAMSize sets the PRG-RAM and battery-backed PRG-RAM sizes in the
+// header of a rom image built by MapperRom.
+func SetPRGRAMSize(img []byte, ramKB, nvramKB int) {
+	img[10] = ramShift(ramKB) | ramShift(nvramKB)<<4
+	if nvramKB > 0 {
+		img[6] |= 0x02 // battery
+	}
+}
+
+// ramShift encodes a RAM size as a NES 2.0 shift count (size = 64 << shift).
+func ramShift(kb int) uint8 {
+	if kb == 0 {
+		return 0
+	}
+	shift := uint8(0)
+	for 64<<shift < kb*1024 {
+		shift++
+	}
+	return shift
+}
+
+func fillBanks(mem []byte) {
+	for i := range mem {
+		bank := i / 1024
+		mem[i] = uint8(bank >> (8 * (i & 1)))
+	}
+}
@@ -36,6 +36,36 @@ var defaultConfig = Config{
 					PaddlePreset: 1,
 				},
 			},
+			FourScore: [2]input.PaddleConfig{
+				{
+					Plugged:      false,
+					PaddlePreset: 2,
+				},
+				{
+					Plugged:      false,
+					PaddlePreset: 3,
+				},
+			},
+			PowerPad: input.PowerPadConfig{
+				Buttons: [12]input.Code{
+					{Scancode: sdl.SCANCODE_R, Type: input.KeyboardCtrl},
+					{Scancode: sdl.SCANCODE_T, Type: input.KeyboardCtrl},
+					{Scancode: sdl.SCANCODE_Y, Type: input.KeyboardCtrl},
+					{Scancode: sdl.SCANCODE_U, Type: input.KeyboardCtrl},
+					{Scancode: sdl.SCANCODE_F, Type: input.KeyboardCtrl},
+					{Scancode: sdl.SCANCODE_G, Type: input.KeyboardCtrl},
+					{Scancode: sdl.SCANCODE_H, Type: input.KeyboardCtrl},
+					{Scancode: sdl.SCANCODE_J, Type: input.KeyboardCtrl},
+					{Scancode: sdl.SCANCODE_V, Type: input.KeyboardCtrl},
+					{Scancode: sdl.SCANCODE_B, Type: input.KeyboardCtrl},
+					{Scancode: sdl.SCANCODE_N, Type: input.KeyboardCtrl},
+					{Scancode: sdl.SCANCODE_M, Type: input.KeyboardCtrl},
+				},
+			},
+			Microphone: input.MicrophoneConfig{
+				Hotkey:    input.Code{Scancode: sdl.SCANCODE_M, Type: input.KeyboardCtrl},
+				Threshold: input.DefaultMicThreshold,
+			},
 			Presets: [8]input.PaddlePreset{
 				{
 					Buttons: [8]input.Code{
@@ -60,6 +90,10 @@ var defaultConfig = Config{
 		Audio: emu.AudioConfig{
 			DisableAudio: false,
 		},
+		Speed: emu.SpeedConfig{
+			FastForward: 300,
+			SlowMotion:  50,
+		},
 		TraceOut: nil,
 	},
 	General: GeneralConfig{
@@ -102,7 +136,9 @@ func LoadConfigOrDefault() Config {
 
 	// Apply post-load operations (fix invalid values, etc).
 	cfg.Input.PostLoad()
+	cfg.Hotkeys.PostLoad()
 	cfg.Video.Check()
+	cfg.Speed.Check()
 	log.ModEmu.Infof("Configuration loaded from %s", configPath())
 	return cfg
 }
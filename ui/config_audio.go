@@ -23,5 +23,11 @@ func buildAudioConfigPage(parent *gtk.Dialog, cfg *emu.AudioConfig, builder *gtk
 		cfg.DisableAudio = !state
 	})
 
+	n163mix := build[gtk.Switch](builder, "n163_mix_switch")
+	n163mix.SetActive(cfg.N163MixChannels)
+	n163mix.Connect("state-set", func(_ *gtk.Switch, state bool) {
+		cfg.N163MixChannels = state
+	})
+
 	return page
 }
@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/gotk3/gotk3/pango"
+
+	"nestor/hw"
+	"nestor/hw/input"
+)
+
+type hotkeysConfigPage struct {
+	parent *gtk.Dialog
+	cfg    *hw.HotkeyBindings
+
+	treeView  *gtk.TreeView
+	listStore *gtk.ListStore
+}
+
+func buildHotkeysConfigPage(parent *gtk.Dialog, cfg *hw.HotkeyBindings, builder *gtk.Builder) *hotkeysConfigPage {
+	page := &hotkeysConfigPage{
+		parent:    parent,
+		cfg:       cfg,
+		treeView:  build[gtk.TreeView](builder, "hotkeys_treeview"),
+		listStore: mustT(gtk.ListStoreNew(glib.TYPE_STRING, glib.TYPE_STRING, glib.TYPE_STRING)),
+	}
+
+	page.treeView.SetModel(page.listStore)
+	actioncell := mustT(gtk.CellRendererTextNew())
+	inputcell := mustT(gtk.CellRendererTextNew())
+	altcell := mustT(gtk.CellRendererTextNew())
+	actioncell.SetProperty("weight", pango.WEIGHT_BOLD)
+	actioncol := mustT(gtk.TreeViewColumnNewWithAttribute("Action", actioncell, "text", 0))
+	inputcol := mustT(gtk.TreeViewColumnNewWithAttribute("Input", inputcell, "text", 1))
+	altcol := mustT(gtk.TreeViewColumnNewWithAttribute("Alternate", altcell, "text", 2))
+	actioncol.SetExpand(true)
+	page.treeView.AppendColumn(actioncol)
+	page.treeView.AppendColumn(inputcol)
+	page.treeView.AppendColumn(altcol)
+
+	// Rows are in hotkey order.
+	page.treeView.Connect("row-activated", func(_ *gtk.TreeView, path *gtk.TreePath, col *gtk.TreeViewColumn) {
+		hk := hw.Hotkey(path.GetIndices()[0])
+		page.captureInput(hk, col.GetTitle() == "Alternate")
+	})
+
+	build[gtk.Button](builder, "hotkey_clear_btn").Connect("clicked", func() {
+		_, iter, ok := mustT(page.treeView.GetSelection()).GetSelected()
+		if !ok {
+			return
+		}
+		hk := hw.Hotkey(mustT(page.listStore.GetPath(iter)).GetIndices()[0])
+		if b := page.cfg.Binding(hk); b != nil {
+			b.Input = input.Code{}
+			b.Alt = input.Code{}
+		}
+		page.updateList()
+	})
+	build[gtk.Button](builder, "hotkeys_defaults_btn").Connect("clicked", func() {
+		*page.cfg = hw.DefaultHotkeys()
+		page.updateList()
+	})
+
+	page.updateList()
+	return page
+}
+
+// binding returns the binding of the given hotkey, adding it if missing.
+func (page *hotkeysConfigPage) binding(hk hw.Hotkey) *hw.HotkeyBinding {
+	if b := page.cfg.Binding(hk); b != nil {
+		return b
+	}
+	*page.cfg = append(*page.cfg, hw.HotkeyBinding{Hotkey: hk})
+	return page.cfg.Binding(hk)
+}
+
+func (page *hotkeysConfigPage) captureInput(hk hw.Hotkey, alt bool) {
+	text := hk.String()
+	if alt {
+		text += ", alternate"
+	}
+	captureCode(page.parent, text, func(code input.Code) {
+		b := page.binding(hk)
+		if alt {
+			b.Alt = code
+		} else {
+			b.Input = code
+		}
+		page.updateList()
+	})
+}
+
+func (page *hotkeysConfigPage) updateList() {
+	page.listStore.Clear()
+
+	for hk := range hw.HotkeyCount {
+		var in, alt string
+		if b := page.cfg.Binding(hk); b != nil {
+			in, alt = codeLabel(b.Input), codeLabel(b.Alt)
+		}
+		iter := page.listStore.Append()
+		must(page.listStore.Set(iter, []int{0, 1, 2}, []any{hk.String(), in, alt}))
+	}
+}
+
+// codeLabel returns the name of the input code, along with its type for game
+// controller inputs.
+func codeLabel(code input.Code) string {
+	switch code.Type {
+	case input.ButtonCtrl, input.AxisCtrl:
+		return fmt.Sprintf("%s (%s)", code.Name(), code.Type)
+	}
+	return code.Name()
+}
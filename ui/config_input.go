@@ -3,6 +3,7 @@ package ui
 import (
 	"fmt"
 	"math"
+	"slices"
 	"strconv"
 
 	"github.com/gotk3/gotk3/cairo"
@@ -18,10 +19,13 @@ type inputConfigPage struct {
 	parent *gtk.Dialog
 	cfg    *input.Config
 
-	drawArea  *gtk.DrawingArea
-	listStore *gtk.ListStore
-	plugcheck *gtk.CheckButton
-	bboxes    [input.PadButtonCount]aabbox
+	drawArea   *gtk.DrawingArea
+	listStore  *gtk.ListStore
+	plugcheck  *gtk.CheckButton
+	turboSpin  *gtk.SpinButton
+	macroView  *gtk.TreeView
+	macroStore *gtk.ListStore
+	bboxes     [numPadInputs]aabbox
 
 	devices   map[string]int // allows to give each joystick a number without using the GUID
 	curpad    int            // currently visible paddle
@@ -37,89 +41,182 @@ func buildInputConfigPage(parent *gtk.Dialog, cfg *input.Config, builder *gtk.Bu
 		devices:   map[string]int{"": 0},
 		plugcheck: build[gtk.CheckButton](builder, "plugged_chk"),
 		drawArea:  build[gtk.DrawingArea](builder, "paddle_drawing"),
-		listStore: mustT(gtk.ListStoreNew(glib.TYPE_STRING, glib.TYPE_STRING, glib.TYPE_STRING)),
+		listStore: mustT(gtk.ListStoreNew(glib.TYPE_STRING, glib.TYPE_STRING, glib.TYPE_STRING, glib.TYPE_STRING, glib.TYPE_STRING)),
+		turboSpin: build[gtk.SpinButton](builder, "turbo_rate_spin"),
 	}
-	radioPad1 := build[gtk.RadioButton](builder, "paddle1_radio")
-	radioPad2 := build[gtk.RadioButton](builder, "paddle2_radio")
 	treeView := build[gtk.TreeView](builder, "treeview")
 	presets := build[gtk.ComboBoxText](builder, "presets_combo")
 
 	treeView.SetModel(page.listStore)
+	btncell := mustT(gtk.CellRendererTextNew())
 	typecell := mustT(gtk.CellRendererTextNew())
 	namecell := mustT(gtk.CellRendererTextNew())
 	devcell := mustT(gtk.CellRendererTextNew())
+	altcell := mustT(gtk.CellRendererTextNew())
+	btncell.SetProperty("weight", pango.WEIGHT_BOLD)
 	typecell.SetProperty("weight", pango.WEIGHT_LIGHT)
 	namecell.SetProperty("weight", pango.WEIGHT_NORMAL)
 	devcell.SetProperty("weight", pango.WEIGHT_NORMAL)
-	typecol := mustT(gtk.TreeViewColumnNewWithAttribute("Type", typecell, "text", 0))
-	namecol := mustT(gtk.TreeViewColumnNewWithAttribute("Name", namecell, "text", 1))
-	devcol := mustT(gtk.TreeViewColumnNewWithAttribute("Device", devcell, "text", 2))
+	altcell.SetProperty("weight", pango.WEIGHT_NORMAL)
+	btncol := mustT(gtk.TreeViewColumnNewWithAttribute("Button", btncell, "text", 0))
+	typecol := mustT(gtk.TreeViewColumnNewWithAttribute("Type", typecell, "text", 1))
+	namecol := mustT(gtk.TreeViewColumnNewWithAttribute("Name", namecell, "text", 2))
+	devcol := mustT(gtk.TreeViewColumnNewWithAttribute("Device", devcell, "text", 3))
+	altcol := mustT(gtk.TreeViewColumnNewWithAttribute("Alternate", altcell, "text", 4))
+	treeView.AppendColumn(btncol)
 	treeView.AppendColumn(typecol)
 	treeView.AppendColumn(namecol)
 	treeView.AppendColumn(devcol)
+	treeView.AppendColumn(altcol)
 
 	page.drawArea.Connect("draw", page.onDrawPaddle)
 	presets.Connect("changed", page.onPresetChanged)
 	page.drawArea.Connect("button-press-event", page.onClick)
 	page.plugcheck.Connect("toggled", func(cb *gtk.CheckButton) {
-		page.cfg.Paddles[page.curpad].Plugged = cb.GetActive()
+		page.cfg.Paddle(page.curpad).Plugged = cb.GetActive()
 	})
-	radioPad1.Connect("clicked", func() {
-		page.curpad = 0
-		presets.SetActive(int(cfg.Paddles[page.curpad].PaddlePreset))
-	})
-	radioPad2.Connect("clicked", func() {
-		page.curpad = 1
-		presets.SetActive(int(cfg.Paddles[page.curpad].PaddlePreset))
+	page.turboSpin.Connect("value-changed", func(spin *gtk.SpinButton) {
+		page.cfg.Paddle(page.curpad).Preset.TurboRate = spin.GetValueAsInt()
 	})
+	for pad := range input.NumPaddles {
+		radio := build[gtk.RadioButton](builder, fmt.Sprintf("paddle%d_radio", pad+1))
+		radio.Connect("clicked", func() {
+			page.curpad = pad
+			presets.SetActive(int(cfg.Paddle(page.curpad).PaddlePreset))
+		})
+	}
 
-	presets.SetActive(int(cfg.Paddles[0].PaddlePreset))
+	page.buildMacroList(builder)
+	presets.SetActive(int(cfg.Paddle(0).PaddlePreset))
+	page.buildPortsCombos(builder)
 
 	return page
 }
 
+// buildPortsCombos fills the combo boxes selecting the device plugged in each
+// controller port.
+func (page *inputConfigPage) buildPortsCombos(builder *gtk.Builder) {
+	var combos [2]*gtk.ComboBoxText
+	for port := range combos {
+		combos[port] = build[gtk.ComboBoxText](builder, fmt.Sprintf("port%d_combo", port+1))
+		for dev := range input.DeviceCount {
+			combos[port].Append(strconv.Itoa(int(dev)), dev.String())
+		}
+		combos[port].SetActive(int(page.cfg.Ports[port]))
+	}
+
+	for port, combo := range combos {
+		other := combos[1-port]
+		combo.Connect("changed", func() {
+			dev := input.Device(combo.GetActive())
+			page.cfg.Ports[port] = dev
+
+			// The Four Score takes both ports.
+			switch {
+			case dev == input.DeviceFourScore && page.cfg.Ports[1-port] != input.DeviceFourScore:
+				other.SetActive(int(input.DeviceFourScore))
+			case dev != input.DeviceFourScore && page.cfg.Ports[1-port] == input.DeviceFourScore:
+				other.SetActive(int(input.DeviceController))
+			}
+		})
+	}
+}
+
 func (page *inputConfigPage) onPresetChanged(presets *gtk.ComboBoxText) {
-	page.cfg.Paddles[page.curpad].PaddlePreset = uint(presets.GetActive())
-	page.cfg.Paddles[page.curpad].Preset = &page.cfg.Presets[page.cfg.Paddles[page.curpad].PaddlePreset]
+	padcfg := page.cfg.Paddle(page.curpad)
+	padcfg.PaddlePreset = uint(presets.GetActive())
+	padcfg.Preset = &page.cfg.Presets[padcfg.PaddlePreset]
 	page.updatePaddleCfg()
 }
 
 func (page *inputConfigPage) updatePaddleCfg() {
-	page.plugcheck.SetActive(page.cfg.Paddles[page.curpad].Plugged)
+	padcfg := page.cfg.Paddle(page.curpad)
+	page.plugcheck.SetActive(padcfg.Plugged)
+	page.turboSpin.SetValue(float64(padcfg.Preset.TurboRate))
 	page.updatePropertyList()
+	page.updateMacroList()
+}
+
+// Paddle inputs which can be assigned: the 8 buttons plus the turbo buttons.
+const (
+	turboA = int(input.PadButtonCount) + iota
+	turboB
+
+	numPadInputs
+)
+
+func padInputName(in int) string {
+	switch in {
+	case turboA:
+		return "Turbo A"
+	case turboB:
+		return "Turbo B"
+	}
+	return input.PaddleButton(in).String()
+}
+
+// binding returns the code assigned to the given input of the current paddle,
+// or its alternate code. Turbo buttons don't have alternate codes.
+func (page *inputConfigPage) binding(in int, alt bool) *input.Code {
+	preset := page.cfg.Paddle(page.curpad).Preset
+	switch {
+	case in == turboA:
+		return &preset.TurboA
+	case in == turboB:
+		return &preset.TurboB
+	case alt:
+		return &preset.AltButtons[in]
+	}
+	return &preset.Buttons[in]
+}
+
+// deviceNum returns the number identifying the joystick with the given GUID,
+// or "" for the keyboard and mouse.
+func (page *inputConfigPage) deviceNum(guid string) string {
+	dev, ok := page.devices[guid]
+	if !ok {
+		dev = len(page.devices)
+		page.devices[guid] = dev
+	}
+	if dev == 0 {
+		return ""
+	}
+	return strconv.Itoa(dev)
 }
 
 func (page *inputConfigPage) updatePropertyList() {
 	page.listStore.Clear()
 
-	for btn := input.PadA; btn <= input.PadRight; btn++ {
+	for in := range numPadInputs {
 		iter := page.listStore.Append()
-		mapping := page.cfg.Paddles[page.curpad].Preset.Buttons[btn]
+		mapping := page.binding(in, false)
 
 		typ := mapping.Type.String()
 		name := mapping.Name()
-		dev, ok := page.devices[mapping.CtrlGUID]
-		if !ok {
-			dev = len(page.devices)
-			page.devices[mapping.CtrlGUID] = dev
-		}
-		devstr := ""
-		if dev > 0 {
-			devstr = strconv.Itoa(dev)
+		devstr := page.deviceNum(mapping.CtrlGUID)
+
+		altstr := ""
+		if in < turboA {
+			alt := page.binding(in, true)
+			altstr = alt.Name()
+			if dev := page.deviceNum(alt.CtrlGUID); dev != "" {
+				altstr += " (" + dev + ")"
+			}
 		}
 
-		must(page.listStore.Set(iter, []int{0, 1, 2}, []any{typ, name, devstr}))
+		must(page.listStore.Set(iter, []int{0, 1, 2, 3, 4}, []any{padInputName(in), typ, name, devstr, altstr}))
 	}
 }
 
-func (page *inputConfigPage) captureInput(btn input.PaddleButton) {
-	page.parent.ToWidget().SetSensitive(false)
+// captureCode shows the input capture window over parent, then calls assign
+// with the captured code, unless the capture has been cancelled.
+func captureCode(parent *gtk.Dialog, text string, assign func(code input.Code)) {
+	parent.ToWidget().SetSensitive(false)
 
 	glib.IdleAdd(func() {
-		text := fmt.Sprintf("%s (Paddle %d)", btn, page.curpad+1)
-		code, err := input.Capture(monitorIdx(mustT(page.parent.Window.GetWindow())), text)
+		code, err := input.Capture(monitorIdx(mustT(parent.Window.GetWindow())), text)
 
-		page.parent.ToWidget().SetSensitive(true)
+		parent.ToWidget().SetSensitive(true)
 
 		if err != nil {
 			gtk.MessageDialogNew(nil, gtk.DIALOG_MODAL, gtk.MESSAGE_ERROR, gtk.BUTTONS_OK, "Error: %s", err).Run()
@@ -129,25 +226,105 @@ func (page *inputConfigPage) captureInput(btn input.PaddleButton) {
 		if code.Type == input.ControlNotSet {
 			return
 		}
+		assign(code)
+	})
+}
 
-		page.cfg.Paddles[page.curpad].Preset.Buttons[btn] = code
+func (page *inputConfigPage) captureInput(in int, alt bool) {
+	text := fmt.Sprintf("%s (Paddle %d)", padInputName(in), page.curpad+1)
+	if alt && in < turboA {
+		text = fmt.Sprintf("%s, alternate (Paddle %d)", padInputName(in), page.curpad+1)
+	}
+	captureCode(page.parent, text, func(code input.Code) {
+		*page.binding(in, alt) = code
 		page.updatePropertyList()
 	})
 }
 
 func (page *inputConfigPage) onClick(da *gtk.DrawingArea, event *gdk.Event) {
-	x, y := gdk.EventButtonNewFromEvent(event).MotionVal()
+	ev := gdk.EventButtonNewFromEvent(event)
+	x, y := ev.MotionVal()
 	x /= page.drawScale
 	y /= page.drawScale
 
 	for i, bbox := range page.bboxes {
 		if bbox.contains(x, y) {
-			page.captureInput(input.PaddleButton(i))
+			page.captureInput(i, ev.Button() == gdk.BUTTON_SECONDARY)
 			return
 		}
 	}
 }
 
+// buildMacroList sets up the list of the macros of the current preset. Macro
+// sequences are edited in place, keys are captured.
+func (page *inputConfigPage) buildMacroList(builder *gtk.Builder) {
+	page.macroView = build[gtk.TreeView](builder, "macros_treeview")
+	page.macroStore = mustT(gtk.ListStoreNew(glib.TYPE_STRING, glib.TYPE_STRING))
+	page.macroView.SetModel(page.macroStore)
+
+	keycell := mustT(gtk.CellRendererTextNew())
+	seqcell := mustT(gtk.CellRendererTextNew())
+	seqcell.SetProperty("editable", true)
+	keycol := mustT(gtk.TreeViewColumnNewWithAttribute("Key", keycell, "text", 0))
+	seqcol := mustT(gtk.TreeViewColumnNewWithAttribute("Sequence", seqcell, "text", 1))
+	seqcol.SetExpand(true)
+	page.macroView.AppendColumn(keycol)
+	page.macroView.AppendColumn(seqcol)
+
+	seqcell.Connect("edited", func(_ *gtk.CellRendererText, path, text string) {
+		i, err := strconv.Atoi(path)
+		if err != nil {
+			return
+		}
+		var seq input.MacroSequence
+		if err := seq.UnmarshalText([]byte(text)); err != nil {
+			gtk.MessageDialogNew(nil, gtk.DIALOG_MODAL, gtk.MESSAGE_ERROR, gtk.BUTTONS_OK, "Invalid macro: %s", err).Run()
+			return
+		}
+		page.cfg.Paddle(page.curpad).Preset.Macros[i].Sequence = seq
+		page.updateMacroList()
+	})
+	page.macroView.Connect("row-activated", func(_ *gtk.TreeView, path *gtk.TreePath, col *gtk.TreeViewColumn) {
+		if col.GetTitle() != "Key" {
+			return
+		}
+		i := path.GetIndices()[0]
+		captureCode(page.parent, fmt.Sprintf("Macro %d (Paddle %d)", i+1, page.curpad+1), func(code input.Code) {
+			page.cfg.Paddle(page.curpad).Preset.Macros[i].Key = code
+			page.updateMacroList()
+		})
+	})
+
+	build[gtk.Button](builder, "macro_add_btn").Connect("clicked", func() {
+		preset := page.cfg.Paddle(page.curpad).Preset
+		text := fmt.Sprintf("Macro %d (Paddle %d)", len(preset.Macros)+1, page.curpad+1)
+		captureCode(page.parent, text, func(code input.Code) {
+			preset.Macros = append(preset.Macros, input.Macro{Key: code})
+			page.updateMacroList()
+		})
+	})
+	build[gtk.Button](builder, "macro_remove_btn").Connect("clicked", func() {
+		_, iter, ok := mustT(page.macroView.GetSelection()).GetSelected()
+		if !ok {
+			return
+		}
+		i := mustT(page.macroStore.GetPath(iter)).GetIndices()[0]
+		preset := page.cfg.Paddle(page.curpad).Preset
+		preset.Macros = slices.Delete(preset.Macros, i, i+1)
+		page.updateMacroList()
+	})
+}
+
+func (page *inputConfigPage) updateMacroList() {
+	page.macroStore.Clear()
+
+	for _, m := range page.cfg.Paddle(page.curpad).Preset.Macros {
+		seq, _ := m.Sequence.MarshalText()
+		iter := page.macroStore.Append()
+		must(page.macroStore.Set(iter, []int{0, 1}, []any{m.Key.Name(), string(seq)}))
+	}
+}
+
 func (page *inputConfigPage) onDrawPaddle(da *gtk.DrawingArea, cr *cairo.Context) {
 	cr.Scale(page.drawScale, page.drawScale)
 
@@ -232,6 +409,20 @@ func (page *inputConfigPage) onDrawPaddle(da *gtk.DrawingArea, cr *cairo.Context
 	page.bboxes[input.PadB] = aabbox{65, 24, 75, 34}
 	page.bboxes[input.PadA] = aabbox{77, 24, 87, 34}
 
+	// Turbo B/A panels and buttons, above B/A.
+	cr.SetSourceRGB(0.9, 0.9, 0.9)
+	roundedRect(cr, 66, 13, 8, 8, 1.5)
+	cr.Fill()
+	roundedRect(cr, 78, 13, 8, 8, 1.5)
+	cr.Fill()
+	cr.SetSourceRGB(0.6, 0, 0)
+	cr.Arc(70, 17, 3, 0, 2*math.Pi)
+	cr.Arc(82, 17, 3, 0, 2*math.Pi)
+	cr.Fill()
+
+	page.bboxes[turboB] = aabbox{66, 13, 74, 21}
+	page.bboxes[turboA] = aabbox{78, 13, 86, 21}
+
 	// B/A buttons.
 	cr.SetSourceRGB(1, 0, 0)
 	cr.Arc(70, 29, 4, 0, 2*math.Pi)
@@ -242,6 +433,11 @@ func (page *inputConfigPage) onDrawPaddle(da *gtk.DrawingArea, cr *cairo.Context
 	cr.ShowText("B")
 	cr.MoveTo(85, 37)
 	cr.ShowText("A")
+
+	cr.SetSourceRGB(0.9, 0, 0)
+	cr.SetFontSize(2.4)
+	cr.MoveTo(72, 11)
+	cr.ShowText("TURBO")
 }
 
 type arrowDir int
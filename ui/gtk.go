@@ -2,10 +2,13 @@ package ui
 
 import (
 	"fmt"
+	"slices"
 
 	"github.com/gotk3/gotk3/gdk"
 	"github.com/gotk3/gotk3/glib"
 	"github.com/gotk3/gotk3/gtk"
+
+	"nestor/archive"
 )
 
 func build[T glib.IObject, P *T](builder *gtk.Builder, name string) *T {
@@ -52,8 +55,10 @@ func openFileDialog(parent *gtk.Window, workdir string) (string, bool) {
 	defer dlg.Close()
 
 	filter := mustT(gtk.FileFilterNew())
-	filter.AddPattern("*.nes")
-	filter.SetName("nes/famicom ROM Files")
+	for _, ext := range slices.Concat(romExtensions, archiveExtensions) {
+		filter.AddPattern("*" + ext)
+	}
+	filter.SetName("nes/famicom ROM, disk and archive Files")
 	dlg.AddFilter(filter)
 	dlg.SetCurrentFolder(workdir)
 	if resp := dlg.Run(); resp != gtk.RESPONSE_OK {
@@ -62,6 +67,40 @@ func openFileDialog(parent *gtk.Window, workdir string) (string, bool) {
 	return dlg.GetFilename(), true
 }
 
+// chooseEntryDialog shows a dialog for selecting one of the roms stored in an
+// archive.
+func chooseEntryDialog(parent *gtk.Window, entries []archive.Entry) (string, bool) {
+	dlg := mustT(gtk.DialogNewWithButtons(
+		"Choose ROM",
+		parent,
+		gtk.DIALOG_MODAL|gtk.DIALOG_DESTROY_WITH_PARENT,
+		[]any{"Cancel", gtk.RESPONSE_CANCEL},
+		[]any{"Open", gtk.RESPONSE_OK},
+	))
+	defer dlg.Destroy()
+
+	combo := mustT(gtk.ComboBoxTextNew())
+	for _, e := range entries {
+		combo.AppendText(e.Name)
+	}
+	combo.SetActive(0)
+	combo.SetMarginTop(8)
+	combo.SetMarginBottom(8)
+	combo.SetMarginStart(8)
+	combo.SetMarginEnd(8)
+
+	area := mustT(dlg.GetContentArea())
+	area.Add(mustT(gtk.LabelNew("Several ROMs found in the archive:")))
+	area.Add(combo)
+	dlg.SetDefaultResponse(gtk.RESPONSE_OK)
+	dlg.ShowAll()
+
+	if resp := dlg.Run(); resp != gtk.RESPONSE_OK {
+		return "", false
+	}
+	return combo.GetActiveText(), true
+}
+
 func pixbufFromBytes(data []byte) (*gdk.Pixbuf, error) {
 	loader := mustT(gdk.PixbufLoaderNew())
 	if _, err := loader.Write(data); err != nil {
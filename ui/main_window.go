@@ -17,6 +17,7 @@ import (
 	"github.com/gotk3/gotk3/glib"
 	"github.com/gotk3/gotk3/gtk"
 
+	"nestor/archive"
 	"nestor/emu/log"
 	"nestor/emu/rpc"
 )
@@ -74,7 +75,7 @@ func showMainWindow(cfg *Config) {
 		if !ok {
 			return
 		}
-		mw.runROM(path)
+		mw.runROM(path, "")
 	})
 
 	onConfig := func(m *gtk.MenuItem) {
@@ -86,6 +87,7 @@ func showMainWindow(cfg *Config) {
 	build[gtk.MenuItem](builder, "menu_input").Connect("activate", onConfig)
 	build[gtk.MenuItem](builder, "menu_video").Connect("activate", onConfig)
 	build[gtk.MenuItem](builder, "menu_audio").Connect("activate", onConfig)
+	build[gtk.MenuItem](builder, "menu_hotkeys").Connect("activate", onConfig)
 }
 
 func (mw *mainWindow) Close(err error) {
@@ -97,13 +99,23 @@ func (mw *mainWindow) Close(err error) {
 	gtk.MainQuit()
 }
 
-func (mw *mainWindow) runROM(path string) {
+// runROM runs the rom at path. If path is an archive, entry is the rom to run
+// in it, if entry is empty the rom is looked for and the user is asked to
+// choose when there are several.
+func (mw *mainWindow) runROM(path, entry string) {
+	if entry == "" && archive.IsArchive(path) {
+		var ok bool
+		if entry, ok = mw.chooseEntry(path); !ok {
+			return
+		}
+	}
+
 	mw.SetSensitive(false)
 
 	monidx := monitorIdx(mustT(mw.GetWindow()))
 
 	panel := showGamePanel(mw.Window)
-	client, wait, err := driveEmulator(path, monidx)
+	client, wait, err := driveEmulator(path, entry, monidx)
 	if err != nil {
 		modGUI.WarnZ("failed to start rom").Error("err", err).End()
 		panel.Close()
@@ -125,12 +137,30 @@ func (mw *mainWindow) runROM(path string) {
 			panel.setGameStopped()
 			modGUI.DebugZ("closing game panel").End()
 			panel.Close()
-			mw.onRomStopped(path, client.TempDir())
+			mw.onRomStopped(path, entry, client.TempDir())
 		})
 	}()
 }
 
-func (mw *mainWindow) onRomStopped(rompath, tmpdir string) {
+// chooseEntry returns the archive entry to run.
+func (mw *mainWindow) chooseEntry(path string) (string, bool) {
+	entries, err := archive.List(path)
+	if err != nil {
+		modGUI.WarnZ("failed to open archive").Error("err", err).End()
+		return "", false
+	}
+	roms := RomEntries(entries)
+	switch len(roms) {
+	case 0:
+		modGUI.WarnZ("no rom found in archive").String("path", path).End()
+		return "", false
+	case 1:
+		return roms[0].Name, true
+	}
+	return chooseEntryDialog(mw.Window, roms)
+}
+
+func (mw *mainWindow) onRomStopped(rompath, entry, tmpdir string) {
 	f, err := os.Open(filepath.Join(tmpdir, "screenshot.png"))
 	if err != nil {
 		modGUI.Warnf("failed to read screenshot: %s", err)
@@ -143,33 +173,41 @@ func (mw *mainWindow) onRomStopped(rompath, tmpdir string) {
 		return
 	}
 
-	if err := mw.addRecentROM(rompath, img); err != nil {
+	if err := mw.addRecentROM(rompath, entry, img); err != nil {
 		modGUI.Warnf("failed to add recent ROM: %s", err)
 	}
 }
 
-func (mw *mainWindow) addRecentROM(romPath string, screenshot image.Image) error {
+func (mw *mainWindow) addRecentROM(romPath, entry string, screenshot image.Image) error {
 	bb := bytes.Buffer{}
 	if err := png.Encode(&bb, screenshot); err != nil {
 		return fmt.Errorf("failed to encode screenshot: %v", err)
 	}
 
+	name := filepath.Base(romPath)
+	if entry != "" {
+		name = filepath.Base(entry)
+	}
 	return mw.rrv.addROM(recentROM{
-		Name:     filepath.Base(romPath),
+		Name:     name,
 		Image:    bb.Bytes(),
 		Path:     romPath,
+		Entry:    entry,
 		LastUsed: time.Now(),
 	})
 }
 
 type waitFunc func() error
 
-func driveEmulator(rompath string, monidx int32) (*rpc.Client, waitFunc, error) {
+func driveEmulator(rompath, entry string, monidx int32) (*rpc.Client, waitFunc, error) {
 	port := rpc.UnusedPort()
 	args := []string{"run",
 		"--monitor", strconv.Itoa(int(monidx)),
-		"--port", strconv.Itoa(port),
-		rompath}
+		"--port", strconv.Itoa(port)}
+	if entry != "" {
+		args = append(args, "--entry", entry)
+	}
+	args = append(args, rompath)
 
 	cmd := exec.Command(mustT(os.Executable()), args...)
 	cmd.Stdout = os.Stdout
@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -83,7 +84,11 @@ func loadRecentROMs() []recentROM {
 				if err != nil {
 					return err
 				}
-				cur.Path = string(bytes.TrimSpace(buf))
+				// The rom path, followed by the archive entry on the 2nd
+				// line, if the rom is in an archive.
+				path, entry, _ := strings.Cut(string(bytes.TrimSpace(buf)), "\n")
+				cur.Path = path
+				cur.Entry = strings.TrimSpace(entry)
 			}
 		}
 
@@ -104,6 +109,7 @@ func loadRecentROMs() []recentROM {
 type recentROM struct {
 	Name     string
 	Path     string `json:"path"`
+	Entry    string `json:"entry"` // archive entry, if Path is an archive
 	Image    []byte `json:"image"`
 	LastUsed time.Time
 }
@@ -129,7 +135,11 @@ func (r recentROM) save() error {
 	if err != nil {
 		return err
 	}
-	if _, err := zfw.Write([]byte(r.Path)); err != nil {
+	infos := r.Path
+	if r.Entry != "" {
+		infos += "\n" + r.Entry
+	}
+	if _, err := zfw.Write([]byte(infos)); err != nil {
 		return err
 	}
 
@@ -158,10 +168,10 @@ type recentROMsView struct {
 	flowbox    *gtk.FlowBox
 	scroll     *gtk.ScrolledWindow
 	recentROMs []recentROM
-	runROM     func(string)
+	runROM     func(path, entry string)
 }
 
-func newRecentRomsView(builder *gtk.Builder, runROM func(path string)) *recentROMsView {
+func newRecentRomsView(builder *gtk.Builder, runROM func(path, entry string)) *recentROMsView {
 	v := &recentROMsView{
 		runROM:     runROM,
 		recentROMs: loadRecentROMs(),
@@ -249,8 +259,8 @@ func (v *recentROMsView) updateView() {
 		label.SetVisible(true)
 		img.SetVisible(true)
 
-		button.Connect("clicked", func() { v.runROM(rom.Path) })
-		child.Connect("activate", func() { v.runROM(rom.Path) })
+		button.Connect("clicked", func() { v.runROM(rom.Path, rom.Entry) })
+		child.Connect("activate", func() { v.runROM(rom.Path, rom.Entry) })
 		return nil
 	}
 
@@ -0,0 +1,34 @@
+package ui
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"nestor/archive"
+)
+
+// romExtensions are the extensions of the rom, disk image and music files
+// nestor can run.
+var romExtensions = []string{".nes", ".unf", ".unif", ".fds", ".qd", ".nsf", ".nsfe"}
+
+// archiveExtensions are the extensions of the archives nestor can read roms
+// from.
+var archiveExtensions = []string{".zip", ".gz", ".7z"}
+
+// IsRomFile reports whether name is a rom, or a disk image, based on its
+// extension.
+func IsRomFile(name string) bool {
+	return slices.Contains(romExtensions, strings.ToLower(filepath.Ext(name)))
+}
+
+// RomEntries returns the archive entries which are roms or disk images.
+func RomEntries(entries []archive.Entry) []archive.Entry {
+	var roms []archive.Entry
+	for _, e := range entries {
+		if IsRomFile(e.Name) {
+			roms = append(roms, e)
+		}
+	}
+	return roms
+}
@@ -0,0 +1,138 @@
+package unif
+
+import "strings"
+
+// boardPrefixes are the prefixes of UNIF board names which tell where the
+// board comes from, rather than what it is.
+var boardPrefixes = []string{"NES-", "HVC-", "UNL-", "BMC-", "BTL-"}
+
+// BoardName normalizes an UNIF board name: its origin prefix (NES-, HVC-,
+// UNL-, BMC- or BTL-) is removed and it is converted to upper case.
+func BoardName(board string) string {
+	board = strings.ToUpper(strings.TrimSpace(board))
+	for _, prefix := range boardPrefixes {
+		if after, ok := strings.CutPrefix(board, prefix); ok {
+			return after
+		}
+	}
+	return board
+}
+
+type inesBoard struct {
+	mapper    uint16
+	submapper uint8
+}
+
+// inesBoards maps normalized UNIF board names to their iNES mapper and
+// submapper numbers.
+var inesBoards = map[string]inesBoard{
+	"NROM":     {0, 0},
+	"NROM-128": {0, 0},
+	"NROM-256": {0, 0},
+	"RROM":     {0, 0},
+	"RROM-128": {0, 0},
+
+	"SAROM":  {1, 0},
+	"SBROM":  {1, 0},
+	"SCROM":  {1, 0},
+	"SC1ROM": {1, 0},
+	"SEROM":  {1, 5},
+	"SFROM":  {1, 0},
+	"SGROM":  {1, 0},
+	"SHROM":  {1, 5},
+	"SH1ROM": {1, 5},
+	"SJROM":  {1, 0},
+	"SKROM":  {1, 0},
+	"SLROM":  {1, 0},
+	"SL1ROM": {1, 0},
+	"SL2ROM": {1, 0},
+	"SL3ROM": {1, 0},
+	"SLRROM": {1, 0},
+	"SNROM":  {1, 0},
+	"SOROM":  {1, 0},
+	"SUROM":  {1, 0},
+	"SXROM":  {1, 0},
+
+	"UNROM": {2, 0},
+	"UOROM": {2, 0},
+
+	"CNROM": {3, 0},
+
+	"HKROM":  {4, 0},
+	"TBROM":  {4, 0},
+	"TEROM":  {4, 0},
+	"TFROM":  {4, 0},
+	"TGROM":  {4, 0},
+	"TKROM":  {4, 0},
+	"TLROM":  {4, 0},
+	"TL1ROM": {4, 0},
+	"TL2ROM": {4, 0},
+	"TNROM":  {4, 0},
+	"TR1ROM": {4, 0},
+	"TSROM":  {4, 0},
+	"TVROM":  {4, 0},
+
+	"EKROM": {5, 0},
+	"ELROM": {5, 0},
+	"ETROM": {5, 0},
+	"EWROM": {5, 0},
+
+	"AMROM":  {7, 0},
+	"ANROM":  {7, 0},
+	"AN1ROM": {7, 0},
+	"AOROM":  {7, 0},
+
+	"PEEOROM": {9, 0},
+	"PNROM":   {9, 0},
+
+	"FJROM": {10, 0},
+	"FKROM": {10, 0},
+
+	"CPROM": {13, 0},
+
+	"UNROM-512-8K":  {30, 0},
+	"UNROM-512-16K": {30, 0},
+	"UNROM-512-32K": {30, 0},
+
+	"BNROM": {34, 2},
+
+	"GNROM": {66, 0},
+	"MHROM": {66, 0},
+
+	"NTBROM": {68, 0},
+
+	"BTR":   {69, 0},
+	"JLROM": {69, 0},
+	"JSROM": {69, 0},
+
+	"UN1ROM": {94, 0},
+
+	"CHEAPOCABRA": {111, 0},
+
+	"TKSROM": {118, 0},
+	"TLSROM": {118, 0},
+
+	"TQROM": {119, 0},
+
+	"SACHEN-8259D":    {137, 0},
+	"SACHEN-8259B":    {138, 0},
+	"SACHEN-8259C":    {139, 0},
+	"SACHEN-8259A":    {141, 0},
+	"TC-U01-1.5M":     {147, 0},
+	"SA-0037":         {148, 0},
+	"SA-0036":         {149, 0},
+	"SACHEN-74LS374N": {150, 0},
+
+	"DEROM":  {206, 0},
+	"DE1ROM": {206, 0},
+	"DRROM":  {206, 0},
+
+	"SMB2J": {304, 0},
+}
+
+// Mapper returns the iNES mapper and submapper numbers of an UNIF board, ok
+// is false if the board has no known iNES equivalent.
+func Mapper(board string) (mapper uint16, submapper uint8, ok bool) {
+	b, ok := inesBoards[BoardName(board)]
+	return b.mapper, b.submapper, ok
+}
@@ -0,0 +1,317 @@
+// package unif implements a Reader for roms in the UNIF file format. UNIF
+// identifies cartridge boards by name rather than by number, it is mostly used
+// for unlicensed and multicart boards.
+package unif
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"nestor/ines"
+)
+
+const Magic = "UNIF"
+
+const headerSize = 32
+
+// Mirroring is the nametable mirroring, as found in the MIRR chunk.
+type Mirroring uint8
+
+const (
+	MirrHorz       Mirroring = iota // hard-wired horizontal mirroring
+	MirrVert                        // hard-wired vertical mirroring
+	MirrOneScreenA                  // hard-wired one-screen, CIRAM page A
+	MirrOneScreenB                  // hard-wired one-screen, CIRAM page B
+	MirrFourScreen                  // 4 nametables, extra VRAM on the cartridge
+	MirrMapper                      // controlled by the mapper
+)
+
+func (m Mirroring) String() string {
+	switch m {
+	case MirrHorz:
+		return "horizontal"
+	case MirrVert:
+		return "vertical"
+	case MirrOneScreenA:
+		return "one-screen A"
+	case MirrOneScreenB:
+		return "one-screen B"
+	case MirrFourScreen:
+		return "four-screen"
+	case MirrMapper:
+		return "mapper"
+	}
+	return fmt.Sprintf("Mirroring(%d)", uint8(m))
+}
+
+// File is a decoded UNIF file.
+type File struct {
+	Revision uint32
+	Board    string // Board name (MAPR), as found in the file.
+	Title    string // Game title (NAME).
+	Comment  string // Free text (READ).
+
+	// Dump infos (DINF).
+	Dumper    string
+	DumpAgent string
+	DumpYear  int
+	DumpMonth int
+	DumpDay   int
+
+	TVSystem    uint8 // TVCI: 0 NTSC, 1 PAL, 2 both.
+	Controllers uint8 // CTRL: bitfield of supported controllers.
+	Mirroring   Mirroring
+	Battery     bool // BATR: PRG-RAM is battery-backed.
+	CHRRAM      bool // VROR: CHR is RAM, even if there's CHR data.
+
+	PRGROM []byte // PRG0 to PRGF chunks, concatenated in order.
+	CHRROM []byte // CHR0 to CHRF chunks, concatenated in order.
+
+	Name string // file name
+}
+
+// ReadFile loads a rom from an UNIF file.
+func ReadFile(path string) (*File, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := Decode(buf)
+	if err != nil {
+		return nil, err
+	}
+	f.Name = filepath.Base(path)
+	return f, nil
+}
+
+// Decode decodes the given buffer into an UNIF file.
+func Decode(buf []byte) (*File, error) {
+	if len(buf) < headerSize {
+		return nil, fmt.Errorf("too small, needs %d bytes", headerSize)
+	}
+	if string(buf[:4]) != Magic {
+		return nil, fmt.Errorf("invalid magic number")
+	}
+
+	f := &File{Revision: binary.LittleEndian.Uint32(buf[4:])}
+	var prg, chr [16][]byte
+
+	off := headerSize
+	for off < len(buf) {
+		if len(buf) < off+8 {
+			return nil, fmt.Errorf("truncated chunk header at offset %d", off)
+		}
+		id := string(buf[off : off+4])
+		size := int(binary.LittleEndian.Uint32(buf[off+4:]))
+		off += 8
+		if size > len(buf)-off {
+			return nil, fmt.Errorf("truncated %s chunk at offset %d", id, off-8)
+		}
+		data := buf[off : off+size]
+		off += size
+
+		switch {
+		case id == "MAPR":
+			f.Board = cstring(data)
+		case id == "NAME":
+			f.Title = cstring(data)
+		case id == "READ":
+			f.Comment = cstring(data)
+		case id == "DINF":
+			if len(data) < 204 {
+				return nil, fmt.Errorf("DINF chunk too small: %d bytes", len(data))
+			}
+			f.Dumper = cstring(data[:100])
+			f.DumpDay = int(data[100])
+			f.DumpMonth = int(data[101])
+			f.DumpYear = int(binary.LittleEndian.Uint16(data[102:]))
+			f.DumpAgent = cstring(data[104:204])
+		case id == "TVCI":
+			f.TVSystem = byteAt(data)
+		case id == "CTRL":
+			f.Controllers = byteAt(data)
+		case id == "MIRR":
+			f.Mirroring = Mirroring(byteAt(data))
+		case id == "BATR":
+			f.Battery = byteAt(data) != 0
+		case id == "VROR":
+			f.CHRRAM = byteAt(data) != 0
+		case strings.HasPrefix(id, "PRG"):
+			if i, ok := chunkIndex(id); ok {
+				prg[i] = data
+			}
+		case strings.HasPrefix(id, "CHR"):
+			if i, ok := chunkIndex(id); ok {
+				chr[i] = data
+			}
+		}
+		// Other chunks (PCKx, CCKx, etc.) are ignored.
+	}
+
+	for i := range 16 {
+		f.PRGROM = append(f.PRGROM, prg[i]...)
+		f.CHRROM = append(f.CHRROM, chr[i]...)
+	}
+
+	if f.Board == "" {
+		return nil, fmt.Errorf("missing MAPR chunk")
+	}
+	if len(f.PRGROM) == 0 {
+		return nil, fmt.Errorf("missing PRG chunk")
+	}
+	return f, nil
+}
+
+// chunkIndex returns the index of a PRGx or CHRx chunk, x being an
+// hexadecimal digit.
+func chunkIndex(id string) (int, bool) {
+	c := id[3]
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0'), true
+	case 'A' <= c && c <= 'F':
+		return int(c-'A') + 10, true
+	}
+	return 0, false
+}
+
+// cstring returns the null-terminated string at the start of b.
+func cstring(b []byte) string {
+	if i := strings.IndexByte(string(b), 0); i != -1 {
+		b = b[:i]
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func byteAt(data []byte) uint8 {
+	if len(data) == 0 {
+		return 0
+	}
+	return data[0]
+}
+
+// Rom converts the UNIF file into an iNES rom. The board is translated to its
+// iNES mapper and submapper numbers, when it has some. In any case the
+// normalized board name is kept in Rom.Board.
+//
+// PRG-ROM and CHR-ROM are mirrored up to a power of 2 size, PRG-RAM is assumed
+// to be 8 KB. CHR-RAM (8 KB) is used when there's no CHR-ROM.
+func (f *File) Rom() (*ines.Rom, error) {
+	board := BoardName(f.Board)
+	mapper, submapper, _ := Mapper(board)
+
+	prg := mirrorPow2(f.PRGROM, 16*1024)
+	var chr []byte
+	if len(f.CHRROM) > 0 {
+		chr = mirrorPow2(f.CHRROM, 8*1024)
+	}
+	nprg, nchr := len(prg)/(16*1024), len(chr)/(8*1024)
+
+	hdr := make([]byte, 16)
+	copy(hdr, ines.Magic)
+	hdr[4] = uint8(nprg)
+	hdr[5] = uint8(nchr)
+	hdr[6] = uint8(mapper&0x0F) << 4
+	hdr[7] = uint8(mapper&0xF0) | 0x08 // NES 2.0
+	hdr[8] = submapper<<4 | uint8(mapper>>8&0x0F)
+	hdr[9] = uint8(nchr>>8&0x0F)<<4 | uint8(nprg>>8&0x0F)
+
+	switch f.Mirroring {
+	case MirrVert:
+		hdr[6] |= 0x01
+	case MirrFourScreen:
+		hdr[6] |= 0x08
+	}
+
+	// PRG-RAM, 8 KB (64 << 7).
+	if f.Battery {
+		hdr[6] |= 0x02
+		hdr[10] = 7 << 4
+	} else {
+		hdr[10] = 7
+	}
+	if len(chr) == 0 {
+		hdr[11] = 7 // 8 KB CHR-RAM
+	}
+	if f.TVSystem <= 2 {
+		hdr[12] = f.TVSystem
+	}
+
+	img := make([]byte, 0, len(hdr)+len(prg)+len(chr))
+	img = append(append(append(img, hdr...), prg...), chr...)
+	rom, err := ines.Decode(img)
+	if err != nil {
+		return nil, err
+	}
+	rom.Name = f.Name
+	rom.Board = board
+	switch f.Mirroring {
+	case MirrOneScreenA:
+		rom.OneScreen = ines.OnlyAScreen
+	case MirrOneScreenB:
+		rom.OneScreen = ines.OnlyBScreen
+	}
+	return rom, nil
+}
+
+// mirrorPow2 repeats mem up to the next power of 2 size, at least minSize bytes.
+func mirrorPow2(mem []byte, minSize int) []byte {
+	size := minSize
+	for size < len(mem) {
+		size <<= 1
+	}
+	if size == len(mem) {
+		return mem
+	}
+	out := make([]byte, size)
+	for i := 0; i < size; i += len(mem) {
+		copy(out[i:], mem)
+	}
+	return out
+}
+
+func (f *File) PrintInfos(w io.Writer) {
+	mapper, submapper, ok := Mapper(f.Board)
+	tv := [...]string{"NTSC", "PAL", "NTSC/PAL"}
+
+	fmt.Fprintf(w, "%s\n", f.Name)
+	fmt.Fprintf(w, "|UNIF revision          | % 14d |\n", f.Revision)
+	fmt.Fprintf(w, "|Board                  | % 14s |\n", f.Board)
+	if ok {
+		fmt.Fprintf(w, "|Mapper                 | % 14d |\n", mapper)
+		fmt.Fprintf(w, "|Submapper              | % 14d |\n", submapper)
+	}
+	if f.Title != "" {
+		fmt.Fprintf(w, "|Title                  | % 14s |\n", f.Title)
+	}
+	fmt.Fprintf(w, "|PRG ROM                | % 13dk |\n", len(f.PRGROM)/1024)
+	fmt.Fprintf(w, "|CHR ROM                | % 13dk |\n", len(f.CHRROM)/1024)
+	fmt.Fprintf(w, "|CHR RAM                | % 14s |\n", yn(f.CHRRAM || len(f.CHRROM) == 0))
+	fmt.Fprintf(w, "|Nametable mirroring    | % 14s |\n", f.Mirroring)
+	fmt.Fprintf(w, "|Persistent             | % 14s |\n", yn(f.Battery))
+	if int(f.TVSystem) < len(tv) {
+		fmt.Fprintf(w, "|TV system              | % 14s |\n", tv[f.TVSystem])
+	}
+	if f.Dumper != "" {
+		fmt.Fprintf(w, "|Dumped by              | % 14s |\n", f.Dumper)
+		fmt.Fprintf(w, "|Dump date              |     %04d-%02d-%02d |\n", f.DumpYear, f.DumpMonth, f.DumpDay)
+	}
+	if f.DumpAgent != "" {
+		fmt.Fprintf(w, "|Dump agent             | % 14s |\n", f.DumpAgent)
+	}
+	if f.Comment != "" {
+		fmt.Fprintf(w, "\n%s\n", f.Comment)
+	}
+}
+
+func yn(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,175 @@
+package unif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"nestor/ines"
+)
+
+func chunk(id string, data []byte) []byte {
+	hdr := make([]byte, 8)
+	copy(hdr, id)
+	binary.LittleEndian.PutUint32(hdr[4:], uint32(len(data)))
+	return append(hdr, data...)
+}
+
+func testFile(board string, prg ...[]byte) []byte {
+	hdr := make([]byte, headerSize)
+	copy(hdr, Magic)
+	hdr[4] = 7
+
+	dinf := make([]byte, 204)
+	copy(dinf, "dumper")
+	dinf[100], dinf[101] = 24, 12
+	binary.LittleEndian.PutUint16(dinf[102:], 2005)
+	copy(dinf[104:], "agent")
+
+	chunks := [][]byte{
+		hdr,
+		chunk("MAPR", append([]byte(board), 0)),
+		chunk("NAME", []byte("Test game\x00")),
+		chunk("DINF", dinf),
+		chunk("MIRR", []byte{1}),
+		chunk("BATR", []byte{1}),
+		chunk("TVCI", []byte{1}),
+		chunk("CHR0", bytes.Repeat([]byte{0xC0}, 8*1024)),
+	}
+	// PRG chunks are stored in reverse order, they must be sorted by number.
+	for i := len(prg) - 1; i >= 0; i-- {
+		chunks = append(chunks, chunk("PRG"+string(rune('0'+i)), prg[i]))
+	}
+	return bytes.Join(chunks, nil)
+}
+
+func TestDecode(t *testing.T) {
+	prg0 := bytes.Repeat([]byte{0x10}, 16*1024)
+	prg1 := bytes.Repeat([]byte{0x11}, 16*1024)
+
+	f, err := Decode(testFile("NES-SNROM", prg0, prg1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Revision != 7 || f.Board != "NES-SNROM" || f.Title != "Test game" {
+		t.Errorf("got revision %d, board %q, title %q", f.Revision, f.Board, f.Title)
+	}
+	if f.Dumper != "dumper" || f.DumpAgent != "agent" || f.DumpYear != 2005 || f.DumpMonth != 12 || f.DumpDay != 24 {
+		t.Errorf("got dump infos %q %q %d-%d-%d", f.Dumper, f.DumpAgent, f.DumpYear, f.DumpMonth, f.DumpDay)
+	}
+	if f.Mirroring != MirrVert || !f.Battery || f.TVSystem != 1 {
+		t.Errorf("got mirroring %s, battery %t, tv %d", f.Mirroring, f.Battery, f.TVSystem)
+	}
+	if !bytes.Equal(f.PRGROM, append(prg0, prg1...)) {
+		t.Errorf("PRG chunks not concatenated in order")
+	}
+	if len(f.CHRROM) != 8*1024 {
+		t.Errorf("got %d bytes of CHR, want 8 KB", len(f.CHRROM))
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	prg := make([]byte, 16*1024)
+	valid := testFile("NROM", prg)
+
+	tests := []struct {
+		name string
+		buf  []byte
+	}{
+		{"too small", valid[:16]},
+		{"bad magic", append([]byte("NES\x1a"), valid[4:]...)},
+		{"truncated chunk", valid[:len(valid)-1]},
+		{"no board", testFile("", prg)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(tt.buf); err == nil {
+				t.Errorf("Decode succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestRom(t *testing.T) {
+	tests := []struct {
+		board     string
+		prgKB     int
+		mapper    uint16
+		submapper uint8
+		normName  string
+	}{
+		{"NES-SNROM", 32, 1, 0, "SNROM"},
+		{"HVC-SEROM", 32, 1, 5, "SEROM"},
+		{"NES-BNROM", 64, 34, 2, "BNROM"},
+		{"NES-NROM-128", 8, 0, 0, "NROM-128"}, // 8 KB PRG is mirrored
+		{"UNL-Sachen-8259A", 64, 141, 0, "SACHEN-8259A"},
+		{"UNL-SOMETHING", 48, 0, 0, "SOMETHING"}, // 48 KB PRG is mirrored
+	}
+	for _, tt := range tests {
+		t.Run(tt.board, func(t *testing.T) {
+			prg := make([]byte, tt.prgKB*1024)
+			prg[0] = 0xAB
+			f, err := Decode(testFile(tt.board, prg))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rom, err := f.Rom()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if rom.Board != tt.normName {
+				t.Errorf("Board = %q, want %q", rom.Board, tt.normName)
+			}
+			if rom.Mapper() != tt.mapper || rom.SubMapper() != tt.submapper {
+				t.Errorf("mapper = %d.%d, want %d.%d", rom.Mapper(), rom.SubMapper(), tt.mapper, tt.submapper)
+			}
+			if n := len(rom.PRGROM); n&(n-1) != 0 || n < 16*1024 {
+				t.Errorf("PRG-ROM is %d bytes, want a power of 2, at least 16 KB", n)
+			}
+			for off := 0; off < len(rom.PRGROM); off += len(prg) {
+				if rom.PRGROM[off] != 0xAB {
+					t.Errorf("PRG-ROM not mirrored at offset %d", off)
+				}
+			}
+			if rom.Mirroring() != ines.VertMirroring {
+				t.Errorf("Mirroring() = %s, want vertical", rom.Mirroring())
+			}
+			if !rom.HasPersistence() || rom.PRGNVRAMSize() != 8*1024 {
+				t.Errorf("want 8 KB battery-backed PRG-RAM, got %d", rom.PRGNVRAMSize())
+			}
+			if rom.Region() != ines.PAL {
+				t.Errorf("Region() = %s, want PAL", rom.Region())
+			}
+		})
+	}
+}
+
+func TestRomMirroring(t *testing.T) {
+	tests := []struct {
+		mirr Mirroring
+		want ines.NTMirroring
+	}{
+		{MirrHorz, ines.HorzMirroring},
+		{MirrVert, ines.VertMirroring},
+		{MirrOneScreenA, ines.OnlyAScreen},
+		{MirrOneScreenB, ines.OnlyBScreen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mirr.String(), func(t *testing.T) {
+			f, err := Decode(testFile("NES-NROM-256", make([]byte, 32*1024)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.Mirroring = tt.mirr
+			rom, err := f.Rom()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rom.Mirroring(); got != tt.want {
+				t.Errorf("Mirroring() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}